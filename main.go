@@ -1,13 +1,75 @@
 package main
 
 import (
+  "JFFMonkeyLang/src/analysis"
+  "JFFMonkeyLang/src/evaluator"
+  "JFFMonkeyLang/src/format"
+  "JFFMonkeyLang/src/lexer"
+  "JFFMonkeyLang/src/object"
+  "JFFMonkeyLang/src/parser"
   "JFFMonkeyLang/src/repl"
+  "flag"
   "fmt"
+  "io"
   "os"
   "os/user"
+  "strings"
+  "time"
 )
 
+// with -time, how long each phase of a run took is printed to stderr
+var reportTime = false
+
+// with -lint, the warnings of analysis go to stderr before a run
+var lint = false
+
+// with -strict, a function ending without a return is an error
+var strict = false
+
+// with -sandbox, builtins touching files are disabled
+var sandbox = false
+
+// what runs and -time go by, tests swap in a fake one
+var clock object.Clock = object.SystemClock
+
+// monkey                     start the repl
+// monkey program.monkey      run a file
+// monkey -e "1 + 2"          eval an inline string and print the result
+// monkey fmt program.monkey  print the file formatted
+// monkey -sandbox ...        run without file access
+// monkey --engine=vm         start the repl on the bytecode vm
+// monkey -time program.monkey  also print the parse and eval times to stderr
+// monkey -lint program.monkey  warn about likely mistakes before running
 func main() {
+  expr := flag.String("e", "", "evaluate `expr` and print the result")
+  flag.BoolVar(&strict, "strict", false, "report functions that end without a return")
+  flag.BoolVar(&sandbox, "sandbox", false, "disable builtins that touch files")
+  flag.StringVar(&repl.Engine, "engine", "eval", "run the repl on `backend`: eval or vm")
+  flag.BoolVar(&reportTime, "time", false, "print how long parsing and evaluation took to stderr")
+  flag.BoolVar(&lint, "lint", false, "warn about likely mistakes before running")
+  flag.Parse()
+
+  if repl.Engine != "eval" && repl.Engine != "vm" {
+    fmt.Fprintf(os.Stderr, "unknown engine %q, want eval or vm\n", repl.Engine)
+    os.Exit(2)
+  }
+
+  if *expr != "" {
+    os.Exit(runSource("-e", *expr, os.Stdout, os.Stderr, true))
+  }
+
+  if flag.Arg(0) == "fmt" {
+    if flag.NArg() != 2 {
+      fmt.Fprintln(os.Stderr, "usage: monkey fmt <file>")
+      os.Exit(2)
+    }
+    os.Exit(runFmt(flag.Arg(1), os.Stdout, os.Stderr))
+  }
+
+  if flag.NArg() > 0 {
+    os.Exit(runFile(flag.Arg(0), os.Stdout, os.Stderr))
+  }
+
   user, err := user.Current()
   if err != nil {
     panic(err)
@@ -15,5 +77,144 @@ func main() {
 
   fmt.Printf("Hello %s! This is the Monkey programming language!\n", user.Username)
   fmt.Print("Feel free to type in commands\n")
+  repl.Strict, repl.Sandbox = strict, sandbox
   repl.Start(os.Stdin, os.Stdout)
 }
+
+func runFile(path string, stdout, stderr io.Writer) int {
+  f, err := os.Open(path)
+  if err != nil {
+    fmt.Fprintf(stderr, "could not read %s: %s\n", path, err)
+    return 1
+  }
+  defer f.Close()
+
+  return runReader(path, f, stdout, stderr, false)
+}
+
+// parse the file and print it formatted to stdout,
+// the file itself is left untouched
+func runFmt(path string, stdout, stderr io.Writer) int {
+  content, err := os.ReadFile(path)
+  if err != nil {
+    fmt.Fprintf(stderr, "could not read %s: %s\n", path, err)
+    return 1
+  }
+
+  l := lexer.New(string(content))
+  p := parser.New(l)
+  program := p.ParseProgram()
+
+  if len(p.Errors()) != 0 {
+    for _, msg := range p.Errors() {
+      fmt.Fprintf(stderr, "%s:%s\n", path, msg)
+    }
+    return 1
+  }
+
+  io.WriteString(stdout, format.Source(program))
+  return 0
+}
+
+// lex, parse and eval src once, errors go to stderr,
+// the return value is the process exit code
+func runSource(name, src string, stdout, stderr io.Writer, printResult bool) int {
+  return runReader(name, strings.NewReader(src), stdout, stderr, printResult)
+}
+
+// same as runSource, the source is read from src as it is lexed
+func runReader(name string, src io.Reader, stdout, stderr io.Writer, printResult bool) int {
+  timer := startTimer()
+  if reportTime {
+    defer timer.report(stderr)
+  }
+
+  // the parser pulls the tokens, so reading and lexing are timed with parsing
+  l := lexer.NewReader(src)
+  p := parser.New(l)
+  program := p.ParseProgram()
+  timer.done("parse")
+
+  // reading the source failed part way
+  if l.Err() != nil {
+    fmt.Fprintf(stderr, "could not read %s: %s\n", name, l.Err())
+    return 1
+  }
+
+  // 1.parser errors, eg: program.monkey:1:5: expected ...
+  if len(p.Errors()) != 0 {
+    for _, msg := range p.Errors() {
+      fmt.Fprintf(stderr, "%s:%s\n", name, msg)
+    }
+    return 1
+  }
+
+  // warnings don't stop the run, eg: program.monkey:1:5: x declared and not used
+  if lint {
+    for _, warning := range analysis.Lint(program) {
+      fmt.Fprintf(stderr, "%s:%s\n", name, warning)
+    }
+  }
+
+  // 2.macro expansion errors, eg: program.monkey: ERROR: macro must return a QUOTE ...
+  macroEnv := newEnvironment()
+  evaluator.DefineMacros(program, macroEnv)
+  expanded, err := evaluator.ExpandMacros(program, macroEnv)
+  if err != nil {
+    fmt.Fprintf(stderr, "%s: %s\n", name, err.Inspect())
+    return 1
+  }
+
+  // 3.runtime errors, eg: program.monkey: ERROR: type mismatch ...
+  evaluated := evaluator.Eval(expanded, newEnvironment())
+  // macro expansion included
+  timer.done("eval")
+  if evaluated != nil && evaluated.Type() == object.ERROR_OBJ {
+    fmt.Fprintf(stderr, "%s: %s\n", name, evaluated.Inspect())
+    return 1
+  }
+
+  if printResult && evaluated != nil {
+    fmt.Fprintln(stdout, evaluated.Inspect())
+  }
+
+  return 0
+}
+
+// a fresh environment, evaluated with the settings of the command line
+func newEnvironment() *object.Environment {
+  env := object.NewEnvironment()
+  env.Settings().Strict = strict
+  env.Settings().Sandbox = sandbox
+  env.Settings().Clock = clock
+  return env
+}
+
+// how long each phase of a run took, on the clock of the evaluation,
+// so a fake clock moves the phases along with sleep
+type phaseTimer struct {
+  last   time.Time
+  phases []string
+  took   []time.Duration
+}
+
+func startTimer() *phaseTimer {
+  return &phaseTimer{last: clock.Now()}
+}
+
+// ends phase, the next one starts right away
+func (pt *phaseTimer) done(phase string) {
+  now := clock.Now()
+  pt.phases = append(pt.phases, phase)
+  pt.took = append(pt.took, now.Sub(pt.last))
+  pt.last = now
+}
+
+// eg: parse: 1.2ms
+//     eval: 350ms
+// a run that stopped early only has the phases it finished
+func (pt *phaseTimer) report(out io.Writer) {
+  for i, phase := range pt.phases {
+    fmt.Fprintf(out, "%s: %s\n", phase, pt.took[i])
+  }
+}
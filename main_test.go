@@ -0,0 +1,199 @@
+package main
+
+import (
+  "JFFMonkeyLang/src/object"
+  "bytes"
+  "context"
+  "fmt"
+  "io"
+  "os"
+  "path/filepath"
+  "strings"
+  "testing"
+  "time"
+)
+
+func TestRunFile(t *testing.T) {
+  dir := t.TempDir()
+
+  tests := []struct {
+    source         string
+    expectedCode   int
+    expectedStderr string
+  }{
+    {"let add = fn(a, b) { a + b }; add(1, 2);", 0, ""},
+    {"let = 5;", 1, "%s:1:5: expected next token to be IDENT, got ASSIGN instead\n"},
+    {"5 + true;", 1, "%s: ERROR: type mismatch: INTEGER + BOOLEAN\n"},
+  }
+
+  for i, tt := range tests {
+    path := filepath.Join(dir, "program.monkey")
+    if err := os.WriteFile(path, []byte(tt.source), 0644); err != nil {
+      t.Fatalf("tests[%d] - could not write temp file: %s", i, err)
+    }
+
+    var stdout, stderr bytes.Buffer
+    code := runFile(path, &stdout, &stderr)
+
+    if code != tt.expectedCode {
+      t.Errorf("tests[%d] - exit code wrong. expected=%d, got=%d",
+        i, tt.expectedCode, code)
+    }
+
+    expectedStderr := tt.expectedStderr
+    if expectedStderr != "" {
+      expectedStderr = fmt.Sprintf(expectedStderr, path)
+    }
+    if stderr.String() != expectedStderr {
+      t.Errorf("tests[%d] - stderr wrong. expected=%q, got=%q",
+        i, expectedStderr, stderr.String())
+    }
+
+    if stdout.String() != "" {
+      t.Errorf("tests[%d] - stdout not empty. got=%q", i, stdout.String())
+    }
+  }
+}
+
+func TestRunFileMissing(t *testing.T) {
+  var stdout, stderr bytes.Buffer
+  code := runFile(filepath.Join(t.TempDir(), "missing.monkey"), &stdout, &stderr)
+
+  if code != 1 {
+    t.Errorf("exit code wrong. expected=1, got=%d", code)
+  }
+  if stderr.Len() == 0 {
+    t.Errorf("expected an error on stderr")
+  }
+}
+
+func TestRunSourceInline(t *testing.T) {
+  var stdout, stderr bytes.Buffer
+  code := runSource("-e", "1 + 2 * 3", &stdout, &stderr, true)
+
+  if code != 0 {
+    t.Fatalf("exit code wrong. expected=0, got=%d (%s)", code, stderr.String())
+  }
+  if stdout.String() != "7\n" {
+    t.Errorf("stdout wrong. expected=%q, got=%q", "7\n", stdout.String())
+  }
+}
+
+func TestRunFmt(t *testing.T) {
+  path := filepath.Join(t.TempDir(), "program.monkey")
+  if err := os.WriteFile(path, []byte("let add=fn(a,b){a+b};add(1,2)"), 0644); err != nil {
+    t.Fatalf("could not write temp file: %s", err)
+  }
+
+  var stdout, stderr bytes.Buffer
+  code := runFmt(path, &stdout, &stderr)
+
+  if code != 0 {
+    t.Fatalf("exit code wrong. expected=0, got=%d (%s)", code, stderr.String())
+  }
+  expected := "let add = fn(a, b) {\n  a + b;\n};\nadd(1, 2);\n"
+  if stdout.String() != expected {
+    t.Errorf("stdout wrong. expected=%q, got=%q", expected, stdout.String())
+  }
+
+  // parser errors are reported, nothing is printed
+  if err := os.WriteFile(path, []byte("let = 5;"), 0644); err != nil {
+    t.Fatalf("could not write temp file: %s", err)
+  }
+  stdout.Reset()
+  stderr.Reset()
+  if code := runFmt(path, &stdout, &stderr); code != 1 || stdout.Len() != 0 {
+    t.Errorf("expected exit code 1 and no output. got=%d, %q", code, stdout.String())
+  }
+}
+
+func TestRunLint(t *testing.T) {
+  defer func(prev bool) { lint = prev }(lint)
+  lint = true
+
+  var stdout, stderr bytes.Buffer
+  code := runSource("-e", "let unused = 1;\nlet x = 2;\nx * 3", &stdout, &stderr, true)
+
+  if code != 0 {
+    t.Errorf("exit code wrong. expected=0, got=%d", code)
+  }
+  if stderr.String() != "-e:1:5: unused declared and not used\n" {
+    t.Errorf("stderr wrong. got=%q", stderr.String())
+  }
+  if stdout.String() != "6\n" {
+    t.Errorf("stdout wrong. got=%q", stdout.String())
+  }
+}
+
+func TestRunStrict(t *testing.T) {
+  defer func(prev bool) { strict = prev }(strict)
+  strict = true
+
+  var stdout, stderr bytes.Buffer
+  code := runSource("-e", "let f = fn(x) { if (x) { return 1 } }; f(false)", &stdout, &stderr, true)
+
+  if code != 1 {
+    t.Errorf("exit code wrong. expected=1, got=%d", code)
+  }
+  if stderr.String() != "-e: ERROR: missing return in fn(x)\n  in function f\n" {
+    t.Errorf("stderr wrong. got=%q", stderr.String())
+  }
+}
+
+func TestRunTimed(t *testing.T) {
+  fake := &fakeClock{now: time.Unix(0, 0)}
+  defer func(prev object.Clock) { clock = prev }(clock)
+  clock = fake
+  defer func(prev bool) { reportTime = prev }(reportTime)
+  reportTime = true
+
+  tests := []struct {
+    source         string
+    expectedStderr string
+  }{
+    // reading the source takes 5ms, sleeping is evaluation
+    {"sleep(250); 1", "parse: 5ms\neval: 250ms\n"},
+    {"1 + 2", "parse: 5ms\neval: 0s\n"},
+    // a failed run reports the phases it got through
+    {"let = 5;", "-:1:5: expected next token to be IDENT, got ASSIGN instead\nparse: 5ms\n"},
+    {"sleep(10); 5 + true", "-: ERROR: type mismatch: INTEGER + BOOLEAN\nparse: 5ms\neval: 10ms\n"},
+  }
+
+  for _, tt := range tests {
+    var stdout, stderr bytes.Buffer
+    source := &slowReader{Reader: strings.NewReader(tt.source), clock: fake, delay: 5 * time.Millisecond}
+    runReader("-", source, &stdout, &stderr, false)
+
+    if stderr.String() != tt.expectedStderr {
+      t.Errorf("%s: stderr wrong. expected=%q, got=%q", tt.source, tt.expectedStderr, stderr.String())
+    }
+  }
+}
+
+// the time only moves on when the program sleeps
+type fakeClock struct {
+  now time.Time
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func (c *fakeClock) Sleep(ctx context.Context, d time.Duration) error {
+  c.now = c.now.Add(d)
+  return nil
+}
+
+// a source that takes delay to read, all in one go
+type slowReader struct {
+  io.Reader
+  clock *fakeClock
+  delay time.Duration
+  read  bool
+}
+
+func (r *slowReader) Read(p []byte) (int, error) {
+  if !r.read {
+    r.read = true
+    r.clock.now = r.clock.now.Add(r.delay)
+  }
+  return r.Reader.Read(p)
+}
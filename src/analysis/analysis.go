@@ -0,0 +1,47 @@
+package analysis
+
+import (
+  "JFFMonkeyLang/src/ast"
+  "fmt"
+  "sort"
+)
+
+// one finding of a check and where it is
+type Warning struct {
+  Message string
+  Line    int // 1-based
+  Column  int // 1-based
+}
+
+// eg: 1:5: x declared and not used
+func (w Warning) String() string {
+  return fmt.Sprintf("%d:%d: %s", w.Line, w.Column, w.Message)
+}
+
+// every check Lint runs
+var checks = []func(*ast.Program) []Warning{
+  UnusedBindings,
+  AssignInCondition,
+  Redeclarations,
+}
+
+// Lint runs every check on program, the warnings in source order
+func Lint(program *ast.Program) []Warning {
+  warnings := []Warning{}
+  for _, check := range checks {
+    warnings = append(warnings, check(program)...)
+  }
+
+  sortWarnings(warnings)
+  return warnings
+}
+
+// by position, warnings at the same place keep their order
+func sortWarnings(warnings []Warning) {
+  sort.SliceStable(warnings, func(i, j int) bool {
+    if warnings[i].Line != warnings[j].Line {
+      return warnings[i].Line < warnings[j].Line
+    }
+    return warnings[i].Column < warnings[j].Column
+  })
+}
@@ -0,0 +1,41 @@
+package analysis
+
+import (
+  "JFFMonkeyLang/src/ast"
+)
+
+// AssignInCondition reports an assignment that is the whole condition
+// of an if or a loop, which usually is a mistyped ==, eg:
+//
+//   if (x = 5) { ... }  // 1:7: assignment used as condition, did you mean ==?
+//
+// an assignment further inside, eg: if (f(x = 5)), is left alone
+func AssignInCondition(program *ast.Program) []Warning {
+  warnings := []Warning{}
+
+  ast.Walk(program, func(node ast.Node) bool {
+    var condition ast.Expression
+    switch node := node.(type) {
+    case *ast.IfExpression:
+      condition = node.Condition
+    case *ast.WhileExpression:
+      condition = node.Condition
+    case *ast.DoWhileExpression:
+      condition = node.Condition
+    case *ast.ForExpression:
+      condition = node.Condition
+    }
+
+    if assign, ok := condition.(*ast.AssignExpression); ok {
+      line, column := assign.Pos()
+      warnings = append(warnings, Warning{
+        Message: "assignment used as condition, did you mean ==?",
+        Line:    line,
+        Column:  column,
+      })
+    }
+    return true
+  })
+
+  return warnings
+}
@@ -0,0 +1,57 @@
+package analysis
+
+import (
+  "strings"
+  "testing"
+)
+
+func TestAssignInCondition(t *testing.T) {
+  tests := []struct {
+    input    string
+    expected []string
+  }{
+    {"let x = 1; if (x = 5) { x }", []string{"1:18: assignment used as condition, did you mean ==?"}},
+    {"let x = 1; while (x = 0) { }", []string{"1:21: assignment used as condition, did you mean ==?"}},
+    {"let x = 1; do { } while (x = 0)", []string{"1:28: assignment used as condition, did you mean ==?"}},
+    {"let a = [1]; if (a[0] = 2) { }", []string{"1:23: assignment used as condition, did you mean ==?"}},
+    {"for (let i = 0; i = 3; i = i + 1) { }", []string{"1:19: assignment used as condition, did you mean ==?"}},
+    // nested conditions are checked too
+    {"let x = 1; fn() { if (true) { while (x = 2) { } } }", []string{"1:40: assignment used as condition, did you mean ==?"}},
+    // comparisons and assignments anywhere else are fine
+    {"let x = 1; if (x == 5) { x }", []string{}},
+    {"let x = 1; if (true) { x = 5 }", []string{}},
+    {"let x = 1; let f = fn(v) { v }; if (f(x = 5)) { }", []string{}},
+    {"let x = 1; if ([x = 5][0]) { }", []string{}},
+    {"let x = 1; for (; x < 3; x = x + 1) { }", []string{}},
+  }
+
+  for _, tt := range tests {
+    warnings := AssignInCondition(testParse(t, tt.input))
+
+    actual := []string{}
+    for _, w := range warnings {
+      actual = append(actual, w.String())
+    }
+    if strings.Join(actual, "\n") != strings.Join(tt.expected, "\n") {
+      t.Errorf("%s: wrong warnings. want=%q, got=%q", tt.input, tt.expected, actual)
+    }
+  }
+}
+
+func TestLint(t *testing.T) {
+  warnings := Lint(testParse(t, "let y = 1;\nlet x = 1; if (x = 2) { }"))
+
+  expected := []string{
+    "1:5: y declared and not used",
+    // x is only ever assigned
+    "2:5: x declared and not used",
+    "2:18: assignment used as condition, did you mean ==?",
+  }
+  actual := []string{}
+  for _, w := range warnings {
+    actual = append(actual, w.String())
+  }
+  if strings.Join(actual, "\n") != strings.Join(expected, "\n") {
+    t.Errorf("wrong warnings. want=%q, got=%q", expected, actual)
+  }
+}
@@ -0,0 +1,20 @@
+package analysis
+
+import (
+  "JFFMonkeyLang/src/ast"
+)
+
+// Redeclarations reports a let or const of a name the same scope
+// already has, where the second silently replaces the first, eg:
+//
+//   let x = 1;
+//   let x = 2;  // 2:5: x redeclared in this scope, first declared at 1:5
+//
+// shadowing a name of an outer scope is fine; parameters, loop variables
+// and the catch name share a scope with their body
+func Redeclarations(program *ast.Program) []Warning {
+  warnings := resolve(program).redeclared
+
+  sortWarnings(warnings)
+  return warnings
+}
@@ -0,0 +1,43 @@
+package analysis
+
+import (
+  "strings"
+  "testing"
+)
+
+func TestRedeclarations(t *testing.T) {
+  tests := []struct {
+    input    string
+    expected []string
+  }{
+    {"let x = 1; let x = 2;", []string{"1:16: x redeclared in this scope, first declared at 1:5"}},
+    {"let x = 1;\nconst x = 2;\nlet x = 3;", []string{
+      "2:7: x redeclared in this scope, first declared at 1:5",
+      "3:5: x redeclared in this scope, first declared at 2:7",
+    }},
+    {"if (true) { let y = 1; let y = 2; }", []string{"1:28: y redeclared in this scope, first declared at 1:17"}},
+    {"let f = fn(x) { let x = 2; x }", []string{"1:21: x redeclared in this scope, first declared at 1:12"}},
+    {"for (v in [1]) { let v = 2; }", []string{"1:22: v redeclared in this scope, first declared at 1:6"}},
+    // shadowing in a nested scope is fine
+    {"let x = 1; if (true) { let x = 2; }", []string{}},
+    {"let x = 1; let f = fn() { let x = 2; x }", []string{}},
+    {"let x = 1; while (false) { let x = 2; }", []string{}},
+    {"for (let i = 0; i < 1; i = i + 1) { let i = 5; }", []string{}},
+    {"try { let e = 1; } catch (e) { 0 }", []string{}},
+    // the same name in sibling scopes
+    {"if (true) { let x = 1; } else { let x = 2; }", []string{}},
+    {"let x = 1; x = 2;", []string{}},
+  }
+
+  for _, tt := range tests {
+    warnings := Redeclarations(testParse(t, tt.input))
+
+    actual := []string{}
+    for _, w := range warnings {
+      actual = append(actual, w.String())
+    }
+    if strings.Join(actual, "\n") != strings.Join(tt.expected, "\n") {
+      t.Errorf("%s: wrong warnings. want=%q, got=%q", tt.input, tt.expected, actual)
+    }
+  }
+}
@@ -0,0 +1,209 @@
+package analysis
+
+import (
+  "JFFMonkeyLang/src/ast"
+  "fmt"
+)
+
+// walks program keeping track of scopes, scopes follow the evaluator:
+// every block is one, a name in a nested scope shadows the outer one,
+// and a function body sees the names of its scopes as they are once the
+// program has run, since that is when closures look them up
+func resolve(program *ast.Program) *checker {
+  c := &checker{}
+  c.statements(program.Statements, newScope(nil))
+
+  // function bodies run after their scopes are complete,
+  // a body may queue the functions inside it
+  for len(c.pending) > 0 {
+    next := c.pending[0]
+    c.pending = c.pending[1:]
+    next()
+  }
+
+  return c
+}
+
+type binding struct {
+  name *ast.Identifier
+  used bool
+}
+
+type scope struct {
+  outer *scope
+  names map[string]*binding
+}
+
+func newScope(outer *scope) *scope {
+  return &scope{outer: outer, names: map[string]*binding{}}
+}
+
+// marks the innermost binding of name as read,
+// builtins and unknown names are left alone
+func (s *scope) use(name string) {
+  for ; s != nil; s = s.outer {
+    if b, ok := s.names[name]; ok {
+      b.used = true
+      return
+    }
+  }
+}
+
+type checker struct {
+  // let and const bindings, the ones that can be reported
+  bindings []*binding
+  // function bodies waiting for their scopes to be complete
+  pending []func()
+  // let and const names already bound in the same scope
+  redeclared []Warning
+}
+
+// a let or const binding, it replaces an earlier one of the same name in s
+func (c *checker) declare(name *ast.Identifier, s *scope) {
+  if earlier, ok := s.names[name.Value]; ok {
+    line, column := name.Pos()
+    earlierLine, earlierColumn := earlier.name.Pos()
+    c.redeclared = append(c.redeclared, Warning{
+      Message: fmt.Sprintf("%s redeclared in this scope, first declared at %d:%d", name.Value, earlierLine, earlierColumn),
+      Line:    line,
+      Column:  column,
+    })
+  }
+
+  b := &binding{name: name}
+  s.names[name.Value] = b
+  c.bindings = append(c.bindings, b)
+}
+
+// parameters, loop variables and catch names shadow, but are not reported
+func (c *checker) bind(name *ast.Identifier, s *scope) {
+  s.names[name.Value] = &binding{name: name, used: true}
+}
+
+func (c *checker) statements(statements []ast.Statement, s *scope) {
+  for _, statement := range statements {
+    c.check(statement, s)
+  }
+}
+
+// the statements of a block in a scope of their own
+func (c *checker) block(bs *ast.BlockStatement, s *scope) {
+  if bs != nil {
+    c.statements(bs.Statements, newScope(s))
+  }
+}
+
+func (c *checker) check(node ast.Node, s *scope) {
+  ast.Walk(node, func(node ast.Node) bool {
+    switch node := node.(type) {
+    case *ast.Identifier:
+      s.use(node.Value)
+
+    // eg: let x = x + 1, the value still sees the outer x
+    case *ast.LetStatement:
+      c.check(node.Value, s)
+      c.declare(node.Name, s)
+      return false
+
+    case *ast.ConstStatement:
+      c.check(node.Value, s)
+      c.declare(node.Name, s)
+      return false
+
+    case *ast.DestructureStatement:
+      c.check(node.Value, s)
+      for _, name := range node.Names {
+        c.declare(name, s)
+      }
+      return false
+
+    // eg: x = 1 only writes x, a[0] = 1 reads a
+    case *ast.AssignExpression:
+      if _, ok := node.Target.(*ast.Identifier); !ok {
+        c.check(node.Target, s)
+      }
+      c.check(node.Value, s)
+      return false
+
+    case *ast.BlockStatement:
+      c.block(node, s)
+      return false
+
+    // eg: for (let i = 0; i < 3; i = i + 1), i lives in the loop's own scope
+    case *ast.ForExpression:
+      loop := newScope(s)
+      if node.Init != nil {
+        c.check(node.Init, loop)
+      }
+      if node.Condition != nil {
+        c.check(node.Condition, loop)
+      }
+      if node.Post != nil {
+        c.check(node.Post, loop)
+      }
+      c.block(node.Body, loop)
+      return false
+
+    case *ast.ForInExpression:
+      c.check(node.Iterable, s)
+      iteration := newScope(s)
+      c.bind(node.Key, iteration)
+      if node.Value != nil {
+        c.bind(node.Value, iteration)
+      }
+      c.statements(node.Body.Statements, iteration)
+      return false
+
+    case *ast.TryExpression:
+      c.block(node.Block, s)
+      catch := newScope(s)
+      c.bind(node.Name, catch)
+      c.statements(node.Catch.Statements, catch)
+      return false
+
+    case *ast.FunctionLiteral:
+      c.pending = append(c.pending, func() {
+        body := newScope(s)
+        // eg: fn(x, y = x * 2), a default sees the parameters before it
+        for i, param := range node.Parameters {
+          if i < len(node.Defaults) && node.Defaults[i] != nil {
+            c.check(node.Defaults[i], body)
+          }
+          c.bind(param, body)
+        }
+        c.statements(node.Body.Statements, body)
+      })
+      return false
+
+    case *ast.MacroLiteral:
+      c.pending = append(c.pending, func() {
+        body := newScope(s)
+        for _, param := range node.Parameters {
+          c.bind(param, body)
+        }
+        c.statements(node.Body.Statements, body)
+      })
+      return false
+
+    // keyword names are parameters of the callee, eg: f(x, step = 2)
+    case *ast.CallExpression:
+      c.check(node.Function, s)
+      for _, arg := range node.Arguments {
+        c.check(arg, s)
+      }
+      for _, value := range node.KeywordValues {
+        c.check(value, s)
+      }
+      return false
+
+    // eg: a.push(1), push is not a variable
+    case *ast.MethodCallExpression:
+      c.check(node.Receiver, s)
+      for _, arg := range node.Arguments {
+        c.check(arg, s)
+      }
+      return false
+    }
+    return true
+  })
+}
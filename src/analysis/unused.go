@@ -0,0 +1,33 @@
+package analysis
+
+import (
+  "JFFMonkeyLang/src/ast"
+  "strings"
+)
+
+// UnusedBindings reports the let and const names that are never read, eg:
+//
+//   let x = 1;      // 1:5: x declared and not used
+//   let y = 2;
+//   x = y;          // assigning is not reading, y is used
+//
+// a closure reading a name counts, and names starting with '_' are never reported
+func UnusedBindings(program *ast.Program) []Warning {
+  c := resolve(program)
+
+  // every let or const binding nothing read
+  warnings := []Warning{}
+  for _, b := range c.bindings {
+    if !b.used && !strings.HasPrefix(b.name.Value, "_") {
+      line, column := b.name.Pos()
+      warnings = append(warnings, Warning{
+        Message: b.name.Value + " declared and not used",
+        Line:    line,
+        Column:  column,
+      })
+    }
+  }
+
+  sortWarnings(warnings)
+  return warnings
+}
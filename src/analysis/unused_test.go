@@ -0,0 +1,72 @@
+package analysis
+
+import (
+  "JFFMonkeyLang/src/ast"
+  "JFFMonkeyLang/src/lexer"
+  "JFFMonkeyLang/src/parser"
+  "strings"
+  "testing"
+)
+
+func TestUnusedBindings(t *testing.T) {
+  tests := []struct {
+    input    string
+    expected []string
+  }{
+    {"let x = 1;", []string{"1:5: x declared and not used"}},
+    {"let x = 1; x", []string{}},
+    {"const limit = 10; let y = limit;", []string{"1:23: y declared and not used"}},
+    // assigning is not reading
+    {"let x = 1; x = 2;", []string{"1:5: x declared and not used"}},
+    {"let a = [1]; a[0] = 2;", []string{}},
+    // captured by a closure
+    {"let x = 1; let f = fn() { x }; f()", []string{}},
+    {"let make = fn(x) { fn(y) { x + y } }; make(1)(2)", []string{}},
+    // a closure sees names declared after it, once it is called
+    {"let f = fn() { g() }; let g = fn() { 1 }; f()", []string{}},
+    {"let f = fn(n) { if (n == 0) { 0 } else { f(n - 1) } }; f(3)", []string{}},
+    // shadowing: the inner x is read, the outer one is not
+    {"let x = 1; if (true) { let x = 2; x }", []string{"1:5: x declared and not used"}},
+    {"let x = 1; let f = fn(x) { x }; f(2)", []string{"1:5: x declared and not used"}},
+    {"let x = 1; if (true) { let x = 2; }; x", []string{"1:28: x declared and not used"}},
+    // the value sees the binding before it
+    {"let x = 1; let x = x + 1; x", []string{}},
+    // a redeclared name replaces the old binding for closures
+    {"let x = 1; let f = fn() { x }; let x = 2; f()", []string{"1:5: x declared and not used"}},
+    {"let f = fn() { let unused = 1; 2 }; f()", []string{"1:20: unused declared and not used"}},
+    // loops, catch and parameters bind names without being reported
+    {"for (let i = 0; i < 3; i = i + 1) { }", []string{}},
+    {"for (k, v in {}) { }", []string{}},
+    {"try { 1 } catch (e) { 0 }", []string{}},
+    {"let f = fn(a, b = a, ...rest) { 0 }; f(1)", []string{}},
+    // keyword and method names are not variables
+    {"let step = 1; let f = fn(step) { step }; f(step = 2)", []string{"1:5: step declared and not used"}},
+    {"let push = 1; [1].push(2)", []string{"1:5: push declared and not used"}},
+    {"let _ignored = 1;", []string{}},
+    // every destructured name is a binding of its own
+    {"let [a, ...rest] = [1, 2]; a", []string{"1:12: rest declared and not used"}},
+    {`let {name, age} = {}; age`, []string{"1:6: name declared and not used"}},
+    {"let b = 1;\nlet a = 2;", []string{"1:5: b declared and not used", "2:5: a declared and not used"}},
+  }
+
+  for _, tt := range tests {
+    warnings := UnusedBindings(testParse(t, tt.input))
+
+    actual := []string{}
+    for _, w := range warnings {
+      actual = append(actual, w.String())
+    }
+    if strings.Join(actual, "\n") != strings.Join(tt.expected, "\n") {
+      t.Errorf("%s: wrong warnings. want=%q, got=%q", tt.input, tt.expected, actual)
+    }
+  }
+}
+
+func testParse(t *testing.T, input string) *ast.Program {
+  p := parser.New(lexer.New(input))
+  program := p.ParseProgram()
+  if len(p.Errors()) != 0 {
+    t.Fatalf("parser errors for %q: %v", input, p.Errors())
+  }
+  return program
+}
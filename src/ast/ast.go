@@ -3,12 +3,14 @@ package ast
 import (
   "JFFMonkeyLang/src/token"
   "bytes"
+  "math/big"
   "strings"
 )
 
 // The base Node interface
 type Node interface {
   TokenLiteral() string
+  Pos() (line, col int) // 1-based source position of the node's token
   String() string // print ast node for test
 }
 
@@ -37,6 +39,13 @@ func (p *Program) TokenLiteral() string {
   }
 }
 
+func (p *Program) Pos() (int, int) {
+  if len(p.Statements) > 0 {
+    return p.Statements[0].Pos()
+  }
+  return 0, 0
+}
+
 func (p *Program) String() string {
   var out bytes.Buffer
 
@@ -62,6 +71,7 @@ type LetStatement struct {
 
 func (ls *LetStatement) statementNode()       {}
 func (ls *LetStatement) TokenLiteral() string { return ls.Token.Literal }
+func (ls *LetStatement) Pos() (int, int)      { return ls.Token.Line, ls.Token.Column }
 func (ls *LetStatement) String() string {
   var out bytes.Buffer
 
@@ -78,6 +88,82 @@ func (ls *LetStatement) String() string {
   return out.String()
 }
 
+// eg: const PI = 3;
+// like a LetStatement, but the name can't be rebound in its scope
+type ConstStatement struct {
+  Token token.Token // the 'const' token
+  Name  *Identifier
+  Value Expression
+}
+
+func (cs *ConstStatement) statementNode()       {}
+func (cs *ConstStatement) TokenLiteral() string { return cs.Token.Literal }
+func (cs *ConstStatement) Pos() (int, int)      { return cs.Token.Line, cs.Token.Column }
+func (cs *ConstStatement) String() string {
+  var out bytes.Buffer
+
+  out.WriteString(cs.TokenLiteral() + " ")
+  out.WriteString(cs.Name.String())
+  out.WriteString(" = ")
+
+  if cs.Value != nil {
+    out.WriteString(cs.Value.String())
+  }
+
+  out.WriteString(";")
+
+  return out.String()
+}
+
+// eg: let [a, b] = pair; let [first, ...rest] = list; let {name, age} = person;
+// binds each name to an element of an array, or to the value of the
+// hash key spelled like the name
+type DestructureStatement struct {
+  Token token.Token // the 'let' token
+  Hash  bool        // {name, age} instead of [a, b]
+  Names []*Identifier
+  Rest  bool // the last name collects the remaining elements, eg: [x, ...xs]
+  Value Expression
+}
+
+func (ds *DestructureStatement) statementNode()       {}
+func (ds *DestructureStatement) TokenLiteral() string { return ds.Token.Literal }
+func (ds *DestructureStatement) Pos() (int, int)      { return ds.Token.Line, ds.Token.Column }
+func (ds *DestructureStatement) String() string {
+  var out bytes.Buffer
+
+  left, right := "[", "]"
+  if ds.Hash {
+    left, right = "{", "}"
+  }
+
+  out.WriteString(ds.TokenLiteral() + " ")
+  out.WriteString(left + ParametersString(ds.Names, nil, ds.Rest) + right)
+  out.WriteString(" = ")
+
+  if ds.Value != nil {
+    out.WriteString(ds.Value.String())
+  }
+
+  out.WriteString(";")
+
+  return out.String()
+}
+
+// eg: throw "not found";
+// the value becomes a runtime error, catchable by try
+type ThrowStatement struct {
+  Token token.Token // the 'throw' token
+  Value Expression
+}
+
+func (ts *ThrowStatement) statementNode()       {}
+func (ts *ThrowStatement) TokenLiteral() string { return ts.Token.Literal }
+func (ts *ThrowStatement) Pos() (int, int)      { return ts.Token.Line, ts.Token.Column }
+func (ts *ThrowStatement) String() string {
+  return ts.TokenLiteral() + " " + ts.Value.String() + ";"
+}
+
 /*
  * return 5;
  * return add(1, 2)
@@ -90,6 +176,7 @@ type ReturnStatement struct {
 
 func (ls *ReturnStatement) statementNode()       {}
 func (ls *ReturnStatement) TokenLiteral() string { return ls.Token.Literal }
+func (ls *ReturnStatement) Pos() (int, int)      { return ls.Token.Line, ls.Token.Column }
 func (rs *ReturnStatement) String() string {
   var out bytes.Buffer
 
@@ -117,6 +204,7 @@ type ExpressionStatement struct {
 
 func (ls *ExpressionStatement) statementNode()       {}
 func (ls *ExpressionStatement) TokenLiteral() string { return ls.Token.Literal }
+func (ls *ExpressionStatement) Pos() (int, int)      { return ls.Token.Line, ls.Token.Column }
 func (es *ExpressionStatement) String() string {
   if es.Expression != nil {
     return es.Expression.String()
@@ -135,6 +223,7 @@ type BlockStatement struct {
 
 func (bs *BlockStatement) statementNode()       {}
 func (bs *BlockStatement) TokenLiteral() string { return bs.Token.Literal }
+func (bs *BlockStatement) Pos() (int, int)      { return bs.Token.Line, bs.Token.Column }
 func (bs *BlockStatement) String() string {
   var out bytes.Buffer
 
@@ -153,6 +242,7 @@ type Identifier struct {
 
 func (i *Identifier) expressionNode()      {}
 func (i *Identifier) TokenLiteral() string { return i.Token.Literal }
+func (i *Identifier) Pos() (int, int)      { return i.Token.Line, i.Token.Column }
 func (i *Identifier) String() string       { return i.Value }
 
 // eg: true, false
@@ -163,6 +253,7 @@ type Boolean struct {
 
 func (b *Boolean) expressionNode()      {}
 func (b *Boolean) TokenLiteral() string { return b.Token.Literal }
+func (b *Boolean) Pos() (int, int)      { return b.Token.Line, b.Token.Column }
 func (b *Boolean) String() string       { return b.Token.Literal }
 
 // eg: {Token: token.INT, Value: 5}
@@ -173,8 +264,56 @@ type IntegerLiteral struct {
 
 func (il *IntegerLiteral) expressionNode()      {}
 func (il *IntegerLiteral) TokenLiteral() string { return il.Token.Literal }
+func (il *IntegerLiteral) Pos() (int, int)      { return il.Token.Line, il.Token.Column }
 func (il *IntegerLiteral) String() string       { return il.Token.Literal }
 
+// eg: 9223372036854775808, an integer literal too big for an int64
+type BigIntegerLiteral struct {
+  Token token.Token
+  Value *big.Int
+}
+
+func (bl *BigIntegerLiteral) expressionNode()      {}
+func (bl *BigIntegerLiteral) TokenLiteral() string { return bl.Token.Literal }
+func (bl *BigIntegerLiteral) Pos() (int, int)      { return bl.Token.Line, bl.Token.Column }
+func (bl *BigIntegerLiteral) String() string       { return bl.Token.Literal }
+
+// eg: "hello world"
+type StringLiteral struct {
+  Token token.Token
+  Value string
+}
+
+func (sl *StringLiteral) expressionNode()      {}
+func (sl *StringLiteral) TokenLiteral() string { return sl.Token.Literal }
+func (sl *StringLiteral) Pos() (int, int)      { return sl.Token.Line, sl.Token.Column }
+func (sl *StringLiteral) String() string       { return sl.Token.Literal }
+
+// eg: "Hello ${name}, you are ${age} years old"
+// Strings are the texts around the expressions, one more than Expressions
+type TemplateLiteral struct {
+  Token       token.Token
+  Strings     []string
+  Expressions []Expression
+}
+
+func (tl *TemplateLiteral) expressionNode()      {}
+func (tl *TemplateLiteral) TokenLiteral() string { return tl.Token.Literal }
+func (tl *TemplateLiteral) Pos() (int, int)      { return tl.Token.Line, tl.Token.Column }
+func (tl *TemplateLiteral) String() string {
+  var out bytes.Buffer
+
+  for i, exp := range tl.Expressions {
+    out.WriteString(tl.Strings[i])
+    out.WriteString("${")
+    out.WriteString(exp.String())
+    out.WriteString("}")
+  }
+  out.WriteString(tl.Strings[len(tl.Strings)-1])
+
+  return out.String()
+}
+
 // eg: !5, -5
 type PrefixExpression struct {
   Token    token.Token // The prefix token, e.g: !
@@ -184,6 +323,7 @@ type PrefixExpression struct {
 
 func (pe *PrefixExpression) expressionNode()      {}
 func (pe *PrefixExpression) TokenLiteral() string { return pe.Token.Literal }
+func (pe *PrefixExpression) Pos() (int, int)      { return pe.Token.Line, pe.Token.Column }
 func (pe *PrefixExpression) String() string {
   var out bytes.Buffer
 
@@ -201,10 +341,12 @@ type InfixExpression struct {
   Left     Expression  // eg: 1
   Operator string      // "+"
   Right    Expression  // eg: 1
+  Grouped  bool        // written in parens, eg: (a < b) < c is not a chain
 }
 
 func (ie *InfixExpression) expressionNode()      {}
 func (ie *InfixExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *InfixExpression) Pos() (int, int)      { return ie.Token.Line, ie.Token.Column }
 func (ie *InfixExpression) String() string {
   var out bytes.Buffer
 
@@ -227,6 +369,7 @@ type IfExpression struct {
 
 func (ie *IfExpression) expressionNode()      {}
 func (ie *IfExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *IfExpression) Pos() (int, int)      { return ie.Token.Line, ie.Token.Column }
 func (ie *IfExpression) String() string {
   var out bytes.Buffer
 
@@ -243,6 +386,139 @@ func (ie *IfExpression) String() string {
   return out.String()
 }
 
+// eg: while (x < 10) { x = x + 1 }
+type WhileExpression struct {
+  Token     token.Token // the 'while' token
+  Condition Expression
+  Body      *BlockStatement
+}
+
+func (we *WhileExpression) expressionNode()      {}
+func (we *WhileExpression) TokenLiteral() string { return we.Token.Literal }
+func (we *WhileExpression) Pos() (int, int)      { return we.Token.Line, we.Token.Column }
+func (we *WhileExpression) String() string {
+  var out bytes.Buffer
+
+  out.WriteString("while")
+  out.WriteString(we.Condition.String())
+  out.WriteString(" ")
+  out.WriteString(we.Body.String())
+
+  return out.String()
+}
+
+// eg: for (x in [1, 2]) { puts(x) }, for (k, v in {"a": 1}) { puts(k, v) }
+// Value is nil with a single loop variable, which then is Key
+type ForInExpression struct {
+  Token    token.Token // the 'for' token
+  Key      *Identifier
+  Value    *Identifier
+  Iterable Expression
+  Body     *BlockStatement
+}
+
+func (fe *ForInExpression) expressionNode()      {}
+func (fe *ForInExpression) TokenLiteral() string { return fe.Token.Literal }
+func (fe *ForInExpression) Pos() (int, int)      { return fe.Token.Line, fe.Token.Column }
+func (fe *ForInExpression) String() string {
+  var out bytes.Buffer
+
+  out.WriteString("for (")
+  out.WriteString(fe.Key.String())
+  if fe.Value != nil {
+    out.WriteString(", ")
+    out.WriteString(fe.Value.String())
+  }
+  out.WriteString(" in ")
+  out.WriteString(fe.Iterable.String())
+  out.WriteString(") ")
+  out.WriteString(fe.Body.String())
+
+  return out.String()
+}
+
+// eg: try { 1 / 0 } catch (e) { 0 }
+// a runtime error in Block runs Catch with its message bound to Name
+type TryExpression struct {
+  Token token.Token // the 'try' token
+  Block *BlockStatement
+  Name  *Identifier
+  Catch *BlockStatement
+}
+
+func (te *TryExpression) expressionNode()      {}
+func (te *TryExpression) TokenLiteral() string { return te.Token.Literal }
+func (te *TryExpression) Pos() (int, int)      { return te.Token.Line, te.Token.Column }
+func (te *TryExpression) String() string {
+  var out bytes.Buffer
+
+  out.WriteString("try ")
+  out.WriteString(te.Block.String())
+  out.WriteString(" catch (")
+  out.WriteString(te.Name.String())
+  out.WriteString(") ")
+  out.WriteString(te.Catch.String())
+
+  return out.String()
+}
+
+// eg: do { x = x + 1 } while (x < 10)
+// the body runs once before the condition is first checked
+type DoWhileExpression struct {
+  Token     token.Token // the 'do' token
+  Body      *BlockStatement
+  Condition Expression
+}
+
+func (de *DoWhileExpression) expressionNode()      {}
+func (de *DoWhileExpression) TokenLiteral() string { return de.Token.Literal }
+func (de *DoWhileExpression) Pos() (int, int)      { return de.Token.Line, de.Token.Column }
+func (de *DoWhileExpression) String() string {
+  var out bytes.Buffer
+
+  out.WriteString("do ")
+  out.WriteString(de.Body.String())
+  out.WriteString(" while")
+  out.WriteString(de.Condition.String())
+
+  return out.String()
+}
+
+// eg: for (let i = 0; i < 10; i = i + 1) { puts(i) }
+// Init, Condition and Post may each be nil, eg: for (;;) { }
+type ForExpression struct {
+  Token     token.Token // the 'for' token
+  Init      Statement
+  Condition Expression
+  Post      Expression
+  Body      *BlockStatement
+}
+
+func (fe *ForExpression) expressionNode()      {}
+func (fe *ForExpression) TokenLiteral() string { return fe.Token.Literal }
+func (fe *ForExpression) Pos() (int, int)      { return fe.Token.Line, fe.Token.Column }
+func (fe *ForExpression) String() string {
+  var out bytes.Buffer
+
+  clauses := []string{"", "", ""}
+  if fe.Init != nil {
+    clauses[0] = strings.TrimSuffix(fe.Init.String(), ";")
+  }
+  if fe.Condition != nil {
+    clauses[1] = fe.Condition.String()
+  }
+  if fe.Post != nil {
+    clauses[2] = fe.Post.String()
+  }
+
+  out.WriteString("for (")
+  out.WriteString(strings.Join(clauses, "; "))
+  out.WriteString(") ")
+  out.WriteString(fe.Body.String())
+
+  return out.String()
+}
+
 // eg:
 // fn(x, y) { return x + y; }
 // fn() { return x + y; }
@@ -251,41 +527,86 @@ func (ie *IfExpression) String() string {
 type FunctionLiteral struct {
   Token      token.Token // the 'fn' token
   Parameters []*Identifier
+  Defaults   []Expression // default value of each parameter, nil if it has none, eg: fn(x, y = 10)
+  Rest       bool         // the last parameter collects the remaining arguments, eg: fn(a, ...rest)
   Body       *BlockStatement
 }
 
 func (fl *FunctionLiteral) expressionNode()      {}
 func (fl *FunctionLiteral) TokenLiteral() string { return fl.Token.Literal }
+func (fl *FunctionLiteral) Pos() (int, int)      { return fl.Token.Line, fl.Token.Column }
 func (fl *FunctionLiteral) String() string {
   var out bytes.Buffer
 
-  params := []string{}
-  for _, p := range fl.Parameters {
-    params = append(params, p.String())
-  }
-
   out.WriteString(fl.TokenLiteral())
   out.WriteString("(")
-  out.WriteString(strings.Join(params, ", "))
+  out.WriteString(ParametersString(fl.Parameters, fl.Defaults, fl.Rest))
   out.WriteString(") ")
   out.WriteString(fl.Body.String())
 
   return out.String()
 }
 
+// eg: macro(x, y) { quote(unquote(y) - unquote(x)) }
+// the arguments are passed in unevaluated, as Quotes
+type MacroLiteral struct {
+  Token      token.Token // the 'macro' token
+  Parameters []*Identifier
+  Body       *BlockStatement
+}
+
+func (ml *MacroLiteral) expressionNode()      {}
+func (ml *MacroLiteral) TokenLiteral() string { return ml.Token.Literal }
+func (ml *MacroLiteral) Pos() (int, int)      { return ml.Token.Line, ml.Token.Column }
+func (ml *MacroLiteral) String() string {
+  var out bytes.Buffer
+
+  out.WriteString(ml.TokenLiteral())
+  out.WriteString("(")
+  out.WriteString(ParametersString(ml.Parameters, nil, false))
+  out.WriteString(") ")
+  out.WriteString(ml.Body.String())
+
+  return out.String()
+}
+
+// eg: x, y = 10, ...rest
+// shared by function literals and function objects
+func ParametersString(params []*Identifier, defaults []Expression, rest bool) string {
+  out := []string{}
+  for i, p := range params {
+    param := p.String()
+    if i < len(defaults) && defaults[i] != nil {
+      param += " = " + defaults[i].String()
+    }
+    if rest && i == len(params)-1 {
+      param = "..." + param
+    }
+    out = append(out, param)
+  }
+
+  return strings.Join(out, ", ")
+}
+
 // eg:
 // add(2, 3)
 // add(2 + 2, 3 * 3)
 // add(1, 2, fn(3, 4) { return 3 * 4; })
 // fn(x, y) { x + y; }(2, 3)
+//
+// keyword arguments follow the positional ones, eg: greet("Hi", name = "Sam"),
+// Keywords are in source order, KeywordValues[i] belongs to Keywords[i]
 type CallExpression struct {
-  Token     token.Token // the '(' token
-  Function  Expression  // Identifier or FunctionLiteral
-  Arguments []Expression
+  Token         token.Token // the '(' token
+  Function      Expression  // Identifier or FunctionLiteral
+  Arguments     []Expression
+  Keywords      []*Identifier
+  KeywordValues []Expression
 }
 
 func (ce *CallExpression) expressionNode()      {}
 func (ce *CallExpression) TokenLiteral() string { return ce.Token.Literal }
+func (ce *CallExpression) Pos() (int, int)      { return ce.Token.Line, ce.Token.Column }
 func (ce *CallExpression) String() string {
   var out bytes.Buffer
 
@@ -293,6 +614,9 @@ func (ce *CallExpression) String() string {
   for _, a := range ce.Arguments {
     args = append(args, a.String())
   }
+  for i, k := range ce.Keywords {
+    args = append(args, k.String()+" = "+ce.KeywordValues[i].String())
+  }
 
   out.WriteString(ce.Function.String())
   out.WriteString("(")
@@ -301,3 +625,184 @@ func (ce *CallExpression) String() string {
 
   return out.String()
 }
+
+// eg: [1, 2].push(3), a builtin called with the receiver first
+type MethodCallExpression struct {
+  Token     token.Token // the '.' token
+  Receiver  Expression
+  Method    *Identifier
+  Arguments []Expression
+}
+
+func (mc *MethodCallExpression) expressionNode()      {}
+func (mc *MethodCallExpression) TokenLiteral() string { return mc.Token.Literal }
+func (mc *MethodCallExpression) Pos() (int, int)      { return mc.Token.Line, mc.Token.Column }
+func (mc *MethodCallExpression) String() string {
+  var out bytes.Buffer
+
+  args := []string{}
+  for _, a := range mc.Arguments {
+    args = append(args, a.String())
+  }
+
+  out.WriteString(mc.Receiver.String())
+  out.WriteString(".")
+  out.WriteString(mc.Method.String())
+  out.WriteString("(")
+  out.WriteString(strings.Join(args, ", "))
+  out.WriteString(")")
+
+  return out.String()
+}
+
+// eg: [1, 2 * 2, fn(x) { x }]
+type ArrayLiteral struct {
+  Token    token.Token // the '[' token
+  Elements []Expression
+}
+
+func (al *ArrayLiteral) expressionNode()      {}
+func (al *ArrayLiteral) TokenLiteral() string { return al.Token.Literal }
+func (al *ArrayLiteral) Pos() (int, int)      { return al.Token.Line, al.Token.Column }
+func (al *ArrayLiteral) String() string {
+  var out bytes.Buffer
+
+  elements := []string{}
+  for _, el := range al.Elements {
+    elements = append(elements, el.String())
+  }
+
+  out.WriteString("[")
+  out.WriteString(strings.Join(elements, ", "))
+  out.WriteString("]")
+
+  return out.String()
+}
+
+// eg:
+// "hello"[1]
+// myArray[1 + 1]
+type IndexExpression struct {
+  Token    token.Token // the '[' token, or '?.' when Optional
+  Left     Expression  // the object being accessed
+  Index    Expression
+  Optional bool // eg: a?.[0], null when a is null
+}
+
+func (ie *IndexExpression) expressionNode()      {}
+func (ie *IndexExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *IndexExpression) Pos() (int, int)      { return ie.Token.Line, ie.Token.Column }
+func (ie *IndexExpression) String() string {
+  var out bytes.Buffer
+
+  out.WriteString("(")
+  out.WriteString(ie.Left.String())
+  if ie.Optional {
+    out.WriteString("?.")
+  }
+  out.WriteString("[")
+  out.WriteString(ie.Index.String())
+  out.WriteString("])")
+
+  return out.String()
+}
+
+// eg: {"one": 1, "two": 1 + 1}
+// Keys and Values are in source order, Values[i] belongs to Keys[i]
+type HashLiteral struct {
+  Token  token.Token // the '{' token
+  Keys   []Expression
+  Values []Expression
+}
+
+func (hl *HashLiteral) expressionNode()      {}
+func (hl *HashLiteral) TokenLiteral() string { return hl.Token.Literal }
+func (hl *HashLiteral) Pos() (int, int)      { return hl.Token.Line, hl.Token.Column }
+func (hl *HashLiteral) String() string {
+  var out bytes.Buffer
+
+  pairs := []string{}
+  for i, key := range hl.Keys {
+    pairs = append(pairs, key.String()+":"+hl.Values[i].String())
+  }
+
+  out.WriteString("{")
+  out.WriteString(strings.Join(pairs, ", "))
+  out.WriteString("}")
+
+  return out.String()
+}
+
+// eg: match x { 1 => "one", 2 => "two", _ => "other" }
+// the identifier `_` as a pattern matches anything
+type MatchExpression struct {
+  Token    token.Token // the 'match' token
+  Subject  Expression
+  Patterns []Expression
+  Results  []Expression
+}
+
+func (me *MatchExpression) expressionNode()      {}
+func (me *MatchExpression) TokenLiteral() string { return me.Token.Literal }
+func (me *MatchExpression) Pos() (int, int)      { return me.Token.Line, me.Token.Column }
+func (me *MatchExpression) String() string {
+  var out bytes.Buffer
+
+  arms := []string{}
+  for i, pattern := range me.Patterns {
+    arms = append(arms, pattern.String()+" => "+me.Results[i].String())
+  }
+
+  out.WriteString("match ")
+  out.WriteString(me.Subject.String())
+  out.WriteString(" { ")
+  out.WriteString(strings.Join(arms, ", "))
+  out.WriteString(" }")
+
+  return out.String()
+}
+
+// eg: x |> f, the same as f(x)
+type PipeExpression struct {
+  Token    token.Token // the '|>' token
+  Left     Expression  // the argument
+  Function Expression
+}
+
+func (pe *PipeExpression) expressionNode()      {}
+func (pe *PipeExpression) TokenLiteral() string { return pe.Token.Literal }
+func (pe *PipeExpression) Pos() (int, int)      { return pe.Token.Line, pe.Token.Column }
+func (pe *PipeExpression) String() string {
+  var out bytes.Buffer
+
+  out.WriteString("(")
+  out.WriteString(pe.Left.String())
+  out.WriteString(" |> ")
+  out.WriteString(pe.Function.String())
+  out.WriteString(")")
+
+  return out.String()
+}
+
+// eg:
+// h["key"] = 1
+// arr[0] = 1
+// x = 1
+type AssignExpression struct {
+  Token  token.Token // the '=' token
+  Target Expression  // an IndexExpression or an Identifier
+  Value  Expression
+}
+
+func (ae *AssignExpression) expressionNode()      {}
+func (ae *AssignExpression) TokenLiteral() string { return ae.Token.Literal }
+func (ae *AssignExpression) Pos() (int, int)      { return ae.Token.Line, ae.Token.Column }
+func (ae *AssignExpression) String() string {
+  var out bytes.Buffer
+
+  out.WriteString(ae.Target.String())
+  out.WriteString(" = ")
+  out.WriteString(ae.Value.String())
+
+  return out.String()
+}
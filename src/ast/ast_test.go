@@ -1 +1,99 @@
 package ast
+
+import (
+  "JFFMonkeyLang/src/token"
+  "testing"
+)
+
+// if (x) { fn(a) { a } }
+func TestDump(t *testing.T) {
+  program := &Program{
+    Statements: []Statement{
+      &ExpressionStatement{
+        Token: token.Token{Type: token.IF, Literal: "if"},
+        Expression: &IfExpression{
+          Token:     token.Token{Type: token.IF, Literal: "if"},
+          Condition: &Identifier{Token: token.Token{Type: token.IDENT, Literal: "x"}, Value: "x"},
+          Consequence: &BlockStatement{
+            Token: token.Token{Type: token.LBRACE, Literal: "{"},
+            Statements: []Statement{
+              &ExpressionStatement{
+                Token: token.Token{Type: token.FUNCTION, Literal: "fn"},
+                Expression: &FunctionLiteral{
+                  Token: token.Token{Type: token.FUNCTION, Literal: "fn"},
+                  Parameters: []*Identifier{
+                    {Token: token.Token{Type: token.IDENT, Literal: "a"}, Value: "a"},
+                  },
+                  Body: &BlockStatement{
+                    Token: token.Token{Type: token.LBRACE, Literal: "{"},
+                    Statements: []Statement{
+                      &ExpressionStatement{
+                        Token:      token.Token{Type: token.IDENT, Literal: "a"},
+                        Expression: &Identifier{Token: token.Token{Type: token.IDENT, Literal: "a"}, Value: "a"},
+                      },
+                    },
+                  },
+                },
+              },
+            },
+          },
+        },
+      },
+    },
+  }
+
+  expected := `Program
+  Statements:
+    ExpressionStatement
+      Expression: IfExpression
+        Condition: Identifier
+          Value: "x"
+        Consequence: BlockStatement
+          Statements:
+            ExpressionStatement
+              Expression: FunctionLiteral
+                Parameters:
+                  Identifier
+                    Value: "a"
+                Defaults: []
+                Rest: false
+                Body: BlockStatement
+                  Statements:
+                    ExpressionStatement
+                      Expression: Identifier
+                        Value: "a"
+        Alternative: nil
+`
+
+  if Dump(program) != expected {
+    t.Errorf("Dump(program) wrong.\nexpected=%s\ngot=%s", expected, Dump(program))
+  }
+}
+
+func TestDumpLiterals(t *testing.T) {
+  tests := []struct {
+    node     Node
+    expected string
+  }{
+    {nil, "nil\n"},
+    {&Program{Statements: []Statement{}}, "Program\n  Statements: []\n"},
+    {
+      &PrefixExpression{
+        Token:    token.Token{Type: token.MINUS, Literal: "-"},
+        Operator: "-",
+        Right:    &IntegerLiteral{Token: token.Token{Type: token.INT, Literal: "5"}, Value: 5},
+      },
+      "PrefixExpression\n  Operator: \"-\"\n  Right: IntegerLiteral\n    Value: 5\n",
+    },
+    {
+      &Boolean{Token: token.Token{Type: token.TRUE, Literal: "true"}, Value: true},
+      "Boolean\n  Value: true\n",
+    },
+  }
+
+  for _, tt := range tests {
+    if actual := Dump(tt.node); actual != tt.expected {
+      t.Errorf("expected=%q, got=%q", tt.expected, actual)
+    }
+  }
+}
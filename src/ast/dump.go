@@ -0,0 +1,102 @@
+package ast
+
+import (
+  "bytes"
+  "fmt"
+  "reflect"
+  "strings"
+)
+
+const dumpIndent = "  "
+
+var nodeType = reflect.TypeOf((*Node)(nil)).Elem()
+
+// Dump renders node as an indented tree, one field per line,
+// eg: `x + 1`
+//
+//   InfixExpression
+//     Left: Identifier
+//       Value: "x"
+//     Operator: "+"
+//     Right: IntegerLiteral
+//       Value: 1
+//
+// Token fields are skipped, they only repeat the source text.
+func Dump(node Node) string {
+  var out bytes.Buffer
+
+  if isNilValue(reflect.ValueOf(node)) {
+    return "nil\n"
+  }
+  dumpNode(&out, reflect.ValueOf(node), 0)
+
+  return out.String()
+}
+
+// v is a non-nil pointer to a node struct
+func dumpNode(out *bytes.Buffer, v reflect.Value, level int) {
+  out.WriteString(v.Elem().Type().Name() + "\n")
+
+  s := v.Elem()
+  for i := 0; i < s.NumField(); i++ {
+    field := s.Type().Field(i)
+    if field.Name == "Token" || !field.IsExported() {
+      continue
+    }
+    dumpField(out, field.Name, s.Field(i), level+1)
+  }
+}
+
+// eg: Name: value
+func dumpField(out *bytes.Buffer, name string, v reflect.Value, level int) {
+  out.WriteString(strings.Repeat(dumpIndent, level) + name + ":")
+
+  if v.Kind() == reflect.Slice {
+    if v.Len() == 0 {
+      out.WriteString(" []\n")
+      return
+    }
+
+    // every element gets its own line, one level deeper
+    out.WriteString("\n")
+    for i := 0; i < v.Len(); i++ {
+      out.WriteString(strings.Repeat(dumpIndent, level+1))
+      dumpValue(out, v.Index(i), level+1)
+    }
+    return
+  }
+
+  out.WriteString(" ")
+  dumpValue(out, v, level)
+}
+
+func dumpValue(out *bytes.Buffer, v reflect.Value, level int) {
+  // Expression, Statement, ... hold a concrete node pointer
+  if v.Kind() == reflect.Interface && !v.IsNil() {
+    v = v.Elem()
+  }
+
+  switch {
+  case isNilValue(v):
+    out.WriteString("nil\n")
+  case v.Type().Implements(nodeType) && v.Kind() == reflect.Ptr:
+    dumpNode(out, v, level)
+  case v.Kind() == reflect.String:
+    fmt.Fprintf(out, "%q\n", v.String())
+  default:
+    fmt.Fprintf(out, "%v\n", v.Interface())
+  }
+}
+
+func isNilValue(v reflect.Value) bool {
+  if !v.IsValid() {
+    return true
+  }
+
+  switch v.Kind() {
+  case reflect.Interface, reflect.Ptr, reflect.Map, reflect.Slice:
+    return v.IsNil()
+  }
+
+  return false
+}
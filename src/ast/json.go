@@ -0,0 +1,185 @@
+package ast
+
+import (
+  "bytes"
+  "encoding/json"
+  "fmt"
+  "reflect"
+)
+
+// concrete node types by name, FromJSON looks the "type" discriminator up here
+var nodeTypes = map[string]reflect.Type{}
+
+func init() {
+  for _, n := range []Node{
+    &Program{}, &LetStatement{}, &ConstStatement{}, &DestructureStatement{},
+    &ReturnStatement{}, &ThrowStatement{},
+    &ExpressionStatement{}, &BlockStatement{}, &Identifier{}, &Boolean{},
+    &IntegerLiteral{}, &BigIntegerLiteral{}, &StringLiteral{},
+    &TemplateLiteral{}, &PrefixExpression{}, &InfixExpression{},
+    &IfExpression{}, &WhileExpression{}, &DoWhileExpression{}, &ForExpression{},
+    &ForInExpression{}, &TryExpression{},
+    &FunctionLiteral{}, &MacroLiteral{}, &CallExpression{}, &MethodCallExpression{},
+    &ArrayLiteral{},
+    &IndexExpression{}, &HashLiteral{}, &AssignExpression{}, &PipeExpression{},
+    &MatchExpression{},
+  } {
+    t := reflect.TypeOf(n).Elem()
+    nodeTypes[t.Name()] = t
+  }
+}
+
+// ToJSON serializes node as one object per node, fields in declaration order,
+// eg: `x + 1`
+//
+//   {"type":"InfixExpression","Token":{...},"Left":{"type":"Identifier",...},
+//    "Operator":"+","Right":{"type":"IntegerLiteral",...}}
+//
+// unlike Dump, Token fields are kept so positions survive the trip.
+func ToJSON(node Node) ([]byte, error) {
+  var out bytes.Buffer
+
+  if err := writeJSONValue(&out, reflect.ValueOf(node)); err != nil {
+    return nil, err
+  }
+
+  return out.Bytes(), nil
+}
+
+// v is a non-nil pointer to a node struct
+func writeJSONNode(out *bytes.Buffer, v reflect.Value) error {
+  s := v.Elem()
+  fmt.Fprintf(out, `{"type":%q`, s.Type().Name())
+
+  for i := 0; i < s.NumField(); i++ {
+    field := s.Type().Field(i)
+    if !field.IsExported() {
+      continue
+    }
+
+    fmt.Fprintf(out, ",%q:", field.Name)
+    if err := writeJSONValue(out, s.Field(i)); err != nil {
+      return err
+    }
+  }
+
+  out.WriteString("}")
+  return nil
+}
+
+func writeJSONValue(out *bytes.Buffer, v reflect.Value) error {
+  // Expression, Statement, ... hold a concrete node pointer
+  if v.Kind() == reflect.Interface && !v.IsNil() {
+    v = v.Elem()
+  }
+
+  switch {
+  case isNilValue(v):
+    // nil slices too, so they come back nil rather than empty
+    out.WriteString("null")
+  case v.Type().Implements(nodeType) && v.Kind() == reflect.Ptr:
+    return writeJSONNode(out, v)
+  case v.Kind() == reflect.Slice:
+    out.WriteString("[")
+    for i := 0; i < v.Len(); i++ {
+      if i > 0 {
+        out.WriteString(",")
+      }
+      if err := writeJSONValue(out, v.Index(i)); err != nil {
+        return err
+      }
+    }
+    out.WriteString("]")
+  default:
+    b, err := json.Marshal(v.Interface())
+    if err != nil {
+      return err
+    }
+    out.Write(b)
+  }
+
+  return nil
+}
+
+// FromJSON rebuilds the tree ToJSON wrote
+func FromJSON(data []byte) (Node, error) {
+  v, err := readJSONNode(data)
+  if err != nil {
+    return nil, err
+  }
+  if !v.IsValid() {
+    return nil, nil
+  }
+
+  return v.Interface().(Node), nil
+}
+
+// returns the zero Value for null
+func readJSONNode(data []byte) (reflect.Value, error) {
+  if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+    return reflect.Value{}, nil
+  }
+
+  var fields map[string]json.RawMessage
+  if err := json.Unmarshal(data, &fields); err != nil {
+    return reflect.Value{}, err
+  }
+
+  var name string
+  if err := json.Unmarshal(fields["type"], &name); err != nil {
+    return reflect.Value{}, fmt.Errorf("node without a type: %s", data)
+  }
+  t, ok := nodeTypes[name]
+  if !ok {
+    return reflect.Value{}, fmt.Errorf("unknown node type: %s", name)
+  }
+
+  v := reflect.New(t)
+  s := v.Elem()
+  for i := 0; i < s.NumField(); i++ {
+    field := t.Field(i)
+    raw, ok := fields[field.Name]
+    if !field.IsExported() || !ok {
+      continue
+    }
+    if err := readJSONValue(raw, s.Field(i)); err != nil {
+      return reflect.Value{}, fmt.Errorf("%s.%s: %s", name, field.Name, err)
+    }
+  }
+
+  return v, nil
+}
+
+// fills dst, which is a settable struct field or slice element
+func readJSONValue(data []byte, dst reflect.Value) error {
+  switch {
+  case dst.Kind() == reflect.Interface, dst.Kind() == reflect.Ptr && dst.Type().Implements(nodeType):
+    node, err := readJSONNode(data)
+    if err != nil || !node.IsValid() {
+      return err
+    }
+    if !node.Type().AssignableTo(dst.Type()) {
+      return fmt.Errorf("%s is not a %s", node.Elem().Type().Name(), dst.Type())
+    }
+    dst.Set(node)
+  case dst.Kind() == reflect.Slice:
+    var elems []json.RawMessage
+    if err := json.Unmarshal(data, &elems); err != nil {
+      return err
+    }
+    if elems == nil {
+      return nil
+    }
+    slice := reflect.MakeSlice(dst.Type(), len(elems), len(elems))
+    for i, elem := range elems {
+      if err := readJSONValue(elem, slice.Index(i)); err != nil {
+        return err
+      }
+    }
+    dst.Set(slice)
+  default:
+    return json.Unmarshal(data, dst.Addr().Interface())
+  }
+
+  return nil
+}
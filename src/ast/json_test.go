@@ -0,0 +1,129 @@
+package ast
+
+import (
+  "JFFMonkeyLang/src/token"
+  "encoding/json"
+  "math/big"
+  "reflect"
+  "testing"
+)
+
+// let f = fn(a, ...rest) { a * 2 }; f(x = 1)
+func TestJSONRoundTrip(t *testing.T) {
+  a := &Identifier{Token: token.Token{Type: token.IDENT, Literal: "a", Line: 1, Column: 12}, Value: "a"}
+  program := &Program{
+    Statements: []Statement{
+      &LetStatement{
+        Token: token.Token{Type: token.LET, Literal: "let", Line: 1, Column: 1},
+        Name:  &Identifier{Token: token.Token{Type: token.IDENT, Literal: "f", Line: 1, Column: 5}, Value: "f"},
+        Value: &FunctionLiteral{
+          Token: token.Token{Type: token.FUNCTION, Literal: "fn", Line: 1, Column: 9},
+          Parameters: []*Identifier{
+            a,
+            {Token: token.Token{Type: token.IDENT, Literal: "rest", Line: 1, Column: 18}, Value: "rest"},
+          },
+          Rest: true,
+          Body: &BlockStatement{
+            Token: token.Token{Type: token.LBRACE, Literal: "{", Line: 1, Column: 24},
+            Statements: []Statement{
+              &ExpressionStatement{
+                Token: a.Token,
+                Expression: &InfixExpression{
+                  Token:    token.Token{Type: token.ASTERISK, Literal: "*", Line: 1, Column: 28},
+                  Left:     a,
+                  Operator: "*",
+                  Right:    &IntegerLiteral{Token: token.Token{Type: token.INT, Literal: "2", Line: 1, Column: 30}, Value: 2},
+                },
+              },
+            },
+          },
+        },
+      },
+      &ExpressionStatement{
+        Token: token.Token{Type: token.IDENT, Literal: "f", Line: 1, Column: 35},
+        Expression: &CallExpression{
+          Token:         token.Token{Type: token.LPAREN, Literal: "(", Line: 1, Column: 36},
+          Function:      &Identifier{Token: token.Token{Type: token.IDENT, Literal: "f", Line: 1, Column: 35}, Value: "f"},
+          Keywords:      []*Identifier{{Token: token.Token{Type: token.IDENT, Literal: "x", Line: 1, Column: 37}, Value: "x"}},
+          KeywordValues: []Expression{&IntegerLiteral{Token: token.Token{Type: token.INT, Literal: "1", Line: 1, Column: 41}, Value: 1}},
+        },
+      },
+      &ExpressionStatement{
+        Token:      token.Token{Type: token.INT, Literal: "18446744073709551616", Line: 1, Column: 45},
+        Expression: &BigIntegerLiteral{Token: token.Token{Type: token.INT, Literal: "18446744073709551616", Line: 1, Column: 45}, Value: new(big.Int).Lsh(big.NewInt(1), 64)},
+      },
+    },
+  }
+
+  data, err := ToJSON(program)
+  if err != nil {
+    t.Fatalf("ToJSON failed: %s", err)
+  }
+
+  node, err := FromJSON(data)
+  if err != nil {
+    t.Fatalf("FromJSON failed: %s", err)
+  }
+
+  if !reflect.DeepEqual(node, program) {
+    t.Errorf("round trip changed the tree. expected=%q, got=%q", program.String(), node.String())
+  }
+}
+
+func TestInfixExpressionJSON(t *testing.T) {
+  infix := &InfixExpression{
+    Token:    token.Token{Type: token.PLUS, Literal: "+", Line: 1, Column: 3},
+    Left:     &Identifier{Token: token.Token{Type: token.IDENT, Literal: "x", Line: 1, Column: 1}, Value: "x"},
+    Operator: "+",
+    Right:    &IntegerLiteral{Token: token.Token{Type: token.INT, Literal: "1", Line: 1, Column: 5}, Value: 1},
+  }
+
+  data, err := ToJSON(infix)
+  if err != nil {
+    t.Fatalf("ToJSON failed: %s", err)
+  }
+
+  var got map[string]interface{}
+  if err := json.Unmarshal(data, &got); err != nil {
+    t.Fatalf("ToJSON wrote invalid JSON %s: %s", data, err)
+  }
+
+  expected := map[string]interface{}{
+    "type":     "InfixExpression",
+    "Token":    map[string]interface{}{"Type": "+", "Literal": "+", "Line": 1.0, "Column": 3.0},
+    "Operator": "+",
+    "Left": map[string]interface{}{
+      "type":  "Identifier",
+      "Token": map[string]interface{}{"Type": "IDENT", "Literal": "x", "Line": 1.0, "Column": 1.0},
+      "Value": "x",
+    },
+    "Right": map[string]interface{}{
+      "type":  "IntegerLiteral",
+      "Token": map[string]interface{}{"Type": "INT", "Literal": "1", "Line": 1.0, "Column": 5.0},
+      "Value": 1.0,
+    },
+    "Grouped": false,
+  }
+
+  if !reflect.DeepEqual(got, expected) {
+    t.Errorf("wrong JSON. expected=%v, got=%s", expected, data)
+  }
+}
+
+func TestFromJSONErrors(t *testing.T) {
+  tests := []struct {
+    input    string
+    expected string
+  }{
+    {`{"Value": "x"}`, `node without a type: {"Value": "x"}`},
+    {`{"type": "Nope"}`, "unknown node type: Nope"},
+    {`{"type": "LetStatement", "Name": {"type": "IntegerLiteral"}}`, "LetStatement.Name: IntegerLiteral is not a *ast.Identifier"},
+  }
+
+  for _, tt := range tests {
+    _, err := FromJSON([]byte(tt.input))
+    if err == nil || err.Error() != tt.expected {
+      t.Errorf("wrong error for %s. expected=%q, got=%v", tt.input, tt.expected, err)
+    }
+  }
+}
@@ -0,0 +1,159 @@
+package ast
+
+// ModifierFunc replaces a node, returning the node itself keeps it
+type ModifierFunc func(Node) Node
+
+// Modify walks node depth first and replaces every child with
+// modifier(child), then gives back modifier(node)
+// eg: turn every `1` into `2`
+//
+//   Modify(program, func(node Node) Node {
+//     if integer, ok := node.(*IntegerLiteral); ok && integer.Value == 1 {
+//       integer.Value = 2
+//     }
+//     return node
+//   })
+func Modify(node Node, modifier ModifierFunc) Node {
+  switch node := node.(type) {
+
+  /* Statements */
+  case *Program:
+    for i, statement := range node.Statements {
+      node.Statements[i], _ = Modify(statement, modifier).(Statement)
+    }
+
+  case *ExpressionStatement:
+    node.Expression, _ = Modify(node.Expression, modifier).(Expression)
+
+  case *BlockStatement:
+    for i, statement := range node.Statements {
+      node.Statements[i], _ = Modify(statement, modifier).(Statement)
+    }
+
+  case *ReturnStatement:
+    node.ReturnValue, _ = Modify(node.ReturnValue, modifier).(Expression)
+
+  case *ThrowStatement:
+    node.Value, _ = Modify(node.Value, modifier).(Expression)
+
+  case *LetStatement:
+    node.Value, _ = Modify(node.Value, modifier).(Expression)
+
+  case *ConstStatement:
+    node.Value, _ = Modify(node.Value, modifier).(Expression)
+
+  case *DestructureStatement:
+    node.Value, _ = Modify(node.Value, modifier).(Expression)
+
+  /* Expressions */
+  case *PrefixExpression:
+    node.Right, _ = Modify(node.Right, modifier).(Expression)
+
+  case *InfixExpression:
+    node.Left, _ = Modify(node.Left, modifier).(Expression)
+    node.Right, _ = Modify(node.Right, modifier).(Expression)
+
+  case *IndexExpression:
+    node.Left, _ = Modify(node.Left, modifier).(Expression)
+    node.Index, _ = Modify(node.Index, modifier).(Expression)
+
+  case *MethodCallExpression:
+    node.Receiver, _ = Modify(node.Receiver, modifier).(Expression)
+    for i, arg := range node.Arguments {
+      node.Arguments[i], _ = Modify(arg, modifier).(Expression)
+    }
+
+  case *PipeExpression:
+    node.Left, _ = Modify(node.Left, modifier).(Expression)
+    node.Function, _ = Modify(node.Function, modifier).(Expression)
+
+  case *AssignExpression:
+    node.Target, _ = Modify(node.Target, modifier).(Expression)
+    node.Value, _ = Modify(node.Value, modifier).(Expression)
+
+  case *IfExpression:
+    node.Condition, _ = Modify(node.Condition, modifier).(Expression)
+    node.Consequence, _ = Modify(node.Consequence, modifier).(*BlockStatement)
+    if node.Alternative != nil {
+      node.Alternative, _ = Modify(node.Alternative, modifier).(*BlockStatement)
+    }
+
+  case *WhileExpression:
+    node.Condition, _ = Modify(node.Condition, modifier).(Expression)
+    node.Body, _ = Modify(node.Body, modifier).(*BlockStatement)
+
+  case *ForExpression:
+    if node.Init != nil {
+      node.Init, _ = Modify(node.Init, modifier).(Statement)
+    }
+    if node.Condition != nil {
+      node.Condition, _ = Modify(node.Condition, modifier).(Expression)
+    }
+    if node.Post != nil {
+      node.Post, _ = Modify(node.Post, modifier).(Expression)
+    }
+    node.Body, _ = Modify(node.Body, modifier).(*BlockStatement)
+
+  case *ForInExpression:
+    node.Key, _ = Modify(node.Key, modifier).(*Identifier)
+    if node.Value != nil {
+      node.Value, _ = Modify(node.Value, modifier).(*Identifier)
+    }
+    node.Iterable, _ = Modify(node.Iterable, modifier).(Expression)
+    node.Body, _ = Modify(node.Body, modifier).(*BlockStatement)
+
+  case *TryExpression:
+    node.Block, _ = Modify(node.Block, modifier).(*BlockStatement)
+    node.Name, _ = Modify(node.Name, modifier).(*Identifier)
+    node.Catch, _ = Modify(node.Catch, modifier).(*BlockStatement)
+
+  case *DoWhileExpression:
+    node.Body, _ = Modify(node.Body, modifier).(*BlockStatement)
+    node.Condition, _ = Modify(node.Condition, modifier).(Expression)
+
+  case *FunctionLiteral:
+    for i, param := range node.Parameters {
+      node.Parameters[i], _ = Modify(param, modifier).(*Identifier)
+    }
+    for i, value := range node.Defaults {
+      if value != nil {
+        node.Defaults[i], _ = Modify(value, modifier).(Expression)
+      }
+    }
+    node.Body, _ = Modify(node.Body, modifier).(*BlockStatement)
+
+  case *CallExpression:
+    node.Function, _ = Modify(node.Function, modifier).(Expression)
+    for i, arg := range node.Arguments {
+      node.Arguments[i], _ = Modify(arg, modifier).(Expression)
+    }
+    for i, value := range node.KeywordValues {
+      node.KeywordValues[i], _ = Modify(value, modifier).(Expression)
+    }
+
+  case *TemplateLiteral:
+    for i, exp := range node.Expressions {
+      node.Expressions[i], _ = Modify(exp, modifier).(Expression)
+    }
+
+  case *ArrayLiteral:
+    for i, element := range node.Elements {
+      node.Elements[i], _ = Modify(element, modifier).(Expression)
+    }
+
+  case *HashLiteral:
+    for i := range node.Keys {
+      node.Keys[i], _ = Modify(node.Keys[i], modifier).(Expression)
+      node.Values[i], _ = Modify(node.Values[i], modifier).(Expression)
+    }
+
+  case *MatchExpression:
+    node.Subject, _ = Modify(node.Subject, modifier).(Expression)
+    for i := range node.Patterns {
+      node.Patterns[i], _ = Modify(node.Patterns[i], modifier).(Expression)
+      node.Results[i], _ = Modify(node.Results[i], modifier).(Expression)
+    }
+  }
+
+  return modifier(node)
+}
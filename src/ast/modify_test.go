@@ -0,0 +1,113 @@
+package ast
+
+import (
+  "reflect"
+  "testing"
+)
+
+func TestModify(t *testing.T) {
+  one := func() Expression { return &IntegerLiteral{Value: 1} }
+  two := func() Expression { return &IntegerLiteral{Value: 2} }
+
+  turnOneIntoTwo := func(node Node) Node {
+    integer, ok := node.(*IntegerLiteral)
+    if !ok {
+      return node
+    }
+
+    if integer.Value != 1 {
+      return node
+    }
+
+    integer.Value = 2
+    return integer
+  }
+
+  tests := []struct {
+    input    Node
+    expected Node
+  }{
+    {one(), two()},
+    {
+      &Program{Statements: []Statement{&ExpressionStatement{Expression: one()}}},
+      &Program{Statements: []Statement{&ExpressionStatement{Expression: two()}}},
+    },
+    {
+      &InfixExpression{Left: one(), Operator: "+", Right: two()},
+      &InfixExpression{Left: two(), Operator: "+", Right: two()},
+    },
+    {
+      &InfixExpression{Left: two(), Operator: "+", Right: one()},
+      &InfixExpression{Left: two(), Operator: "+", Right: two()},
+    },
+    {
+      &PrefixExpression{Operator: "-", Right: one()},
+      &PrefixExpression{Operator: "-", Right: two()},
+    },
+    {
+      &IndexExpression{Left: one(), Index: one()},
+      &IndexExpression{Left: two(), Index: two()},
+    },
+    {
+      &AssignExpression{Target: &IndexExpression{Left: one(), Index: one()}, Value: one()},
+      &AssignExpression{Target: &IndexExpression{Left: two(), Index: two()}, Value: two()},
+    },
+    {
+      &IfExpression{
+        Condition:   one(),
+        Consequence: &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: one()}}},
+        Alternative: &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: one()}}},
+      },
+      &IfExpression{
+        Condition:   two(),
+        Consequence: &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: two()}}},
+        Alternative: &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: two()}}},
+      },
+    },
+    {
+      &WhileExpression{
+        Condition: one(),
+        Body:      &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: one()}}},
+      },
+      &WhileExpression{
+        Condition: two(),
+        Body:      &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: two()}}},
+      },
+    },
+    {&ReturnStatement{ReturnValue: one()}, &ReturnStatement{ReturnValue: two()}},
+    {&LetStatement{Value: one()}, &LetStatement{Value: two()}},
+    {&ConstStatement{Value: one()}, &ConstStatement{Value: two()}},
+    {
+      &FunctionLiteral{
+        Parameters: []*Identifier{},
+        Defaults:   []Expression{one()},
+        Body:       &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: one()}}},
+      },
+      &FunctionLiteral{
+        Parameters: []*Identifier{},
+        Defaults:   []Expression{two()},
+        Body:       &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: two()}}},
+      },
+    },
+    {
+      &CallExpression{Function: one(), Arguments: []Expression{one()}, KeywordValues: []Expression{one()}},
+      &CallExpression{Function: two(), Arguments: []Expression{two()}, KeywordValues: []Expression{two()}},
+    },
+    {
+      &ArrayLiteral{Elements: []Expression{one(), one()}},
+      &ArrayLiteral{Elements: []Expression{two(), two()}},
+    },
+    {
+      &HashLiteral{Keys: []Expression{one(), one()}, Values: []Expression{one(), one()}},
+      &HashLiteral{Keys: []Expression{two(), two()}, Values: []Expression{two(), two()}},
+    },
+  }
+
+  for _, tt := range tests {
+    modified := Modify(tt.input, turnOneIntoTwo)
+
+    if !reflect.DeepEqual(modified, tt.expected) {
+      t.Errorf("not equal.\ngot=%s\nwant=%s", Dump(modified), Dump(tt.expected))
+    }
+  }
+}
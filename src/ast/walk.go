@@ -0,0 +1,163 @@
+package ast
+
+// Walk visits node and then its children depth first, in source order;
+// children are skipped when visit returns false, like go/ast.Inspect
+// eg: count identifiers
+//
+//   Walk(program, func(node Node) bool {
+//     if _, ok := node.(*Identifier); ok {
+//       count++
+//     }
+//     return true
+//   })
+func Walk(node Node, visit func(Node) bool) {
+  if node == nil || !visit(node) {
+    return
+  }
+
+  switch node := node.(type) {
+
+  /* Statements */
+  case *Program:
+    for _, statement := range node.Statements {
+      Walk(statement, visit)
+    }
+
+  case *ExpressionStatement:
+    Walk(node.Expression, visit)
+
+  case *BlockStatement:
+    for _, statement := range node.Statements {
+      Walk(statement, visit)
+    }
+
+  case *ReturnStatement:
+    Walk(node.ReturnValue, visit)
+
+  case *ThrowStatement:
+    Walk(node.Value, visit)
+
+  case *LetStatement:
+    Walk(node.Name, visit)
+    Walk(node.Value, visit)
+
+  case *ConstStatement:
+    Walk(node.Name, visit)
+    Walk(node.Value, visit)
+
+  case *DestructureStatement:
+    for _, name := range node.Names {
+      Walk(name, visit)
+    }
+    Walk(node.Value, visit)
+
+  /* Expressions */
+  case *PrefixExpression:
+    Walk(node.Right, visit)
+
+  case *InfixExpression:
+    Walk(node.Left, visit)
+    Walk(node.Right, visit)
+
+  case *IndexExpression:
+    Walk(node.Left, visit)
+    Walk(node.Index, visit)
+
+  case *PipeExpression:
+    Walk(node.Left, visit)
+    Walk(node.Function, visit)
+
+  case *AssignExpression:
+    Walk(node.Target, visit)
+    Walk(node.Value, visit)
+
+  case *IfExpression:
+    Walk(node.Condition, visit)
+    Walk(node.Consequence, visit)
+    if node.Alternative != nil {
+      Walk(node.Alternative, visit)
+    }
+
+  case *WhileExpression:
+    Walk(node.Condition, visit)
+    Walk(node.Body, visit)
+
+  case *ForExpression:
+    Walk(node.Init, visit)
+    Walk(node.Condition, visit)
+    Walk(node.Post, visit)
+    Walk(node.Body, visit)
+
+  case *ForInExpression:
+    Walk(node.Key, visit)
+    if node.Value != nil {
+      Walk(node.Value, visit)
+    }
+    Walk(node.Iterable, visit)
+    Walk(node.Body, visit)
+
+  case *TryExpression:
+    Walk(node.Block, visit)
+    Walk(node.Name, visit)
+    Walk(node.Catch, visit)
+
+  case *DoWhileExpression:
+    Walk(node.Body, visit)
+    Walk(node.Condition, visit)
+
+  case *FunctionLiteral:
+    for i, param := range node.Parameters {
+      Walk(param, visit)
+      if i < len(node.Defaults) {
+        Walk(node.Defaults[i], visit)
+      }
+    }
+    Walk(node.Body, visit)
+
+  case *MacroLiteral:
+    for _, param := range node.Parameters {
+      Walk(param, visit)
+    }
+    Walk(node.Body, visit)
+
+  case *CallExpression:
+    Walk(node.Function, visit)
+    for _, arg := range node.Arguments {
+      Walk(arg, visit)
+    }
+    for i, keyword := range node.Keywords {
+      Walk(keyword, visit)
+      Walk(node.KeywordValues[i], visit)
+    }
+
+  case *MethodCallExpression:
+    Walk(node.Receiver, visit)
+    Walk(node.Method, visit)
+    for _, arg := range node.Arguments {
+      Walk(arg, visit)
+    }
+
+  case *TemplateLiteral:
+    for _, exp := range node.Expressions {
+      Walk(exp, visit)
+    }
+
+  case *ArrayLiteral:
+    for _, element := range node.Elements {
+      Walk(element, visit)
+    }
+
+  case *HashLiteral:
+    for i := range node.Keys {
+      Walk(node.Keys[i], visit)
+      Walk(node.Values[i], visit)
+    }
+
+  case *MatchExpression:
+    Walk(node.Subject, visit)
+    for i := range node.Patterns {
+      Walk(node.Patterns[i], visit)
+      Walk(node.Results[i], visit)
+    }
+  }
+}
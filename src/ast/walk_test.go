@@ -0,0 +1,78 @@
+package ast
+
+import (
+  "reflect"
+  "testing"
+)
+
+func TestWalk(t *testing.T) {
+  ident := func(name string) *Identifier { return &Identifier{Value: name} }
+  block := func(statements ...Statement) *BlockStatement { return &BlockStatement{Statements: statements} }
+  expr := func(e Expression) Statement { return &ExpressionStatement{Expression: e} }
+
+  // let f = fn(a, b = c) { if (a) { b } else { [d, {e: g}] } };
+  // const h = macro(i) { j };
+  // f(k[l], m = n);
+  // while (o) { p = -q };
+  // return r;
+  program := &Program{Statements: []Statement{
+    &LetStatement{Name: ident("f"), Value: &FunctionLiteral{
+      Parameters: []*Identifier{ident("a"), ident("b")},
+      Defaults:   []Expression{nil, ident("c")},
+      Body: block(expr(&IfExpression{
+        Condition:   ident("a"),
+        Consequence: block(expr(ident("b"))),
+        Alternative: block(expr(&ArrayLiteral{Elements: []Expression{
+          ident("d"),
+          &HashLiteral{Keys: []Expression{ident("e")}, Values: []Expression{ident("g")}},
+        }})),
+      })),
+    }},
+    &ConstStatement{Name: ident("h"), Value: &MacroLiteral{
+      Parameters: []*Identifier{ident("i")},
+      Body:       block(expr(ident("j"))),
+    }},
+    expr(&CallExpression{
+      Function:      ident("f"),
+      Arguments:     []Expression{&IndexExpression{Left: ident("k"), Index: ident("l")}},
+      Keywords:      []*Identifier{ident("m")},
+      KeywordValues: []Expression{ident("n")},
+    }),
+    expr(&WhileExpression{
+      Condition: ident("o"),
+      Body: block(expr(&AssignExpression{
+        Target: ident("p"),
+        Value:  &PrefixExpression{Operator: "-", Right: ident("q")},
+      })),
+    }),
+    &ReturnStatement{ReturnValue: &InfixExpression{Left: ident("r"), Operator: "+", Right: &IntegerLiteral{Value: 1}}},
+  }}
+
+  names := []string{}
+  Walk(program, func(node Node) bool {
+    if identifier, ok := node.(*Identifier); ok {
+      names = append(names, identifier.Value)
+    }
+    return true
+  })
+
+  expected := []string{"f", "a", "b", "c", "a", "b", "d", "e", "g", "h", "i", "j", "f", "k", "l", "m", "n", "o", "p", "q", "r"}
+  if !reflect.DeepEqual(names, expected) {
+    t.Errorf("wrong identifiers visited. expected=%v, got=%v", expected, names)
+  }
+
+  // returning false keeps Walk out of function bodies
+  names = []string{}
+  Walk(program, func(node Node) bool {
+    if identifier, ok := node.(*Identifier); ok {
+      names = append(names, identifier.Value)
+    }
+    _, isFunction := node.(*FunctionLiteral)
+    return !isFunction
+  })
+
+  expected = []string{"f", "h", "i", "j", "f", "k", "l", "m", "n", "o", "p", "q", "r"}
+  if !reflect.DeepEqual(names, expected) {
+    t.Errorf("wrong identifiers visited when pruning. expected=%v, got=%v", expected, names)
+  }
+}
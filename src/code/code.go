@@ -0,0 +1,174 @@
+package code
+
+import (
+  "bytes"
+  "encoding/binary"
+  "fmt"
+)
+
+// the bytecode of a compiled program, opcodes followed by their operands
+type Instructions []byte
+
+func (ins Instructions) String() string {
+  return String(ins)
+}
+
+// String disassembles ins, one instruction per line prefixed by its offset,
+// eg: 1 + 2
+//
+//   0000 OpConstant 0
+//   0003 OpConstant 1
+//   0006 OpAdd
+//   0007 OpPop
+func String(ins Instructions) string {
+  var out bytes.Buffer
+
+  i := 0
+  for i < len(ins) {
+    def, err := Lookup(ins[i])
+    if err != nil {
+      fmt.Fprintf(&out, "ERROR: %s\n", err)
+      // an unknown byte has no operands we could skip
+      i++
+      continue
+    }
+
+    operands, read := ReadOperands(def, ins[i+1:])
+    fmt.Fprintf(&out, "%04d %s\n", i, fmtInstruction(def, operands))
+
+    i += 1 + read
+  }
+
+  return out.String()
+}
+
+// eg: OpConstant 0, OpAdd
+func fmtInstruction(def *Definition, operands []int) string {
+  if len(operands) != len(def.OperandWidths) {
+    return fmt.Sprintf("ERROR: operand len %d does not match defined %d\n", len(operands), len(def.OperandWidths))
+  }
+
+  switch len(operands) {
+  case 0:
+    return def.Name
+  case 1:
+    return fmt.Sprintf("%s %d", def.Name, operands[0])
+  }
+
+  return fmt.Sprintf("ERROR: unhandled operand count for %s\n", def.Name)
+}
+
+type Opcode byte
+
+const (
+  OpConstant      Opcode = iota // push constants[operand]
+  OpPop                         // drop the top, after every expression statement
+  OpAdd                         // +, pops the right then the left operand
+  OpSub                         // -
+  OpMul                         // *
+  OpDiv                         // /
+  OpMod                         // %
+  OpPower                       // **
+  OpEqual                       // ==
+  OpNotEqual                    // !=
+  OpLessThan                    // <
+  OpGreaterThan                 // >
+  OpMinus                       // -x
+  OpBang                        // !x
+  OpTrue                        // push true
+  OpFalse                       // push false
+  OpNull                        // push null, eg: an if without else
+  OpJump                        // continue at the operand offset
+  OpJumpNotTruthy               // pop, jump to the operand offset if it was falsy
+  OpGetGlobal                   // push globals[operand]
+  OpSetGlobal                   // pop into globals[operand]
+)
+
+// eg: OpConstant has one two-byte operand, the constant index
+type Definition struct {
+  Name          string
+  OperandWidths []int // in bytes
+}
+
+var definitions = map[Opcode]*Definition{
+  OpConstant:      {"OpConstant", []int{2}},
+  OpPop:           {"OpPop", []int{}},
+  OpAdd:           {"OpAdd", []int{}},
+  OpSub:           {"OpSub", []int{}},
+  OpMul:           {"OpMul", []int{}},
+  OpDiv:           {"OpDiv", []int{}},
+  OpMod:           {"OpMod", []int{}},
+  OpPower:         {"OpPower", []int{}},
+  OpEqual:         {"OpEqual", []int{}},
+  OpNotEqual:      {"OpNotEqual", []int{}},
+  OpLessThan:      {"OpLessThan", []int{}},
+  OpGreaterThan:   {"OpGreaterThan", []int{}},
+  OpMinus:         {"OpMinus", []int{}},
+  OpBang:          {"OpBang", []int{}},
+  OpTrue:          {"OpTrue", []int{}},
+  OpFalse:         {"OpFalse", []int{}},
+  OpNull:          {"OpNull", []int{}},
+  OpJump:          {"OpJump", []int{2}},
+  OpJumpNotTruthy: {"OpJumpNotTruthy", []int{2}},
+  OpGetGlobal:     {"OpGetGlobal", []int{2}},
+  OpSetGlobal:     {"OpSetGlobal", []int{2}},
+}
+
+func Lookup(op byte) (*Definition, error) {
+  def, ok := definitions[Opcode(op)]
+  if !ok {
+    return nil, fmt.Errorf("opcode %d undefined", op)
+  }
+
+  return def, nil
+}
+
+// encode one instruction, operands are big endian
+// eg: Make(OpConstant, 65534) => [OpConstant, 0xFF, 0xFE]
+func Make(op Opcode, operands ...int) []byte {
+  def, ok := definitions[op]
+  if !ok {
+    return []byte{}
+  }
+
+  instructionLen := 1
+  for _, w := range def.OperandWidths {
+    instructionLen += w
+  }
+
+  instruction := make([]byte, instructionLen)
+  instruction[0] = byte(op)
+
+  offset := 1
+  for i, o := range operands {
+    width := def.OperandWidths[i]
+    switch width {
+    case 2:
+      binary.BigEndian.PutUint16(instruction[offset:], uint16(o))
+    }
+    offset += width
+  }
+
+  return instruction
+}
+
+// decode the operands of def at the start of ins,
+// also returns how many bytes they took
+func ReadOperands(def *Definition, ins Instructions) ([]int, int) {
+  operands := make([]int, len(def.OperandWidths))
+  offset := 0
+
+  for i, width := range def.OperandWidths {
+    switch width {
+    case 2:
+      operands[i] = int(ReadUint16(ins[offset:]))
+    }
+    offset += width
+  }
+
+  return operands, offset
+}
+
+func ReadUint16(ins Instructions) uint16 {
+  return binary.BigEndian.Uint16(ins)
+}
@@ -0,0 +1,88 @@
+package code
+
+import "testing"
+
+func TestMake(t *testing.T) {
+  tests := []struct {
+    op       Opcode
+    operands []int
+    expected []byte
+  }{
+    {OpConstant, []int{65534}, []byte{byte(OpConstant), 255, 254}},
+    {OpAdd, []int{}, []byte{byte(OpAdd)}},
+    {OpJumpNotTruthy, []int{7}, []byte{byte(OpJumpNotTruthy), 0, 7}},
+  }
+
+  for _, tt := range tests {
+    instruction := Make(tt.op, tt.operands...)
+
+    if len(instruction) != len(tt.expected) {
+      t.Fatalf("instruction has wrong length. want=%d, got=%d", len(tt.expected), len(instruction))
+    }
+    for i, b := range tt.expected {
+      if instruction[i] != b {
+        t.Errorf("wrong byte at pos %d. want=%d, got=%d", i, b, instruction[i])
+      }
+    }
+  }
+}
+
+func TestInstructionsString(t *testing.T) {
+  instructions := []Instructions{
+    Make(OpAdd),
+    Make(OpConstant, 2),
+    Make(OpConstant, 65535),
+    Make(OpJump, 1),
+  }
+
+  expected := `0000 OpAdd
+0001 OpConstant 2
+0004 OpConstant 65535
+0007 OpJump 1
+`
+
+  concatted := Instructions{}
+  for _, ins := range instructions {
+    concatted = append(concatted, ins...)
+  }
+
+  if concatted.String() != expected {
+    t.Errorf("instructions wrongly formatted.\nwant=%q\ngot=%q", expected, concatted.String())
+  }
+
+  // an unknown opcode doesn't stop the listing
+  broken := append(Instructions{255}, Make(OpPop)...)
+  if actual := String(broken); actual != "ERROR: opcode 255 undefined\n0001 OpPop\n" {
+    t.Errorf("wrong listing of unknown opcode. got=%q", actual)
+  }
+}
+
+func TestReadOperands(t *testing.T) {
+  tests := []struct {
+    op        Opcode
+    operands  []int
+    bytesRead int
+  }{
+    {OpConstant, []int{65535}, 2},
+    {OpPop, []int{}, 0},
+  }
+
+  for _, tt := range tests {
+    instruction := Make(tt.op, tt.operands...)
+
+    def, err := Lookup(byte(tt.op))
+    if err != nil {
+      t.Fatalf("definition not found: %q", err)
+    }
+
+    operandsRead, n := ReadOperands(def, instruction[1:])
+    if n != tt.bytesRead {
+      t.Fatalf("n wrong. want=%d, got=%d", tt.bytesRead, n)
+    }
+    for i, want := range tt.operands {
+      if operandsRead[i] != want {
+        t.Errorf("operand wrong. want=%d, got=%d", want, operandsRead[i])
+      }
+    }
+  }
+}
@@ -0,0 +1,260 @@
+package compiler
+
+import (
+  "JFFMonkeyLang/src/ast"
+  "JFFMonkeyLang/src/code"
+  "JFFMonkeyLang/src/object"
+  "bytes"
+  "fmt"
+)
+
+// the opcode of each infix operator
+var infixOpcodes = map[string]code.Opcode{
+  "+":  code.OpAdd,
+  "-":  code.OpSub,
+  "*":  code.OpMul,
+  "/":  code.OpDiv,
+  "%":  code.OpMod,
+  "**": code.OpPower,
+  "==": code.OpEqual,
+  "!=": code.OpNotEqual,
+  "<":  code.OpLessThan,
+  ">":  code.OpGreaterThan,
+}
+
+type EmittedInstruction struct {
+  Opcode   code.Opcode
+  Position int
+}
+
+// turns an ast into bytecode for the vm, so far integers, booleans,
+// their operators, if expressions and lets; anything else is a compile error
+type Compiler struct {
+  instructions code.Instructions
+  constants    []object.Object
+  symbolTable  *SymbolTable
+
+  // the last two emitted, eg: to drop the OpPop closing an if branch
+  lastInstruction     EmittedInstruction
+  previousInstruction EmittedInstruction
+}
+
+func New() *Compiler {
+  return &Compiler{
+    instructions: code.Instructions{},
+    constants:    []object.Object{},
+    symbolTable:  NewSymbolTable(),
+  }
+}
+
+// what the vm runs
+type Bytecode struct {
+  Instructions code.Instructions
+  Constants    []object.Object
+}
+
+// the disassembled instructions, then the constants by index
+// eg: 0000 OpConstant 0
+//     0003 OpPop
+//     constants:
+//     0000 INTEGER 5
+func (b *Bytecode) String() string {
+  var out bytes.Buffer
+
+  out.WriteString(code.String(b.Instructions))
+  if len(b.Constants) > 0 {
+    out.WriteString("constants:\n")
+  }
+  for i, constant := range b.Constants {
+    fmt.Fprintf(&out, "%04d %s %s\n", i, constant.Type(), constant.Inspect())
+  }
+
+  return out.String()
+}
+
+func (c *Compiler) Bytecode() *Bytecode {
+  return &Bytecode{Instructions: c.instructions, Constants: c.constants}
+}
+
+func (c *Compiler) Compile(node ast.Node) error {
+  switch node := node.(type) {
+
+  /* Statements */
+  case *ast.Program:
+    for _, s := range node.Statements {
+      if err := c.Compile(s); err != nil {
+        return err
+      }
+    }
+
+  case *ast.ExpressionStatement:
+    if err := c.Compile(node.Expression); err != nil {
+      return err
+    }
+    c.emit(code.OpPop)
+
+  case *ast.BlockStatement:
+    for _, s := range node.Statements {
+      if err := c.Compile(s); err != nil {
+        return err
+      }
+    }
+
+  case *ast.LetStatement:
+    if err := c.Compile(node.Value); err != nil {
+      return err
+    }
+    // defined after the value, like the evaluator: let x = x is an error
+    symbol := c.symbolTable.Define(node.Name.Value)
+    c.emit(code.OpSetGlobal, symbol.Index)
+
+  case *ast.ConstStatement:
+    if err := c.Compile(node.Value); err != nil {
+      return err
+    }
+    symbol := c.symbolTable.Define(node.Name.Value)
+    c.emit(code.OpSetGlobal, symbol.Index)
+
+  /* Expressions */
+  case *ast.Identifier:
+    symbol, ok := c.symbolTable.Resolve(node.Value)
+    if !ok {
+      return fmt.Errorf("identifier not found: %s", node.Value)
+    }
+    c.emit(code.OpGetGlobal, symbol.Index)
+
+  case *ast.IntegerLiteral:
+    c.emit(code.OpConstant, c.addConstant(&object.Integer{Value: node.Value}))
+
+  case *ast.BigIntegerLiteral:
+    c.emit(code.OpConstant, c.addConstant(&object.BigInteger{Value: node.Value}))
+
+  case *ast.Boolean:
+    if node.Value {
+      c.emit(code.OpTrue)
+    } else {
+      c.emit(code.OpFalse)
+    }
+
+  case *ast.PrefixExpression:
+    if err := c.Compile(node.Right); err != nil {
+      return err
+    }
+    switch node.Operator {
+    case "!":
+      c.emit(code.OpBang)
+    case "-":
+      c.emit(code.OpMinus)
+    default:
+      return fmt.Errorf("unknown operator %s", node.Operator)
+    }
+
+  case *ast.InfixExpression:
+    op, ok := infixOpcodes[node.Operator]
+    if !ok {
+      return fmt.Errorf("compiling %s is not supported yet", node.Operator)
+    }
+    // left first, like the evaluator, so the same error wins
+    if err := c.Compile(node.Left); err != nil {
+      return err
+    }
+    if err := c.Compile(node.Right); err != nil {
+      return err
+    }
+    c.emit(op)
+
+  case *ast.IfExpression:
+    return c.compileIfExpression(node)
+
+  default:
+    return fmt.Errorf("compiling %T is not supported yet", node)
+  }
+
+  return nil
+}
+
+// eg: if (x) { 10 } else { 20 }; 3333;
+//
+//   0000 OpTrue
+//   0001 OpJumpNotTruthy 10
+//   0004 OpConstant 0
+//   0007 OpJump 13
+//   0010 OpConstant 1
+//   0013 OpPop
+//   0014 OpConstant 2
+//   0017 OpPop
+func (c *Compiler) compileIfExpression(node *ast.IfExpression) error {
+  // 1.condition, then a jump over the consequence patched once its size is known
+  if err := c.Compile(node.Condition); err != nil {
+    return err
+  }
+  jumpNotTruthyPos := c.emit(code.OpJumpNotTruthy, 9999)
+
+  // 2.the branch leaves its last value on the stack, it's the if's value
+  if err := c.compileBranch(node.Consequence); err != nil {
+    return err
+  }
+  jumpPos := c.emit(code.OpJump, 9999)
+  c.changeOperand(jumpNotTruthyPos, len(c.instructions))
+
+  // 3.a missing else gives null
+  if node.Alternative == nil {
+    c.emit(code.OpNull)
+  } else if err := c.compileBranch(node.Alternative); err != nil {
+    return err
+  }
+  c.changeOperand(jumpPos, len(c.instructions))
+
+  return nil
+}
+
+// a block whose value stays on the stack, null if it is empty;
+// its lets are out of scope after it, like in the evaluator
+func (c *Compiler) compileBranch(block *ast.BlockStatement) error {
+  c.symbolTable = NewBlockSymbolTable(c.symbolTable)
+  err := c.Compile(block)
+  c.symbolTable = c.symbolTable.Outer
+  if err != nil {
+    return err
+  }
+
+  if c.lastInstructionIs(code.OpPop) {
+    c.removeLastPop()
+  } else {
+    c.emit(code.OpNull)
+  }
+
+  return nil
+}
+
+func (c *Compiler) addConstant(obj object.Object) int {
+  c.constants = append(c.constants, obj)
+  return len(c.constants) - 1
+}
+
+// returns the position of the new instruction
+func (c *Compiler) emit(op code.Opcode, operands ...int) int {
+  ins := code.Make(op, operands...)
+  pos := len(c.instructions)
+  c.instructions = append(c.instructions, ins...)
+
+  c.previousInstruction = c.lastInstruction
+  c.lastInstruction = EmittedInstruction{Opcode: op, Position: pos}
+
+  return pos
+}
+
+func (c *Compiler) lastInstructionIs(op code.Opcode) bool {
+  return len(c.instructions) > 0 && c.lastInstruction.Opcode == op
+}
+
+func (c *Compiler) removeLastPop() {
+  c.instructions = c.instructions[:c.lastInstruction.Position]
+  c.lastInstruction = c.previousInstruction
+}
+
+// rewrite the operand of the instruction at opPos, eg: a jump target
+func (c *Compiler) changeOperand(opPos int, operand int) {
+  op := code.Opcode(c.instructions[opPos])
+  copy(c.instructions[opPos:], code.Make(op, operand))
+}
@@ -0,0 +1,271 @@
+package compiler
+
+import (
+  "JFFMonkeyLang/src/ast"
+  "JFFMonkeyLang/src/code"
+  "JFFMonkeyLang/src/lexer"
+  "JFFMonkeyLang/src/object"
+  "JFFMonkeyLang/src/parser"
+  "flag"
+  "os"
+  "path/filepath"
+  "strings"
+  "testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the .golden files")
+
+// every testdata/*.monkey is compiled and its disassembly compared with its .golden file
+func TestDisassemblyGolden(t *testing.T) {
+  inputs, err := filepath.Glob(filepath.Join("testdata", "*.monkey"))
+  if err != nil || len(inputs) == 0 {
+    t.Fatalf("no golden inputs found: %v", err)
+  }
+
+  for _, input := range inputs {
+    source, err := os.ReadFile(input)
+    if err != nil {
+      t.Fatalf("could not read %s: %s", input, err)
+    }
+
+    compiler := New()
+    if err := compiler.Compile(parse(string(source))); err != nil {
+      t.Fatalf("%s: compiler error: %s", input, err)
+    }
+    actual := compiler.Bytecode().String()
+
+    golden := strings.TrimSuffix(input, ".monkey") + ".golden"
+    if *update {
+      if err := os.WriteFile(golden, []byte(actual), 0644); err != nil {
+        t.Fatalf("could not write %s: %s", golden, err)
+      }
+    }
+
+    expected, err := os.ReadFile(golden)
+    if err != nil {
+      t.Fatalf("could not read %s: %s", golden, err)
+    }
+    if actual != string(expected) {
+      t.Errorf("%s wrong.\nexpected=\n%s\ngot=\n%s", input, expected, actual)
+    }
+  }
+}
+
+type compilerTestCase struct {
+  input                string
+  expectedConstants    []int64
+  expectedInstructions []code.Instructions
+}
+
+func TestIntegerArithmetic(t *testing.T) {
+  tests := []compilerTestCase{
+    {
+      input:             "1 + 2",
+      expectedConstants: []int64{1, 2},
+      expectedInstructions: []code.Instructions{
+        code.Make(code.OpConstant, 0),
+        code.Make(code.OpConstant, 1),
+        code.Make(code.OpAdd),
+        code.Make(code.OpPop),
+      },
+    },
+    {
+      input:             "1; 2",
+      expectedConstants: []int64{1, 2},
+      expectedInstructions: []code.Instructions{
+        code.Make(code.OpConstant, 0),
+        code.Make(code.OpPop),
+        code.Make(code.OpConstant, 1),
+        code.Make(code.OpPop),
+      },
+    },
+    {
+      input:             "-(2 ** 3) % 5",
+      expectedConstants: []int64{2, 3, 5},
+      expectedInstructions: []code.Instructions{
+        code.Make(code.OpConstant, 0),
+        code.Make(code.OpConstant, 1),
+        code.Make(code.OpPower),
+        code.Make(code.OpMinus),
+        code.Make(code.OpConstant, 2),
+        code.Make(code.OpMod),
+        code.Make(code.OpPop),
+      },
+    },
+  }
+
+  runCompilerTests(t, tests)
+}
+
+func TestBooleanExpressions(t *testing.T) {
+  tests := []compilerTestCase{
+    {
+      input:             "1 < 2 == !false",
+      expectedConstants: []int64{1, 2},
+      expectedInstructions: []code.Instructions{
+        code.Make(code.OpConstant, 0),
+        code.Make(code.OpConstant, 1),
+        code.Make(code.OpLessThan),
+        code.Make(code.OpFalse),
+        code.Make(code.OpBang),
+        code.Make(code.OpEqual),
+        code.Make(code.OpPop),
+      },
+    },
+  }
+
+  runCompilerTests(t, tests)
+}
+
+func TestConditionals(t *testing.T) {
+  tests := []compilerTestCase{
+    {
+      input:             "if (true) { 10 }; 3333;",
+      expectedConstants: []int64{10, 3333},
+      expectedInstructions: []code.Instructions{
+        // 0000
+        code.Make(code.OpTrue),
+        // 0001
+        code.Make(code.OpJumpNotTruthy, 10),
+        // 0004
+        code.Make(code.OpConstant, 0),
+        // 0007
+        code.Make(code.OpJump, 11),
+        // 0010
+        code.Make(code.OpNull),
+        // 0011
+        code.Make(code.OpPop),
+        // 0012
+        code.Make(code.OpConstant, 1),
+        // 0015
+        code.Make(code.OpPop),
+      },
+    },
+    {
+      input:             "if (true) { 10 } else { 20 }; 3333;",
+      expectedConstants: []int64{10, 20, 3333},
+      expectedInstructions: []code.Instructions{
+        // 0000
+        code.Make(code.OpTrue),
+        // 0001
+        code.Make(code.OpJumpNotTruthy, 10),
+        // 0004
+        code.Make(code.OpConstant, 0),
+        // 0007
+        code.Make(code.OpJump, 13),
+        // 0010
+        code.Make(code.OpConstant, 1),
+        // 0013
+        code.Make(code.OpPop),
+        // 0014
+        code.Make(code.OpConstant, 2),
+        // 0017
+        code.Make(code.OpPop),
+      },
+    },
+    {
+      // an empty branch is null as well
+      input:             "if (false) { }",
+      expectedConstants: []int64{},
+      expectedInstructions: []code.Instructions{
+        code.Make(code.OpFalse),
+        code.Make(code.OpJumpNotTruthy, 8),
+        code.Make(code.OpNull),
+        code.Make(code.OpJump, 9),
+        code.Make(code.OpNull),
+        code.Make(code.OpPop),
+      },
+    },
+  }
+
+  runCompilerTests(t, tests)
+}
+
+func TestGlobalLetStatements(t *testing.T) {
+  tests := []compilerTestCase{
+    {
+      input:             "let one = 1; const two = 2; one + two",
+      expectedConstants: []int64{1, 2},
+      expectedInstructions: []code.Instructions{
+        code.Make(code.OpConstant, 0),
+        code.Make(code.OpSetGlobal, 0),
+        code.Make(code.OpConstant, 1),
+        code.Make(code.OpSetGlobal, 1),
+        code.Make(code.OpGetGlobal, 0),
+        code.Make(code.OpGetGlobal, 1),
+        code.Make(code.OpAdd),
+        code.Make(code.OpPop),
+      },
+    },
+    {
+      input:             "let one = 1; let two = one; two",
+      expectedConstants: []int64{1},
+      expectedInstructions: []code.Instructions{
+        code.Make(code.OpConstant, 0),
+        code.Make(code.OpSetGlobal, 0),
+        code.Make(code.OpGetGlobal, 0),
+        code.Make(code.OpSetGlobal, 1),
+        code.Make(code.OpGetGlobal, 1),
+        code.Make(code.OpPop),
+      },
+    },
+  }
+
+  runCompilerTests(t, tests)
+}
+
+func TestCompilerErrors(t *testing.T) {
+  tests := []struct {
+    input    string
+    expected string
+  }{
+    {"fn(x) { x }", "compiling *ast.FunctionLiteral is not supported yet"},
+    {"let x = x;", "identifier not found: x"},
+    {`"a"`, "compiling *ast.StringLiteral is not supported yet"},
+    {"true && false", "compiling && is not supported yet"},
+    {"if (x) { 1 }", "identifier not found: x"},
+  }
+
+  for _, tt := range tests {
+    err := New().Compile(parse(tt.input))
+    if err == nil || err.Error() != tt.expected {
+      t.Errorf("%s: wrong error. want=%q, got=%v", tt.input, tt.expected, err)
+    }
+  }
+}
+
+func runCompilerTests(t *testing.T, tests []compilerTestCase) {
+  t.Helper()
+
+  for _, tt := range tests {
+    compiler := New()
+    if err := compiler.Compile(parse(tt.input)); err != nil {
+      t.Fatalf("compiler error: %s", err)
+    }
+
+    bytecode := compiler.Bytecode()
+
+    expected := code.Instructions{}
+    for _, ins := range tt.expectedInstructions {
+      expected = append(expected, ins...)
+    }
+    if bytecode.Instructions.String() != expected.String() {
+      t.Errorf("%s: wrong instructions.\nwant=\n%s\ngot=\n%s", tt.input, expected, bytecode.Instructions)
+    }
+
+    if len(bytecode.Constants) != len(tt.expectedConstants) {
+      t.Fatalf("%s: wrong number of constants. want=%d, got=%d", tt.input, len(tt.expectedConstants), len(bytecode.Constants))
+    }
+    for i, constant := range tt.expectedConstants {
+      integer, ok := bytecode.Constants[i].(*object.Integer)
+      if !ok || integer.Value != constant {
+        t.Errorf("%s: constant %d wrong. want=%d, got=%s", tt.input, i, constant, bytecode.Constants[i].Inspect())
+      }
+    }
+  }
+}
+
+func parse(input string) *ast.Program {
+  p := parser.New(lexer.New(input))
+  return p.ParseProgram()
+}
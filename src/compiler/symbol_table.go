@@ -0,0 +1,109 @@
+package compiler
+
+type SymbolScope string
+
+const (
+  GlobalScope SymbolScope = "GLOBAL" // let at the top level
+  LocalScope  SymbolScope = "LOCAL"  // parameters and lets inside a function
+  FreeScope   SymbolScope = "FREE"   // a local of an enclosing function, captured by a closure
+)
+
+// where a name lives, eg: {x GLOBAL 0}
+type Symbol struct {
+  Name  string
+  Scope SymbolScope
+  Index int
+}
+
+// names resolved at compile time, the vm only sees the indices
+// one table per function and per if branch, Outer is the enclosing one
+type SymbolTable struct {
+  Outer *SymbolTable
+  // a branch's names go out of scope with it, its slots are Outer's
+  block bool
+
+  store          map[string]Symbol
+  numDefinitions int
+
+  // the enclosing locals this function captures, in capture order,
+  // FreeScope symbol i refers to FreeSymbols[i] one level out
+  FreeSymbols []Symbol
+}
+
+func NewSymbolTable() *SymbolTable {
+  return &SymbolTable{store: make(map[string]Symbol), FreeSymbols: []Symbol{}}
+}
+
+// the table of a function body inside outer
+func NewEnclosedSymbolTable(outer *SymbolTable) *SymbolTable {
+  s := NewSymbolTable()
+  s.Outer = outer
+  return s
+}
+
+// the table of a block inside outer, like the evaluator's branch environment
+// eg: let x = 1; if (true) { let x = 2 }; x is 1, the inner x is a new global slot
+func NewBlockSymbolTable(outer *SymbolTable) *SymbolTable {
+  s := NewEnclosedSymbolTable(outer)
+  s.block = true
+  return s
+}
+
+// a new slot for name, global at the top level and local everywhere else,
+// defining a name again gives it a fresh slot
+func (s *SymbolTable) Define(name string) Symbol {
+  scope, index := s.newSlot()
+  symbol := Symbol{Name: name, Index: index, Scope: scope}
+
+  s.store[name] = symbol
+  return symbol
+}
+
+// a block hands out the slots of the function (or top level) it is in
+func (s *SymbolTable) newSlot() (SymbolScope, int) {
+  if s.block {
+    return s.Outer.newSlot()
+  }
+
+  index := s.numDefinitions
+  s.numDefinitions++
+  if s.Outer == nil {
+    return GlobalScope, index
+  }
+  return LocalScope, index
+}
+
+// how many slots Define handed out, eg: the locals a function needs
+func (s *SymbolTable) NumDefinitions() int {
+  return s.numDefinitions
+}
+
+// look name up here, then in the enclosing tables;
+// an enclosing function's local becomes a free symbol of every table in between
+func (s *SymbolTable) Resolve(name string) (Symbol, bool) {
+  // 1.defined here, or captured already
+  if symbol, ok := s.store[name]; ok {
+    return symbol, true
+  }
+  if s.Outer == nil {
+    return Symbol{}, false
+  }
+
+  // 2.from outside, globals stay globals,
+  //   a block is in the same function, so nothing is captured
+  symbol, ok := s.Outer.Resolve(name)
+  if !ok || symbol.Scope == GlobalScope || s.block {
+    return symbol, ok
+  }
+
+  // 3.an outer local (or free) symbol is captured
+  return s.defineFree(symbol), true
+}
+
+func (s *SymbolTable) defineFree(original Symbol) Symbol {
+  s.FreeSymbols = append(s.FreeSymbols, original)
+
+  symbol := Symbol{Name: original.Name, Index: len(s.FreeSymbols) - 1, Scope: FreeScope}
+  s.store[original.Name] = symbol
+  return symbol
+}
@@ -0,0 +1,198 @@
+package compiler
+
+import "testing"
+
+func TestDefine(t *testing.T) {
+  global := NewSymbolTable()
+  local := NewEnclosedSymbolTable(global)
+  nested := NewEnclosedSymbolTable(local)
+
+  tests := []struct {
+    table    *SymbolTable
+    name     string
+    expected Symbol
+  }{
+    {global, "a", Symbol{Name: "a", Scope: GlobalScope, Index: 0}},
+    {global, "b", Symbol{Name: "b", Scope: GlobalScope, Index: 1}},
+    {local, "c", Symbol{Name: "c", Scope: LocalScope, Index: 0}},
+    {local, "d", Symbol{Name: "d", Scope: LocalScope, Index: 1}},
+    {nested, "e", Symbol{Name: "e", Scope: LocalScope, Index: 0}},
+    // a redefinition gets a fresh slot
+    {global, "a", Symbol{Name: "a", Scope: GlobalScope, Index: 2}},
+  }
+
+  for _, tt := range tests {
+    if symbol := tt.table.Define(tt.name); symbol != tt.expected {
+      t.Errorf("wrong symbol for %s. expected=%+v, got=%+v", tt.name, tt.expected, symbol)
+    }
+  }
+
+  if global.NumDefinitions() != 3 || local.NumDefinitions() != 2 {
+    t.Errorf("wrong definition counts. global=%d, local=%d", global.NumDefinitions(), local.NumDefinitions())
+  }
+}
+
+func TestResolveNestedLocals(t *testing.T) {
+  global := NewSymbolTable()
+  global.Define("a")
+  local := NewEnclosedSymbolTable(global)
+  local.Define("b")
+  nested := NewEnclosedSymbolTable(local)
+  nested.Define("b") // shadows the outer b
+  nested.Define("c")
+
+  tests := []struct {
+    table    *SymbolTable
+    expected []Symbol
+  }{
+    {global, []Symbol{{Name: "a", Scope: GlobalScope, Index: 0}}},
+    {local, []Symbol{
+      {Name: "a", Scope: GlobalScope, Index: 0},
+      {Name: "b", Scope: LocalScope, Index: 0},
+    }},
+    {nested, []Symbol{
+      {Name: "a", Scope: GlobalScope, Index: 0},
+      {Name: "b", Scope: LocalScope, Index: 0},
+      {Name: "c", Scope: LocalScope, Index: 1},
+    }},
+  }
+
+  for _, tt := range tests {
+    for _, expected := range tt.expected {
+      symbol, ok := tt.table.Resolve(expected.Name)
+      if !ok {
+        t.Fatalf("name %s not resolvable", expected.Name)
+      }
+      if symbol != expected {
+        t.Errorf("wrong symbol for %s. expected=%+v, got=%+v", expected.Name, expected, symbol)
+      }
+    }
+    // shadowing and globals capture nothing
+    if len(tt.table.FreeSymbols) != 0 {
+      t.Errorf("unexpected free symbols: %+v", tt.table.FreeSymbols)
+    }
+  }
+}
+
+// eg: fn(a) { fn(b) { fn(c) { a + b + c } } }
+func TestResolveFree(t *testing.T) {
+  global := NewSymbolTable()
+  global.Define("g")
+  first := NewEnclosedSymbolTable(global)
+  first.Define("a")
+  second := NewEnclosedSymbolTable(first)
+  second.Define("b")
+  third := NewEnclosedSymbolTable(second)
+  third.Define("c")
+
+  tests := []struct {
+    table        *SymbolTable
+    expected     []Symbol
+    expectedFree []Symbol
+  }{
+    {
+      third,
+      []Symbol{
+        {Name: "g", Scope: GlobalScope, Index: 0},
+        {Name: "b", Scope: FreeScope, Index: 0},
+        {Name: "a", Scope: FreeScope, Index: 1},
+        {Name: "c", Scope: LocalScope, Index: 0},
+      },
+      // the originals as seen one level out
+      []Symbol{
+        {Name: "b", Scope: LocalScope, Index: 0},
+        {Name: "a", Scope: FreeScope, Index: 0},
+      },
+    },
+    {
+      // second captured a on the way, so third could
+      second,
+      []Symbol{
+        {Name: "a", Scope: FreeScope, Index: 0},
+        {Name: "b", Scope: LocalScope, Index: 0},
+      },
+      []Symbol{
+        {Name: "a", Scope: LocalScope, Index: 0},
+      },
+    },
+  }
+
+  for _, tt := range tests {
+    for _, expected := range tt.expected {
+      symbol, ok := tt.table.Resolve(expected.Name)
+      if !ok {
+        t.Fatalf("name %s not resolvable", expected.Name)
+      }
+      if symbol != expected {
+        t.Errorf("wrong symbol for %s. expected=%+v, got=%+v", expected.Name, expected, symbol)
+      }
+    }
+
+    if len(tt.table.FreeSymbols) != len(tt.expectedFree) {
+      t.Fatalf("wrong number of free symbols. expected=%d, got=%d (%+v)",
+        len(tt.expectedFree), len(tt.table.FreeSymbols), tt.table.FreeSymbols)
+    }
+    for i, expected := range tt.expectedFree {
+      if tt.table.FreeSymbols[i] != expected {
+        t.Errorf("wrong free symbol %d. expected=%+v, got=%+v", i, expected, tt.table.FreeSymbols[i])
+      }
+    }
+  }
+}
+
+// eg: let a = 1; if (true) { let a = 2; let b = a }; a
+func TestBlockScope(t *testing.T) {
+  global := NewSymbolTable()
+  global.Define("a")
+  block := NewBlockSymbolTable(global)
+
+  // the block's names take the next global slots
+  if symbol := block.Define("a"); symbol != (Symbol{Name: "a", Scope: GlobalScope, Index: 1}) {
+    t.Errorf("wrong shadowing symbol. got=%+v", symbol)
+  }
+  if symbol := block.Define("b"); symbol != (Symbol{Name: "b", Scope: GlobalScope, Index: 2}) {
+    t.Errorf("wrong block symbol. got=%+v", symbol)
+  }
+  if global.NumDefinitions() != 3 {
+    t.Errorf("wrong definition count. want=3, got=%d", global.NumDefinitions())
+  }
+
+  // and are gone after it
+  if symbol, _ := global.Resolve("a"); symbol.Index != 0 {
+    t.Errorf("block's a leaked out. got=%+v", symbol)
+  }
+  if _, ok := global.Resolve("b"); ok {
+    t.Errorf("block's b resolved outside it")
+  }
+
+  // a block in a function sees the function's locals without capturing them
+  local := NewEnclosedSymbolTable(global)
+  local.Define("c")
+  inner := NewBlockSymbolTable(local)
+  if symbol := inner.Define("d"); symbol != (Symbol{Name: "d", Scope: LocalScope, Index: 1}) {
+    t.Errorf("wrong local block symbol. got=%+v", symbol)
+  }
+  if symbol, ok := inner.Resolve("c"); !ok || symbol != (Symbol{Name: "c", Scope: LocalScope, Index: 0}) {
+    t.Errorf("wrong enclosing local. got=%+v", symbol)
+  }
+  if len(inner.FreeSymbols) != 0 {
+    t.Errorf("unexpected free symbols: %+v", inner.FreeSymbols)
+  }
+}
+
+func TestResolveUnresolvable(t *testing.T) {
+  global := NewSymbolTable()
+  global.Define("a")
+  local := NewEnclosedSymbolTable(global)
+  local.Define("b")
+
+  for _, name := range []string{"c", "d"} {
+    if _, ok := local.Resolve(name); ok {
+      t.Errorf("name %s resolved, but was never defined", name)
+    }
+  }
+  // nothing was captured on the failed lookups
+  if len(local.FreeSymbols) != 0 {
+    t.Errorf("unexpected free symbols: %+v", local.FreeSymbols)
+  }
+}
@@ -0,0 +1,1332 @@
+package evaluator
+
+import (
+  "JFFMonkeyLang/src/object"
+  "errors"
+  "math"
+  "math/big"
+  "math/rand"
+  "os"
+  "sort"
+  "strconv"
+  "strings"
+  "time"
+  "unicode/utf8"
+)
+
+// functions every program can call without defining them
+var builtins = map[string]*object.Builtin{
+  // eg: type(5) => "INTEGER"
+  "type": {
+    Fn: func(args ...object.Object) object.Object {
+      if len(args) != 1 {
+        return newError("wrong number of arguments: want=1, got=%d", len(args))
+      }
+
+      return &object.String{Value: string(args[0].Type())}
+    },
+  },
+
+  // eg: len("héllo") => 5, len([1, 2]) => 2, len({"a": 1}) => 1
+  // strings count runes, like string indexing
+  "len": {
+    Fn: func(args ...object.Object) object.Object {
+      if len(args) != 1 {
+        return newError("wrong number of arguments: want=1, got=%d", len(args))
+      }
+
+      switch arg := args[0].(type) {
+      case *object.String:
+        return newInteger(int64(utf8.RuneCountInString(arg.Value)))
+      case *object.Array:
+        return newInteger(int64(len(arg.Elements)))
+      case *object.Hash:
+        return newInteger(int64(len(arg.Pairs)))
+      default:
+        return newError("argument to `len` not supported, got %s", args[0].Type())
+      }
+    },
+  },
+
+  // eg: substr("hello", 1, 3) => "ell"
+  // start and len count runes, like string indexing,
+  // and are clamped to the string, so substr("hi", 1, 10) => "i"
+  "substr": {
+    Fn: func(args ...object.Object) object.Object {
+      if len(args) != 3 {
+        return newError("wrong number of arguments: want=3, got=%d", len(args))
+      }
+
+      str, ok := args[0].(*object.String)
+      if !ok {
+        return newError("argument to `substr` must be STRING, got %s", args[0].Type())
+      }
+      start, ok := args[1].(*object.Integer)
+      if !ok {
+        return newError("argument to `substr` must be INTEGER, got %s", args[1].Type())
+      }
+      length, ok := args[2].(*object.Integer)
+      if !ok {
+        return newError("argument to `substr` must be INTEGER, got %s", args[2].Type())
+      }
+
+      runes := []rune(str.Value)
+      size := int64(len(runes))
+
+      // 1.clamp start to [0, size]
+      from := start.Value
+      if from < 0 {
+        from = 0
+      }
+      if from > size {
+        from = size
+      }
+
+      // 2.clamp end to [from, size]
+      to := size
+      if length.Value < size-from {
+        to = from + length.Value
+      }
+      if to < from {
+        to = from
+      }
+
+      return &object.String{Value: string(runes[from:to])}
+    },
+  },
+
+  // eg: split("a,b,c", ",") => ["a", "b", "c"]
+  // like strings.Split, an empty sep splits into single characters
+  "split": {
+    Fn: func(args ...object.Object) object.Object {
+      if len(args) != 2 {
+        return newError("wrong number of arguments: want=2, got=%d", len(args))
+      }
+
+      str, ok := args[0].(*object.String)
+      if !ok {
+        return newError("argument to `split` must be STRING, got %s", args[0].Type())
+      }
+      sep, ok := args[1].(*object.String)
+      if !ok {
+        return newError("argument to `split` must be STRING, got %s", args[1].Type())
+      }
+
+      parts := strings.Split(str.Value, sep.Value)
+      elements := make([]object.Object, len(parts))
+      for i, part := range parts {
+        elements[i] = &object.String{Value: part}
+      }
+
+      return &object.Array{Elements: elements}
+    },
+  },
+
+  // eg: str(42) => "42"
+  "str": {
+    Fn: func(args ...object.Object) object.Object {
+      if len(args) != 1 {
+        return newError("wrong number of arguments: want=1, got=%d", len(args))
+      }
+
+      return &object.String{Value: args[0].Inspect()}
+    },
+  },
+
+  // eg: int("42") => 42
+  "int": {
+    Fn: func(args ...object.Object) object.Object {
+      if len(args) != 1 {
+        return newError("wrong number of arguments: want=1, got=%d", len(args))
+      }
+
+      switch arg := args[0].(type) {
+      case *object.Integer, *object.BigInteger:
+        return arg
+      case *object.String:
+        return parseInteger(arg.Value)
+      default:
+        return newError("argument to `int` not supported, got %s", args[0].Type())
+      }
+    },
+  },
+
+  // eg: keys({"a": 1, "b": 2}) => ["a", "b"]
+  // in insertion order
+  "keys": {
+    Fn: func(args ...object.Object) object.Object {
+      if len(args) != 1 {
+        return newError("wrong number of arguments: want=1, got=%d", len(args))
+      }
+
+      hash, ok := args[0].(*object.Hash)
+      if !ok {
+        return newError("argument to `keys` must be HASH, got %s", args[0].Type())
+      }
+
+      keys := make([]object.Object, 0, len(hash.Order))
+      for _, pair := range hash.OrderedPairs() {
+        keys = append(keys, pair.Key)
+      }
+
+      return &object.Array{Elements: keys}
+    },
+  },
+
+  // eg: values({"a": 1, "b": 2}) => [1, 2]
+  // in insertion order
+  "values": {
+    Fn: func(args ...object.Object) object.Object {
+      if len(args) != 1 {
+        return newError("wrong number of arguments: want=1, got=%d", len(args))
+      }
+
+      hash, ok := args[0].(*object.Hash)
+      if !ok {
+        return newError("argument to `values` must be HASH, got %s", args[0].Type())
+      }
+
+      values := make([]object.Object, 0, len(hash.Order))
+      for _, pair := range hash.OrderedPairs() {
+        values = append(values, pair.Value)
+      }
+
+      return &object.Array{Elements: values}
+    },
+  },
+
+  // eg: delete({"a": 1, "b": 2}, "a") => {"b": 2}
+  // gives back a new hash, the argument is left untouched,
+  // deleting a missing key is a no-op copy
+  "delete": {
+    Fn: func(args ...object.Object) object.Object {
+      if len(args) != 2 {
+        return newError("wrong number of arguments: want=2, got=%d", len(args))
+      }
+
+      hash, ok := args[0].(*object.Hash)
+      if !ok {
+        return newError("first argument to `delete` must be HASH, got %s", args[0].Type())
+      }
+      key, ok := args[1].(object.Hashable)
+      if !ok {
+        return newError("unusable as hash key: %s", args[1].Type())
+      }
+
+      deleted := key.HashKey()
+      result := object.NewHash()
+      for _, hashKey := range hash.Order {
+        if hashKey != deleted {
+          result.Set(hashKey, hash.Pairs[hashKey])
+        }
+      }
+
+      return result
+    },
+  },
+
+  // eg:
+  // contains([1, 2, 3], 2)      => true, an element equals item
+  // contains("hello", "ell")    => true, substring
+  // contains({"a": 1}, "a")     => true, key exists
+  "contains": {
+    Fn: func(args ...object.Object) object.Object {
+      if len(args) != 2 {
+        return newError("wrong number of arguments: want=2, got=%d", len(args))
+      }
+
+      switch collection := args[0].(type) {
+      case *object.Array:
+        for _, el := range collection.Elements {
+          if objectsEqual(el, args[1]) {
+            return TRUE
+          }
+        }
+        return FALSE
+
+      case *object.String:
+        item, ok := args[1].(*object.String)
+        if !ok {
+          return newError("second argument to `contains` must be STRING, got %s", args[1].Type())
+        }
+        return nativeBoolToBooleanObject(strings.Contains(collection.Value, item.Value))
+
+      case *object.Hash:
+        key, ok := args[1].(object.Hashable)
+        if !ok {
+          return newError("unusable as hash key: %s", args[1].Type())
+        }
+        _, ok = collection.Get(key.HashKey())
+        return nativeBoolToBooleanObject(ok)
+
+      default:
+        return newError("argument to `contains` not supported, got %s", args[0].Type())
+      }
+    },
+  },
+
+  // eg: lazy_range(0)     => 0, 1, 2, ... without end
+  // lazy_range(10, -2)      => 10, 8, 6, ...
+  // nothing is computed until the sequence is consumed, eg: by take
+  "lazy_range": {
+    Fn: func(args ...object.Object) object.Object {
+      if len(args) != 1 && len(args) != 2 {
+        return newError("wrong number of arguments: want=1 or 2, got=%d", len(args))
+      }
+      for _, arg := range args {
+        if !isInteger(arg) {
+          return newError("argument to `lazy_range` must be INTEGER, got %s", arg.Type())
+        }
+      }
+
+      start := args[0]
+      var step object.Object = newInteger(1)
+      if len(args) == 2 {
+        step = args[1]
+      }
+
+      return &object.Lazy{Iterate: func() func() (object.Object, bool) {
+        current := start
+        return func() (object.Object, bool) {
+          element := current
+          // past int64 it carries on with big integers, like `+` does
+          current = evalInfixExpression("+", current, step)
+          return element, true
+        }
+      }}
+    },
+  },
+
+  // eg: zip([1, 2], [3, 4])      => [[1, 3], [2, 4]]
+  // zip([1, 2, 3], ["a", "b"])     => [[1, "a"], [2, "b"]], as long as the shortest
+  // zip([1], [2], [3])             => [[1, 2, 3]]
+  "zip": {
+    Fn: func(args ...object.Object) object.Object {
+      if len(args) == 0 {
+        return newError("`zip` needs at least one ARRAY")
+      }
+
+      arrays := make([]*object.Array, len(args))
+      length := -1
+      for i, arg := range args {
+        array, ok := arg.(*object.Array)
+        if !ok {
+          return newError("arguments to `zip` must be ARRAY, got %s", arg.Type())
+        }
+        arrays[i] = array
+        if length < 0 || len(array.Elements) < length {
+          length = len(array.Elements)
+        }
+      }
+
+      zipped := make([]object.Object, length)
+      for i := range zipped {
+        tuple := make([]object.Object, len(arrays))
+        for j, array := range arrays {
+          tuple[j] = array.Elements[i]
+        }
+        zipped[i] = &object.Array{Elements: tuple}
+      }
+
+      return &object.Array{Elements: zipped}
+    },
+  },
+
+  // eg: slice([1, 2, 3, 4], 1, 3)  => [2, 3], end is exclusive
+  // slice([1, 2, 3, 4], -2)           => [3, 4], negative bounds count from the end
+  // slice("héllo", 1, -1)             => "éll", strings are sliced by rune
+  // bounds past either end are clamped, so the result may be empty
+  "slice": {
+    Fn: func(args ...object.Object) object.Object {
+      if len(args) != 2 && len(args) != 3 {
+        return newError("wrong number of arguments: want=2 or 3, got=%d", len(args))
+      }
+      bounds := []int64{0, math.MaxInt64}
+      for i, arg := range args[1:] {
+        integer, ok := arg.(*object.Integer)
+        if !ok {
+          return newError("bounds of `slice` must be INTEGER, got %s", arg.Type())
+        }
+        bounds[i] = integer.Value
+      }
+
+      switch arg := args[0].(type) {
+      case *object.Array:
+        from, to := sliceBounds(bounds[0], bounds[1], int64(len(arg.Elements)))
+        elements := make([]object.Object, to-from)
+        copy(elements, arg.Elements[from:to])
+        return &object.Array{Elements: elements}
+      case *object.String:
+        runes := []rune(arg.Value)
+        from, to := sliceBounds(bounds[0], bounds[1], int64(len(runes)))
+        return &object.String{Value: string(runes[from:to])}
+      default:
+        return newError("first argument to `slice` must be ARRAY or STRING, got %s", args[0].Type())
+      }
+    },
+  },
+
+  // eg: reverse([1, 2, 3]) => [3, 2, 1], reverse("héllo") => "olléh"
+  // strings are reversed by rune, like string indexing
+  "reverse": {
+    Fn: func(args ...object.Object) object.Object {
+      if len(args) != 1 {
+        return newError("wrong number of arguments: want=1, got=%d", len(args))
+      }
+
+      switch arg := args[0].(type) {
+      case *object.String:
+        runes := []rune(arg.Value)
+        for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+          runes[i], runes[j] = runes[j], runes[i]
+        }
+        return &object.String{Value: string(runes)}
+      case *object.Array:
+        elements := make([]object.Object, len(arg.Elements))
+        for i, el := range arg.Elements {
+          elements[len(elements)-1-i] = el
+        }
+        return &object.Array{Elements: elements}
+      default:
+        return newError("argument to `reverse` not supported, got %s", args[0].Type())
+      }
+    },
+  },
+
+  // eg: push([1, 2], 3) => [1, 2, 3], the array passed in is left alone
+  // arrays can be changed in place by index assignment, so the elements are
+  // always copied, sharing them would let a[0] = x show up in the pushed array
+  "push": {
+    Fn: func(args ...object.Object) object.Object {
+      if len(args) != 2 {
+        return newError("wrong number of arguments: want=2, got=%d", len(args))
+      }
+      array, ok := args[0].(*object.Array)
+      if !ok {
+        return newError("first argument to `push` must be ARRAY, got %s", args[0].Type())
+      }
+
+      elements := make([]object.Object, len(array.Elements), len(array.Elements)+1)
+      copy(elements, array.Elements)
+      return &object.Array{Elements: append(elements, args[1])}
+    },
+  },
+
+  // eg: first([1, 2]) => 1, first([]) => null
+  "first": {
+    Fn: func(args ...object.Object) object.Object {
+      if len(args) != 1 {
+        return newError("wrong number of arguments: want=1, got=%d", len(args))
+      }
+      array, ok := args[0].(*object.Array)
+      if !ok {
+        return newError("argument to `first` must be ARRAY, got %s", args[0].Type())
+      }
+
+      if len(array.Elements) == 0 {
+        return NULL
+      }
+      return array.Elements[0]
+    },
+  },
+
+  // eg: upper("abc") => "ABC", upper("é") => "É"
+  // casing is Unicode aware, like strings.ToUpper
+  "upper": {
+    Fn: func(args ...object.Object) object.Object {
+      return mapString("upper", args, strings.ToUpper)
+    },
+  },
+
+  // eg: lower("ABC") => "abc", lower("É") => "é"
+  "lower": {
+    Fn: func(args ...object.Object) object.Object {
+      return mapString("lower", args, strings.ToLower)
+    },
+  },
+
+  // eg: trim("  hi\n") => "hi", any Unicode white space goes
+  "trim": {
+    Fn: func(args ...object.Object) object.Object {
+      return mapString("trim", args, strings.TrimSpace)
+    },
+  },
+
+  // eg: min(5, 2, 8) => 2, min([5, 2, 8]) => 2
+  "min": {
+    Fn: func(args ...object.Object) object.Object {
+      return extremeInteger("min", args, -1)
+    },
+  },
+
+  // eg: max(5, 2, 8) => 8, max([5, 2, 8]) => 8
+  "max": {
+    Fn: func(args ...object.Object) object.Object {
+      return extremeInteger("max", args, 1)
+    },
+  },
+
+  // eg: abs(-4) => 4
+  "abs": {
+    Fn: func(args ...object.Object) object.Object {
+      if len(args) != 1 {
+        return newError("wrong number of arguments: want=1, got=%d", len(args))
+      }
+      if !isInteger(args[0]) {
+        return newError("argument to `abs` must be INTEGER, got %s", args[0].Type())
+      }
+
+      if toBigInt(args[0]).Sign() < 0 {
+        return evalMinusPrefixOperatorExpression(args[0])
+      }
+      return args[0]
+    },
+  },
+
+  // eg: sqrt(16) => 4, sqrt(10) => 3
+  // integers only, so the root is rounded down
+  "sqrt": {
+    Fn: func(args ...object.Object) object.Object {
+      if len(args) != 1 {
+        return newError("wrong number of arguments: want=1, got=%d", len(args))
+      }
+      if !isInteger(args[0]) {
+        return newError("argument to `sqrt` must be INTEGER, got %s", args[0].Type())
+      }
+
+      value := toBigInt(args[0])
+      if value.Sign() < 0 {
+        return newError("argument to `sqrt` must not be negative, got %s", value)
+      }
+      return normalizeBigInteger(new(big.Int).Sqrt(value))
+    },
+  },
+
+  // eg: pow(2, 10) => 1024, the same as 2 ** 10
+  "pow": {
+    Fn: func(args ...object.Object) object.Object {
+      if len(args) != 2 {
+        return newError("wrong number of arguments: want=2, got=%d", len(args))
+      }
+      for _, arg := range args {
+        if !isInteger(arg) {
+          return newError("arguments to `pow` must be INTEGER, got %s", arg.Type())
+        }
+      }
+
+      return evalInfixExpression("**", args[0], args[1])
+    },
+  },
+
+  // eg: read_file("notes.txt") => "the contents"
+  "read_file": {
+    Fn: func(args ...object.Object) object.Object {
+      if len(args) != 1 {
+        return newError("wrong number of arguments: want=1, got=%d", len(args))
+      }
+      path, ok := args[0].(*object.String)
+      if !ok {
+        return newError("argument to `read_file` must be STRING, got %s", args[0].Type())
+      }
+
+      content, err := os.ReadFile(path.Value)
+      if err != nil {
+        return newError("could not read %s: %s", path.Value, err)
+      }
+      return &object.String{Value: string(content)}
+    },
+  },
+
+  // eg: write_file("notes.txt", "the contents") => null,
+  // the file is created or truncated
+  "write_file": {
+    Fn: func(args ...object.Object) object.Object {
+      if len(args) != 2 {
+        return newError("wrong number of arguments: want=2, got=%d", len(args))
+      }
+      for _, arg := range args {
+        if arg.Type() != object.STRING_OBJ {
+          return newError("argument to `write_file` must be STRING, got %s", arg.Type())
+        }
+      }
+
+      path, content := args[0].(*object.String).Value, args[1].(*object.String).Value
+      if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+        return newError("could not write %s: %s", path, err)
+      }
+      return NULL
+    },
+  },
+
+  // eg: sum([1, 2, 3]) => 6, sum([]) => 0
+  "sum": {
+    Fn: func(args ...object.Object) object.Object {
+      if len(args) != 1 {
+        return newError("wrong number of arguments: want=1, got=%d", len(args))
+      }
+      array, ok := args[0].(*object.Array)
+      if !ok {
+        return newError("argument to `sum` must be ARRAY, got %s", args[0].Type())
+      }
+
+      var total object.Object = newInteger(0)
+      for _, el := range array.Elements {
+        if !isInteger(el) {
+          return newError("elements passed to `sum` must be INTEGER, got %s", el.Type())
+        }
+        // an overflow carries on as a BigInteger, like `+`
+        total = evalInfixExpression("+", total, el)
+      }
+
+      return total
+    },
+  },
+
+  // eg: clone([1, [2]]) => [1, [2]], a deep copy sharing nothing mutable
+  "clone": {
+    Fn: func(args ...object.Object) object.Object {
+      if len(args) != 1 {
+        return newError("wrong number of arguments: want=1, got=%d", len(args))
+      }
+
+      return cloneObject(args[0])
+    },
+  },
+}
+
+// the builtins callable as methods, by receiver type, eg: [1, 2].len()
+var methods = map[object.ObjectType][]string{
+  object.ARRAY_OBJ:  {"len", "push", "first"},
+  object.STRING_OBJ: {"len", "upper", "lower", "trim", "replace", "format"},
+  object.HASH_OBJ:   {"len"},
+}
+
+// eg: "abc".upper() is upper("abc")
+func callMethod(receiver object.Object, name string, args []object.Object, settings *object.Settings) object.Object {
+  for _, method := range methods[receiver.Type()] {
+    if method == name {
+      builtin, _ := lookupBuiltin(name, settings)
+      return builtin.Fn(append([]object.Object{receiver}, args...)...)
+    }
+  }
+
+  return newError("unknown method: %s.%s", receiver.Type(), name)
+}
+
+// template with every {} taking the next of args and every {n} args[n],
+// both by Inspect(); {{ and }} are a literal brace,
+// every byte a placeholder adds costs an operation of the evaluation with settings
+func formatString(template string, args []object.Object, settings *object.Settings) object.Object {
+  var out strings.Builder
+  next := 0
+
+  for i := 0; i < len(template); i++ {
+    ch := template[i]
+
+    // 1.doubled braces and anything else but a placeholder stay as they are
+    if (ch == '{' || ch == '}') && i+1 < len(template) && template[i+1] == ch {
+      out.WriteByte(ch)
+      i++
+      continue
+    }
+    if ch == '}' {
+      return newError("unmatched } in `format` template at %d", i)
+    }
+    if ch != '{' {
+      out.WriteByte(ch)
+      continue
+    }
+
+    // 2.{} or {n}
+    end := strings.IndexByte(template[i:], '}')
+    if end < 0 {
+      return newError("unmatched { in `format` template at %d", i)
+    }
+    placeholder := template[i+1 : i+end]
+
+    index := next
+    if placeholder == "" {
+      next++
+    } else {
+      n, err := strconv.Atoi(placeholder)
+      if err != nil || n < 0 {
+        return newError("bad placeholder {%s} in `format` template", placeholder)
+      }
+      // checked before any arithmetic, eg: {9223372036854775807} + 1 overflows
+      if n >= len(args) {
+        return newError("bad placeholder {%s} in `format` template: only %d arguments", placeholder, len(args))
+      }
+      index = n
+    }
+    if index >= len(args) {
+      return newError("not enough arguments to `format`: want at least %d, got=%d", index+1, len(args))
+    }
+
+    inspected := args[index].Inspect()
+    if err := charge(settings, len(inspected)); err != nil {
+      return err
+    }
+    out.WriteString(inspected)
+    i += end
+  }
+
+  return &object.String{Value: out.String()}
+}
+
+// the decimal number in str, a BigInteger when it doesn't fit an int64,
+// like the same digits written as a literal,
+// eg: int("99999999999999999999") => 99999999999999999999
+func parseInteger(str string) object.Object {
+  value, err := strconv.ParseInt(str, 10, 64)
+  if err == nil {
+    return newInteger(value)
+  }
+  if !errors.Is(err, strconv.ErrRange) {
+    return newError("could not parse %q as integer", str)
+  }
+
+  // every decimal digit is more than 3 bits, the cap of `**` applies here too
+  if int64(len(str)) > MaxIntegerBits/3 {
+    return newError("integer too large: %d digits", len(str))
+  }
+  huge, ok := new(big.Int).SetString(str, 10)
+  if !ok {
+    return newError("could not parse %q as integer", str)
+  }
+  return normalizeBigInteger(huge)
+}
+
+// upper, lower and trim, the one STRING in args passed through fn
+func mapString(name string, args []object.Object, fn func(string) string) object.Object {
+  if len(args) != 1 {
+    return newError("wrong number of arguments: want=1, got=%d", len(args))
+  }
+  str, ok := args[0].(*object.String)
+  if !ok {
+    return newError("argument to `%s` must be STRING, got %s", name, args[0].Type())
+  }
+
+  return &object.String{Value: fn(str.Value)}
+}
+
+// start and end of slice as indices into size elements,
+// eg: -1 is size - 1, and both are clamped to [0, size] with from <= to
+func sliceBounds(start, end, size int64) (from, to int64) {
+  clamp := func(i int64) int64 {
+    if i < 0 {
+      i += size
+    }
+    if i < 0 {
+      return 0
+    }
+    if i > size {
+      return size
+    }
+    return i
+  }
+
+  from, to = clamp(start), clamp(end)
+  if to < from {
+    to = from
+  }
+  return from, to
+}
+
+// min and max, the integers are either args or the one array in args,
+// sign is -1 for the smallest and 1 for the largest
+func extremeInteger(name string, args []object.Object, sign int) object.Object {
+  values := args
+  if len(args) == 1 {
+    if array, ok := args[0].(*object.Array); ok {
+      values = array.Elements
+    }
+  }
+  if len(values) == 0 {
+    return newError("`%s` needs at least one INTEGER", name)
+  }
+
+  extreme := values[0]
+  for _, value := range values {
+    if !isInteger(value) {
+      return newError("arguments to `%s` must be INTEGER, got %s", name, value.Type())
+    }
+    if toBigInt(value).Cmp(toBigInt(extreme)) == sign {
+      extreme = value
+    }
+  }
+
+  return extreme
+}
+
+// arrays and hashes are copied recursively, integers and strings by value,
+// everything else (booleans, null, functions) is immutable and shared
+func cloneObject(obj object.Object) object.Object {
+  switch obj := obj.(type) {
+  case *object.Integer:
+    return newInteger(obj.Value)
+
+  case *object.String:
+    return &object.String{Value: obj.Value}
+
+  case *object.Array:
+    elements := make([]object.Object, len(obj.Elements))
+    for i, el := range obj.Elements {
+      elements[i] = cloneObject(el)
+    }
+    return &object.Array{Elements: elements}
+
+  case *object.Hash:
+    hash := object.NewHash()
+    for _, hashKey := range obj.Order {
+      pair := obj.Pairs[hashKey]
+      hash.Set(hashKey, object.HashPair{Key: pair.Key, Value: cloneObject(pair.Value)})
+    }
+    return hash
+
+  default:
+    return obj
+  }
+}
+
+// the builtins object.Settings.Sandbox turns off
+var unsafeBuiltins = map[string]bool{
+  "read_file":  true,
+  "write_file": true,
+}
+
+// builtins which call back into monkey functions use applyFunction,
+// and applyFunction (through Eval) reads the builtins table,
+// registering them here avoids an initialization cycle
+func init() {
+  builtins["map"] = &object.Builtin{Fn: builtinMap}
+  builtins["reduce"] = &object.Builtin{Fn: builtinReduce}
+  builtins["filter"] = &object.Builtin{Fn: builtinFilter}
+  builtins["sort"] = &object.Builtin{Fn: builtinSort}
+}
+
+// builtins that depend on the evaluation calling them, eg: sleep wakes up
+// once that evaluation is cancelled, the one a program gets is bound to
+// the settings of the environment it is looked up in
+type settingsBuiltin func(settings *object.Settings, args ...object.Object) object.Object
+
+var settingsBuiltins = map[string]settingsBuiltin{
+  "range":   builtinRange,
+  "take":    builtinTake,
+  "join":    builtinJoin,
+  "format":  builtinFormat,
+  "replace": builtinReplace,
+  "rand":    builtinRand,
+  "srand":   builtinSrand,
+  "time":    builtinTime,
+  "sleep":   builtinSleep,
+}
+
+// the builtin called name as seen by the evaluation with settings
+func lookupBuiltin(name string, settings *object.Settings) (*object.Builtin, bool) {
+  if fn, ok := settingsBuiltins[name]; ok {
+    return &object.Builtin{Fn: func(args ...object.Object) object.Object {
+      return fn(settings, args...)
+    }}, true
+  }
+
+  builtin, ok := builtins[name]
+  return builtin, ok
+}
+
+// names of every builtin function, sorted
+func BuiltinNames() []string {
+  names := make([]string, 0, len(builtins)+len(settingsBuiltins))
+  for name := range builtins {
+    names = append(names, name)
+  }
+  for name := range settingsBuiltins {
+    names = append(names, name)
+  }
+  sort.Strings(names)
+  return names
+}
+
+// nil if there is no builtin called name,
+// one depending on its evaluation gets settings nothing was configured for
+func LookupBuiltin(name string) *object.Builtin {
+  builtin, _ := lookupBuiltin(name, object.NewSettings())
+  return builtin
+}
+
+// MaxRangeLength caps the elements of a `range` result, budget or not,
+// so range(9223372036854775807) is an error instead of eating all memory
+var MaxRangeLength = 1 << 26
+
+// eg:
+// range(3)         => [0, 1, 2]
+// range(1, 4)      => [1, 2, 3]
+// range(1, 10, 2)  => [1, 3, 5, 7, 9], end is exclusive
+// range(3, 0, -1)  => [3, 2, 1]
+func builtinRange(settings *object.Settings, args ...object.Object) object.Object {
+  if len(args) < 1 || len(args) > 3 {
+    return newError("wrong number of arguments: want=1, 2 or 3, got=%d", len(args))
+  }
+
+  bounds := make([]int64, len(args))
+  for i, arg := range args {
+    integer, ok := arg.(*object.Integer)
+    if !ok {
+      return newError("argument to `range` must be INTEGER, got %s", arg.Type())
+    }
+    bounds[i] = integer.Value
+  }
+
+  // 1.fill in the defaults, range(end) and range(start, end)
+  start, end, step := int64(0), bounds[0], int64(1)
+  if len(bounds) > 1 {
+    start, end = bounds[0], bounds[1]
+  }
+  if len(bounds) > 2 {
+    step = bounds[2]
+  }
+
+  // 2.the step must move start towards end
+  if step == 0 {
+    return newError("step of `range` must not be zero")
+  }
+  if (step > 0 && start > end) || (step < 0 && start < end) {
+    return newError("step of `range` never reaches %d from %d, got %d", end, start, step)
+  }
+
+  // 3.every element costs an operation, a range too big for the budget
+  //   is refused before anything is built
+  length := rangeLength(start, end, step)
+  if err := charge(settings, length); err != nil {
+    return err
+  }
+  if length > MaxRangeLength {
+    return newError("range too large: %d elements, at most %d", length, MaxRangeLength)
+  }
+
+  elements := make([]object.Object, 0, length)
+  for i := start; (step > 0 && i < end) || (step < 0 && i > end); i += step {
+    elements = append(elements, newInteger(i))
+  }
+
+  return &object.Array{Elements: elements}
+}
+
+// how many elements range(start, end, step) has, step moves start towards end,
+// the distance is unsigned so eg: range(-2 ** 62, 2 ** 62) doesn't overflow
+func rangeLength(start, end, step int64) int {
+  distance, stride := uint64(end-start), uint64(step)
+  if step < 0 {
+    distance, stride = uint64(start-end), uint64(-step)
+  }
+
+  length := distance / stride
+  if distance%stride != 0 {
+    length++
+  }
+  if length > math.MaxInt {
+    return math.MaxInt
+  }
+  return int(length)
+}
+
+// eg: take(lazy_range(1), 3) => [1, 2, 3], take([1, 2, 3], 5) => [1, 2, 3]
+// the first n elements of a lazy sequence or array as a new array
+func builtinTake(settings *object.Settings, args ...object.Object) object.Object {
+  if len(args) != 2 {
+    return newError("wrong number of arguments: want=2, got=%d", len(args))
+  }
+  n, ok := args[1].(*object.Integer)
+  if !ok || n.Value < 0 {
+    return newError("second argument to `take` must be a non-negative INTEGER, got %s", args[1].Inspect())
+  }
+
+  switch seq := args[0].(type) {
+  case *object.Array:
+    count := int64(len(seq.Elements))
+    if n.Value < count {
+      count = n.Value
+    }
+    if err := charge(settings, int(count)); err != nil {
+      return err
+    }
+    elements := make([]object.Object, count)
+    copy(elements, seq.Elements)
+    return &object.Array{Elements: elements}
+
+  case *object.Lazy:
+    elements := []object.Object{}
+    next := seq.Iterate()
+    for int64(len(elements)) < n.Value {
+      element, ok := next()
+      if !ok {
+        break
+      }
+      if isError(element) {
+        return element
+      }
+      if err := charge(settings, 1); err != nil {
+        return err
+      }
+      elements = append(elements, element)
+    }
+    return &object.Array{Elements: elements}
+
+  default:
+    return newError("first argument to `take` must be ARRAY or LAZY, got %s", args[0].Type())
+  }
+}
+
+// eg: join(["a", "b", "c"], "-") => "a-b-c"
+func builtinJoin(settings *object.Settings, args ...object.Object) object.Object {
+  if len(args) != 2 {
+    return newError("wrong number of arguments: want=2, got=%d", len(args))
+  }
+
+  array, ok := args[0].(*object.Array)
+  if !ok {
+    return newError("argument to `join` must be ARRAY, got %s", args[0].Type())
+  }
+  sep, ok := args[1].(*object.String)
+  if !ok {
+    return newError("argument to `join` must be STRING, got %s", args[1].Type())
+  }
+
+  parts := make([]string, len(array.Elements))
+  size := 0
+  for i, el := range array.Elements {
+    str, ok := el.(*object.String)
+    if !ok {
+      return newError("elements passed to `join` must be STRING, got %s", el.Type())
+    }
+    parts[i] = str.Value
+    size += len(str.Value)
+    if i > 0 {
+      size += len(sep.Value)
+    }
+  }
+
+  // every byte of the result costs an operation
+  if err := charge(settings, size); err != nil {
+    return err
+  }
+  return &object.String{Value: strings.Join(parts, sep.Value)}
+}
+
+// eg: format("{} + {} = {}", 1, 2, 3) => "1 + 2 = 3"
+//     format("{1}{0}, {{}}", "a", "b") => "ba, {}"
+func builtinFormat(settings *object.Settings, args ...object.Object) object.Object {
+  if len(args) == 0 {
+    return newError("wrong number of arguments: want=1 or more, got=0")
+  }
+  template, ok := args[0].(*object.String)
+  if !ok {
+    return newError("first argument to `format` must be STRING, got %s", args[0].Type())
+  }
+
+  return formatString(template.Value, args[1:], settings)
+}
+
+// eg: replace("a-b-c", "-", "_") => "a_b_c", every occurrence is replaced
+func builtinReplace(settings *object.Settings, args ...object.Object) object.Object {
+  if len(args) != 3 {
+    return newError("wrong number of arguments: want=3, got=%d", len(args))
+  }
+
+  strs := make([]string, len(args))
+  for i, arg := range args {
+    str, ok := arg.(*object.String)
+    if !ok {
+      return newError("argument to `replace` must be STRING, got %s", arg.Type())
+    }
+    strs[i] = str.Value
+  }
+
+  // every byte of the result costs an operation
+  size := len(strs[0]) + strings.Count(strs[0], strs[1])*(len(strs[2])-len(strs[1]))
+  if err := charge(settings, size); err != nil {
+    return err
+  }
+  return &object.String{Value: strings.ReplaceAll(strs[0], strs[1], strs[2])}
+}
+
+// eg: rand(6) => 0 to 5
+func builtinRand(settings *object.Settings, args ...object.Object) object.Object {
+  if len(args) != 1 {
+    return newError("wrong number of arguments: want=1, got=%d", len(args))
+  }
+  n, ok := args[0].(*object.Integer)
+  if !ok {
+    return newError("argument to `rand` must be INTEGER, got %s", args[0].Type())
+  }
+  if n.Value <= 0 {
+    return newError("argument to `rand` must be positive, got %d", n.Value)
+  }
+
+  return newInteger(random(settings).Int63n(n.Value))
+}
+
+// eg: srand(42), the same seed gives the same rand numbers again
+func builtinSrand(settings *object.Settings, args ...object.Object) object.Object {
+  if len(args) != 1 {
+    return newError("wrong number of arguments: want=1, got=%d", len(args))
+  }
+  seed, ok := args[0].(*object.Integer)
+  if !ok {
+    return newError("argument to `srand` must be INTEGER, got %s", args[0].Type())
+  }
+
+  random(settings).Seed(seed.Value)
+  return NULL
+}
+
+// the source of rand in the evaluation with settings,
+// without one set, it is seeded from the time on first use
+func random(settings *object.Settings) *rand.Rand {
+  if settings.Rand == nil {
+    settings.Rand = rand.New(rand.NewSource(time.Now().UnixNano()))
+  }
+  return settings.Rand
+}
+
+// milliseconds since the unix epoch, eg: time() => 1700000000000
+func builtinTime(settings *object.Settings, args ...object.Object) object.Object {
+  if len(args) != 0 {
+    return newError("wrong number of arguments: want=0, got=%d", len(args))
+  }
+
+  return newInteger(settings.Clock.Now().UnixMilli())
+}
+
+// eg: sleep(100) pauses for 100 milliseconds,
+// a cancelled evaluation wakes it up early
+func builtinSleep(settings *object.Settings, args ...object.Object) object.Object {
+  if len(args) != 1 {
+    return newError("wrong number of arguments: want=1, got=%d", len(args))
+  }
+  ms, ok := args[0].(*object.Integer)
+  if !ok {
+    return newError("argument to `sleep` must be INTEGER, got %s", args[0].Type())
+  }
+  if ms.Value < 0 {
+    return newError("argument to `sleep` must not be negative, got %d", ms.Value)
+  }
+
+  if err := settings.Clock.Sleep(settings.Context, time.Duration(ms.Value)*time.Millisecond); err != nil {
+    return newError("evaluation cancelled: %s", err)
+  }
+  return NULL
+}
+
+func isCallable(obj object.Object) bool {
+  switch obj.(type) {
+  case *object.Function, *object.Builtin:
+    return true
+  }
+  return false
+}
+
+// eg: map([1, 2, 3], fn(x) { x * 2 }) => [2, 4, 6]
+// a lazy sequence gives a lazy sequence, fn runs as its elements are taken
+func builtinMap(args ...object.Object) object.Object {
+  if len(args) != 2 {
+    return newError("wrong number of arguments: want=2, got=%d", len(args))
+  }
+
+  seq, lazy := args[0].(*object.Lazy)
+  array, ok := args[0].(*object.Array)
+  if !ok && !lazy {
+    return newError("first argument to `map` must be ARRAY or LAZY, got %s", args[0].Type())
+  }
+  if !isCallable(args[1]) {
+    return newError("second argument to `map` must be callable, got %s", args[1].Type())
+  }
+  if lazy {
+    return lazyMap(seq, args[1])
+  }
+
+  mapped := make([]object.Object, len(array.Elements))
+  for i, el := range array.Elements {
+    result := applyFunction(args[1], []object.Object{el})
+    if isError(result) {
+      return result
+    }
+    mapped[i] = result
+  }
+
+  return &object.Array{Elements: mapped}
+}
+
+// an error from fn becomes an element, and ends the take that reaches it
+func lazyMap(seq *object.Lazy, fn object.Object) *object.Lazy {
+  return &object.Lazy{Iterate: func() func() (object.Object, bool) {
+    next := seq.Iterate()
+    return func() (object.Object, bool) {
+      element, ok := next()
+      if !ok || isError(element) {
+        return element, ok
+      }
+      return applyFunction(fn, []object.Object{element}), true
+    }
+  }}
+}
+
+// fold left, eg: reduce([1, 2, 3], 0, fn(acc, x) { acc + x }) => 6
+// an empty array gives back initial
+func builtinReduce(args ...object.Object) object.Object {
+  if len(args) != 3 {
+    return newError("wrong number of arguments: want=3, got=%d", len(args))
+  }
+
+  array, ok := args[0].(*object.Array)
+  if !ok {
+    return newError("first argument to `reduce` must be ARRAY, got %s", args[0].Type())
+  }
+  if !isCallable(args[2]) {
+    return newError("third argument to `reduce` must be callable, got %s", args[2].Type())
+  }
+
+  accumulator := args[1]
+  for _, el := range array.Elements {
+    accumulator = applyFunction(args[2], []object.Object{accumulator, el})
+    if isError(accumulator) {
+      return accumulator
+    }
+  }
+
+  return accumulator
+}
+
+// eg: filter([1, 2, 3, 4], fn(x) { x % 2 == 0 }) => [2, 4]
+// keeps the elements the predicate is truthy for, same rules as `if`,
+// a lazy sequence gives a lazy sequence
+func builtinFilter(args ...object.Object) object.Object {
+  if len(args) != 2 {
+    return newError("wrong number of arguments: want=2, got=%d", len(args))
+  }
+
+  seq, lazy := args[0].(*object.Lazy)
+  array, ok := args[0].(*object.Array)
+  if !ok && !lazy {
+    return newError("first argument to `filter` must be ARRAY or LAZY, got %s", args[0].Type())
+  }
+  if !isCallable(args[1]) {
+    return newError("second argument to `filter` must be callable, got %s", args[1].Type())
+  }
+  if lazy {
+    return lazyFilter(seq, args[1])
+  }
+
+  filtered := []object.Object{}
+  for _, el := range array.Elements {
+    result := applyFunction(args[1], []object.Object{el})
+    if isError(result) {
+      return result
+    }
+    if isTruthy(result) {
+      filtered = append(filtered, el)
+    }
+  }
+
+  return &object.Array{Elements: filtered}
+}
+
+// skips ahead to the next element the predicate keeps,
+// on an infinite sequence that keeps nothing this never returns
+func lazyFilter(seq *object.Lazy, predicate object.Object) *object.Lazy {
+  return &object.Lazy{Iterate: func() func() (object.Object, bool) {
+    next := seq.Iterate()
+    return func() (object.Object, bool) {
+      for {
+        element, ok := next()
+        if !ok || isError(element) {
+          return element, ok
+        }
+        result := applyFunction(predicate, []object.Object{element})
+        if isError(result) {
+          return result, true
+        }
+        if isTruthy(result) {
+          return element, true
+        }
+      }
+    }
+  }}
+}
+
+// eg: sort([3, 1, 2]) => [1, 2, 3], sort(["b", "a"]) => ["a", "b"]
+// sort([1, 3, 2], fn(a, b) { b - a }) => [3, 2, 1]
+// without a comparator the elements must be all integers or all strings,
+// the comparator gives a negative, zero or positive INTEGER; the sort is stable
+// and gives a new array
+func builtinSort(args ...object.Object) object.Object {
+  if len(args) != 1 && len(args) != 2 {
+    return newError("wrong number of arguments: want=1 or 2, got=%d", len(args))
+  }
+
+  array, ok := args[0].(*object.Array)
+  if !ok {
+    return newError("first argument to `sort` must be ARRAY, got %s", args[0].Type())
+  }
+  if len(args) == 2 && !isCallable(args[1]) {
+    return newError("second argument to `sort` must be callable, got %s", args[1].Type())
+  }
+
+  sorted := make([]object.Object, len(array.Elements))
+  copy(sorted, array.Elements)
+
+  // 1.pick the comparison
+  compare := compareNatural
+  if len(args) == 2 {
+    compare = func(a, b object.Object) (int, *object.Error) {
+      result := applyFunction(args[1], []object.Object{a, b})
+      if errObj, ok := result.(*object.Error); ok {
+        return 0, errObj
+      }
+      integer, ok := result.(*object.Integer)
+      if !ok {
+        return 0, newError("comparator passed to `sort` must return INTEGER, got %s", result.Type())
+      }
+      return int(integer.Value), nil
+    }
+  } else if err := checkSortable(sorted); err != nil {
+    return err
+  }
+
+  // 2.the first error stops the comparisons, the order doesn't matter anymore
+  var err *object.Error
+  sort.SliceStable(sorted, func(i, j int) bool {
+    if err != nil {
+      return false
+    }
+    var order int
+    order, err = compare(sorted[i], sorted[j])
+    return order < 0
+  })
+  if err != nil {
+    return err
+  }
+
+  return &object.Array{Elements: sorted}
+}
+
+// all integers or all strings
+func checkSortable(elements []object.Object) *object.Error {
+  for _, el := range elements {
+    if !isInteger(el) && el.Type() != object.STRING_OBJ {
+      return newError("elements passed to `sort` must be INTEGER or STRING, got %s", el.Type())
+    }
+    if isInteger(el) != isInteger(elements[0]) {
+      return newError("elements passed to `sort` must not mix %s and %s without a comparator",
+        elements[0].Type(), el.Type())
+    }
+  }
+  return nil
+}
+
+// a and b are both integers or both strings
+func compareNatural(a, b object.Object) (int, *object.Error) {
+  if a, ok := a.(*object.String); ok {
+    return strings.Compare(a.Value, b.(*object.String).Value), nil
+  }
+  return toBigInt(a).Cmp(toBigInt(b)), nil
+}
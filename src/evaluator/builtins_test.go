@@ -0,0 +1,1008 @@
+package evaluator
+
+import (
+  "JFFMonkeyLang/src/lexer"
+  "JFFMonkeyLang/src/object"
+  "JFFMonkeyLang/src/parser"
+  "context"
+  "math/rand"
+  "os"
+  "path/filepath"
+  "reflect"
+  "testing"
+  "time"
+)
+
+func TestBuiltinType(t *testing.T) {
+  tests := []struct {
+    input    string
+    expected string
+  }{
+    {"type(5)", "INTEGER"},
+    {"type(true)", "BOOLEAN"},
+    {"type(if (false) { 1 })", "NULL"},
+    {"type(fn(){})", "FUNCTION"},
+    {"type(type)", "BUILTIN"},
+    {"type(type(5))", "STRING"},
+    {"type([1, 2])", "ARRAY"},
+    {"type({})", "HASH"},
+    {"let f = fn(x) { x }; type(f(1 < 2))", "BOOLEAN"},
+  }
+
+  for _, tt := range tests {
+    testStringObject(t, testEval(tt.input), tt.expected)
+  }
+}
+
+func TestBuiltinLen(t *testing.T) {
+  tests := []struct {
+    input    string
+    expected interface{}
+  }{
+    {`len("")`, 0},
+    {`len("four")`, 4},
+    {`len("héllo")`, 5},
+    {`len([])`, 0},
+    {`len([1, 2, 3])`, 3},
+    {`len({})`, 0},
+    {`len({"a": 1, "b": 2})`, 2},
+    {`len({"a": 1, "a": 2})`, 1},
+    {`let h = {"a": 1}; h["b"] = 2; len(h)`, 2},
+    {`len(1)`, "argument to `len` not supported, got INTEGER"},
+    {`len(fn() {})`, "argument to `len` not supported, got FUNCTION"},
+    {`len("one", "two")`, "wrong number of arguments: want=1, got=2"},
+  }
+
+  for _, tt := range tests {
+    evaluated := testEval(tt.input)
+    switch expected := tt.expected.(type) {
+    case int:
+      testIntegerObject(t, evaluated, int64(expected))
+    case string:
+      testErrorObject(t, evaluated, expected)
+    }
+  }
+}
+
+func TestBuiltinSubstr(t *testing.T) {
+  tests := []struct {
+    input    string
+    expected string
+  }{
+    {`substr("hello", 1, 3)`, "ell"},
+    {`substr("hello", 0, 5)`, "hello"},
+    {`substr("hello", 3, 10)`, "lo"},
+    {`substr("hello", -2, 3)`, "hel"},
+    {`substr("hello", 10, 2)`, ""},
+    {`substr("hello", 2, -1)`, ""},
+    {`substr("héllo", 1, 2)`, "él"},
+  }
+
+  for _, tt := range tests {
+    testStringObject(t, testEval(tt.input), tt.expected)
+  }
+}
+
+func TestBuiltinSplit(t *testing.T) {
+  tests := []struct {
+    input    string
+    expected []string
+  }{
+    {`split("a,b,c", ",")`, []string{"a", "b", "c"}},
+    {`split("a", ",")`, []string{"a"}},
+    {`split("a,,b", ",")`, []string{"a", "", "b"}},
+    {`split("", ",")`, []string{""}},
+    {`split("añb", "")`, []string{"a", "ñ", "b"}},
+  }
+
+  for _, tt := range tests {
+    evaluated := testEval(tt.input)
+    array, ok := evaluated.(*object.Array)
+    if !ok {
+      t.Errorf("object is not Array. got=%T (%+v)", evaluated, evaluated)
+      continue
+    }
+
+    if len(array.Elements) != len(tt.expected) {
+      t.Errorf("wrong num of elements. want=%d, got=%d",
+        len(tt.expected), len(array.Elements))
+      continue
+    }
+
+    for i, expected := range tt.expected {
+      testStringObject(t, array.Elements[i], expected)
+    }
+  }
+}
+
+func TestBuiltinJoin(t *testing.T) {
+  tests := []struct {
+    input    string
+    expected string
+  }{
+    {`join(["a", "b", "c"], "-")`, "a-b-c"},
+    {`join([], "-")`, ""},
+    {`join(["a"], ", ")`, "a"},
+  }
+
+  for _, tt := range tests {
+    testStringObject(t, testEval(tt.input), tt.expected)
+  }
+
+  // round trip
+  testBooleanObject(t, testEval(`let x = "a,b,c"; join(split(x, ","), ",") == x`), true)
+}
+
+func TestBuiltinConversions(t *testing.T) {
+  tests := []struct {
+    input    string
+    expected interface{}
+  }{
+    {`int("42") + 1`, 43},
+    {`int("-7")`, -7},
+    {`int(5)`, 5},
+    {`str(42) + "!"`, "42!"},
+    {`str(true)`, "true"},
+    {`str("a")`, "a"},
+    {`str([1, "a"])`, "[1, a]"},
+    {`int(str(123))`, 123},
+    // past int64 it gives a BigInteger, like the same literal does
+    {`str(int(2 ** 100))`, "1267650600228229401496703205376"},
+    {`str(int("99999999999999999999"))`, "99999999999999999999"},
+    {`str(int("-99999999999999999999"))`, "-99999999999999999999"},
+    {`int("99999999999999999999") == 99999999999999999999`, true},
+    {`int("9223372036854775807")`, 9223372036854775807},
+    {`int(str(2 ** 64)) - 2 ** 64`, 0},
+  }
+
+  for _, tt := range tests {
+    evaluated := testEval(tt.input)
+    switch expected := tt.expected.(type) {
+    case int:
+      testIntegerObject(t, evaluated, int64(expected))
+    case string:
+      testStringObject(t, evaluated, expected)
+    case bool:
+      testBooleanObject(t, evaluated, expected)
+    }
+  }
+
+  if evaluated := testEval(`int("99999999999999999999")`); evaluated.Type() != object.BIG_INTEGER_OBJ {
+    t.Errorf("int of a huge string is not BIG_INTEGER. got=%s", evaluated.Type())
+  }
+}
+
+func TestBuiltinMap(t *testing.T) {
+  tests := []struct {
+    input    string
+    expected []int64
+  }{
+    {"map([1, 2, 3], fn(x) { x * 2 })", []int64{2, 4, 6}},
+    {"map([], fn(x) { x * 2 })", []int64{}},
+    {"let double = fn(x) { x * 2 }; map(map([1], double), double)", []int64{4}},
+    {`map(["1", "22"], int)`, []int64{1, 22}},
+  }
+
+  for _, tt := range tests {
+    testIntegerArray(t, testEval(tt.input), tt.expected)
+  }
+}
+
+func TestBuiltinReduce(t *testing.T) {
+  tests := []struct {
+    input    string
+    expected interface{}
+  }{
+    {"reduce([1, 2, 3, 4], 0, fn(acc, x) { acc + x })", 10},
+    {"reduce([1, 2, 3, 4], 1, fn(acc, x) { acc * x })", 24},
+    {"reduce([], 42, fn(acc, x) { acc + x })", 42},
+    {`reduce(["a", "b"], "", fn(acc, x) { acc + x })`, "ab"},
+    // left fold: ((0 - 1) - 2) - 3
+    {"reduce([1, 2, 3], 0, fn(acc, x) { acc - x })", -6},
+  }
+
+  for _, tt := range tests {
+    evaluated := testEval(tt.input)
+    switch expected := tt.expected.(type) {
+    case int:
+      testIntegerObject(t, evaluated, int64(expected))
+    case string:
+      testStringObject(t, evaluated, expected)
+    }
+  }
+}
+
+func TestBuiltinFilter(t *testing.T) {
+  tests := []struct {
+    input    string
+    expected []int64
+  }{
+    {"filter([1, 2, 3, 4], fn(x) { x % 2 == 0 })", []int64{2, 4}},
+    {"filter([1, 2, 3], fn(x) { false })", []int64{}},
+    {"filter([], fn(x) { true })", []int64{}},
+    // truthy like `if`: integers are truthy, null is not
+    {"filter([1, 2, 3], fn(x) { if (x > 1) { x } })", []int64{2, 3}},
+  }
+
+  for _, tt := range tests {
+    testIntegerArray(t, testEval(tt.input), tt.expected)
+  }
+}
+
+func TestBuiltinFilterStopsAtError(t *testing.T) {
+  input := `
+let seen = fn(x) { if (x == 2) { x + true } else { true } };
+filter([1, 2, 3], seen)`
+
+  testErrorObject(t, testEval(input), "type mismatch: INTEGER + BOOLEAN")
+}
+
+func TestBuiltinLazy(t *testing.T) {
+  tests := []struct {
+    input    string
+    expected []int64
+  }{
+    {"take(lazy_range(0), 5)", []int64{0, 1, 2, 3, 4}},
+    {"take(lazy_range(10, -3), 4)", []int64{10, 7, 4, 1}},
+    {"take(lazy_range(0), 0)", []int64{}},
+    {"take(map(lazy_range(1), fn(x) { x * x }), 5)", []int64{1, 4, 9, 16, 25}},
+    {"take(filter(lazy_range(0), fn(x) { x % 3 == 0 }), 5)", []int64{0, 3, 6, 9, 12}},
+    {"take(map(filter(lazy_range(1), fn(x) { x % 2 == 1 }), fn(x) { x * 10 }), 3)", []int64{10, 30, 50}},
+    // a sequence can be taken from again, from its start
+    {"let odd = filter(lazy_range(0), fn(x) { x % 2 == 1 }); take(odd, 2); take(odd, 3)", []int64{1, 3, 5}},
+    {"take([1, 2, 3], 2)", []int64{1, 2}},
+    {"take([1, 2, 3], 5)", []int64{1, 2, 3}},
+  }
+
+  for _, tt := range tests {
+    testIntegerArray(t, testEval(tt.input), tt.expected)
+  }
+
+  testStringObject(t, testEval(`type(map(lazy_range(0), fn(x) { x }))`), "LAZY")
+  // past int64 it goes on with big integers
+  evaluated := testEval("take(lazy_range(9223372036854775807), 2)[1]")
+  if evaluated.Inspect() != "9223372036854775808" {
+    t.Errorf("wrong big integer. got=%s", evaluated.Inspect())
+  }
+}
+
+// map only calls fn for the elements that are taken
+func TestBuiltinLazyMapIsLazy(t *testing.T) {
+  calls := 0
+  env := object.NewEnvironment()
+  env.Set("count", &object.Builtin{Fn: func(args ...object.Object) object.Object {
+    calls++
+    return args[0]
+  }})
+
+  program := parser.New(lexer.New("let seq = map(lazy_range(0), count); take(seq, 3)")).ParseProgram()
+  testIntegerArray(t, Eval(program, env), []int64{0, 1, 2})
+  if calls != 3 {
+    t.Errorf("fn called %d times, want=3", calls)
+  }
+
+  // an error from fn ends the take
+  testErrorObject(t, testEval("take(map(lazy_range(0), fn(x) { if (x == 2) { x + true } else { x } }), 5)"),
+    "type mismatch: INTEGER + BOOLEAN")
+}
+
+func TestBuiltinZip(t *testing.T) {
+  tests := []struct {
+    input    string
+    expected string
+  }{
+    {"zip([1, 2], [3, 4])", "[[1, 3], [2, 4]]"},
+    {`zip([1, 2, 3], ["a", "b"])`, "[[1, a], [2, b]]"},
+    {"zip([1], [2, 3, 4])", "[[1, 2]]"},
+    {"zip([1, 2], [3, 4], [5, 6])", "[[1, 3, 5], [2, 4, 6]]"},
+    {"zip([1, 2])", "[[1], [2]]"},
+    {"zip([], [1])", "[]"},
+  }
+
+  for _, tt := range tests {
+    if evaluated := testEval(tt.input); evaluated.Inspect() != tt.expected {
+      t.Errorf("%s wrong. want=%s, got=%s", tt.input, tt.expected, evaluated.Inspect())
+    }
+  }
+}
+
+func TestBuiltinSlice(t *testing.T) {
+  tests := []struct {
+    input    string
+    expected string
+  }{
+    {"slice([1, 2, 3, 4], 1, 3)", "[2, 3]"},
+    {"slice([1, 2, 3, 4], 2)", "[3, 4]"},
+    {"slice([1, 2, 3, 4], -2)", "[3, 4]"},
+    {"slice([1, 2, 3, 4], 0, -1)", "[1, 2, 3]"},
+    {"slice([1, 2, 3, 4], -3, -1)", "[2, 3]"},
+    // clamped to the array
+    {"slice([1, 2, 3, 4], -10, 10)", "[1, 2, 3, 4]"},
+    {"slice([1, 2, 3, 4], 3, 1)", "[]"},
+    {"slice([1, 2, 3, 4], 5, 9)", "[]"},
+    {"slice([], 0, 1)", "[]"},
+    {`slice("hello", 1, 3)`, "el"},
+    {`slice("héllo", 1, -1)`, "éll"},
+    {`slice("hello", -3)`, "llo"},
+    {`slice("hello", 10)`, ""},
+  }
+
+  for _, tt := range tests {
+    if evaluated := testEval(tt.input); evaluated.Inspect() != tt.expected {
+      t.Errorf("%s wrong. want=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+    }
+  }
+
+  // the result is a copy
+  testIntegerArray(t, testEval("let a = [1, 2, 3]; let b = slice(a, 0, 2); b[0] = 9; a"), []int64{1, 2, 3})
+}
+
+func TestBuiltinKeysValues(t *testing.T) {
+  tests := []struct {
+    input    string
+    expected string
+  }{
+    {`keys({"a": 1, "b": 2})`, "[a, b]"},
+    {`values({"a": 1, "b": 2})`, "[1, 2]"},
+    {`keys({})`, "[]"},
+    {`values({})`, "[]"},
+    {`keys({3: "c", 1: "a", 2: "b"})`, "[3, 1, 2]"},
+    {`values({3: "c", 1: "a", 2: "b"})`, "[c, a, b]"},
+  }
+
+  for _, tt := range tests {
+    // go map order is random, insertion order must win every time
+    for i := 0; i < 20; i++ {
+      evaluated := testEval(tt.input)
+      if evaluated.Inspect() != tt.expected {
+        t.Fatalf("%s wrong. want=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+      }
+    }
+  }
+}
+
+func TestBuiltinDelete(t *testing.T) {
+  tests := []struct {
+    input    string
+    expected string
+  }{
+    {`delete({"a": 1, "b": 2}, "a")`, "{b: 2}"},
+    {`delete({"a": 1, "b": 2}, "c")`, "{a: 1, b: 2}"},
+    {`delete({}, 1)`, "{}"},
+    {`delete({1: 1, 2: 2, 3: 3}, 2)`, "{1: 1, 3: 3}"},
+    // the original hash is untouched
+    {`let h = {"a": 1}; delete(h, "a"); h`, "{a: 1}"},
+    // set a new key, then read it back
+    {`let h = delete({"a": 1}, "a"); h["b"] = 2; [h["b"], h]`, "[2, {b: 2}]"},
+  }
+
+  for _, tt := range tests {
+    evaluated := testEval(tt.input)
+    if evaluated.Inspect() != tt.expected {
+      t.Errorf("%s wrong. want=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+    }
+  }
+}
+
+func TestBuiltinRange(t *testing.T) {
+  tests := []struct {
+    input    string
+    expected []int64
+  }{
+    {"range(3)", []int64{0, 1, 2}},
+    {"range(0)", []int64{}},
+    {"range(1, 4)", []int64{1, 2, 3}},
+    {"range(1, 10, 2)", []int64{1, 3, 5, 7, 9}},
+    {"range(3, 0, -1)", []int64{3, 2, 1}},
+    {"range(5, 5, -2)", []int64{}},
+    {"range(1, 10, 4)", []int64{1, 5, 9}},
+    {"range(10, 1, -4)", []int64{10, 6, 2}},
+    {"map(range(3), fn(x) { x * x })", []int64{0, 1, 4}},
+  }
+
+  for _, tt := range tests {
+    evaluated := testEval(tt.input)
+    testIntegerArray(t, evaluated, tt.expected)
+
+    // the length is known up front, the elements are allocated once
+    if array, ok := evaluated.(*object.Array); ok && cap(array.Elements) != len(tt.expected) {
+      t.Errorf("%s: elements not pre-sized. len=%d, cap=%d", tt.input, len(array.Elements), cap(array.Elements))
+    }
+  }
+}
+
+func TestBuiltinClone(t *testing.T) {
+  tests := []struct {
+    input    string
+    expected string
+  }{
+    {`clone([1, 2, 3])`, "[1, 2, 3]"},
+    {`clone({"a": [1, {"b": 2}]})`, "{a: [1, {b: 2}]}"},
+    {`clone(5)`, "5"},
+    {`clone("hi")`, "hi"},
+    // mutating the clone leaves the original unchanged
+    {`let a = [1, [2, 3]]; let b = clone(a); b[1][0] = 9; [a, b]`, "[[1, [2, 3]], [1, [9, 3]]]"},
+    {`let h = {"a": {"b": 1}}; let c = clone(h); c["a"]["b"] = 2; c["x"] = 3; [h, c]`,
+      "[{a: {b: 1}}, {a: {b: 2}, x: 3}]"},
+    {`let h = {"a": [1]}; let c = clone(h); c["a"][0] = 2; h["a"]`, "[1]"},
+  }
+
+  for _, tt := range tests {
+    evaluated := testEval(tt.input)
+    if evaluated.Inspect() != tt.expected {
+      t.Errorf("%s wrong. want=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+    }
+  }
+
+  // immutable singletons and functions are shared
+  if testEval("clone(true)") != TRUE {
+    t.Errorf("clone(true) is not the TRUE singleton")
+  }
+  if testEval("clone(if (false) { 1 })") != NULL {
+    t.Errorf("clone(null) is not the NULL singleton")
+  }
+  if testEval("let f = fn(x) { x }; clone(f) == f") != TRUE {
+    t.Errorf("clone(f) is not the same function")
+  }
+}
+
+func TestBuiltinContains(t *testing.T) {
+  tests := []struct {
+    input    string
+    expected bool
+  }{
+    {`contains([1, 2, 3], 2)`, true},
+    {`contains([1, 2, 3], 4)`, false},
+    {`contains([], 1)`, false},
+    {`contains(["a", "b"], "b")`, true},
+    {`contains([true], true)`, true},
+    {`contains([true], false)`, false},
+    {`contains([1], "1")`, false},
+    {`contains("hello", "ell")`, true},
+    {`contains("hello", "")`, true},
+    {`contains("hello", "xyz")`, false},
+    {`contains({"a": 1}, "a")`, true},
+    {`contains({"a": 1}, "b")`, false},
+    {`contains({1: "a"}, 1)`, true},
+  }
+
+  for _, tt := range tests {
+    testBooleanObject(t, testEval(tt.input), tt.expected)
+  }
+}
+
+func TestBuiltinErrors(t *testing.T) {
+  tests := []struct {
+    input           string
+    expectedMessage string
+  }{
+    {"type()", "wrong number of arguments: want=1, got=0"},
+    {"type(1, 2)", "wrong number of arguments: want=1, got=2"},
+    {`substr("a", 1)`, "wrong number of arguments: want=3, got=2"},
+    {`substr(1, 0, 1)`, "argument to `substr` must be STRING, got INTEGER"},
+    {`substr("a", "b", 1)`, "argument to `substr` must be INTEGER, got STRING"},
+    {`substr("a", 0, true)`, "argument to `substr` must be INTEGER, got BOOLEAN"},
+    {`split("a")`, "wrong number of arguments: want=2, got=1"},
+    {`split(1, ",")`, "argument to `split` must be STRING, got INTEGER"},
+    {`split("a", 1)`, "argument to `split` must be STRING, got INTEGER"},
+    {`join("a", ",")`, "argument to `join` must be ARRAY, got STRING"},
+    {`join(["a"], 1)`, "argument to `join` must be STRING, got INTEGER"},
+    {`join(["a", 1], ",")`, "elements passed to `join` must be STRING, got INTEGER"},
+    {`str()`, "wrong number of arguments: want=1, got=0"},
+    {`int("4", "2")`, "wrong number of arguments: want=1, got=2"},
+    {`int("abc")`, `could not parse "abc" as integer`},
+    {`int("99999999999999999999x")`, `could not parse "99999999999999999999x" as integer`},
+    {`int(join(map(range(40000), fn(i) { "9999999999" }), ""))`, "integer too large: 400000 digits"},
+    {`int("")`, `could not parse "" as integer`},
+    {`int(true)`, "argument to `int` not supported, got BOOLEAN"},
+    {`map([1])`, "wrong number of arguments: want=2, got=1"},
+    {`map(1, fn(x) { x })`, "first argument to `map` must be ARRAY or LAZY, got INTEGER"},
+    {`map([1], 1)`, "second argument to `map` must be callable, got INTEGER"},
+    {`map([1, true], fn(x) { x + 1 })`, "type mismatch: BOOLEAN + INTEGER"},
+    {`map([1], fn(x, y) { x })`, "wrong number of arguments: want=2, got=1"},
+    {`reduce([1], 0)`, "wrong number of arguments: want=3, got=2"},
+    {`reduce(1, 0, fn(a, x) { a })`, "first argument to `reduce` must be ARRAY, got INTEGER"},
+    {`reduce([1], 0, 0)`, "third argument to `reduce` must be callable, got INTEGER"},
+    {`reduce([], 0, 0)`, "third argument to `reduce` must be callable, got INTEGER"},
+    {`reduce([1, 2], 0, fn(a, x) { a + true })`, "type mismatch: INTEGER + BOOLEAN"},
+    {`filter([1])`, "wrong number of arguments: want=2, got=1"},
+    {`filter(1, fn(x) { x })`, "first argument to `filter` must be ARRAY or LAZY, got INTEGER"},
+    {`filter([1], "a")`, "second argument to `filter` must be callable, got STRING"},
+    {`map(lazy_range(0), 1)`, "second argument to `map` must be callable, got INTEGER"},
+    {`lazy_range()`, "wrong number of arguments: want=1 or 2, got=0"},
+    {`lazy_range("a")`, "argument to `lazy_range` must be INTEGER, got STRING"},
+    {`take(lazy_range(0))`, "wrong number of arguments: want=2, got=1"},
+    {`take(lazy_range(0), -1)`, "second argument to `take` must be a non-negative INTEGER, got -1"},
+    {`take("abc", 1)`, "first argument to `take` must be ARRAY or LAZY, got STRING"},
+    {`zip()`, "`zip` needs at least one ARRAY"},
+    {`slice([1])`, "wrong number of arguments: want=2 or 3, got=1"},
+    {`slice([1], "a")`, "bounds of `slice` must be INTEGER, got STRING"},
+    {`slice([1], 0, true)`, "bounds of `slice` must be INTEGER, got BOOLEAN"},
+    {`slice({}, 0)`, "first argument to `slice` must be ARRAY or STRING, got HASH"},
+    {`zip([1], "a")`, "arguments to `zip` must be ARRAY, got STRING"},
+    {`zip(lazy_range(0), [1])`, "arguments to `zip` must be ARRAY, got LAZY"},
+    {`keys()`, "wrong number of arguments: want=1, got=0"},
+    {`keys([1])`, "argument to `keys` must be HASH, got ARRAY"},
+    {`values({}, {})`, "wrong number of arguments: want=1, got=2"},
+    {`values("a")`, "argument to `values` must be HASH, got STRING"},
+    {`delete({})`, "wrong number of arguments: want=2, got=1"},
+    {`delete([1], 0)`, "first argument to `delete` must be HASH, got ARRAY"},
+    {`delete({}, [])`, "unusable as hash key: ARRAY"},
+    {`contains([1])`, "wrong number of arguments: want=2, got=1"},
+    {`contains(1, 1)`, "argument to `contains` not supported, got INTEGER"},
+    {`contains("abc", 1)`, "second argument to `contains` must be STRING, got INTEGER"},
+    {`contains({}, fn() {})`, "unusable as hash key: FUNCTION"},
+    {`clone()`, "wrong number of arguments: want=1, got=0"},
+    {`range()`, "wrong number of arguments: want=1, 2 or 3, got=0"},
+    {`range(1, 2, 3, 4)`, "wrong number of arguments: want=1, 2 or 3, got=4"},
+    {`range("3")`, "argument to `range` must be INTEGER, got STRING"},
+    {`range(0, 3, true)`, "argument to `range` must be INTEGER, got BOOLEAN"},
+    {`range(0, 3, 0)`, "step of `range` must not be zero"},
+    {`range(0, 3, -1)`, "step of `range` never reaches 3 from 0, got -1"},
+    {`range(3, 0, 1)`, "step of `range` never reaches 0 from 3, got 1"},
+    {`range(-1)`, "step of `range` never reaches -1 from 0, got 1"},
+    // refused before anything is allocated, with no budget set
+    {`range(9223372036854775807)`, "range too large: 9223372036854775807 elements, at most 67108864"},
+    {`range(0, 9223372036854775807, 1)`, "range too large: 9223372036854775807 elements, at most 67108864"},
+    {`range(-9223372036854775807 - 1, 9223372036854775807, 2)`, "range too large: 9223372036854775807 elements, at most 67108864"},
+    {`reverse()`, "wrong number of arguments: want=1, got=0"},
+    {`reverse(123)`, "argument to `reverse` not supported, got INTEGER"},
+    {`reverse({"a": 1})`, "argument to `reverse` not supported, got HASH"},
+    {`push([1])`, "wrong number of arguments: want=2, got=1"},
+    {`push("a", "b")`, "first argument to `push` must be ARRAY, got STRING"},
+    {`first()`, "wrong number of arguments: want=1, got=0"},
+    {`first("abc")`, "argument to `first` must be ARRAY, got STRING"},
+    {`upper()`, "wrong number of arguments: want=1, got=0"},
+    {`upper(1)`, "argument to `upper` must be STRING, got INTEGER"},
+    {`lower(["A"])`, "argument to `lower` must be STRING, got ARRAY"},
+    {`read_file()`, "wrong number of arguments: want=1, got=0"},
+    {`read_file(1)`, "argument to `read_file` must be STRING, got INTEGER"},
+    {`write_file("a")`, "wrong number of arguments: want=2, got=1"},
+    {`write_file("a", 1)`, "argument to `write_file` must be STRING, got INTEGER"},
+    {`format()`, "wrong number of arguments: want=1 or more, got=0"},
+    {`format(1, 2)`, "first argument to `format` must be STRING, got INTEGER"},
+    {`format("{} {}", 1)`, "not enough arguments to `format`: want at least 2, got=1"},
+    {`format("{3}", 1)`, "bad placeholder {3} in `format` template: only 1 arguments"},
+    {`format("{9223372036854775807}", 1)`, "bad placeholder {9223372036854775807} in `format` template: only 1 arguments"},
+    {`format("{99999999999999999999}", 1)`, "bad placeholder {99999999999999999999} in `format` template"},
+    {`format("{x}", 1)`, "bad placeholder {x} in `format` template"},
+    {`format("{-1}", 1)`, "bad placeholder {-1} in `format` template"},
+    {`format("a { b", 1)`, "unmatched { in `format` template at 2"},
+    {`format("a } b", 1)`, "unmatched } in `format` template at 2"},
+    {`trim()`, "wrong number of arguments: want=1, got=0"},
+    {`trim(1)`, "argument to `trim` must be STRING, got INTEGER"},
+    {`replace("a", "b")`, "wrong number of arguments: want=3, got=2"},
+    {`replace(1, "b", "c")`, "argument to `replace` must be STRING, got INTEGER"},
+    {`replace("a", "b", true)`, "argument to `replace` must be STRING, got BOOLEAN"},
+    {`min()`, "`min` needs at least one INTEGER"},
+    {`max([])`, "`max` needs at least one INTEGER"},
+    {`min(1, "2")`, "arguments to `min` must be INTEGER, got STRING"},
+    {`max([1, [2]])`, "arguments to `max` must be INTEGER, got ARRAY"},
+    {`max([1], [2])`, "arguments to `max` must be INTEGER, got ARRAY"},
+    {`abs()`, "wrong number of arguments: want=1, got=0"},
+    {`abs("-1")`, "argument to `abs` must be INTEGER, got STRING"},
+    {`sum(1, 2)`, "wrong number of arguments: want=1, got=2"},
+    {`sum(1)`, "argument to `sum` must be ARRAY, got INTEGER"},
+    {`sum([1, "2"])`, "elements passed to `sum` must be INTEGER, got STRING"},
+    {`sqrt(-1)`, "argument to `sqrt` must not be negative, got -1"},
+    {`sqrt("4")`, "argument to `sqrt` must be INTEGER, got STRING"},
+    {`sqrt(4, 2)`, "wrong number of arguments: want=1, got=2"},
+    {`pow(2)`, "wrong number of arguments: want=2, got=1"},
+    {`pow(2, true)`, "arguments to `pow` must be INTEGER, got BOOLEAN"},
+    {`pow(2, -1)`, "negative exponent: -1"},
+    {`rand()`, "wrong number of arguments: want=1, got=0"},
+    {`rand("6")`, "argument to `rand` must be INTEGER, got STRING"},
+    {`rand(0)`, "argument to `rand` must be positive, got 0"},
+    {`srand()`, "wrong number of arguments: want=1, got=0"},
+    {`srand(true)`, "argument to `srand` must be INTEGER, got BOOLEAN"},
+    {`time(1)`, "wrong number of arguments: want=0, got=1"},
+    {`sleep()`, "wrong number of arguments: want=1, got=0"},
+    {`sleep("1")`, "argument to `sleep` must be INTEGER, got STRING"},
+    {`sleep(-1)`, "argument to `sleep` must not be negative, got -1"},
+    {`sort()`, "wrong number of arguments: want=1 or 2, got=0"},
+    {`sort("cba")`, "first argument to `sort` must be ARRAY, got STRING"},
+    {`sort([1], 1)`, "second argument to `sort` must be callable, got INTEGER"},
+    {`sort([1, "a"])`, "elements passed to `sort` must not mix INTEGER and STRING without a comparator"},
+    {`sort([true, false])`, "elements passed to `sort` must be INTEGER or STRING, got BOOLEAN"},
+    {`sort([1, 2], fn(a, b) { a < b })`, "comparator passed to `sort` must return INTEGER, got BOOLEAN"},
+    {`sort([1, 2], fn(a, b) { a + true })`, "type mismatch: INTEGER + BOOLEAN"},
+  }
+
+  for _, tt := range tests {
+    testErrorObject(t, testEval(tt.input), tt.expectedMessage)
+  }
+}
+
+func TestBuiltinNumeric(t *testing.T) {
+  tests := []struct {
+    input    string
+    expected int64
+  }{
+    {"max([3, 1, 2])", 3},
+    {"max(3, 1, 2)", 3},
+    {"max(-5)", -5},
+    {"max([-5])", -5},
+    {"min(5, 2, 8)", 2},
+    {"min([5, 2, 8])", 2},
+    {"min(-1, -7, 0)", -7},
+    {"abs(-4)", 4},
+    {"abs(4)", 4},
+    {"abs(0)", 0},
+    {"sum([1, 2, 3])", 6},
+    {"sum([])", 0},
+    {"sum([-1, 1, -1])", -1},
+    {"sum([2 ** 64, -(2 ** 64), 5])", 5},
+    {"sqrt(16)", 4},
+    {"sqrt(10)", 3},
+    {"sqrt(0)", 0},
+    {"sqrt(2 ** 100)", 1125899906842624},
+    {"pow(2, 10)", 1024},
+    {"pow(-3, 3)", -27},
+    {"pow(5, 0)", 1},
+  }
+
+  for _, tt := range tests {
+    testIntegerObject(t, testEval(tt.input), tt.expected)
+  }
+
+  // results that don't fit an int64
+  inspected := []struct {
+    input    string
+    expected string
+  }{
+    {"abs(-9223372036854775807 - 1)", "9223372036854775808"},
+    {"sum([9223372036854775807, 1])", "9223372036854775808"},
+    {"max(1, 2 ** 64)", "18446744073709551616"},
+    {"min(1, -(2 ** 64))", "-18446744073709551616"},
+    {"pow(10, 20)", "100000000000000000000"},
+  }
+
+  for _, tt := range inspected {
+    if actual := testEval(tt.input).Inspect(); actual != tt.expected {
+      t.Errorf("%s wrong. expected=%s, got=%s", tt.input, tt.expected, actual)
+    }
+  }
+}
+
+func TestBuiltinRand(t *testing.T) {
+  // 1.a seed gives the sequence math/rand gives for it, every time
+  expected := rand.New(rand.NewSource(42))
+  evaluated := testEval("srand(42); [rand(100), rand(100), rand(100), rand(100), rand(100)]")
+  for _, el := range evaluated.(*object.Array).Elements {
+    testIntegerObject(t, el, expected.Int63n(100))
+  }
+
+  first := testEval("srand(7); [rand(1000), rand(1000), rand(1000)]").Inspect()
+  second := testEval("srand(7); [rand(1000), rand(1000), rand(1000)]").Inspect()
+  if first != second {
+    t.Errorf("same seed gave different numbers. first=%s, second=%s", first, second)
+  }
+
+  // an embedding seeds its evaluations through the settings,
+  // srand in another one leaves that sequence alone
+  seeded := object.NewEnvironment()
+  seeded.Settings().Rand = rand.New(rand.NewSource(3))
+  expected = rand.New(rand.NewSource(3))
+  testIntegerObject(t, Eval(parser.New(lexer.New("rand(1000)")).ParseProgram(), seeded), expected.Int63n(1000))
+  testNullObject(t, testEval("srand(3)"))
+  testIntegerObject(t, Eval(parser.New(lexer.New("rand(1000)")).ParseProgram(), seeded), expected.Int63n(1000))
+
+  // 2.always in [0, n)
+  evaluated = testEval("map(range(1000), fn(i) { rand(10) })")
+  for _, el := range evaluated.(*object.Array).Elements {
+    if value := el.(*object.Integer).Value; value < 0 || value >= 10 {
+      t.Fatalf("rand(10) out of range. got=%d", value)
+    }
+  }
+  testIntegerObject(t, testEval("rand(1)"), 0)
+}
+
+func TestBuiltinFiles(t *testing.T) {
+  dir := t.TempDir()
+  path := filepath.Join(dir, "notes.txt")
+
+  testNullObject(t, testEval(`write_file("`+path+`", "héllo, world")`))
+  content, err := os.ReadFile(path)
+  if err != nil || string(content) != "héllo, world" {
+    t.Fatalf("wrong file content. got=%q (%v)", content, err)
+  }
+  testStringObject(t, testEval(`read_file("`+path+`")`), "héllo, world")
+
+  // writing again truncates
+  testStringObject(t, testEval(`write_file("`+path+`", "x"); read_file("`+path+`")`), "x")
+
+  missing := filepath.Join(dir, "missing.txt")
+  testErrorObject(t, testEval(`read_file("`+missing+`")`),
+    "could not read "+missing+": open "+missing+": no such file or directory")
+  testErrorObject(t, testEval(`write_file("`+dir+`", "x")`),
+    "could not write "+dir+": open "+dir+": is a directory")
+}
+
+func TestBuiltinFilesSandbox(t *testing.T) {
+  sandbox := func(settings *object.Settings) { settings.Sandbox = true }
+
+  path := filepath.Join(t.TempDir(), "notes.txt")
+  if err := os.WriteFile(path, []byte("secret"), 0644); err != nil {
+    t.Fatal(err)
+  }
+
+  testErrorObject(t, testEvalWith(`read_file("`+path+`")`, sandbox), "builtin disabled in sandbox: read_file")
+  testErrorObject(t, testEvalWith(`write_file("`+path+`", "x")`, sandbox), "builtin disabled in sandbox: write_file")
+  testErrorObject(t, testEvalWith(`let f = write_file; f("`+path+`", "x")`, sandbox), "builtin disabled in sandbox: write_file")
+  // only the builtins are off, not the names
+  testIntegerObject(t, testEvalWith(`let read_file = fn(path) { 1 }; read_file("`+path+`")`, sandbox), 1)
+  // everything else still works
+  testIntegerObject(t, testEvalWith(`len("abc") + sum([1, 2])`, sandbox), 6)
+
+  // the file is untouched
+  if content, _ := os.ReadFile(path); string(content) != "secret" {
+    t.Errorf("write_file wrote in the sandbox. got=%q", content)
+  }
+
+  // an evaluation without the sandbox reads files
+  testStringObject(t, testEval(`read_file("`+path+`")`), "secret")
+}
+
+// time stands still unless something sleeps
+type fakeClock struct {
+  now   time.Time
+  slept []time.Duration
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func (c *fakeClock) Sleep(ctx context.Context, d time.Duration) error {
+  c.slept = append(c.slept, d)
+  c.now = c.now.Add(d)
+  return nil
+}
+
+func TestBuiltinTime(t *testing.T) {
+  clock := &fakeClock{now: time.UnixMilli(1700000000123)}
+  fake := func(settings *object.Settings) { settings.Clock = clock }
+
+  testIntegerObject(t, testEvalWith("time()", fake), 1700000000123)
+
+  // the fake sleep returns right away, only the clock moves on
+  start := time.Now()
+  testIntegerObject(t, testEvalWith("let start = time(); sleep(60000); sleep(0); time() - start", fake), 60000)
+  if elapsed := time.Since(start); elapsed > time.Second {
+    t.Errorf("sleep under the fake clock took %s", elapsed)
+  }
+  expected := []time.Duration{time.Minute, 0}
+  if !reflect.DeepEqual(clock.slept, expected) {
+    t.Errorf("wrong sleeps. expected=%v, got=%v", expected, clock.slept)
+  }
+
+  // evaluations without the fake clock keep the real one
+  if now := testEval("time()").(*object.Integer).Value; now < time.Now().Add(-time.Minute).UnixMilli() {
+    t.Errorf("time() went by the fake clock: %d", now)
+  }
+}
+
+func TestBuiltinSleepCancelled(t *testing.T) {
+  l := lexer.New("sleep(60000)")
+  p := parser.New(l)
+  program := p.ParseProgram()
+
+  ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+  defer cancel()
+
+  start := time.Now()
+  evaluated := EvalWithContext(ctx, program, object.NewEnvironment())
+  testErrorObject(t, evaluated, "evaluation cancelled: context deadline exceeded")
+  if elapsed := time.Since(start); elapsed > time.Second {
+    t.Errorf("sleep took too long to cancel: %s", elapsed)
+  }
+
+  testNullObject(t, testEval("sleep(1)"))
+}
+
+func TestBuiltinReverse(t *testing.T) {
+  arrays := []struct {
+    input    string
+    expected []int64
+  }{
+    {"reverse([1, 2, 3])", []int64{3, 2, 1}},
+    {"reverse([1, 2])", []int64{2, 1}},
+    {"reverse([])", []int64{}},
+    // the input is left alone
+    {"let a = [1, 2, 3]; reverse(a); a", []int64{1, 2, 3}},
+  }
+
+  for _, tt := range arrays {
+    testIntegerArray(t, testEval(tt.input), tt.expected)
+  }
+
+  strings := []struct {
+    input    string
+    expected string
+  }{
+    {`reverse("abc")`, "cba"},
+    {`reverse("")`, ""},
+    // by rune, so multi-byte characters stay whole
+    {`reverse("héllo")`, "olléh"},
+    {`reverse("变量")`, "量变"},
+    {`let s = "abc"; reverse(s); s`, "abc"},
+  }
+
+  for _, tt := range strings {
+    testStringObject(t, testEval(tt.input), tt.expected)
+  }
+}
+
+func TestBuiltinPushFirst(t *testing.T) {
+  testIntegerArray(t, testEval("push([1, 2], 3)"), []int64{1, 2, 3})
+  testIntegerArray(t, testEval("push([], 1)"), []int64{1})
+  // the input is left alone
+  testIntegerArray(t, testEval("let a = [1]; push(a, 2); a"), []int64{1})
+  testIntegerArray(t, testEval("let a = [1]; let b = push(a, 2); let c = push(a, 3); b"), []int64{1, 2})
+  // changing either array afterwards doesn't show up in the other
+  testIntegerArray(t, testEval("let a = [1]; let b = push(a, 2); a[0] = 9; b"), []int64{1, 2})
+  testIntegerArray(t, testEval("let a = [1]; let b = push(a, 2); b[0] = 9; a"), []int64{1})
+
+  testIntegerObject(t, testEval("first([4, 5])"), 4)
+  testNullObject(t, testEval("first([])"))
+}
+
+// go test -bench Push -benchmem ./src/evaluator
+func BenchmarkBuiltinPush(b *testing.B) {
+  program := parser.New(lexer.New(`
+let a = [];
+let i = 0;
+while (i < 2000) { a = push(a, i); i = i + 1 };
+a`)).ParseProgram()
+  b.ReportAllocs()
+  b.ResetTimer()
+
+  for i := 0; i < b.N; i++ {
+    array, ok := Eval(program, object.NewEnvironment()).(*object.Array)
+    if !ok || len(array.Elements) != 2000 {
+      b.Fatalf("wrong result: %v", array)
+    }
+    if last := array.Elements[1999].(*object.Integer); last.Value != 1999 {
+      b.Fatalf("wrong last element: %d", last.Value)
+    }
+  }
+}
+
+func TestBuiltinStrings(t *testing.T) {
+  tests := []struct {
+    input    string
+    expected string
+  }{
+    {`upper("abc")`, "ABC"},
+    {`upper("héllo 1")`, "HÉLLO 1"},
+    {`lower("ABC")`, "abc"},
+    {`lower("")`, ""},
+    {`lower("ÀÉÎ")`, "àéî"},
+    {`trim("  hi  ")`, "hi"},
+    {"trim(\"\t hi there\n\")", "hi there"},
+    {`trim("   ")`, ""},
+    {`replace("a-b-c", "-", "_")`, "a_b_c"},
+    {`replace("aaa", "a", "bb")`, "bbbbbb"},
+    {`replace("abc", "x", "y")`, "abc"},
+    {`replace("a b", " ", "")`, "ab"},
+    {`"  Hi ".trim().replace("H", "h")`, "hi"},
+  }
+
+  for _, tt := range tests {
+    testStringObject(t, testEval(tt.input), tt.expected)
+  }
+}
+
+func TestBuiltinFormat(t *testing.T) {
+  tests := []struct {
+    input    string
+    expected string
+  }{
+    {`format("{} + {} = {}", 1, 2, 3)`, "1 + 2 = 3"},
+    {`format("{1}{0}", "a", "b")`, "ba"},
+    {`format("{0}, {0}!", "hey")`, "hey, hey!"},
+    {`format("{{}} {{{}}} }}", 1)`, "{} {1} }"},
+    {`format("no placeholders")`, "no placeholders"},
+    {`format("")`, ""},
+    // extra arguments are ignored
+    {`format("{}", 1, 2)`, "1"},
+    {`format("{} {}", [1, "a"], {"k": true})`, `[1, a] {k: true}`},
+    {`format("{}", 2 ** 64)`, "18446744073709551616"},
+    {`format("é{}变", "ü")`, "éü变"},
+    {`"{}-{}".format("a", 1)`, "a-1"},
+  }
+
+  for _, tt := range tests {
+    testStringObject(t, testEval(tt.input), tt.expected)
+  }
+}
+
+func TestBuiltinSort(t *testing.T) {
+  tests := []struct {
+    input    string
+    expected []int64
+  }{
+    {"sort([3, 1, 2])", []int64{1, 2, 3}},
+    {"sort([])", []int64{}},
+    {"sort([5, -1, 5, 0])", []int64{-1, 0, 5, 5}},
+    {"sort([3, 1, 2], fn(a, b) { b - a })", []int64{3, 2, 1}},
+    // stable, equal keys keep their order: sorted by the tens only
+    {"sort([31, 12, 33, 14], fn(a, b) { a / 10 - b / 10 })", []int64{12, 14, 31, 33}},
+    // the input is left alone
+    {"let a = [3, 1, 2]; sort(a); a", []int64{3, 1, 2}},
+  }
+
+  for _, tt := range tests {
+    testIntegerArray(t, testEval(tt.input), tt.expected)
+  }
+
+  inspected := []struct {
+    input    string
+    expected string
+  }{
+    {`sort(["banana", "apple", "cherry"])`, "[apple, banana, cherry]"},
+    {`sort(["b", "B", "a"])`, "[B, a, b]"},
+    {`sort([2 ** 64, 1, -(2 ** 64)])`, "[-18446744073709551616, 1, 18446744073709551616]"},
+  }
+
+  for _, tt := range inspected {
+    evaluated := testEval(tt.input)
+    if evaluated.Inspect() != tt.expected {
+      t.Errorf("%s wrong. expected=%s, got=%s", tt.input, tt.expected, evaluated.Inspect())
+    }
+  }
+}
+
+func TestBuiltinShadowing(t *testing.T) {
+  testIntegerObject(t, testEval("let type = fn(x) { 1 }; type(true)"), 1)
+}
+
+func testIntegerArray(t *testing.T, obj object.Object, expected []int64) bool {
+  array, ok := obj.(*object.Array)
+  if !ok {
+    t.Errorf("object is not Array. got=%T (%+v)", obj, obj)
+    return false
+  }
+
+  if len(array.Elements) != len(expected) {
+    t.Errorf("wrong num of elements. want=%d, got=%d",
+      len(expected), len(array.Elements))
+    return false
+  }
+
+  for i, expectedElem := range expected {
+    if !testIntegerObject(t, array.Elements[i], expectedElem) {
+      return false
+    }
+  }
+
+  return true
+}
+
+func testStringObject(t *testing.T, obj object.Object, expected string) bool {
+  result, ok := obj.(*object.String)
+  if !ok {
+    t.Errorf("object is not String. got=%T (%+v)", obj, obj)
+    return false
+  }
+
+  if result.Value != expected {
+    t.Errorf("object has wrong value. got=%q, want=%q", result.Value, expected)
+    return false
+  }
+
+  return true
+}
+
+func testErrorObject(t *testing.T, obj object.Object, expected string) bool {
+  errObj, ok := obj.(*object.Error)
+  if !ok {
+    t.Errorf("object is not Error. got=%T (%+v)", obj, obj)
+    return false
+  }
+
+  if errObj.Message != expected {
+    t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+    return false
+  }
+
+  return true
+}
@@ -0,0 +1,1350 @@
+package evaluator
+
+import (
+  "JFFMonkeyLang/src/ast"
+  "JFFMonkeyLang/src/object"
+  "context"
+  "fmt"
+  "math"
+  "math/big"
+  "strings"
+)
+
+// There is only one true, false and null value,
+// so we reuse them instead of allocating new objects
+var (
+  NULL  = &object.Null{}
+  TRUE  = &object.Boolean{Value: true}
+  FALSE = &object.Boolean{Value: false}
+)
+
+// small integers are shared the same way, loop counters, indexes and
+// lengths mostly stay in this range, eg: 1 + 2 and 3 are the same object
+const (
+  minCachedInteger = -128
+  maxCachedInteger = 255
+)
+
+var cachedIntegers = func() []*object.Integer {
+  integers := make([]*object.Integer, maxCachedInteger-minCachedInteger+1)
+  for i := range integers {
+    integers[i] = &object.Integer{Value: int64(i + minCachedInteger)}
+  }
+  return integers
+}()
+
+// integer objects are never modified, so a cached one is safe to hand out
+func newInteger(value int64) *object.Integer {
+  if value >= minCachedInteger && value <= maxCachedInteger {
+    return cachedIntegers[value-minCachedInteger]
+  }
+  return &object.Integer{Value: value}
+}
+
+// same as Eval, but aborts with an error once ctx is done,
+// ctx only applies to this evaluation of env, not to others running alongside
+func EvalWithContext(ctx context.Context, node ast.Node, env *object.Environment) object.Object {
+  settings := env.Settings()
+  defer func(prev context.Context) { settings.Context = prev }(settings.Context)
+  settings.Context = ctx
+
+  return Eval(node, env)
+}
+
+// add n operations to the budget of the evaluation with settings,
+// an error once they run over it
+func charge(settings *object.Settings, n int) *object.Error {
+  budget := settings.Budget
+  if budget == nil {
+    return nil
+  }
+
+  // compared before adding, n may be large enough to overflow Used
+  if budget.Max > 0 && n > budget.Max-budget.Used {
+    budget.Used = budget.Max + 1
+    return newError("operation budget exceeded (%d)", budget.Max)
+  }
+  budget.Used += n
+  return nil
+}
+
+func Eval(node ast.Node, env *object.Environment) object.Object {
+  // every visited node costs one operation
+  if err := charge(env.Settings(), 1); err != nil {
+    return err
+  }
+
+  switch node := node.(type) {
+
+  /* Statements */
+  case *ast.Program:
+    return evalProgram(node, env)
+
+  case *ast.ExpressionStatement:
+    return Eval(node.Expression, env)
+
+  case *ast.BlockStatement:
+    return evalBlockStatement(node, env)
+
+  case *ast.ReturnStatement:
+    val := Eval(node.ReturnValue, env)
+    if isError(val) {
+      return val
+    }
+    return &object.ReturnValue{Value: val}
+
+  case *ast.ThrowStatement:
+    val := Eval(node.Value, env)
+    if isError(val) {
+      return val
+    }
+    // eg: throw "oops" and 1 / 0 look the same to a catch
+    return newError("%s", val.Inspect())
+
+  case *ast.LetStatement:
+    val := Eval(node.Value, env)
+    if isError(val) {
+      return val
+    }
+    nameFunction(val, node.Name.Value)
+    if err := env.Declare(node.Name.Value, val, false); err != nil {
+      return newError("%s", err)
+    }
+
+  case *ast.ConstStatement:
+    val := Eval(node.Value, env)
+    if isError(val) {
+      return val
+    }
+    nameFunction(val, node.Name.Value)
+    if err := env.Declare(node.Name.Value, val, true); err != nil {
+      return newError("%s", err)
+    }
+
+  case *ast.DestructureStatement:
+    val := Eval(node.Value, env)
+    if isError(val) {
+      return val
+    }
+    if err := evalDestructureStatement(node, val, env); err != nil {
+      return err
+    }
+
+  /* Expressions */
+  case *ast.IntegerLiteral:
+    return newInteger(node.Value)
+
+  case *ast.BigIntegerLiteral:
+    return &object.BigInteger{Value: node.Value}
+
+  case *ast.Boolean:
+    return nativeBoolToBooleanObject(node.Value)
+
+  case *ast.StringLiteral:
+    return &object.String{Value: node.Value}
+
+  case *ast.TemplateLiteral:
+    return evalTemplateLiteral(node, env)
+
+  case *ast.PrefixExpression:
+    right := Eval(node.Right, env)
+    if isError(right) {
+      return right
+    }
+    return evalPrefixExpression(node.Operator, right)
+
+  case *ast.InfixExpression:
+    left := Eval(node.Left, env)
+    if isError(left) {
+      return left
+    }
+    // the right side of &&, || and ?? only runs when it decides the result
+    if node.Operator == "&&" || node.Operator == "||" || node.Operator == "??" {
+      return evalLogicalExpression(node.Operator, left, node.Right, env)
+    }
+    right := Eval(node.Right, env)
+    if isError(right) {
+      return right
+    }
+    // a joined string costs its bytes, eg: s = s + s doubles them
+    if size, ok := joinedSize(node.Operator, left, right); ok {
+      if err := charge(env.Settings(), size); err != nil {
+        return err
+      }
+    }
+    return evalInfixExpression(node.Operator, left, right)
+
+  case *ast.IfExpression:
+    return evalIfExpression(node, env)
+
+  case *ast.WhileExpression:
+    return evalWhileExpression(node, env)
+
+  case *ast.DoWhileExpression:
+    return evalDoWhileExpression(node, env)
+
+  case *ast.ForExpression:
+    return evalForExpression(node, env)
+
+  case *ast.ForInExpression:
+    return evalForInExpression(node, env)
+
+  case *ast.Identifier:
+    return evalIdentifier(node, env)
+
+  case *ast.FunctionLiteral:
+    params := node.Parameters
+    body := node.Body
+    return &object.Function{Parameters: params, Defaults: node.Defaults, Rest: node.Rest, Env: env, Body: body}
+
+  case *ast.MacroLiteral:
+    return newError("macros can only be defined by a top level let: %s", node)
+
+  case *ast.CallExpression:
+    // quote(...) keeps its argument unevaluated
+    if node.Function.TokenLiteral() == "quote" {
+      return quote(node, env)
+    }
+
+    function := Eval(node.Function, env)
+    if isError(function) {
+      return function
+    }
+    args := evalExpressions(node.Arguments, env)
+    if len(args) == 1 && isError(args[0]) {
+      return args[0]
+    }
+    if len(node.Keywords) > 0 {
+      return evalKeywordCall(function, args, node, env)
+    }
+    return applyFunction(function, args)
+
+  case *ast.MethodCallExpression:
+    receiver := Eval(node.Receiver, env)
+    if isError(receiver) {
+      return receiver
+    }
+    args := evalExpressions(node.Arguments, env)
+    if len(args) == 1 && isError(args[0]) {
+      return args[0]
+    }
+    return callMethod(receiver, node.Method.Value, args, env.Settings())
+
+  case *ast.PipeExpression:
+    arg := Eval(node.Left, env)
+    if isError(arg) {
+      return arg
+    }
+    function := Eval(node.Function, env)
+    if isError(function) {
+      return function
+    }
+    return applyFunction(function, []object.Object{arg})
+
+  case *ast.ArrayLiteral:
+    elements := evalExpressions(node.Elements, env)
+    if len(elements) == 1 && isError(elements[0]) {
+      return elements[0]
+    }
+    return &object.Array{Elements: elements}
+
+  case *ast.HashLiteral:
+    return evalHashLiteral(node, env)
+
+  case *ast.MatchExpression:
+    return evalMatchExpression(node, env)
+
+  case *ast.TryExpression:
+    return evalTryExpression(node, env)
+
+  case *ast.AssignExpression:
+    return evalAssignExpression(node, env)
+
+  case *ast.IndexExpression:
+    left := Eval(node.Left, env)
+    if isError(left) {
+      return left
+    }
+    // a?.[i] stops at a null a, the index isn't evaluated
+    if node.Optional && left == NULL {
+      return NULL
+    }
+    index := Eval(node.Index, env)
+    if isError(index) {
+      return index
+    }
+    return evalIndexExpression(left, index)
+  }
+
+  return nil
+}
+
+/* eval Statements */
+func evalProgram(program *ast.Program, env *object.Environment) object.Object {
+  var result object.Object
+
+  for _, statement := range program.Statements {
+    result = Eval(statement, env)
+
+    switch result := result.(type) {
+    case *object.ReturnValue:
+      // 1.top level return, unwrap it
+      return result.Value
+    case *object.Error:
+      // 2.stop at the first error
+      return result
+    }
+  }
+
+  return result
+}
+
+func evalBlockStatement(block *ast.BlockStatement, env *object.Environment) object.Object {
+  var result object.Object
+
+  for _, statement := range block.Statements {
+    result = Eval(statement, env)
+
+    // keep the ReturnValue wrapped, so the outer block stops too
+    if result != nil {
+      rt := result.Type()
+      if rt == object.RETURN_VALUE_OBJ || rt == object.ERROR_OBJ {
+        return result
+      }
+    }
+  }
+
+  return result
+}
+
+// Prefix and Infix apply an operator to operands already evaluated,
+// the vm shares them so both backends give the same results and errors
+// eg: Infix("+", 1, 2) => 3, Infix("/", 1, 0) => division by zero
+func Prefix(operator string, right object.Object) object.Object {
+  return evalPrefixExpression(operator, right)
+}
+
+func Infix(operator string, left, right object.Object) object.Object {
+  return evalInfixExpression(operator, left, right)
+}
+
+/* eval Expressions */
+// eg: !true, -5
+func evalPrefixExpression(operator string, right object.Object) object.Object {
+  switch operator {
+  case "!":
+    return evalBangOperatorExpression(right)
+  case "-":
+    return evalMinusPrefixOperatorExpression(right)
+  default:
+    return newError("unknown operator: %s%s", operator, right.Type())
+  }
+}
+
+func evalBangOperatorExpression(right object.Object) object.Object {
+  switch right {
+  case TRUE:
+    return FALSE
+  case FALSE:
+    return TRUE
+  case NULL:
+    return TRUE
+  default:
+    return FALSE
+  }
+}
+
+func evalMinusPrefixOperatorExpression(right object.Object) object.Object {
+  switch right := right.(type) {
+  case *object.Integer:
+    // -MinInt64 doesn't fit
+    if right.Value == math.MinInt64 {
+      return normalizeBigInteger(new(big.Int).Neg(big.NewInt(right.Value)))
+    }
+    return newInteger(-right.Value)
+  case *object.BigInteger:
+    return normalizeBigInteger(new(big.Int).Neg(right.Value))
+  default:
+    return newError("unknown operator: -%s", right.Type())
+  }
+}
+
+// eg: 1 + 2, true == false
+func evalInfixExpression(operator string, left, right object.Object) object.Object {
+  switch {
+  case left.Type() == object.INTEGER_OBJ && right.Type() == object.INTEGER_OBJ:
+    return evalIntegerInfixExpression(operator, left, right)
+  case isInteger(left) && isInteger(right):
+    return evalBigIntegerInfixExpression(operator, left, right)
+  case left.Type() == object.STRING_OBJ && right.Type() == object.STRING_OBJ:
+    return evalStringInfixExpression(operator, left, right)
+  // eg: [1, [2]] == [1, [2]], true == true
+  case operator == "==":
+    return nativeBoolToBooleanObject(objectsEqual(left, right))
+  case operator == "!=":
+    return nativeBoolToBooleanObject(!objectsEqual(left, right))
+  case left.Type() != right.Type():
+    return newError("type mismatch: %s %s %s",
+      left.Type(), operator, right.Type())
+  default:
+    return newError("unknown operator: %s %s %s",
+      left.Type(), operator, right.Type())
+  }
+}
+
+func evalIntegerInfixExpression(operator string, left, right object.Object) object.Object {
+  leftVal := left.(*object.Integer).Value
+  rightVal := right.(*object.Integer).Value
+
+  switch operator {
+  // an overflow carries on as a BigInteger
+  case "+":
+    sum := leftVal + rightVal
+    // both operands differ in sign from the result
+    if (leftVal^sum)&(rightVal^sum) < 0 {
+      return evalBigIntegerInfixExpression(operator, left, right)
+    }
+    return newInteger(sum)
+  case "-":
+    diff := leftVal - rightVal
+    if (leftVal^rightVal)&(leftVal^diff) < 0 {
+      return evalBigIntegerInfixExpression(operator, left, right)
+    }
+    return newInteger(diff)
+  case "*":
+    if !checkedMul(leftVal, rightVal) {
+      return evalBigIntegerInfixExpression(operator, left, right)
+    }
+    return newInteger(leftVal * rightVal)
+  case "**":
+    return evalBigIntegerInfixExpression(operator, left, right)
+  case "/", "%":
+    // go would panic and take the host down with it
+    if rightVal == 0 {
+      return newError("division by zero")
+    }
+    // MinInt64 / -1 is the one division that overflows
+    if leftVal == math.MinInt64 && rightVal == -1 {
+      return evalBigIntegerInfixExpression(operator, left, right)
+    }
+    if operator == "/" {
+      return newInteger(leftVal / rightVal)
+    }
+    return newInteger(leftVal % rightVal)
+  case "<":
+    return nativeBoolToBooleanObject(leftVal < rightVal)
+  case ">":
+    return nativeBoolToBooleanObject(leftVal > rightVal)
+  case "==":
+    return nativeBoolToBooleanObject(leftVal == rightVal)
+  case "!=":
+    return nativeBoolToBooleanObject(leftVal != rightVal)
+  default:
+    return newError("unknown operator: %s %s %s",
+      left.Type(), operator, right.Type())
+  }
+}
+
+// whether a * b fits in an int64
+func checkedMul(a, b int64) bool {
+  if a == 0 || b == 0 {
+    return true
+  }
+  // -1 * MinInt64 wraps back to MinInt64, which the division check misses
+  if (a == -1 && b == math.MinInt64) || (b == -1 && a == math.MinInt64) {
+    return false
+  }
+  return a*b/b == a
+}
+
+// MaxIntegerBits caps the size of a `**` result,
+// so 2 ** 100000000000 is an error instead of eating all memory
+var MaxIntegerBits int64 = 1 << 20
+
+// at least one side is a BigInteger, or the int64 result overflowed,
+// the result is an Integer again whenever it fits
+func evalBigIntegerInfixExpression(operator string, left, right object.Object) object.Object {
+  leftVal := toBigInt(left)
+  rightVal := toBigInt(right)
+
+  switch operator {
+  case "+":
+    return normalizeBigInteger(new(big.Int).Add(leftVal, rightVal))
+  case "-":
+    return normalizeBigInteger(new(big.Int).Sub(leftVal, rightVal))
+  case "*":
+    return normalizeBigInteger(new(big.Int).Mul(leftVal, rightVal))
+  case "/", "%":
+    if rightVal.Sign() == 0 {
+      return newError("division by zero")
+    }
+    // Quo and Rem truncate like int64 / and %
+    if operator == "/" {
+      return normalizeBigInteger(new(big.Int).Quo(leftVal, rightVal))
+    }
+    return normalizeBigInteger(new(big.Int).Rem(leftVal, rightVal))
+  case "**":
+    if rightVal.Sign() < 0 {
+      return newError("negative exponent: %s", rightVal)
+    }
+    // 0, 1 and -1 stay small whatever the exponent
+    if leftVal.CmpAbs(big.NewInt(1)) > 0 {
+      bits := int64(leftVal.BitLen() - 1)
+      if !rightVal.IsInt64() || rightVal.Int64() > MaxIntegerBits/bits {
+        return newError("integer too large: %s ** %s", leftVal, rightVal)
+      }
+    }
+    return normalizeBigInteger(new(big.Int).Exp(leftVal, rightVal, nil))
+  case "<":
+    return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) < 0)
+  case ">":
+    return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) > 0)
+  case "==":
+    return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) == 0)
+  case "!=":
+    return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) != 0)
+  default:
+    return newError("unknown operator: %s %s %s",
+      left.Type(), operator, right.Type())
+  }
+}
+
+// Integer or BigInteger
+func isInteger(obj object.Object) bool {
+  return obj.Type() == object.INTEGER_OBJ || obj.Type() == object.BIG_INTEGER_OBJ
+}
+
+// obj is an Integer or BigInteger, the result may not be modified
+func toBigInt(obj object.Object) *big.Int {
+  if integer, ok := obj.(*object.Integer); ok {
+    return big.NewInt(integer.Value)
+  }
+  return obj.(*object.BigInteger).Value
+}
+
+// values that fit an int64 are always Integers,
+// so both kinds never stand for the same number
+func normalizeBigInteger(value *big.Int) object.Object {
+  if value.IsInt64() {
+    return newInteger(value.Int64())
+  }
+  return &object.BigInteger{Value: value}
+}
+
+// eg: "foo" + "bar", "foo" == "bar"
+// the length of the string operator builds from left and right, if it builds one
+func joinedSize(operator string, left, right object.Object) (int, bool) {
+  leftStr, ok := left.(*object.String)
+  if !ok || operator != "+" {
+    return 0, false
+  }
+  rightStr, ok := right.(*object.String)
+  if !ok {
+    return 0, false
+  }
+  return len(leftStr.Value) + len(rightStr.Value), true
+}
+
+func evalStringInfixExpression(operator string, left, right object.Object) object.Object {
+  leftVal := left.(*object.String).Value
+  rightVal := right.(*object.String).Value
+
+  switch operator {
+  case "+":
+    return &object.String{Value: leftVal + rightVal}
+  case "==":
+    return nativeBoolToBooleanObject(leftVal == rightVal)
+  case "!=":
+    return nativeBoolToBooleanObject(leftVal != rightVal)
+  default:
+    return newError("unknown operator: %s %s %s",
+      left.Type(), operator, right.Type())
+  }
+}
+
+// eg: if (x > y) { x } else { y }
+// eg: if (x) { 1 } else { 2 }
+// without a taken branch, or when the branch leaves no value
+// (eg: `if (true) {}`, `if (true) { let a = 1 }`), it evaluates to NULL
+func evalIfExpression(ie *ast.IfExpression, env *object.Environment) object.Object {
+  condition := Eval(ie.Condition, env)
+  if isError(condition) {
+    return condition
+  }
+
+  // a branch gets its own scope, its lets don't leak out
+  var result object.Object
+  if isTruthy(condition) {
+    result = Eval(ie.Consequence, object.NewEnclosedEnvironment(env))
+  } else if ie.Alternative != nil {
+    result = Eval(ie.Alternative, object.NewEnclosedEnvironment(env))
+  }
+
+  if result == nil {
+    return NULL
+  }
+  return result
+}
+
+// an error in the try block runs the catch block instead, the error's
+// message bound to the catch name; a cancelled evaluation can't be caught
+func evalTryExpression(te *ast.TryExpression, env *object.Environment) object.Object {
+  result := Eval(te.Block, object.NewEnclosedEnvironment(env))
+
+  errObj, ok := result.(*object.Error)
+  if !ok {
+    if result == nil {
+      return NULL
+    }
+    return result
+  }
+  if err := env.Settings().Context.Err(); err != nil {
+    return errObj
+  }
+
+  catchEnv := object.NewEnclosedEnvironment(env)
+  catchEnv.Declare(te.Name.Value, &object.String{Value: errObj.Message}, false)
+  result = Eval(te.Catch, catchEnv)
+  if result == nil {
+    return NULL
+  }
+  return result
+}
+
+// eg: "hello"[1], [1, 2, 3][0]
+func evalIndexExpression(left, index object.Object) object.Object {
+  switch {
+  // a BigInteger never fits an int64, so it is out of range like [1][5]
+  case (left.Type() == object.ARRAY_OBJ || left.Type() == object.STRING_OBJ) && index.Type() == object.BIG_INTEGER_OBJ:
+    return NULL
+  case left.Type() == object.ARRAY_OBJ && index.Type() == object.INTEGER_OBJ:
+    return evalArrayIndexExpression(left, index)
+  case left.Type() == object.STRING_OBJ && index.Type() == object.INTEGER_OBJ:
+    return evalStringIndexExpression(left, index)
+  case left.Type() == object.HASH_OBJ:
+    return evalHashIndexExpression(left, index)
+  default:
+    return newError("index operator not supported: %s", left.Type())
+  }
+}
+
+// out of range indices give null
+func evalArrayIndexExpression(array, index object.Object) object.Object {
+  arrayObject := array.(*object.Array)
+  idx := index.(*object.Integer).Value
+  max := int64(len(arrayObject.Elements) - 1)
+
+  if idx < 0 || idx > max {
+    return NULL
+  }
+
+  return arrayObject.Elements[idx]
+}
+
+// strings are indexed by rune, not byte,
+// so "héllo"[1] is "é", out of range indices give null
+func evalStringIndexExpression(str, index object.Object) object.Object {
+  runes := []rune(str.(*object.String).Value)
+  idx := index.(*object.Integer).Value
+  max := int64(len(runes) - 1)
+
+  if idx < 0 || idx > max {
+    return NULL
+  }
+
+  return &object.String{Value: string(runes[idx])}
+}
+
+// missing keys give null
+func evalHashIndexExpression(hash, index object.Object) object.Object {
+  hashObject := hash.(*object.Hash)
+
+  key, ok := index.(object.Hashable)
+  if !ok {
+    return newError("unusable as hash key: %s", index.Type())
+  }
+
+  pair, ok := hashObject.Get(key.HashKey())
+  if !ok {
+    return NULL
+  }
+
+  return pair.Value
+}
+
+// eg: {"one": 1, "two": 2}
+// pairs are evaluated in source order
+func evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Object {
+  hash := object.NewHash()
+
+  for i, keyNode := range node.Keys {
+    key := Eval(keyNode, env)
+    if isError(key) {
+      return key
+    }
+
+    hashKey, ok := key.(object.Hashable)
+    if !ok {
+      return newError("unusable as hash key: %s", key.Type())
+    }
+
+    value := Eval(node.Values[i], env)
+    if isError(value) {
+      return value
+    }
+
+    hash.Set(hashKey.HashKey(), object.HashPair{Key: key, Value: value})
+  }
+
+  return hash
+}
+
+// eg: while (x < 10) { x = x + 1 }
+// the loop itself evaluates to null
+func evalWhileExpression(we *ast.WhileExpression, env *object.Environment) object.Object {
+  for {
+    if err := env.Settings().Context.Err(); err != nil {
+      return newError("evaluation cancelled: %s", err)
+    }
+
+    condition := Eval(we.Condition, env)
+    if isError(condition) {
+      return condition
+    }
+    if !isTruthy(condition) {
+      return NULL
+    }
+
+    // every iteration gets a fresh scope, a return or an error ends the loop
+    result := Eval(we.Body, object.NewEnclosedEnvironment(env))
+    if result != nil {
+      rt := result.Type()
+      if rt == object.RETURN_VALUE_OBJ || rt == object.ERROR_OBJ {
+        return result
+      }
+    }
+  }
+}
+
+// the init clause gets its own scope, so the loop variable is gone afterwards,
+// every iteration's body gets a fresh one inside it
+func evalForExpression(fe *ast.ForExpression, env *object.Environment) object.Object {
+  loopEnv := object.NewEnclosedEnvironment(env)
+
+  if fe.Init != nil {
+    if init := Eval(fe.Init, loopEnv); isError(init) {
+      return init
+    }
+  }
+
+  for {
+    if err := env.Settings().Context.Err(); err != nil {
+      return newError("evaluation cancelled: %s", err)
+    }
+
+    // a missing condition loops until return
+    if fe.Condition != nil {
+      condition := Eval(fe.Condition, loopEnv)
+      if isError(condition) {
+        return condition
+      }
+      if !isTruthy(condition) {
+        return NULL
+      }
+    }
+
+    result := Eval(fe.Body, object.NewEnclosedEnvironment(loopEnv))
+    if result != nil {
+      rt := result.Type()
+      if rt == object.RETURN_VALUE_OBJ || rt == object.ERROR_OBJ {
+        return result
+      }
+    }
+
+    if fe.Post != nil {
+      if post := Eval(fe.Post, loopEnv); isError(post) {
+        return post
+      }
+    }
+  }
+}
+
+// arrays give (element) or (index, element), hashes give (key) or (key, value),
+// every iteration binds the variables in a fresh scope
+func evalForInExpression(fe *ast.ForInExpression, env *object.Environment) object.Object {
+  iterable := Eval(fe.Iterable, env)
+  if isError(iterable) {
+    return iterable
+  }
+
+  // 1.collect the bindings up front, so changing the collection
+  // in the body doesn't change the iterations
+  var keys, values []object.Object
+  switch iterable := iterable.(type) {
+  case *object.Array:
+    for i, element := range iterable.Elements {
+      keys = append(keys, newInteger(int64(i)))
+      values = append(values, element)
+    }
+    if fe.Value == nil {
+      keys = values
+    }
+  case *object.Hash:
+    for _, pair := range iterable.OrderedPairs() {
+      keys = append(keys, pair.Key)
+      values = append(values, pair.Value)
+    }
+  default:
+    return newError("for-in needs an ARRAY or HASH, got %s", iterable.Type())
+  }
+
+  // 2.run the body once per binding
+  for i := range keys {
+    if err := env.Settings().Context.Err(); err != nil {
+      return newError("evaluation cancelled: %s", err)
+    }
+
+    iterEnv := object.NewEnclosedEnvironment(env)
+    iterEnv.Declare(fe.Key.Value, keys[i], false)
+    if fe.Value != nil {
+      iterEnv.Declare(fe.Value.Value, values[i], false)
+    }
+
+    result := Eval(fe.Body, iterEnv)
+    if result != nil {
+      rt := result.Type()
+      if rt == object.RETURN_VALUE_OBJ || rt == object.ERROR_OBJ {
+        return result
+      }
+    }
+  }
+
+  return NULL
+}
+
+// eg: "1 + 1 = ${1 + 1}" is "1 + 1 = 2", values are written as Inspect shows them
+func evalTemplateLiteral(tl *ast.TemplateLiteral, env *object.Environment) object.Object {
+  var out strings.Builder
+
+  for i, exp := range tl.Expressions {
+    out.WriteString(tl.Strings[i])
+
+    value := Eval(exp, env)
+    if isError(value) {
+      return value
+    }
+    inspected := value.Inspect()
+    if err := charge(env.Settings(), len(inspected)); err != nil {
+      return err
+    }
+    out.WriteString(inspected)
+  }
+  out.WriteString(tl.Strings[len(tl.Strings)-1])
+
+  return &object.String{Value: out.String()}
+}
+
+// the first arm whose pattern equals the subject, like `==` does, gives the result,
+// `_` matches anything; no matching arm gives NULL, like an if without else
+func evalMatchExpression(me *ast.MatchExpression, env *object.Environment) object.Object {
+  subject := Eval(me.Subject, env)
+  if isError(subject) {
+    return subject
+  }
+
+  for i, pattern := range me.Patterns {
+    if ident, ok := pattern.(*ast.Identifier); ok && ident.Value == "_" {
+      return Eval(me.Results[i], env)
+    }
+
+    value := Eval(pattern, env)
+    if isError(value) {
+      return value
+    }
+    // different types just don't match
+    if evalInfixExpression("==", subject, value) == TRUE {
+      return Eval(me.Results[i], env)
+    }
+  }
+
+  return NULL
+}
+
+// eg: let [a, ...rest] = [1, 2, 3], a is 1 and rest is [2, 3];
+// an array needs exactly one element per name, or at least one per name
+// before the rest; a hash gives null for a missing key, like indexing
+func evalDestructureStatement(ds *ast.DestructureStatement, val object.Object, env *object.Environment) *object.Error {
+  values := make([]object.Object, len(ds.Names))
+
+  switch {
+  case ds.Hash:
+    hash, ok := val.(*object.Hash)
+    if !ok {
+      return newError("cannot destructure %s as HASH", val.Type())
+    }
+    for i, name := range ds.Names {
+      values[i] = NULL
+      if pair, ok := hash.Get((&object.String{Value: name.Value}).HashKey()); ok {
+        values[i] = pair.Value
+      }
+    }
+
+  default:
+    array, ok := val.(*object.Array)
+    if !ok {
+      return newError("cannot destructure %s as ARRAY", val.Type())
+    }
+    elements := array.Elements
+
+    // 1.the rest name takes a copy of whatever the others leave
+    if ds.Rest {
+      fixed := len(ds.Names) - 1
+      if len(elements) < fixed {
+        return newError("wrong number of values to destructure: want at least %d, got=%d", fixed, len(elements))
+      }
+      rest := make([]object.Object, len(elements)-fixed)
+      copy(rest, elements[fixed:])
+      values[fixed] = &object.Array{Elements: rest}
+      elements = elements[:fixed]
+    } else if len(elements) != len(ds.Names) {
+      return newError("wrong number of values to destructure: want=%d, got=%d", len(ds.Names), len(elements))
+    }
+
+    // 2.one element per name
+    copy(values, elements)
+  }
+
+  for i, name := range ds.Names {
+    nameFunction(values[i], name.Value)
+    if err := env.Declare(name.Value, values[i], false); err != nil {
+      return newError("%s", err)
+    }
+  }
+  return nil
+}
+
+// like while, but the condition is checked after each run of the body
+func evalDoWhileExpression(de *ast.DoWhileExpression, env *object.Environment) object.Object {
+  for {
+    if err := env.Settings().Context.Err(); err != nil {
+      return newError("evaluation cancelled: %s", err)
+    }
+
+    result := Eval(de.Body, object.NewEnclosedEnvironment(env))
+    if result != nil {
+      rt := result.Type()
+      if rt == object.RETURN_VALUE_OBJ || rt == object.ERROR_OBJ {
+        return result
+      }
+    }
+
+    condition := Eval(de.Condition, env)
+    if isError(condition) {
+      return condition
+    }
+    if !isTruthy(condition) {
+      return NULL
+    }
+  }
+}
+
+// eg: x = 1, h["a"] = 1, arr[0] = 1
+// mutates the binding, array or hash in place, gives back the value
+func evalAssignExpression(node *ast.AssignExpression, env *object.Environment) object.Object {
+  // eg: x = 1, the name must already be bound
+  if ident, ok := node.Target.(*ast.Identifier); ok {
+    value := Eval(node.Value, env)
+    if isError(value) {
+      return value
+    }
+    if err := env.Assign(ident.Value, value); err != nil {
+      return newError("%s", err)
+    }
+    return value
+  }
+
+  target := node.Target.(*ast.IndexExpression)
+
+  left := Eval(target.Left, env)
+  if isError(left) {
+    return left
+  }
+  index := Eval(target.Index, env)
+  if isError(index) {
+    return index
+  }
+  value := Eval(node.Value, env)
+  if isError(value) {
+    return value
+  }
+
+  switch left := left.(type) {
+  case *object.Array:
+    idx, ok := index.(*object.Integer)
+    if !ok {
+      return newError("array index must be INTEGER, got %s", index.Type())
+    }
+    if idx.Value < 0 || idx.Value >= int64(len(left.Elements)) {
+      return newError("index out of range: %d", idx.Value)
+    }
+    left.Elements[idx.Value] = value
+
+  case *object.Hash:
+    key, ok := index.(object.Hashable)
+    if !ok {
+      return newError("unusable as hash key: %s", index.Type())
+    }
+    left.Set(key.HashKey(), object.HashPair{Key: index, Value: value})
+
+  default:
+    return newError("index assignment not supported: %s", left.Type())
+  }
+
+  return value
+}
+
+func evalIdentifier(node *ast.Identifier, env *object.Environment) object.Object {
+  // 1.user bindings shadow builtins
+  if val, ok := env.Get(node.Value); ok {
+    return val
+  }
+
+  // 2.builtin functions, eg: type
+  if builtin, ok := lookupBuiltin(node.Value, env.Settings()); ok {
+    if env.Settings().Sandbox && unsafeBuiltins[node.Value] {
+      return newError("builtin disabled in sandbox: %s", node.Value)
+    }
+    return builtin
+  }
+
+  return newError("identifier not found: " + node.Value)
+}
+
+// evaluate from left to right, stop at the first error
+func evalExpressions(exps []ast.Expression, env *object.Environment) []object.Object {
+  result := make([]object.Object, 0, len(exps))
+
+  for _, e := range exps {
+    evaluated := Eval(e, env)
+    if isError(evaluated) {
+      return []object.Object{evaluated}
+    }
+    result = append(result, evaluated)
+  }
+
+  return result
+}
+
+// eg: greet("Hi", name = "Sam")
+// keywords fill the parameter slots by name, the slots left empty take their default
+func evalKeywordCall(fn object.Object, args []object.Object, node *ast.CallExpression, env *object.Environment) object.Object {
+  if _, ok := fn.(*object.Builtin); ok {
+    return newError("keyword arguments not supported: %s", fn.Type())
+  }
+  function, ok := fn.(*object.Function)
+  if !ok {
+    return newError("not a function: %s", fn.Type())
+  }
+
+  params := len(function.Parameters)
+  if function.Rest {
+    params--
+  }
+  if len(args) > params && !function.Rest {
+    return newError("wrong number of arguments: want at most %d, got=%d", params, len(args))
+  }
+
+  // 1.positional arguments first, nil marks an empty slot
+  slots := make([]object.Object, params)
+  copy(slots, args)
+
+  // 2.then every keyword goes to the parameter of the same name
+  for i, keyword := range node.Keywords {
+    paramIdx := -1
+    for idx := 0; idx < params; idx++ {
+      if function.Parameters[idx].Value == keyword.Value {
+        paramIdx = idx
+      }
+    }
+    if paramIdx == -1 {
+      return newError("unknown keyword argument: %s", keyword.Value)
+    }
+    if slots[paramIdx] != nil {
+      return newError("multiple values for argument: %s", keyword.Value)
+    }
+
+    value := Eval(node.KeywordValues[i], env)
+    if isError(value) {
+      return value
+    }
+    slots[paramIdx] = value
+  }
+
+  // 3.an empty slot needs a default
+  for paramIdx, slot := range slots {
+    if slot == nil && defaultValue(function, paramIdx) == nil {
+      return newError("missing argument: %s", function.Parameters[paramIdx].Value)
+    }
+  }
+
+  // 4.extra positional arguments go to the rest parameter
+  if len(args) > params {
+    slots = append(slots, args[params:]...)
+  }
+
+  return callFunction(function, slots)
+}
+
+// eg: add(1, 2)
+func applyFunction(fn object.Object, args []object.Object) object.Object {
+  if builtin, ok := fn.(*object.Builtin); ok {
+    return builtin.Fn(args...)
+  }
+
+  function, ok := fn.(*object.Function)
+  if !ok {
+    return newError("not a function: %s", fn.Type())
+  }
+
+  if err := checkArgumentCount(function, len(args)); err != nil {
+    return err
+  }
+
+  return callFunction(function, args)
+}
+
+// eg: let f = fn() {}, the function is f from now on;
+// one that already has a name keeps it, so `let g = f` is still f
+func nameFunction(val object.Object, name string) {
+  if function, ok := val.(*object.Function); ok && function.Name == "" {
+    function.Name = name
+  }
+}
+
+// args are already checked against the parameters
+func callFunction(function *object.Function, args []object.Object) (result object.Object) {
+  settings := function.Env.Settings()
+  if err := settings.Context.Err(); err != nil {
+    return newError("evaluation cancelled: %s", err)
+  }
+
+  if settings.CallDepth >= settings.MaxCallDepth {
+    return newError("maximum call depth exceeded (%d)", settings.MaxCallDepth)
+  }
+  settings.CallDepth++
+  defer func() { settings.CallDepth-- }()
+
+  // an error passing through remembers this function, eg: in function f,
+  // a call refused above never ran, so it is not part of the trace
+  defer func() {
+    if errObj, ok := result.(*object.Error); ok {
+      errObj.Trace = append(errObj.Trace, function.DisplayName())
+    }
+  }()
+
+  // 1.bind arguments in a new scope enclosed by the closure env
+  extendedEnv, err := extendFunctionEnv(function, args)
+  if err != nil {
+    return err
+  }
+  // 2.eval function body
+  evaluated := Eval(function.Body, extendedEnv)
+  // 3.in strict mode an implicit NULL means a missing return
+  if settings.Strict && (evaluated == nil || evaluated == NULL) && len(function.Body.Statements) > 0 {
+    return newError("missing return in fn(%s)",
+      ast.ParametersString(function.Parameters, function.Defaults, function.Rest))
+  }
+  // 4.a return only ends the current function
+  return unwrapReturnValue(evaluated)
+}
+
+// every required parameter needs an argument, defaulted ones may be left out,
+// and only a rest parameter takes any number of extra arguments
+func checkArgumentCount(fn *object.Function, got int) *object.Error {
+  params := len(fn.Parameters)
+  if fn.Rest {
+    params--
+  }
+
+  required := 0
+  for paramIdx := 0; paramIdx < params; paramIdx++ {
+    if defaultValue(fn, paramIdx) == nil {
+      required++
+    }
+  }
+
+  switch {
+  case required == params && !fn.Rest && got != params:
+    return newError("wrong number of arguments: want=%d, got=%d", params, got)
+  case got < required:
+    return newError("wrong number of arguments: want at least %d, got=%d", required, got)
+  case got > params && !fn.Rest:
+    return newError("wrong number of arguments: want at most %d, got=%d", params, got)
+  }
+
+  return nil
+}
+
+func extendFunctionEnv(fn *object.Function, args []object.Object) (*object.Environment, *object.Error) {
+  env := object.NewEnclosedEnvironment(fn.Env)
+
+  for paramIdx, param := range fn.Parameters {
+    // eg: fn(a, ...rest)(1, 2, 3), rest is [2, 3]
+    if fn.Rest && paramIdx == len(fn.Parameters)-1 {
+      rest := []object.Object{}
+      if paramIdx < len(args) {
+        rest = make([]object.Object, len(args)-paramIdx)
+        copy(rest, args[paramIdx:])
+      }
+      env.Set(param.Value, &object.Array{Elements: rest})
+      break
+    }
+
+    // a nil arg is a slot left empty by a keyword call
+    if paramIdx < len(args) && args[paramIdx] != nil {
+      env.Set(param.Value, args[paramIdx])
+      continue
+    }
+
+    // eg: fn(x, y = x * 2)(1), the default sees the earlier parameters
+    value := Eval(defaultValue(fn, paramIdx), env)
+    if errObj, ok := value.(*object.Error); ok {
+      return nil, errObj
+    }
+    env.Set(param.Value, value)
+  }
+
+  return env, nil
+}
+
+// nil if the parameter has no default
+func defaultValue(fn *object.Function, paramIdx int) ast.Expression {
+  if paramIdx < len(fn.Defaults) {
+    return fn.Defaults[paramIdx]
+  }
+  return nil
+}
+
+func unwrapReturnValue(obj object.Object) object.Object {
+  if returnValue, ok := obj.(*object.ReturnValue); ok {
+    return returnValue.Value
+  }
+
+  return obj
+}
+
+/* eval utils */
+func nativeBoolToBooleanObject(input bool) *object.Boolean {
+  if input {
+    return TRUE
+  }
+  return FALSE
+}
+
+// only false and null are falsy, like in the book,
+// 0, "", [] and {} are all truthy
+// eg: false && f() is false, 0 || "none" is 0, null || "none" is "none";
+// like the if condition, the operand that decides is returned as is.
+// ?? only looks at null: false ?? true is false
+func evalLogicalExpression(operator string, left object.Object, right ast.Expression, env *object.Environment) object.Object {
+  switch {
+  case operator == "??" && left != NULL:
+    return left
+  case operator != "??" && isTruthy(left) == (operator == "||"):
+    return left
+  }
+  return Eval(right, env)
+}
+
+func isTruthy(obj object.Object) bool {
+  switch obj {
+  case NULL:
+    return false
+  case TRUE:
+    return true
+  case FALSE:
+    return false
+  default:
+    return true
+  }
+}
+
+// integers and strings compare by value, arrays and hashes by their contents,
+// everything else (booleans and null are singletons, functions) by identity
+func objectsEqual(a, b object.Object) bool {
+  return equalObjects(a, b, map[[2]object.Object]bool{})
+}
+
+// comparing is a pair of arrays or hashes already being compared further up,
+// they are taken as equal there so cyclic structures still terminate
+func equalObjects(a, b object.Object, comparing map[[2]object.Object]bool) bool {
+  if a == b {
+    return true
+  }
+
+  switch a := a.(type) {
+  case *object.Integer:
+    other, ok := b.(*object.Integer)
+    return ok && a.Value == other.Value
+  case *object.BigInteger:
+    other, ok := b.(*object.BigInteger)
+    return ok && a.Value.Cmp(other.Value) == 0
+  case *object.String:
+    other, ok := b.(*object.String)
+    return ok && a.Value == other.Value
+
+  case *object.Array:
+    other, ok := b.(*object.Array)
+    if !ok || len(a.Elements) != len(other.Elements) {
+      return false
+    }
+    pair := [2]object.Object{a, other}
+    if comparing[pair] {
+      return true
+    }
+    comparing[pair] = true
+    defer delete(comparing, pair)
+
+    for i, el := range a.Elements {
+      if !equalObjects(el, other.Elements[i], comparing) {
+        return false
+      }
+    }
+    return true
+
+  case *object.Hash:
+    other, ok := b.(*object.Hash)
+    if !ok || len(a.Pairs) != len(other.Pairs) {
+      return false
+    }
+    pair := [2]object.Object{a, other}
+    if comparing[pair] {
+      return true
+    }
+    comparing[pair] = true
+    defer delete(comparing, pair)
+
+    // the order keys were added in doesn't matter
+    for key, p := range a.Pairs {
+      otherPair, ok := other.Pairs[key]
+      if !ok || !equalObjects(p.Value, otherPair.Value, comparing) {
+        return false
+      }
+    }
+    return true
+
+  default:
+    return false
+  }
+}
+
+func newError(format string, a ...interface{}) *object.Error {
+  return &object.Error{Message: fmt.Sprintf(format, a...)}
+}
+
+func isError(obj object.Object) bool {
+  if obj != nil {
+    return obj.Type() == object.ERROR_OBJ
+  }
+  return false
+}
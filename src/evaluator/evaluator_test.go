@@ -0,0 +1,1747 @@
+package evaluator
+
+import (
+  "JFFMonkeyLang/src/lexer"
+  "JFFMonkeyLang/src/object"
+  "JFFMonkeyLang/src/parser"
+  "context"
+  "fmt"
+  "strings"
+  "testing"
+  "time"
+)
+
+func TestEvalIntegerExpression(t *testing.T) {
+  tests := []struct {
+    input    string
+    expected int64
+  }{
+    {"5", 5},
+    {"10", 10},
+    {"-5", -5},
+    {"-10", -10},
+    {"5 + 5 + 5 + 5 - 10", 10},
+    {"2 * 2 * 2 * 2 * 2", 32},
+    {"-50 + 100 + -50", 0},
+    {"5 * 2 + 10", 20},
+    {"5 + 2 * 10", 25},
+    {"50 / 2 * 2 + 10", 60},
+    {"2 * (5 + 10)", 30},
+    {"3 * (3 * 3) + 10", 37},
+    {"(5 + 10 * 2 + 15 / 3) * 2 + -10", 50},
+    {"1_000_000", 1000000},
+    {"0xFF", 255},
+    {"0o17", 15},
+    {"0b1010", 10},
+    {"0xff + 0b1", 256},
+    {"7 % 3", 1},
+    {"-7 % 3", -1},
+    {"2 * 7 % 4", 2},
+    // right at the edges of int64
+    {"9223372036854775806 + 1", 9223372036854775807},
+    {"-9223372036854775807 - 1", -9223372036854775808},
+    {"3037000499 * 3037000499", 9223372030926249001},
+    {"-4611686018427387904 * 2", -9223372036854775808},
+  }
+
+  for _, tt := range tests {
+    evaluated := testEval(tt.input)
+    testIntegerObject(t, evaluated, tt.expected)
+  }
+}
+
+func TestBigIntegers(t *testing.T) {
+  tests := []struct {
+    input    string
+    expected string
+  }{
+    {"100 ** 100", "1" + strings.Repeat("0", 200)},
+    {"9223372036854775808", "9223372036854775808"},
+    {"0x1_0000_0000_0000_0000", "18446744073709551616"},
+    // int64 overflow carries on as a big integer
+    {"9223372036854775807 + 1", "9223372036854775808"},
+    {"-9223372036854775807 - 2", "-9223372036854775809"},
+    {"3037000500 * 3037000500", "9223372037000250000"},
+    {"let min = -9223372036854775807 - 1; min * -1", "9223372036854775808"},
+    {"let min = -9223372036854775807 - 1; min / -1", "9223372036854775808"},
+    {"let min = -9223372036854775807 - 1; -min", "9223372036854775808"},
+    {"-(2 ** 64)", "-18446744073709551616"},
+    {"2 ** 3 ** 4", "2417851639229258349412352"},
+    {"(2 ** 70) % 1000 + 2 ** 70", "1180591620717411303848"},
+  }
+
+  for _, tt := range tests {
+    evaluated := testEval(tt.input)
+    result, ok := evaluated.(*object.BigInteger)
+    if !ok {
+      t.Errorf("%q: object is not BigInteger. got=%T (%+v)", tt.input, evaluated, evaluated)
+      continue
+    }
+    if result.Inspect() != tt.expected {
+      t.Errorf("%q: wrong value. expected=%s, got=%s", tt.input, tt.expected, result.Inspect())
+    }
+  }
+
+  // results that fit an int64 are plain integers again
+  integers := []struct {
+    input    string
+    expected int64
+  }{
+    {"2 ** 62", 4611686018427387904},
+    {"(2 ** 64) / (2 ** 60)", 16},
+    {"9223372036854775808 - 1", 9223372036854775807},
+    {"-9223372036854775808", -9223372036854775808},
+    {"(10 ** 30) % 7", 1},
+    {"(-1) ** 100000000000", 1},
+  }
+  for _, tt := range integers {
+    testIntegerObject(t, testEval(tt.input), tt.expected)
+  }
+
+  comparisons := []struct {
+    input    string
+    expected bool
+  }{
+    {"2 ** 64 > 1", true},
+    {"1 < 2 ** 64", true},
+    {"2 ** 64 == 2 ** 64", true},
+    {"2 ** 64 != 2 ** 65", true},
+    {"-(2 ** 64) > 0", false},
+    {"2 ** 64 == 18446744073709551616", true},
+  }
+  for _, tt := range comparisons {
+    testBooleanObject(t, testEval(tt.input), tt.expected)
+  }
+
+  // equal values give equal hash keys
+  testIntegerObject(t, testEval("let h = {2 ** 64: 1}; h[18446744073709551616]"), 1)
+}
+
+func TestEvalBooleanExpression(t *testing.T) {
+  tests := []struct {
+    input    string
+    expected bool
+  }{
+    {"true", true},
+    {"false", false},
+    {"1 < 2", true},
+    {"1 > 2", false},
+    {"1 == 1", true},
+    {"1 != 1", false},
+    {"true == true", true},
+    {"true != false", true},
+    {"(1 < 2) == true", true},
+    {"(1 > 2) == true", false},
+  }
+
+  for _, tt := range tests {
+    evaluated := testEval(tt.input)
+    testBooleanObject(t, evaluated, tt.expected)
+  }
+}
+
+func TestCollectionEquality(t *testing.T) {
+  tests := []struct {
+    input    string
+    expected bool
+  }{
+    {"[1, 2] == [1, 2]", true},
+    {"[1, 2] != [1, 2]", false},
+    {"[1, 2] == [2, 1]", false},
+    {"[1, 2] == [1, 2, 3]", false},
+    {"[] == []", true},
+    {`[1, "a", true, [2, [3]]] == [1, "a", true, [2, [3]]]`, true},
+    {"[1, [2, [3]]] == [1, [2, [4]]]", false},
+    {"[2 ** 64] == [2 ** 64]", true},
+    {`{"a": 1, "b": [2]} == {"b": [2], "a": 1}`, true},
+    {`{"a": 1} == {"a": 2}`, false},
+    {`{"a": 1} == {"b": 1}`, false},
+    {`{"a": 1} == {"a": 1, "b": 2}`, false},
+    {`{"a": {"b": [1, {"c": 2}]}} == {"a": {"b": [1, {"c": 2}]}}`, true},
+    {`{"a": {"b": [1, {"c": 2}]}} != {"a": {"b": [1, {"c": 3}]}}`, true},
+    // different types are never equal
+    {"[1] == 1", false},
+    {`[] == {}`, false},
+    {`{1: 1} == [1]`, false},
+    // functions compare by identity
+    {"let f = fn(x) { x }; [f] == [f]", true},
+    {"[fn(x) { x }] == [fn(x) { x }]", false},
+    // cyclic structures still terminate
+    {"let a = [1]; a[0] = a; let b = [1]; b[0] = b; a == b", true},
+    {"let a = [1, 2]; a[0] = a; let b = [1, 3]; b[0] = b; a == b", false},
+    {`let h = {}; h["self"] = h; h == h`, true},
+  }
+
+  for _, tt := range tests {
+    evaluated := testEval(tt.input)
+    if !testBooleanObject(t, evaluated, tt.expected) {
+      t.Errorf("for %s", tt.input)
+    }
+  }
+
+  // contains and match use the same equality
+  testBooleanObject(t, testEval("contains([[1, 2], [3]], [3])"), true)
+  testIntegerObject(t, testEval("match [1, [2]] { [1, [3]] => 1, [1, [2]] => 2, _ => 3 }"), 2)
+}
+
+func TestLogicalOperators(t *testing.T) {
+  tests := []struct {
+    input    string
+    expected interface{}
+  }{
+    {"true && true", true},
+    {"true && false", false},
+    {"false || true", true},
+    {"false || false", false},
+    {"1 < 2 && 2 < 3 || false", true},
+    // the operand that decides is the result
+    {"1 && 2", 2},
+    {"0 || 2", 0},
+    {"if (false) { 1 } || 2", 2},
+    {"if (false) { 1 } && 2", nil},
+    {`"" && "yes"`, "yes"},
+    // errors on the taken side still surface
+    {"true && -true", "unknown operator: -BOOLEAN"},
+    {"-true || true", "unknown operator: -BOOLEAN"},
+    {"false && -true", false},
+    {"true || -true", true},
+  }
+
+  for _, tt := range tests {
+    evaluated := testEval(tt.input)
+    switch expected := tt.expected.(type) {
+    case bool:
+      testBooleanObject(t, evaluated, expected)
+    case int:
+      testIntegerObject(t, evaluated, int64(expected))
+    case string:
+      if str, ok := evaluated.(*object.String); ok {
+        if str.Value != expected {
+          t.Errorf("%q: wrong string. expected=%q, got=%q", tt.input, expected, str.Value)
+        }
+        continue
+      }
+      testErrorObject(t, evaluated, expected)
+    default:
+      testNullObject(t, evaluated)
+    }
+  }
+}
+
+func TestNullishCoalescing(t *testing.T) {
+  tests := []struct {
+    input    string
+    expected interface{}
+  }{
+    {"let none = if (false) { 1 }; none ?? 5", 5},
+    {"3 ?? 5", 3},
+    // only null is replaced
+    {"false ?? true", false},
+    {"0 ?? 5", 0},
+    {`"" ?? "x"`, ""},
+    {`let h = {"a": 1}; h["b"] ?? h["a"] ?? 0`, 1},
+    {`let h = {}; h?.a ?? "none"`, "none"},
+    {"-true ?? 1", "unknown operator: -BOOLEAN"},
+  }
+
+  for _, tt := range tests {
+    evaluated := testEval(tt.input)
+    switch expected := tt.expected.(type) {
+    case bool:
+      testBooleanObject(t, evaluated, expected)
+    case int:
+      testIntegerObject(t, evaluated, int64(expected))
+    case string:
+      if str, ok := evaluated.(*object.String); ok {
+        if str.Value != expected {
+          t.Errorf("%q: wrong string. expected=%q, got=%q", tt.input, expected, str.Value)
+        }
+        continue
+      }
+      testErrorObject(t, evaluated, expected)
+    }
+  }
+}
+
+func TestLogicalShortCircuit(t *testing.T) {
+  tests := []struct {
+    input    string
+    expected []string
+  }{
+    {`false && puts("right")`, []string{}},
+    {`true || puts("right")`, []string{}},
+    {`true && puts("right")`, []string{"right"}},
+    {`false || puts("right")`, []string{"right"}},
+    {`puts("a") || puts("b") && puts("c")`, []string{"a", "b"}},
+    {`false && puts("a") || puts("b")`, []string{"b"}},
+    {`0 ?? puts("right")`, []string{}},
+    {`false ?? puts("right")`, []string{}},
+    {`puts("a") ?? puts("b")`, []string{"a", "b"}},
+  }
+
+  for _, tt := range tests {
+    // puts records its argument and gives null, which is falsy
+    printed := []string{}
+    env := object.NewEnvironment()
+    env.Set("puts", &object.Builtin{Fn: func(args ...object.Object) object.Object {
+      printed = append(printed, args[0].Inspect())
+      return NULL
+    }})
+
+    Eval(parser.New(lexer.New(tt.input)).ParseProgram(), env)
+
+    if strings.Join(printed, " ") != strings.Join(tt.expected, " ") {
+      t.Errorf("%s printed wrong. want=%v, got=%v", tt.input, tt.expected, printed)
+    }
+  }
+}
+
+func TestMethodCallExpression(t *testing.T) {
+  tests := []struct {
+    input    string
+    expected interface{}
+  }{
+    {"[1, 2, 3].len()", 3},
+    {`"héllo".len()`, 5},
+    {`{"a": 1}.len()`, 1},
+    {"[1, 2].push(3).len()", 3},
+    {"let a = [1]; a.push(2).push(3).first() + a.len()", 2},
+    {`"Abc".upper().lower()`, "abc"},
+    {`let s = "x"; s.upper() + s`, "Xx"},
+    {"[[5]].first().first()", 5},
+    {"[].first()", nil},
+    {`"abc".push(1)`, "unknown method: STRING.push"},
+    {"5.len()", "unknown method: INTEGER.len"},
+    {"[1].upper()", "unknown method: ARRAY.upper"},
+    {"[1].push()", "wrong number of arguments: want=2, got=1"},
+    {"[1].push(-true)", "unknown operator: -BOOLEAN"},
+  }
+
+  for _, tt := range tests {
+    evaluated := testEval(tt.input)
+    switch expected := tt.expected.(type) {
+    case int:
+      testIntegerObject(t, evaluated, int64(expected))
+    case string:
+      if str, ok := evaluated.(*object.String); ok {
+        if str.Value != expected {
+          t.Errorf("%q: wrong string. expected=%q, got=%q", tt.input, expected, str.Value)
+        }
+        continue
+      }
+      testErrorObject(t, evaluated, expected)
+    default:
+      testNullObject(t, evaluated)
+    }
+  }
+}
+
+func TestPipeExpression(t *testing.T) {
+  tests := []struct {
+    input    string
+    expected interface{}
+  }{
+    {"5 |> fn(x) { x + 1 } |> fn(x) { x * 2 }", 12},
+    {"let double = fn(x) { x * 2 }; let inc = fn(x) { x + 1 }; 5 |> double |> inc", 11},
+    {"[3, 1, 2] |> reverse |> len", 3},
+    {"let add = fn(a, b = 10) { a + b }; 1 |> add", 11},
+    // the right side is a value, called with one argument
+    {"let adder = fn(n) { fn(x) { x + n } }; 1 |> adder(2)", 3},
+    {"5 |> 1", "not a function: INTEGER"},
+    {"-true |> fn(x) { x }", "unknown operator: -BOOLEAN"},
+    {"let add = fn(a, b) { a + b }; 1 |> add", "wrong number of arguments: want=2, got=1"},
+  }
+
+  for _, tt := range tests {
+    evaluated := testEval(tt.input)
+    switch expected := tt.expected.(type) {
+    case int:
+      testIntegerObject(t, evaluated, int64(expected))
+    case string:
+      testErrorObject(t, evaluated, expected)
+    }
+  }
+}
+
+func TestBangOperator(t *testing.T) {
+  tests := []struct {
+    input    string
+    expected bool
+  }{
+    {"!true", false},
+    {"!false", true},
+    {"!5", false},
+    {"!!true", true},
+    {"!!false", false},
+    {"!!5", true},
+  }
+
+  for _, tt := range tests {
+    evaluated := testEval(tt.input)
+    testBooleanObject(t, evaluated, tt.expected)
+  }
+}
+
+func TestIfElseExpressions(t *testing.T) {
+  tests := []struct {
+    input    string
+    expected interface{}
+  }{
+    {"if (true) { 10 }", 10},
+    {"if (false) { 10 }", nil},
+    {"if (1) { 10 }", 10},
+    {"if (1 < 2) { 10 }", 10},
+    {"if (1 > 2) { 10 }", nil},
+    {"if (1 > 2) { 10 } else { 20 }", 20},
+    {"if (1 < 2) { 10 } else { 20 }", 10},
+    {"if (true) {}", nil},
+    {"if (false) {} else {}", nil},
+    {"if (true) { let a = 1 }", nil},
+  }
+
+  for _, tt := range tests {
+    evaluated := testEval(tt.input)
+    integer, ok := tt.expected.(int)
+    if ok {
+      testIntegerObject(t, evaluated, int64(integer))
+    } else {
+      testNullObject(t, evaluated)
+    }
+  }
+}
+
+func TestTruthiness(t *testing.T) {
+  tests := []struct {
+    input    string
+    expected bool
+  }{
+    {"true", true},
+    {"false", false},
+    {"if (false) { 1 }", false},
+    {"0", true},
+    {"-1", true},
+    {`""`, true},
+    {"[]", true},
+    {"{}", true},
+    {"fn() {}", true},
+  }
+
+  for _, tt := range tests {
+    input := "if (" + tt.input + ") { true } else { false }"
+    testBooleanObject(t, testEval(input), tt.expected)
+
+    // `!` agrees with `if`
+    testBooleanObject(t, testEval("!!("+tt.input+")"), tt.expected)
+  }
+
+  // the null result is the NULL singleton, so it can be bound and compared
+  testBooleanObject(t, testEval("let a = if (false) { 1 }; let b = if (true) {}; a == b"), true)
+}
+
+func TestReturnStatements(t *testing.T) {
+  tests := []struct {
+    input    string
+    expected int64
+  }{
+    {"return 10;", 10},
+    {"return 10; 9;", 10},
+    {"return 2 * 5; 9;", 10},
+    {"9; return 2 * 5; 9;", 10},
+    {`
+if (10 > 1) {
+  if (10 > 1) {
+    return 10;
+  }
+
+  return 1;
+}
+`, 10},
+  }
+
+  for _, tt := range tests {
+    evaluated := testEval(tt.input)
+    testIntegerObject(t, evaluated, tt.expected)
+  }
+}
+
+func TestErrorHandling(t *testing.T) {
+  tests := []struct {
+    input           string
+    expectedMessage string
+  }{
+    {"5 + true;", "type mismatch: INTEGER + BOOLEAN"},
+    {"5 + true; 5;", "type mismatch: INTEGER + BOOLEAN"},
+    {"-true", "unknown operator: -BOOLEAN"},
+    {"true + false;", "unknown operator: BOOLEAN + BOOLEAN"},
+    {"5; true + false; 5", "unknown operator: BOOLEAN + BOOLEAN"},
+    {"if (10 > 1) { true + false; }", "unknown operator: BOOLEAN + BOOLEAN"},
+    {`
+if (10 > 1) {
+  if (10 > 1) {
+    return true + false;
+  }
+
+  return 1;
+}
+`, "unknown operator: BOOLEAN + BOOLEAN"},
+    {"foobar", "identifier not found: foobar"},
+    {"let f = fn(x) { x }; f(1, 2)", "wrong number of arguments: want=1, got=2"},
+    {"5(1)", "not a function: INTEGER"},
+    {`"Hello" - "World"`, "unknown operator: STRING - STRING"},
+    {`5[0]`, "index operator not supported: INTEGER"},
+    {`"hello"["h"]`, "index operator not supported: STRING"},
+    {`{"name": "Monkey"}[fn(x) { x }];`, "unusable as hash key: FUNCTION"},
+    {`{fn(x) { x }: 1};`, "unusable as hash key: FUNCTION"},
+    {`let h = {}; h[[1]] = 1`, "unusable as hash key: ARRAY"},
+    {`let a = [1]; a[1] = 2`, "index out of range: 1"},
+    {`let a = [1]; a[-1] = 2`, "index out of range: -1"},
+    {`let a = [1]; a["x"] = 2`, "array index must be INTEGER, got STRING"},
+    {`let s = "abc"; s[0] = "x"`, "index assignment not supported: STRING"},
+    {"5 / 0", "division by zero"},
+    {"5 % 0", "division by zero"},
+    {"let x = 0; 10 / (x * 2)", "division by zero"},
+    {"100000000000000000000 / 0", "division by zero"},
+    {"5 % (100000000000000000000 - 100000000000000000000)", "division by zero"},
+    {"2 ** -1", "negative exponent: -1"},
+    {"2 ** 100000000000", "integer too large: 2 ** 100000000000"},
+    {"100000000000000000000 + true", "type mismatch: BIG_INTEGER + BOOLEAN"},
+  }
+
+  for _, tt := range tests {
+    evaluated := testEval(tt.input)
+
+    errObj, ok := evaluated.(*object.Error)
+    if !ok {
+      t.Errorf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+      continue
+    }
+
+    if errObj.Message != tt.expectedMessage {
+      t.Errorf("wrong error message. expected=%q, got=%q",
+        tt.expectedMessage, errObj.Message)
+    }
+  }
+}
+
+func TestStringLiteral(t *testing.T) {
+  testStringObject(t, testEval(`"Hello World!"`), "Hello World!")
+}
+
+func TestStringConcatenation(t *testing.T) {
+  testStringObject(t, testEval(`"Hello" + " " + "World!"`), "Hello World!")
+}
+
+func TestTemplateLiterals(t *testing.T) {
+  tests := []struct {
+    input    string
+    expected string
+  }{
+    {`let name = "Sam"; let age = 30; "Hello ${name}, you are ${age} years old"`, "Hello Sam, you are 30 years old"},
+    {`"1 + 1 = ${1 + 1}"`, "1 + 1 = 2"},
+    {`"${true}${[1, "a"]}"`, "true[1, a]"},
+    {`let h = {"a": 1}; "a is ${h["a"]}"`, "a is 1"},
+    {`let x = "in"; "out ${"and ${x}"}"`, "out and in"},
+  }
+
+  for _, tt := range tests {
+    testStringObject(t, testEval(tt.input), tt.expected)
+  }
+
+  testErrorObject(t, testEval(`"a ${1 + true} b"`), "type mismatch: INTEGER + BOOLEAN")
+  testErrorObject(t, testEval(`"a ${missing}"`), "identifier not found: missing")
+}
+
+func TestStringIndexExpressions(t *testing.T) {
+  tests := []struct {
+    input    string
+    expected interface{}
+  }{
+    {`"hello"[0]`, "h"},
+    {`"hello"[1]`, "e"},
+    {`"hello"[4]`, "o"},
+    {`let i = 1; "hello"[i + 1]`, "l"},
+    {`"hello"[5]`, nil},
+    {`"hello"[-1]`, nil},
+    {`""[0]`, nil},
+    // indexed by rune, not byte
+    {`"héllo"[1]`, "é"},
+    {`"héllo"[2]`, "l"},
+    {`"变量"[1]`, "量"},
+    {`"hello"[2 ** 70]`, nil},
+    {`"hello"[-(2 ** 70)]`, nil},
+  }
+
+  for _, tt := range tests {
+    evaluated := testEval(tt.input)
+    str, ok := tt.expected.(string)
+    if ok {
+      testStringObject(t, evaluated, str)
+    } else {
+      testNullObject(t, evaluated)
+    }
+  }
+}
+
+func TestStringComparison(t *testing.T) {
+  tests := []struct {
+    input    string
+    expected bool
+  }{
+    {`"a" == "a"`, true},
+    {`"a" == "b"`, false},
+    {`"a" != "b"`, true},
+    {`"a" + "b" == "ab"`, true},
+  }
+
+  for _, tt := range tests {
+    testBooleanObject(t, testEval(tt.input), tt.expected)
+  }
+}
+
+func TestArrayLiterals(t *testing.T) {
+  input := "[1, 2 * 2, 3 + 3]"
+
+  evaluated := testEval(input)
+  result, ok := evaluated.(*object.Array)
+  if !ok {
+    t.Fatalf("object is not Array. got=%T (%+v)", evaluated, evaluated)
+  }
+
+  if len(result.Elements) != 3 {
+    t.Fatalf("array has wrong num of elements. got=%d", len(result.Elements))
+  }
+
+  testIntegerObject(t, result.Elements[0], 1)
+  testIntegerObject(t, result.Elements[1], 4)
+  testIntegerObject(t, result.Elements[2], 6)
+}
+
+func TestOptionalIndexExpressions(t *testing.T) {
+  tests := []struct {
+    input    string
+    expected interface{}
+  }{
+    {"[1, 2]?.[0]", 1},
+    {"let none = if (false) { 1 }; none?.[0]", nil},
+    {`let none = if (false) { 1 }; none?.key`, nil},
+    {`{"key": 5}?.key`, 5},
+    {`{"key": 5}?.other`, nil},
+    {`[{"a": [7]}]?.[0]?.a?.[0]`, 7},
+    {`[{"a": 1}]?.[1]?.a`, nil},
+    // the index is skipped along with the access
+    {"let none = if (false) { 1 }; none?.[1 / 0]", nil},
+    {"[1]?.[1 / 0]", "division by zero"},
+    // only null is skipped
+    {"5?.[0]", "index operator not supported: INTEGER"},
+  }
+
+  for _, tt := range tests {
+    evaluated := testEval(tt.input)
+    switch expected := tt.expected.(type) {
+    case int:
+      testIntegerObject(t, evaluated, int64(expected))
+    case string:
+      testErrorObject(t, evaluated, expected)
+    default:
+      testNullObject(t, evaluated)
+    }
+  }
+}
+
+func TestArrayIndexExpressions(t *testing.T) {
+  tests := []struct {
+    input    string
+    expected interface{}
+  }{
+    {"[1, 2, 3][0]", 1},
+    {"[1, 2, 3][1]", 2},
+    {"[1, 2, 3][2]", 3},
+    {"let i = 0; [1][i];", 1},
+    {"[1, 2, 3][1 + 1];", 3},
+    {"let myArray = [1, 2, 3]; myArray[2];", 3},
+    {"let myArray = [1, 2, 3]; myArray[0] + myArray[1] + myArray[2];", 6},
+    {"[1, 2, 3][3]", nil},
+    {"[1, 2, 3][-1]", nil},
+    {"[1, 2][2 ** 70]", nil},
+    {"[1, 2][-(2 ** 70)]", nil},
+    {"[1, 2][9223372036854775807 + 1]", nil},
+  }
+
+  for _, tt := range tests {
+    evaluated := testEval(tt.input)
+    integer, ok := tt.expected.(int)
+    if ok {
+      testIntegerObject(t, evaluated, int64(integer))
+    } else {
+      testNullObject(t, evaluated)
+    }
+  }
+}
+
+func TestHashLiterals(t *testing.T) {
+  input := `let two = "two";
+{
+  "one": 10 - 9,
+  two: 1 + 1,
+  "thr" + "ee": 6 / 2,
+  4: 4,
+  true: 5,
+  false: 6
+}`
+
+  evaluated := testEval(input)
+  result, ok := evaluated.(*object.Hash)
+  if !ok {
+    t.Fatalf("Eval didn't return Hash. got=%T (%+v)", evaluated, evaluated)
+  }
+
+  expected := map[object.HashKey]int64{
+    (&object.String{Value: "one"}).HashKey():   1,
+    (&object.String{Value: "two"}).HashKey():   2,
+    (&object.String{Value: "three"}).HashKey(): 3,
+    (&object.Integer{Value: 4}).HashKey():      4,
+    TRUE.HashKey():                             5,
+    FALSE.HashKey():                            6,
+  }
+
+  if len(result.Pairs) != len(expected) {
+    t.Fatalf("Hash has wrong num of pairs. got=%d", len(result.Pairs))
+  }
+
+  for expectedKey, expectedValue := range expected {
+    pair, ok := result.Pairs[expectedKey]
+    if !ok {
+      t.Errorf("no pair for given key in Pairs")
+    }
+
+    testIntegerObject(t, pair.Value, expectedValue)
+  }
+
+  if result.Inspect() != "{one: 1, two: 2, three: 3, 4: 4, true: 5, false: 6}" {
+    t.Errorf("hash.Inspect() not in insertion order. got=%q", result.Inspect())
+  }
+}
+
+func TestHashIndexExpressions(t *testing.T) {
+  tests := []struct {
+    input    string
+    expected interface{}
+  }{
+    {`{"foo": 5}["foo"]`, 5},
+    {`{"foo": 5}["bar"]`, nil},
+    {`let key = "foo"; {"foo": 5}[key]`, 5},
+    {`{}["foo"]`, nil},
+    {`{5: 5}[5]`, 5},
+    {`{true: 5}[true]`, 5},
+    {`{false: 5}[false]`, 5},
+  }
+
+  for _, tt := range tests {
+    evaluated := testEval(tt.input)
+    integer, ok := tt.expected.(int)
+    if ok {
+      testIntegerObject(t, evaluated, int64(integer))
+    } else {
+      testNullObject(t, evaluated)
+    }
+  }
+}
+
+func TestIndexAssignment(t *testing.T) {
+  tests := []struct {
+    input    string
+    expected string
+  }{
+    {`let h = {"a": 1}; h["b"] = 2; h["b"]`, "2"},
+    {`let h = {"a": 1}; h["a"] = 3; h`, "{a: 3}"},
+    {`let h = {}; h[1] = "x"; h[true] = "y"; h`, "{1: x, true: y}"},
+    {`let arr = [1, 2, 3]; arr[1] = 5; arr`, "[1, 5, 3]"},
+    {`let h = {}; h["a"] = 7`, "7"},
+    {`let a = [0]; let b = [0]; a[0] = b[0] = 1; [a, b]`, "[[1], [1]]"},
+  }
+
+  for _, tt := range tests {
+    evaluated := testEval(tt.input)
+    if evaluated.Inspect() != tt.expected {
+      t.Errorf("%s wrong. want=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+    }
+  }
+}
+
+func TestIdentifierAssignment(t *testing.T) {
+  tests := []struct {
+    input    string
+    expected interface{}
+  }{
+    {"let a = 1; a = 2; a", 2},
+    {"let a = 1; a = a + 1", 2},
+    {"let a = 1; let b = 1; a = b = 3; a + b", 6},
+    {"let a = 1; let set = fn() { a = 5 }; set(); a", 5},
+    {"b = 1", "identifier not found: b"},
+  }
+
+  for _, tt := range tests {
+    evaluated := testEval(tt.input)
+    switch expected := tt.expected.(type) {
+    case int:
+      testIntegerObject(t, evaluated, int64(expected))
+    case string:
+      testErrorObject(t, evaluated, expected)
+    }
+  }
+}
+
+func TestWhileExpressions(t *testing.T) {
+  tests := []struct {
+    input    string
+    expected interface{}
+  }{
+    {"let i = 0; while (i < 10) { i = i + 1 }; i", 10},
+    {"let i = 0; let sum = 0; while (i < 5) { i = i + 1; sum = sum + i }; sum", 15},
+    {"while (false) { 1 }", nil},
+    {"let f = fn() { let i = 0; while (true) { i = i + 1; if (i == 3) { return i } } }; f()", 3},
+    {"while (true) { 1 + true }", "type mismatch: INTEGER + BOOLEAN"},
+  }
+
+  for _, tt := range tests {
+    evaluated := testEval(tt.input)
+    switch expected := tt.expected.(type) {
+    case int:
+      testIntegerObject(t, evaluated, int64(expected))
+    case string:
+      testErrorObject(t, evaluated, expected)
+    default:
+      testNullObject(t, evaluated)
+    }
+  }
+}
+
+func TestForExpressions(t *testing.T) {
+  tests := []struct {
+    input    string
+    expected interface{}
+  }{
+    {"let sum = 0; for (let i = 0; i < 5; i = i + 1) { sum = sum + i }; sum", 10},
+    {"let i = 0; for (; i < 3; i = i + 1) { }; i", 3},
+    {"let i = 0; for (i = 10; i < 3; i = i + 1) { }; i", 10},
+    {"let n = 0; for (let i = 0; i < 4;) { i = i + 1; n = n + i }; n", 10},
+    {"let f = fn() { for (let i = 0; ; i = i + 1) { if (i == 7) { return i } } }; f()", 7},
+    {"let f = fn() { let i = 0; for (;;) { i = i + 1; if (i == 2) { return i } } }; f()", 2},
+    {"for (let i = 0; i < 3; i = i + 1) { i }", nil},
+    // the loop variable doesn't leak
+    {"for (let i = 0; i < 3; i = i + 1) { }; i", "identifier not found: i"},
+    {"for (let i = 0 + true; i < 3; i = i + 1) { }", "type mismatch: INTEGER + BOOLEAN"},
+    {"for (let i = 0; i < true; i = i + 1) { }", "type mismatch: INTEGER < BOOLEAN"},
+    {"for (let i = 0; i < 3; i = i + true) { }", "type mismatch: INTEGER + BOOLEAN"},
+  }
+
+  for _, tt := range tests {
+    evaluated := testEval(tt.input)
+    switch expected := tt.expected.(type) {
+    case int:
+      testIntegerObject(t, evaluated, int64(expected))
+    case string:
+      testErrorObject(t, evaluated, expected)
+    default:
+      testNullObject(t, evaluated)
+    }
+  }
+}
+
+func TestForInExpressions(t *testing.T) {
+  tests := []struct {
+    input    string
+    expected interface{}
+  }{
+    {"let sum = 0; for (x in [1, 2, 3, 4]) { sum = sum + x }; sum", 10},
+    {"let sum = 0; for (i, x in [10, 20, 30]) { sum = sum + i * x }; sum", 80},
+    {`let ks = ""; for (k in {"a": 1, "b": 2, "c": 3}) { ks = ks + k }; ks`, "abc"},
+    {`let sum = 0; for (k, v in {"a": 1, "b": 2}) { sum = sum + v }; sum`, 3},
+    {"let n = 0; for (x in []) { n = n + 1 }; n", 0},
+    {"for (x in [1, 2]) { x }", nil},
+    {"let f = fn() { for (x in [1, 2, 3]) { if (x == 2) { return x } } }; f()", 2},
+    // changing the array in the body doesn't change the iterations
+    {"let a = [1, 2]; let n = 0; for (x in a) { a = [1, 2, 3, 4]; n = n + 1 }; n", 2},
+    // the loop variables don't leak
+    {"for (x in [1]) { }; x", "identifier not found: x"},
+    {"for (x in 5) { }", "for-in needs an ARRAY or HASH, got INTEGER"},
+    {`for (x in "abc") { }`, "for-in needs an ARRAY or HASH, got STRING"},
+    {"for (x in [1]) { x + true }", "type mismatch: INTEGER + BOOLEAN"},
+  }
+
+  for _, tt := range tests {
+    evaluated := testEval(tt.input)
+    switch expected := tt.expected.(type) {
+    case int:
+      testIntegerObject(t, evaluated, int64(expected))
+    case string:
+      if str, ok := evaluated.(*object.String); ok {
+        if str.Value != expected {
+          t.Errorf("%q: wrong string. expected=%q, got=%q", tt.input, expected, str.Value)
+        }
+        continue
+      }
+      testErrorObject(t, evaluated, expected)
+    default:
+      testNullObject(t, evaluated)
+    }
+  }
+}
+
+func TestMatchExpressions(t *testing.T) {
+  tests := []struct {
+    input    string
+    expected interface{}
+  }{
+    {`let x = 2; match x { 1 => "one", 2 => "two", _ => "other" }`, "two"},
+    {`match 7 { 1 => "one", 2 => "two", _ => "other" }`, "other"},
+    {`match "b" { "a" => 1, "b" => 2 }`, 2},
+    {`match 1 + 1 { 3 - 1 => "two", _ => "other" }`, "two"},
+    {`match true { false => 0, true => 1 }`, 1},
+    // the first matching arm wins, even before the wildcard
+    {`match 1 { _ => 0, 1 => 1 }`, 0},
+    {`match 1 { 1 => 1, 1 => 2 }`, 1},
+    // different types don't match
+    {`match "1" { 1 => "int", _ => "other" }`, "other"},
+    {`match 2 ** 64 { 18446744073709551616 => 1 }`, 1},
+    // no arm matches
+    {`match 3 { 1 => "one", 2 => "two" }`, nil},
+    {`match 3 { }`, nil},
+    // later arms are not evaluated
+    {`match 1 { 1 => 1, 2 => 1 + true }`, 1},
+    {`match 2 { 1 => 1, 2 => 1 + true }`, "type mismatch: INTEGER + BOOLEAN"},
+    {`match 1 + true { _ => 1 }`, "type mismatch: INTEGER + BOOLEAN"},
+    {`match 1 { y => 1 }`, "identifier not found: y"},
+  }
+
+  for _, tt := range tests {
+    evaluated := testEval(tt.input)
+    switch expected := tt.expected.(type) {
+    case int:
+      testIntegerObject(t, evaluated, int64(expected))
+    case string:
+      if str, ok := evaluated.(*object.String); ok {
+        if str.Value != expected {
+          t.Errorf("%q: wrong string. expected=%q, got=%q", tt.input, expected, str.Value)
+        }
+        continue
+      }
+      testErrorObject(t, evaluated, expected)
+    default:
+      testNullObject(t, evaluated)
+    }
+  }
+}
+
+func TestTryExpressions(t *testing.T) {
+  tests := []struct {
+    input    string
+    expected interface{}
+  }{
+    {"try { 10 / 0 } catch (e) { -1 }", -1},
+    {"let safeDiv = fn(a, b) { try { a / b } catch (e) { 0 } }; safeDiv(10, 2) + safeDiv(1, 0)", 5},
+    {"try { 10 / 2 } catch (e) { -1 }", 5},
+    {`try { 1 + true } catch (e) { e }`, "type mismatch: INTEGER + BOOLEAN"},
+    {`let f = fn() { missing }; try { f() } catch (e) { e }`, "identifier not found: missing"},
+    // a return goes through, only errors are caught
+    {"let f = fn() { try { return 1; 2 } catch (e) { 3 } }; f()", 1},
+    {"let f = fn() { try { 1 / 0 } catch (e) { return 2 }; 3 }; f()", 2},
+    {"try { } catch (e) { 1 }", nil},
+    // the blocks' bindings don't leak
+    {"try { let x = 1 } catch (e) { }; x", "identifier not found: x"},
+    {"try { 1 / 0 } catch (e) { }; e", "identifier not found: e"},
+    // an error in the catch block is not caught again
+    {"try { 1 / 0 } catch (e) { e + 1 }", "type mismatch: STRING + INTEGER"},
+    {"try { try { 1 / 0 } catch (e) { e + 1 } } catch (e) { e }", "type mismatch: STRING + INTEGER"},
+  }
+
+  for _, tt := range tests {
+    evaluated := testEval(tt.input)
+    switch expected := tt.expected.(type) {
+    case int:
+      testIntegerObject(t, evaluated, int64(expected))
+    case string:
+      if str, ok := evaluated.(*object.String); ok {
+        if str.Value != expected {
+          t.Errorf("%q: wrong string. expected=%q, got=%q", tt.input, expected, str.Value)
+        }
+        continue
+      }
+      testErrorObject(t, evaluated, expected)
+    default:
+      testNullObject(t, evaluated)
+    }
+  }
+
+  // a cancelled evaluation is not caught
+  l := lexer.New("try { while (true) { } } catch (e) { 1 }")
+  p := parser.New(l)
+  ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+  defer cancel()
+  testErrorObject(t, EvalWithContext(ctx, p.ParseProgram(), object.NewEnvironment()),
+    "evaluation cancelled: context deadline exceeded")
+}
+
+func TestDoWhileExpressions(t *testing.T) {
+  tests := []struct {
+    input    string
+    expected interface{}
+  }{
+    // the body runs once even though the condition is false from the start
+    {"let i = 0; do { i = i + 1 } while (false); i", 1},
+    {"let i = 10; do { i = i + 1 } while (i < 5); i", 11},
+    {"let i = 0; do { i = i + 1 } while (i < 10); i", 10},
+    {"do { 1 } while (false)", nil},
+    {"let f = fn() { let i = 0; do { i = i + 1; if (i == 3) { return i } } while (true) }; f()", 3},
+    {"do { 1 + true } while (false)", "type mismatch: INTEGER + BOOLEAN"},
+    {"let i = 0; do { i = i + 1 } while (i + true)", "type mismatch: INTEGER + BOOLEAN"},
+    // the body's bindings don't leak
+    {"do { let x = 1 } while (false); x", "identifier not found: x"},
+  }
+
+  for _, tt := range tests {
+    evaluated := testEval(tt.input)
+    switch expected := tt.expected.(type) {
+    case int:
+      testIntegerObject(t, evaluated, int64(expected))
+    case string:
+      testErrorObject(t, evaluated, expected)
+    default:
+      testNullObject(t, evaluated)
+    }
+  }
+}
+
+func TestOperationBudget(t *testing.T) {
+  l := lexer.New("while (true) {}")
+  p := parser.New(l)
+  program := p.ParseProgram()
+  env := object.NewEnvironment()
+  env.Settings().Budget = &object.Budget{Max: 100}
+
+  testErrorObject(t, Eval(program, env), "operation budget exceeded (100)")
+
+  // the budget is shared with function scopes
+  l = lexer.New("let f = fn() { f() }; f()")
+  p = parser.New(l)
+  program = p.ParseProgram()
+  env = object.NewEnvironment()
+  env.Settings().Budget = &object.Budget{Max: 50}
+
+  testErrorObject(t, Eval(program, env), "operation budget exceeded (50)")
+
+  // a program within the budget is unaffected
+  l = lexer.New("let i = 0; while (i < 3) { i = i + 1 }; i")
+  p = parser.New(l)
+  program = p.ParseProgram()
+  env = object.NewEnvironment()
+  env.Settings().Budget = &object.Budget{Max: 1000}
+
+  testIntegerObject(t, Eval(program, env), 3)
+}
+
+// building arrays and strings costs an operation per element or byte,
+// so a budget stops a program before it allocates too much
+func TestOperationBudgetAllocations(t *testing.T) {
+  tests := []struct {
+    input    string
+    expected interface{}
+  }{
+    {"range(1000000000)", "operation budget exceeded (1000)"},
+    {"range(0, 9223372036854775807, 3)", "operation budget exceeded (1000)"},
+    {"range(9223372036854775807, -9223372036854775807, -1)", "operation budget exceeded (1000)"},
+    {"take(lazy_range(0), 1000000000)", "operation budget exceeded (1000)"},
+    {"let s = \"ab\"; while (true) { s = s + s }", "operation budget exceeded (1000)"},
+    {"let s = \"ab\"; while (true) { s = \"${s}${s}\" }", "operation budget exceeded (1000)"},
+    {"let s = \"ab\"; while (true) { s = join([s, s], \"\") }", "operation budget exceeded (1000)"},
+    {"let s = \"ab\"; while (true) { s = format(\"{0}{0}\", s) }", "operation budget exceeded (1000)"},
+    {"let s = \"ab\"; while (true) { s = s.replace(\"a\", \"aa\") }", "operation budget exceeded (1000)"},
+    // what fits in the budget is built as usual
+    {"len(range(500))", 500},
+    {"len(take(lazy_range(0), 500))", 500},
+    {"let s = \"ab\"; let i = 0; while (i < 5) { s = s + s; i = i + 1 }; len(s)", 64},
+  }
+
+  for _, tt := range tests {
+    evaluated := testEvalWith(tt.input, func(settings *object.Settings) {
+      settings.Budget = &object.Budget{Max: 1000}
+    })
+    switch expected := tt.expected.(type) {
+    case int:
+      testIntegerObject(t, evaluated, int64(expected))
+    case string:
+      testErrorObject(t, evaluated, expected)
+    }
+  }
+}
+
+func TestEvalWithContext(t *testing.T) {
+  tests := []struct {
+    input    string
+    expected string
+  }{
+    {"while (true) {}", "evaluation cancelled: context deadline exceeded"},
+    {"let f = fn() { f() }; f()", "evaluation cancelled: context deadline exceeded"},
+  }
+
+  for _, tt := range tests {
+    l := lexer.New(tt.input)
+    p := parser.New(l)
+    program := p.ParseProgram()
+
+    // the call depth limit must not stop the recursion first
+    env := object.NewEnvironment()
+    env.Settings().MaxCallDepth = 1 << 30
+
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+    start := time.Now()
+    evaluated := EvalWithContext(ctx, program, env)
+    cancel()
+
+    testErrorObject(t, evaluated, tt.expected)
+    if elapsed := time.Since(start); elapsed > time.Second {
+      t.Errorf("%s took too long to cancel: %s", tt.input, elapsed)
+    }
+  }
+
+  // an uncancelled context evaluates normally
+  l := lexer.New("let i = 0; while (i < 3) { i = i + 1 }; i")
+  p := parser.New(l)
+  testIntegerObject(t, EvalWithContext(context.Background(), p.ParseProgram(), object.NewEnvironment()), 3)
+}
+
+func TestEvalWithContextConcurrent(t *testing.T) {
+  // the second evaluation runs until the first one has stopped
+  stopped := make(chan struct{})
+  env := object.NewEnvironment()
+  env.Set("stopped", &object.Builtin{Fn: func(args ...object.Object) object.Object {
+    select {
+    case <-stopped:
+      return TRUE
+    default:
+      return FALSE
+    }
+  }})
+
+  ctx, cancel := context.WithCancel(context.Background())
+  cancelled := make(chan object.Object)
+  go func() {
+    p := parser.New(lexer.New("while (true) {}"))
+    cancelled <- EvalWithContext(ctx, p.ParseProgram(), object.NewEnvironment())
+  }()
+  running := make(chan object.Object)
+  go func() {
+    p := parser.New(lexer.New("while (!stopped()) {}; 1"))
+    running <- EvalWithContext(context.Background(), p.ParseProgram(), env)
+  }()
+
+  cancel()
+  select {
+  case evaluated := <-cancelled:
+    testErrorObject(t, evaluated, "evaluation cancelled: context canceled")
+  case <-time.After(5 * time.Second):
+    t.Fatalf("the cancelled evaluation did not stop")
+  }
+
+  close(stopped)
+  testIntegerObject(t, <-running, 1)
+}
+
+func TestLetStatements(t *testing.T) {
+  tests := []struct {
+    input    string
+    expected int64
+  }{
+    {"let a = 5; a;", 5},
+    {"let a = 5 * 5; a;", 25},
+    {"let a = 5; let b = a; b;", 5},
+    {"let a = 5; let b = a; let c = a + b + 5; c;", 15},
+  }
+
+  for _, tt := range tests {
+    testIntegerObject(t, testEval(tt.input), tt.expected)
+  }
+}
+
+func TestConstStatements(t *testing.T) {
+  tests := []struct {
+    input    string
+    expected interface{}
+  }{
+    {"const PI = 3; PI", 3},
+    {"const PI = 3; PI * 2", 6},
+    {"const x = 1; x = 2;", "cannot assign to constant: x"},
+    {"const x = 1; let f = fn() { x = 2 }; f()", "cannot assign to constant: x"},
+    {"const x = 1; const x = 2;", "cannot assign to constant: x"},
+    {"const x = 1; let x = 2;", "cannot assign to constant: x"},
+    {"let x = 1; const x = 2; x", 2},
+    // a nested scope may shadow the constant
+    {"const x = 1; let f = fn() { const x = 2; x }; f()", 2},
+    {"const x = 1; let f = fn() { let x = 2; x = 3; x }; [f(), x][0] + x", 4},
+    {"const x = 1; let f = fn(x) { x = 5; x }; f(0)", 5},
+  }
+
+  for _, tt := range tests {
+    evaluated := testEval(tt.input)
+    switch expected := tt.expected.(type) {
+    case int:
+      testIntegerObject(t, evaluated, int64(expected))
+    case string:
+      testErrorObject(t, evaluated, expected)
+    }
+  }
+}
+
+func TestDestructureStatements(t *testing.T) {
+  tests := []struct {
+    input    string
+    expected string
+  }{
+    {"let [a, b] = [1, 2]; [a, b]", "[1, 2]"},
+    {"let [x, ...xs] = [1, 2, 3]; [x, xs]", "[1, [2, 3]]"},
+    {"let [x, ...xs] = [1]; [x, xs]", "[1, []]"},
+    {"let [...all] = [1, 2]; all", "[1, 2]"},
+    {`let {name, age} = {"name": "monkey", "age": 3}; [name, age]`, "[monkey, 3]"},
+    {`let {name, age} = {"name": "monkey"}; [name, age]`, "[monkey, null]"},
+    // the rest is a copy, changing it leaves the original alone
+    {"let arr = [1, 2, 3]; let [_, ...xs] = arr; xs[0] = 9; arr", "[1, 2, 3]"},
+    // functions bound by destructuring are named after their binding
+    {"let [f] = [fn() { 1 }]; f", "fn f() {\n1\n}"},
+  }
+
+  for _, tt := range tests {
+    evaluated := testEval(tt.input)
+    if evaluated.Inspect() != tt.expected {
+      t.Errorf("%s: wrong result. want=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+    }
+  }
+}
+
+func TestDestructureCallResults(t *testing.T) {
+  tests := []struct {
+    input    string
+    expected string
+  }{
+    {"let swap = fn(x, y) { [y, x] }; let [a, b] = swap(1, 2); [a, b]", "[2, 1]"},
+    {"let swap = fn(x, y) { return [y, x]; }; let x = 1; let y = 2; let [x, y] = swap(x, y); [x, y]", "[2, 1]"},
+    {`let person = fn() { {"name": "monkey"} }; let {name} = person(); name`, "monkey"},
+    {"let split = fn(list) { [list[0], slice(list, 1)] }; let [head, tail] = split([1, 2, 3]); tail", "[2, 3]"},
+    // an error from the call is reported as is, not destructured
+    {"let f = fn() { [1] + 1 }; let [a] = f(); a", "ERROR: type mismatch: ARRAY + INTEGER\n  in function f"},
+  }
+
+  for _, tt := range tests {
+    evaluated := testEval(tt.input)
+    if evaluated.Inspect() != tt.expected {
+      t.Errorf("%s: wrong result. want=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+    }
+  }
+}
+
+func TestDestructureErrors(t *testing.T) {
+  tests := []struct {
+    input    string
+    expected string
+  }{
+    {"let [a, b] = [1];", "wrong number of values to destructure: want=2, got=1"},
+    {"let [a, b] = [1, 2, 3];", "wrong number of values to destructure: want=2, got=3"},
+    {"let [a, b, ...rest] = [1];", "wrong number of values to destructure: want at least 2, got=1"},
+    {"let [a] = 1;", "cannot destructure INTEGER as ARRAY"},
+    {"let {a} = [1];", "cannot destructure ARRAY as HASH"},
+    {"const a = 1; let [a] = [2];", "cannot assign to constant: a"},
+  }
+
+  for _, tt := range tests {
+    testErrorObject(t, testEval(tt.input), tt.expected)
+  }
+}
+
+func TestBlockScope(t *testing.T) {
+  tests := []struct {
+    input    string
+    expected interface{}
+  }{
+    // a let in a block shadows the outer binding without touching it
+    {"let x = 1; if (true) { let x = 2; x }", 2},
+    {"let x = 1; if (true) { let x = 2; }; x", 1},
+    {"let x = 1; if (false) { 0 } else { let x = 3; }; x", 1},
+    {"let x = 1; let i = 0; while (i < 3) { let x = i; i = i + 1 }; x", 1},
+    // assignment still reaches the outer binding
+    {"let x = 1; if (true) { x = 2; }; x", 2},
+    {"let sum = 0; let i = 0; while (i < 3) { let next = i + 1; sum = sum + next; i = next }; sum", 6},
+    // a const may be shadowed inside a block
+    {"const x = 1; if (true) { const x = 2; x }", 2},
+    // names declared only inside a block are gone afterwards
+    {"if (true) { let y = 2; }; y", "identifier not found: y"},
+    {"let i = 0; while (i < 1) { let y = i; i = i + 1 }; y", "identifier not found: y"},
+    {"if (false) { 0 } else { let y = 2; }; y", "identifier not found: y"},
+  }
+
+  for _, tt := range tests {
+    evaluated := testEval(tt.input)
+    switch expected := tt.expected.(type) {
+    case int:
+      testIntegerObject(t, evaluated, int64(expected))
+    case string:
+      testErrorObject(t, evaluated, expected)
+    }
+  }
+}
+
+func TestFunctionObject(t *testing.T) {
+  input := "fn(x) { x + 2; };"
+
+  evaluated := testEval(input)
+  fn, ok := evaluated.(*object.Function)
+  if !ok {
+    t.Fatalf("object is not Function. got=%T (%+v)", evaluated, evaluated)
+  }
+
+  if len(fn.Parameters) != 1 {
+    t.Fatalf("function has wrong parameters. Parameters=%+v",
+      fn.Parameters)
+  }
+
+  if fn.Parameters[0].String() != "x" {
+    t.Fatalf("parameter is not 'x'. got=%q", fn.Parameters[0])
+  }
+
+  expectedBody := "(x + 2)"
+
+  if fn.Body.String() != expectedBody {
+    t.Fatalf("body is not %q. got=%q", expectedBody, fn.Body.String())
+  }
+}
+
+func TestFunctionApplication(t *testing.T) {
+  tests := []struct {
+    input    string
+    expected int64
+  }{
+    {"let identity = fn(x) { x; }; identity(5);", 5},
+    {"let identity = fn(x) { return x; }; identity(5);", 5},
+    {"let double = fn(x) { x * 2; }; double(5);", 10},
+    {"let add = fn(x, y) { x + y; }; add(5, 5);", 10},
+    {"let add = fn(x, y) { x + y; }; add(5 + 5, add(5, 5));", 20},
+    {"fn(x) { x; }(5)", 5},
+  }
+
+  for _, tt := range tests {
+    testIntegerObject(t, testEval(tt.input), tt.expected)
+  }
+}
+
+func TestRestParameters(t *testing.T) {
+  tests := []struct {
+    input    string
+    expected string
+  }{
+    {"let f = fn(first, ...rest) { [first, rest] }; f(1, 2, 3)", "[1, [2, 3]]"},
+    {"let f = fn(first, ...rest) { rest }; f(1)", "[]"},
+    {"let f = fn(...all) { all }; f()", "[]"},
+    {"let sum = fn(...nums) { reduce(nums, 0, fn(acc, x) { acc + x }) }; sum(1, 2, 3, 4)", "10"},
+    {"let f = fn(a, b, ...rest) { a }; f(1)", "ERROR: wrong number of arguments: want at least 2, got=1"},
+    {"fn(a, ...rest) { a }", "fn(a, ...rest) {\na\n}"},
+  }
+
+  for _, tt := range tests {
+    evaluated := testEval(tt.input)
+    if evaluated.Inspect() != tt.expected {
+      t.Errorf("%s wrong. want=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+    }
+  }
+}
+
+func TestDefaultParameters(t *testing.T) {
+  tests := []struct {
+    input    string
+    expected string
+  }{
+    {"let f = fn(x, y = 10) { x + y }; f(1)", "11"},
+    {"let f = fn(x, y = 10) { x + y }; f(1, 2)", "3"},
+    {"let f = fn(x, y = x * 2) { [x, y] }; f(3)", "[3, 6]"},
+    {"let n = 5; let f = fn(x = n) { x }; let n = 7; f()", "7"},
+    // defaults are evaluated again on every call
+    {"let f = fn(x = [0]) { x[0] = x[0] + 1; x }; f(); f()", "[1]"},
+    {"let f = fn(x = 1, ...rest) { [x, rest] }; f()", "[1, []]"},
+    {"let f = fn(x = 1, ...rest) { [x, rest] }; f(2, 3)", "[2, [3]]"},
+    {"let f = fn(x, y = 10) { x }; f()", "ERROR: wrong number of arguments: want at least 1, got=0"},
+    {"let f = fn(x, y = 10) { x }; f(1, 2, 3)", "ERROR: wrong number of arguments: want at most 2, got=3"},
+    {"let f = fn(x = 1 + true) { x }; f()", "ERROR: type mismatch: INTEGER + BOOLEAN\n  in function f"},
+    {"fn(x, y = 10) { x }", "fn(x, y = 10) {\nx\n}"},
+  }
+
+  for _, tt := range tests {
+    evaluated := testEval(tt.input)
+    if evaluated.Inspect() != tt.expected {
+      t.Errorf("%s wrong. want=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+    }
+  }
+}
+
+func TestKeywordArguments(t *testing.T) {
+  greet := `let greet = fn(greeting, name, punctuation = "!") { greeting + ", " + name + punctuation };`
+
+  tests := []struct {
+    input    string
+    expected string
+  }{
+    {greet + `greet(name = "Sam", greeting = "Hi")`, "Hi, Sam!"},
+    {greet + `greet("Hello", name = "Sam")`, "Hello, Sam!"},
+    {greet + `greet("Hi", punctuation = "?", name = "Sam")`, "Hi, Sam?"},
+    {greet + `greet(name = "Sam")`, "ERROR: missing argument: greeting"},
+    {greet + `greet("Hi", greeting = "Yo", name = "Sam")`, "ERROR: multiple values for argument: greeting"},
+    {greet + `greet("Hi", nme = "Sam")`, "ERROR: unknown keyword argument: nme"},
+    {greet + `greet("Hi", "Sam", "!", "?", name = "Sam")`, "ERROR: wrong number of arguments: want at most 3, got=4"},
+    {`let f = fn(a, ...rest) { [a, rest] }; f(1, 2, 3, a = 0)`, "ERROR: multiple values for argument: a"},
+    {`let f = fn(a, b = 2, ...rest) { [a, b, rest] }; f(b = 5, a = 1)`, "[1, 5, []]"},
+    {`type(x = 1)`, "ERROR: keyword arguments not supported: BUILTIN"},
+    {`let x = 1; x(a = 1)`, "ERROR: not a function: INTEGER"},
+  }
+
+  for _, tt := range tests {
+    evaluated := testEval(tt.input)
+    if evaluated.Inspect() != tt.expected {
+      t.Errorf("%s wrong. want=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+    }
+  }
+}
+
+func TestClosures(t *testing.T) {
+  input := `
+let newAdder = fn(x) {
+  fn(y) { x + y };
+};
+
+let addTwo = newAdder(2);
+addTwo(2);`
+
+  testIntegerObject(t, testEval(input), 4)
+}
+
+// closures made from the same literal share its body, only the env differs
+func TestClosuresShareBody(t *testing.T) {
+  input := `
+let newAdder = fn(x) { fn(y) { x + y } };
+[newAdder(1), newAdder(2), newAdder(3)];`
+
+  adders, ok := testEval(input).(*object.Array)
+  if !ok || len(adders.Elements) != 3 {
+    t.Fatalf("expected 3 adders, got=%v", adders)
+  }
+
+  first := adders.Elements[0].(*object.Function)
+  for i, element := range adders.Elements {
+    adder := element.(*object.Function)
+    if adder.Body != first.Body {
+      t.Errorf("adder %d has a copy of the body", i)
+    }
+    if i > 0 && adder.Env == first.Env {
+      t.Errorf("adder %d shares the env of adder 0", i)
+    }
+    testIntegerObject(t, applyFunction(adder, []object.Object{newInteger(10)}), int64(11+i))
+  }
+}
+
+func TestStrictMissingReturn(t *testing.T) {
+  tests := []struct {
+    input    string
+    expected interface{}
+  }{
+    // the if without an else falls off the end for a false x
+    {"let f = fn(x) { if (x) { return 1 } }; f(true)", 1},
+    {"let f = fn(x) { if (x) { return 1 } }; f(false)", "missing return in fn(x)"},
+    {"let f = fn(x) { if (x) { 1 } else { 2 } }; f(false)", 2},
+    {"let f = fn() { let y = 1 }; f()", "missing return in fn()"},
+    {"let f = fn(a, b = 2) { while (false) { } }; f(1)", "missing return in fn(a, b = 2)"},
+  }
+
+  strict := func(settings *object.Settings) { settings.Strict = true }
+  for _, tt := range tests {
+    evaluated := testEvalWith(tt.input, strict)
+    switch expected := tt.expected.(type) {
+    case int:
+      testIntegerObject(t, evaluated, int64(expected))
+    case string:
+      testErrorObject(t, evaluated, expected)
+    default:
+      testNullObject(t, evaluated)
+    }
+  }
+
+  // without strict mode the same function gives NULL
+  testNullObject(t, testEval("let f = fn(x) { if (x) { return 1 } }; f(false)"))
+}
+
+func TestFunctionNames(t *testing.T) {
+  tests := []struct {
+    input    string
+    expected string
+  }{
+    {"let f = fn(x) { x }; f", "f"},
+    {"const g = fn() { 1 }; g", "g"},
+    {"fn(x) { x }", ""},
+    // an already named function keeps its name
+    {"let f = fn(x) { x }; let g = f; g", "f"},
+    {"let adder = fn(x) { fn(y) { x + y } }; let addTwo = adder(2); addTwo", "addTwo"},
+    {"let fs = [fn() { 1 }]; fs[0]", ""},
+  }
+
+  for _, tt := range tests {
+    function, ok := testEval(tt.input).(*object.Function)
+    if !ok {
+      t.Fatalf("%s: object is not Function", tt.input)
+    }
+    if function.Name != tt.expected {
+      t.Errorf("%s: wrong name. expected=%q, got=%q", tt.input, tt.expected, function.Name)
+    }
+  }
+
+  function := testEval("let inc = fn(x) { x + 1 }; inc").(*object.Function)
+  if expected := "fn inc(x) {\n(x + 1)\n}"; function.Inspect() != expected {
+    t.Errorf("wrong Inspect(). expected=%q, got=%q", expected, function.Inspect())
+  }
+}
+
+func TestErrorTrace(t *testing.T) {
+  tests := []struct {
+    input    string
+    expected string
+  }{
+    {"let f = fn(x) { x + true }; f(1)", "ERROR: type mismatch: INTEGER + BOOLEAN\n  in function f"},
+    {"let inner = fn() { 1 / 0 }; let outer = fn() { inner() }; outer()",
+      "ERROR: division by zero\n  in function inner\n  in function outer"},
+    {"fn() { -true }()", "ERROR: unknown operator: -BOOLEAN\n  in function <anonymous>"},
+    {"let count = fn(n) { if (n == 0) { missing } else { count(n - 1) } }; count(2)",
+      "ERROR: identifier not found: missing\n  in function count (3 times)"},
+    // the call refused for its depth never ran
+    {"let f = fn(n) { f(n + 1) }; f(0)", "ERROR: maximum call depth exceeded (1000)\n  in function f (1000 times)"},
+    // errors outside any function have no trace
+    {"1 + true", "ERROR: type mismatch: INTEGER + BOOLEAN"},
+    {"let f = fn(x) { x }; f(1, 2)", "ERROR: wrong number of arguments: want=1, got=2"},
+  }
+
+  for _, tt := range tests {
+    if actual := testEval(tt.input).Inspect(); actual != tt.expected {
+      t.Errorf("%s wrong. want=%q, got=%q", tt.input, tt.expected, actual)
+    }
+  }
+}
+
+func TestThrowStatement(t *testing.T) {
+  tests := []struct {
+    input    string
+    expected interface{}
+  }{
+    {`throw "boom"; 1`, "boom"},
+    {`try { throw "boom" } catch (e) { e }`, "boom"},
+    // any value is thrown as its printed form
+    {`try { throw 42 } catch (e) { e }`, "42"},
+    {`try { throw [1, "a"] } catch (e) { e }`, "[1, a]"},
+    {`try { throw "x: ${1 + 1}" } catch (e) { e }`, "x: 2"},
+    // an error in the value is raised as it is
+    {`throw 1 / 0`, "division by zero"},
+    {`let check = fn(n) { if (n < 0) { throw "negative" } n }; check(1) + check(2)`, 3},
+    {`let check = fn(n) { if (n < 0) { throw "negative" } n }; try { check(-1) } catch (e) { e }`, "negative"},
+    {`let check = fn(n) { if (n < 0) { throw "negative" } n }; check(-1)`, "negative"},
+    {`for (x in [1, 2, 3]) { if (x == 2) { throw "stop at ${x}" } }`, "stop at 2"},
+  }
+
+  for _, tt := range tests {
+    evaluated := testEval(tt.input)
+    switch expected := tt.expected.(type) {
+    case int:
+      testIntegerObject(t, evaluated, int64(expected))
+    case string:
+      if str, ok := evaluated.(*object.String); ok {
+        if str.Value != expected {
+          t.Errorf("%q: wrong string. expected=%q, got=%q", tt.input, expected, str.Value)
+        }
+        continue
+      }
+      testErrorObject(t, evaluated, expected)
+    }
+  }
+
+  // uncaught, it is traced like any other error
+  input := `let fail = fn() { throw "bad" }; fail()`
+  if actual := testEval(input).Inspect(); actual != "ERROR: bad\n  in function fail" {
+    t.Errorf("%s wrong. got=%q", input, actual)
+  }
+}
+
+func TestMaxCallDepth(t *testing.T) {
+  input := `
+let loop = fn(n) { loop(n + 1) };
+loop(0);
+`
+  testErrorObject(t, testEval(input), "maximum call depth exceeded (1000)")
+
+  // the depth is released again once the calls return
+  input = `
+let countdown = fn(n) { if (n == 0) { 0 } else { countdown(n - 1) } };
+countdown(500);
+`
+  testIntegerObject(t, testEval(input), 0)
+  testIntegerObject(t, testEval(input), 0)
+}
+
+// every evaluation counts its own calls, a deep one doesn't use up the depth of another
+func TestMaxCallDepthConcurrent(t *testing.T) {
+  input := `
+let countdown = fn(n) { if (n == 0) { bottom() } else { countdown(n - 1) } };
+countdown(900);
+`
+  // the first evaluation waits 900 calls deep until the second one is done
+  reached, release := make(chan struct{}), make(chan struct{})
+  waiting := object.NewEnvironment()
+  waiting.Set("bottom", &object.Builtin{Fn: func(args ...object.Object) object.Object {
+    close(reached)
+    <-release
+    return TRUE
+  }})
+  waited := make(chan object.Object)
+  go func() {
+    waited <- Eval(parser.New(lexer.New(input)).ParseProgram(), waiting)
+  }()
+  <-reached
+
+  env := object.NewEnvironment()
+  env.Set("bottom", &object.Builtin{Fn: func(args ...object.Object) object.Object { return FALSE }})
+  testBooleanObject(t, Eval(parser.New(lexer.New(input)).ParseProgram(), env), false)
+
+  close(release)
+  testBooleanObject(t, <-waited, true)
+}
+
+func TestMaxCallDepthConfigurable(t *testing.T) {
+  input := `
+let countdown = fn(n) { if (n == 0) { 0 } else { countdown(n - 1) } };
+countdown(%d);
+`
+  shallow := func(settings *object.Settings) { settings.MaxCallDepth = 10 }
+  testIntegerObject(t, testEvalWith(fmt.Sprintf(input, 9), shallow), 0)
+  testErrorObject(t, testEvalWith(fmt.Sprintf(input, 10), shallow), "maximum call depth exceeded (10)")
+  testIntegerObject(t, testEval(fmt.Sprintf(input, 10)), 0)
+}
+
+func TestSharedObjects(t *testing.T) {
+  tests := []struct {
+    left   string
+    right  string
+    shared bool
+  }{
+    {"1 + 2", "3", true},
+    {"0 - 128", "-128", true},
+    {"200 + 55", "255", true},
+    {"len([1, 2])", "2", true},
+    {"[7][0] * 1", "7", true},
+    {"200 + 56", "256", false},
+    {"0 - 129", "-129", false},
+    {"1 < 2", "true", true},
+    {"!true", "1 == 2", true},
+  }
+
+  for _, tt := range tests {
+    left := testEval(tt.left)
+    right := testEval(tt.right)
+    if (left == right) != tt.shared {
+      t.Errorf("%s and %s shared=%t, want=%t", tt.left, tt.right, left == right, tt.shared)
+    }
+    if left.Inspect() != right.Inspect() {
+      t.Errorf("%s and %s differ: %s, %s", tt.left, tt.right, left.Inspect(), right.Inspect())
+    }
+  }
+}
+
+// go test -bench SmallIntegers -benchmem ./src/evaluator
+// the loop stays inside the integer cache, so it runs without allocating integers
+func BenchmarkSmallIntegers(b *testing.B) {
+  benchmarkEval(b, "let x = 0; let i = 0; while (i < 200) { x = (x + i) % 100; i = i + 1 }; x")
+}
+
+// same loop with integers outside the cache, for comparison
+func BenchmarkLargeIntegers(b *testing.B) {
+  benchmarkEval(b, "let x = 1000; let i = 1000; while (i < 1200) { x = (x + i) % 1000 + 1000; i = i + 1 }; x")
+}
+
+// thousands of closures from one literal, each called once
+func BenchmarkClosures(b *testing.B) {
+  benchmarkEval(b, `
+let newAdder = fn(x) { fn(y) { x + y } };
+let total = 0;
+let i = 0;
+while (i < 5000) { total = total + newAdder(i)(1); i = i + 1 };
+total`)
+}
+
+func benchmarkEval(b *testing.B, input string) {
+  program := parser.New(lexer.New(input)).ParseProgram()
+  b.ReportAllocs()
+  b.ResetTimer()
+
+  for i := 0; i < b.N; i++ {
+    Eval(program, object.NewEnvironment())
+  }
+}
+
+func testEval(input string) object.Object {
+  l := lexer.New(input)
+  p := parser.New(l)
+  program := p.ParseProgram()
+  env := object.NewEnvironment()
+
+  return Eval(program, env)
+}
+
+// same as testEval, with the settings of the evaluation adjusted by set first
+func testEvalWith(input string, set func(settings *object.Settings)) object.Object {
+  l := lexer.New(input)
+  p := parser.New(l)
+  program := p.ParseProgram()
+  env := object.NewEnvironment()
+  set(env.Settings())
+
+  return Eval(program, env)
+}
+
+func testIntegerObject(t *testing.T, obj object.Object, expected int64) bool {
+  result, ok := obj.(*object.Integer)
+  if !ok {
+    t.Errorf("object is not Integer. got=%T (%+v)", obj, obj)
+    return false
+  }
+
+  if result.Value != expected {
+    t.Errorf("object has wrong value. got=%d, want=%d",
+      result.Value, expected)
+    return false
+  }
+
+  return true
+}
+
+func testBooleanObject(t *testing.T, obj object.Object, expected bool) bool {
+  result, ok := obj.(*object.Boolean)
+  if !ok {
+    t.Errorf("object is not Boolean. got=%T (%+v)", obj, obj)
+    return false
+  }
+
+  if result.Value != expected {
+    t.Errorf("object has wrong value. got=%t, want=%t",
+      result.Value, expected)
+    return false
+  }
+
+  return true
+}
+
+func testNullObject(t *testing.T, obj object.Object) bool {
+  if obj != NULL {
+    t.Errorf("object is not NULL. got=%T (%+v)", obj, obj)
+    return false
+  }
+
+  return true
+}
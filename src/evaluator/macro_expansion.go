@@ -0,0 +1,148 @@
+package evaluator
+
+import (
+  "JFFMonkeyLang/src/ast"
+  "JFFMonkeyLang/src/object"
+)
+
+// move every top level `let name = macro(...) {...}` out of program
+// and into env, so ExpandMacros can find it
+func DefineMacros(program *ast.Program, env *object.Environment) {
+  definitions := []int{}
+
+  // 1.find and define the macros
+  for i, statement := range program.Statements {
+    if isMacroDefinition(statement) {
+      addMacro(statement, env)
+      definitions = append(definitions, i)
+    }
+  }
+
+  // 2.remove them from the program, back to front so indexes stay valid
+  for i := len(definitions) - 1; i >= 0; i-- {
+    definitionIndex := definitions[i]
+    program.Statements = append(
+      program.Statements[:definitionIndex],
+      program.Statements[definitionIndex+1:]...,
+    )
+  }
+}
+
+func isMacroDefinition(node ast.Statement) bool {
+  letStatement, ok := node.(*ast.LetStatement)
+  if !ok {
+    return false
+  }
+
+  _, ok = letStatement.Value.(*ast.MacroLiteral)
+  return ok
+}
+
+func addMacro(stmt ast.Statement, env *object.Environment) {
+  letStatement, _ := stmt.(*ast.LetStatement)
+  macroLiteral, _ := letStatement.Value.(*ast.MacroLiteral)
+
+  macro := &object.Macro{
+    Parameters: macroLiteral.Parameters,
+    Env:        env,
+    Body:       macroLiteral.Body,
+  }
+
+  env.Set(letStatement.Name.Value, macro)
+}
+
+// replace every call to a macro in env with the ast the macro gives back,
+// eg: let unless = macro(c, x) { quote(if (!(unquote(c))) { unquote(x) }) };
+//     unless(10 > 5, "no") => if (!(10 > 5)) { "no" }
+// the first failing expansion stops it and is returned as the error
+func ExpandMacros(program ast.Node, env *object.Environment) (ast.Node, *object.Error) {
+  var expandErr *object.Error
+
+  expanded := ast.Modify(program, func(node ast.Node) ast.Node {
+    if expandErr != nil {
+      return node
+    }
+
+    call, ok := node.(*ast.CallExpression)
+    if !ok {
+      return node
+    }
+
+    macro, ok := isMacroCall(call, env)
+    if !ok {
+      return node
+    }
+
+    if len(call.Keywords) != 0 {
+      expandErr = newError("keyword arguments not supported: %s", macro.Type())
+      return node
+    }
+    if len(call.Arguments) != len(macro.Parameters) {
+      expandErr = newError("wrong number of arguments: want=%d, got=%d",
+        len(macro.Parameters), len(call.Arguments))
+      return node
+    }
+
+    // 1.the arguments go in unevaluated
+    evalEnv := extendMacroEnv(macro, quoteArgs(call))
+
+    // 2.the macro body must give back a quote, its node replaces the call
+    evaluated := Eval(macro.Body, evalEnv)
+    if errObj, ok := evaluated.(*object.Error); ok {
+      expandErr = errObj
+      return node
+    }
+    quote, ok := unwrapReturnValue(evaluated).(*object.Quote)
+    if !ok {
+      expandErr = newError("macro must return a QUOTE, got %s", typeOf(evaluated))
+      return node
+    }
+
+    return quote.Node
+  })
+
+  return expanded, expandErr
+}
+
+func isMacroCall(exp *ast.CallExpression, env *object.Environment) (*object.Macro, bool) {
+  identifier, ok := exp.Function.(*ast.Identifier)
+  if !ok {
+    return nil, false
+  }
+
+  obj, ok := env.Get(identifier.Value)
+  if !ok {
+    return nil, false
+  }
+
+  macro, ok := obj.(*object.Macro)
+  return macro, ok
+}
+
+func quoteArgs(exp *ast.CallExpression) []*object.Quote {
+  args := []*object.Quote{}
+
+  for _, a := range exp.Arguments {
+    args = append(args, &object.Quote{Node: a})
+  }
+
+  return args
+}
+
+func extendMacroEnv(macro *object.Macro, args []*object.Quote) *object.Environment {
+  extended := object.NewEnclosedEnvironment(macro.Env)
+
+  for paramIdx, param := range macro.Parameters {
+    extended.Set(param.Value, args[paramIdx])
+  }
+
+  return extended
+}
+
+// eg: NULL for a body that gives back nothing
+func typeOf(obj object.Object) object.ObjectType {
+  if obj == nil {
+    return object.NULL_OBJ
+  }
+  return unwrapReturnValue(obj).Type()
+}
@@ -0,0 +1,145 @@
+package evaluator
+
+import (
+  "JFFMonkeyLang/src/ast"
+  "JFFMonkeyLang/src/lexer"
+  "JFFMonkeyLang/src/object"
+  "JFFMonkeyLang/src/parser"
+  "testing"
+)
+
+func TestDefineMacros(t *testing.T) {
+  input := `
+let number = 1;
+let function = fn(x, y) { x + y };
+let mymacro = macro(x, y) { x + y; };
+`
+
+  env := object.NewEnvironment()
+  program := testParseProgram(input)
+
+  DefineMacros(program, env)
+
+  if len(program.Statements) != 2 {
+    t.Fatalf("Wrong number of statements. got=%d", len(program.Statements))
+  }
+
+  if _, ok := env.Get("number"); ok {
+    t.Fatalf("number should not be defined")
+  }
+  if _, ok := env.Get("function"); ok {
+    t.Fatalf("function should not be defined")
+  }
+
+  obj, ok := env.Get("mymacro")
+  if !ok {
+    t.Fatalf("macro not in environment.")
+  }
+
+  macro, ok := obj.(*object.Macro)
+  if !ok {
+    t.Fatalf("object is not Macro. got=%T (%+v)", obj, obj)
+  }
+
+  if len(macro.Parameters) != 2 {
+    t.Fatalf("Wrong number of macro parameters. got=%d", len(macro.Parameters))
+  }
+  if macro.Parameters[0].String() != "x" || macro.Parameters[1].String() != "y" {
+    t.Fatalf("parameters wrong. got=%v", macro.Parameters)
+  }
+
+  expectedBody := "(x + y)"
+  if macro.Body.String() != expectedBody {
+    t.Fatalf("body is not %q. got=%q", expectedBody, macro.Body.String())
+  }
+}
+
+func TestExpandMacros(t *testing.T) {
+  tests := []struct {
+    input    string
+    expected string
+  }{
+    {
+      `
+let infixExpression = macro() { quote(1 + 2); };
+
+infixExpression();
+`,
+      `(1 + 2)`,
+    },
+    {
+      `
+let reverse = macro(a, b) { quote(unquote(b) - unquote(a)); };
+
+reverse(2 + 2, 10 - 5);
+`,
+      `(10 - 5) - (2 + 2)`,
+    },
+    {
+      `
+let unless = macro(condition, consequence, alternative) {
+  quote(if (!(unquote(condition))) {
+    unquote(consequence);
+  } else {
+    unquote(alternative);
+  });
+};
+
+unless(10 > 5, puts("not greater"), puts("greater"));
+`,
+      `if (!(10 > 5)) { puts("not greater") } else { puts("greater") }`,
+    },
+  }
+
+  for _, tt := range tests {
+    expected := testParseProgram(tt.expected)
+    program := testParseProgram(tt.input)
+
+    env := object.NewEnvironment()
+    DefineMacros(program, env)
+    expanded, err := ExpandMacros(program, env)
+    if err != nil {
+      t.Fatalf("unexpected error: %s", err.Message)
+    }
+
+    if expanded.String() != expected.String() {
+      t.Errorf("not equal. want=%q, got=%q", expected.String(), expanded.String())
+    }
+  }
+}
+
+func TestExpandMacrosErrors(t *testing.T) {
+  tests := []struct {
+    input    string
+    expected string
+  }{
+    {`let m = macro(x) { 1 }; m(2)`, "macro must return a QUOTE, got INTEGER"},
+    {`let m = macro(x) { }; m(2)`, "macro must return a QUOTE, got NULL"},
+    {`let m = macro(x) { quote(x) }; m(1, 2)`, "wrong number of arguments: want=1, got=2"},
+    {`let m = macro(x) { quote(x) }; m(x = 1)`, "keyword arguments not supported: MACRO"},
+    {`let m = macro(x) { 1 + true }; m(1)`, "type mismatch: INTEGER + BOOLEAN"},
+  }
+
+  for _, tt := range tests {
+    program := testParseProgram(tt.input)
+
+    env := object.NewEnvironment()
+    DefineMacros(program, env)
+    _, err := ExpandMacros(program, env)
+    if err == nil {
+      t.Fatalf("%s: expected an error", tt.input)
+    }
+    testErrorObject(t, err, tt.expected)
+  }
+}
+
+func TestMacroLiteralOutsideLet(t *testing.T) {
+  testErrorObject(t, testEval(`fn() { macro(x) { x } }()`),
+    "macros can only be defined by a top level let: macro(x) x")
+}
+
+func testParseProgram(input string) *ast.Program {
+  l := lexer.New(input)
+  p := parser.New(l)
+  return p.ParseProgram()
+}
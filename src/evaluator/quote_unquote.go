@@ -0,0 +1,82 @@
+package evaluator
+
+import (
+  "JFFMonkeyLang/src/ast"
+  "JFFMonkeyLang/src/object"
+  "JFFMonkeyLang/src/token"
+  "fmt"
+)
+
+// eg: quote(1 + 2) => QUOTE((1 + 2))
+// the argument is not evaluated, only the unquote(...) calls inside it are
+func quote(call *ast.CallExpression, env *object.Environment) object.Object {
+  if len(call.Arguments) != 1 || len(call.Keywords) != 0 {
+    return newError("wrong number of arguments: want=1, got=%d",
+      len(call.Arguments)+len(call.Keywords))
+  }
+
+  node := evalUnquoteCalls(call.Arguments[0], env)
+  return &object.Quote{Node: node}
+}
+
+// eg: quote(8 + unquote(4 + 4)) => QUOTE((8 + 8))
+func evalUnquoteCalls(quoted ast.Node, env *object.Environment) ast.Node {
+  return ast.Modify(quoted, func(node ast.Node) ast.Node {
+    if !isUnquoteCall(node) {
+      return node
+    }
+
+    call, _ := node.(*ast.CallExpression)
+    if len(call.Arguments) != 1 || len(call.Keywords) != 0 {
+      return node
+    }
+
+    // a value without a literal form (eg: an array) keeps the unquote call
+    unquoted := Eval(call.Arguments[0], env)
+    if converted := convertObjectToASTNode(unquoted); converted != nil {
+      return converted
+    }
+    return node
+  })
+}
+
+func isUnquoteCall(node ast.Node) bool {
+  call, ok := node.(*ast.CallExpression)
+  if !ok {
+    return false
+  }
+
+  return call.Function.TokenLiteral() == "unquote"
+}
+
+// nil if obj can't be written as source
+func convertObjectToASTNode(obj object.Object) ast.Node {
+  switch obj := obj.(type) {
+  case *object.Integer:
+    t := token.Token{Type: token.INT, Literal: fmt.Sprintf("%d", obj.Value)}
+    return &ast.IntegerLiteral{Token: t, Value: obj.Value}
+
+  case *object.BigInteger:
+    t := token.Token{Type: token.INT, Literal: obj.Value.String()}
+    return &ast.BigIntegerLiteral{Token: t, Value: obj.Value}
+
+  case *object.Boolean:
+    var t token.Token
+    if obj.Value {
+      t = token.Token{Type: token.TRUE, Literal: "true"}
+    } else {
+      t = token.Token{Type: token.FALSE, Literal: "false"}
+    }
+    return &ast.Boolean{Token: t, Value: obj.Value}
+
+  case *object.String:
+    t := token.Token{Type: token.STRING, Literal: obj.Value}
+    return &ast.StringLiteral{Token: t, Value: obj.Value}
+
+  case *object.Quote:
+    return obj.Node
+
+  default:
+    return nil
+  }
+}
@@ -0,0 +1,71 @@
+package evaluator
+
+import (
+  "JFFMonkeyLang/src/object"
+  "testing"
+)
+
+func TestQuote(t *testing.T) {
+  tests := []struct {
+    input    string
+    expected string
+  }{
+    {`quote(5)`, `5`},
+    {`quote(5 + 8)`, `(5 + 8)`},
+    {`quote(foobar)`, `foobar`},
+    {`quote(foobar + barfoo)`, `(foobar + barfoo)`},
+  }
+
+  for _, tt := range tests {
+    testQuoteObject(t, testEval(tt.input), tt.expected)
+  }
+}
+
+func TestQuoteUnquote(t *testing.T) {
+  tests := []struct {
+    input    string
+    expected string
+  }{
+    {`quote(unquote(4))`, `4`},
+    {`quote(unquote(4 + 4))`, `8`},
+    {`quote(8 + unquote(4 + 4))`, `(8 + 8)`},
+    {`quote(unquote(4 + 4) + 8)`, `(8 + 8)`},
+    {`let foobar = 8; quote(foobar)`, `foobar`},
+    {`let foobar = 8; quote(unquote(foobar))`, `8`},
+    {`quote(unquote(true))`, `true`},
+    {`quote(unquote(true == false))`, `false`},
+    {`quote(unquote("a" + "b"))`, `ab`},
+    {`quote(unquote(quote(4 + 4)))`, `(4 + 4)`},
+    {`let quotedInfix = quote(4 + 4); quote(unquote(4 + 4) + unquote(quotedInfix))`, `(8 + (4 + 4))`},
+    {`quote(f(unquote(1 + 1)))`, `f(2)`},
+  }
+
+  for _, tt := range tests {
+    testQuoteObject(t, testEval(tt.input), tt.expected)
+  }
+}
+
+func TestQuoteErrors(t *testing.T) {
+  testErrorObject(t, testEval(`quote(1, 2)`), "wrong number of arguments: want=1, got=2")
+  testErrorObject(t, testEval(`quote()`), "wrong number of arguments: want=1, got=0")
+}
+
+func testQuoteObject(t *testing.T, obj object.Object, expected string) bool {
+  quote, ok := obj.(*object.Quote)
+  if !ok {
+    t.Errorf("expected *object.Quote. got=%T (%+v)", obj, obj)
+    return false
+  }
+
+  if quote.Node == nil {
+    t.Errorf("quote.Node is nil")
+    return false
+  }
+
+  if quote.Node.String() != expected {
+    t.Errorf("not equal. got=%q, want=%q", quote.Node.String(), expected)
+    return false
+  }
+
+  return true
+}
@@ -0,0 +1,285 @@
+package format
+
+import (
+  "JFFMonkeyLang/src/ast"
+  "JFFMonkeyLang/src/parser"
+  "bytes"
+  "strings"
+)
+
+const indent = "  "
+
+// operator precedence of infix expressions, same levels as the parser
+var precedences = map[string]int{
+  "??": parser.NULLISH,
+  "||": parser.LOGICAL_OR,
+  "&&": parser.LOGICAL_AND,
+  "==": parser.EQUALS,
+  "!=": parser.EQUALS,
+  "<":  parser.LESSGREATER,
+  ">":  parser.LESSGREATER,
+  "+":  parser.SUM,
+  "-":  parser.SUM,
+  "*":  parser.PRODUCT,
+  "/":  parser.PRODUCT,
+  "%":  parser.PRODUCT,
+  "**": parser.POWER,
+}
+
+// Source renders program as canonical monkey source,
+// one statement per line, blocks indented by two spaces, eg:
+//
+//   let max = fn(a, b) {
+//     if (a > b) {
+//       a;
+//     } else {
+//       b;
+//     }
+//   };
+//
+// parentheses are only kept where precedence needs them
+func Source(program *ast.Program) string {
+  var out bytes.Buffer
+
+  for _, stmt := range program.Statements {
+    out.WriteString(statement(stmt, 0))
+    out.WriteString("\n")
+  }
+
+  return out.String()
+}
+
+// one statement at the given indent level, without the trailing newline
+func statement(stmt ast.Statement, level int) string {
+  prefix := strings.Repeat(indent, level)
+
+  switch stmt := stmt.(type) {
+  case *ast.LetStatement:
+    return prefix + "let " + stmt.Name.Value + " = " + expression(stmt.Value, level) + ";"
+
+  case *ast.ConstStatement:
+    return prefix + "const " + stmt.Name.Value + " = " + expression(stmt.Value, level) + ";"
+
+  case *ast.DestructureStatement:
+    // eg: let [a, ...rest] = list; let {name, age} = person;
+    pattern := "[" + ast.ParametersString(stmt.Names, nil, stmt.Rest) + "]"
+    if stmt.Hash {
+      pattern = "{" + ast.ParametersString(stmt.Names, nil, false) + "}"
+    }
+    return prefix + "let " + pattern + " = " + expression(stmt.Value, level) + ";"
+
+  case *ast.ReturnStatement:
+    return prefix + "return " + expression(stmt.ReturnValue, level) + ";"
+
+  case *ast.ThrowStatement:
+    return prefix + "throw " + expression(stmt.Value, level) + ";"
+
+  case *ast.ExpressionStatement:
+    // if and while end in a '}' already
+    switch stmt.Expression.(type) {
+    case *ast.IfExpression, *ast.WhileExpression, *ast.ForExpression, *ast.ForInExpression,
+      *ast.TryExpression:
+      return prefix + expression(stmt.Expression, level)
+    }
+    return prefix + expression(stmt.Expression, level) + ";"
+
+  case *ast.BlockStatement:
+    return prefix + block(stmt, level)
+
+  default:
+    return prefix + stmt.String()
+  }
+}
+
+// eg: {
+//       x + 1;
+//     }
+// the '{' goes on the current line, the '}' on its own line at level
+func block(bs *ast.BlockStatement, level int) string {
+  if bs == nil || len(bs.Statements) == 0 {
+    return "{}"
+  }
+
+  var out bytes.Buffer
+
+  out.WriteString("{\n")
+  for _, stmt := range bs.Statements {
+    out.WriteString(statement(stmt, level+1))
+    out.WriteString("\n")
+  }
+  out.WriteString(strings.Repeat(indent, level) + "}")
+
+  return out.String()
+}
+
+func expression(exp ast.Expression, level int) string {
+  switch exp := exp.(type) {
+  case *ast.StringLiteral:
+    return `"` + exp.Value + `"`
+
+  case *ast.TemplateLiteral:
+    out := `"`
+    for i, e := range exp.Expressions {
+      out += exp.Strings[i] + "${" + expression(e, level) + "}"
+    }
+    return out + exp.Strings[len(exp.Strings)-1] + `"`
+
+  case *ast.PrefixExpression:
+    return exp.Operator + operand(exp.Right, parser.PREFIX, level)
+
+  case *ast.InfixExpression:
+    precedence := precedences[exp.Operator]
+    // left associative, so an equal precedence on the right needs parentheses
+    left := operand(exp.Left, precedence, level)
+    right := operand(exp.Right, precedence+1, level)
+    // '**' is the other way around
+    if exp.Operator == "**" {
+      left = operand(exp.Left, precedence+1, level)
+      right = operand(exp.Right, precedence, level)
+    }
+    return left + " " + exp.Operator + " " + right
+
+  case *ast.PipeExpression:
+    return operand(exp.Left, parser.PIPE, level) + " |> " + operand(exp.Function, parser.PIPE+1, level)
+
+  case *ast.AssignExpression:
+    return operand(exp.Target, parser.ASSIGN+1, level) + " = " + expression(exp.Value, level)
+
+  case *ast.IfExpression:
+    out := "if (" + expression(exp.Condition, level) + ") " + block(exp.Consequence, level)
+    if exp.Alternative != nil {
+      out += " else " + block(exp.Alternative, level)
+    }
+    return out
+
+  case *ast.WhileExpression:
+    return "while (" + expression(exp.Condition, level) + ") " + block(exp.Body, level)
+
+  case *ast.ForExpression:
+    // eg: for (let i = 0; i < 3; i = i + 1), for (;;)
+    out := "for ("
+    if exp.Init != nil {
+      out += strings.TrimSuffix(statement(exp.Init, 0), ";")
+    }
+    out += ";"
+    if exp.Condition != nil {
+      out += " " + expression(exp.Condition, level)
+    }
+    out += ";"
+    if exp.Post != nil {
+      out += " " + expression(exp.Post, level)
+    }
+    return out + ") " + block(exp.Body, level)
+
+  case *ast.ForInExpression:
+    vars := exp.Key.Value
+    if exp.Value != nil {
+      vars += ", " + exp.Value.Value
+    }
+    return "for (" + vars + " in " + expression(exp.Iterable, level) + ") " + block(exp.Body, level)
+
+  case *ast.TryExpression:
+    return "try " + block(exp.Block, level) + " catch (" + exp.Name.Value + ") " + block(exp.Catch, level)
+
+  case *ast.DoWhileExpression:
+    return "do " + block(exp.Body, level) + " while (" + expression(exp.Condition, level) + ")"
+
+  case *ast.FunctionLiteral:
+    params := []string{}
+    for i, param := range exp.Parameters {
+      p := param.Value
+      if i < len(exp.Defaults) && exp.Defaults[i] != nil {
+        p += " = " + expression(exp.Defaults[i], level)
+      }
+      if exp.Rest && i == len(exp.Parameters)-1 {
+        p = "..." + p
+      }
+      params = append(params, p)
+    }
+    return "fn(" + strings.Join(params, ", ") + ") " + block(exp.Body, level)
+
+  case *ast.MacroLiteral:
+    params := []string{}
+    for _, param := range exp.Parameters {
+      params = append(params, param.Value)
+    }
+    return "macro(" + strings.Join(params, ", ") + ") " + block(exp.Body, level)
+
+  case *ast.CallExpression:
+    args := []string{}
+    for _, arg := range exp.Arguments {
+      args = append(args, expression(arg, level))
+    }
+    for i, keyword := range exp.Keywords {
+      args = append(args, keyword.Value+" = "+expression(exp.KeywordValues[i], level))
+    }
+    return operand(exp.Function, parser.CALL, level) + "(" + strings.Join(args, ", ") + ")"
+
+  case *ast.MethodCallExpression:
+    args := []string{}
+    for _, arg := range exp.Arguments {
+      args = append(args, expression(arg, level))
+    }
+    return operand(exp.Receiver, parser.INDEX, level) + "." + exp.Method.Value + "(" + strings.Join(args, ", ") + ")"
+
+  case *ast.IndexExpression:
+    out := operand(exp.Left, parser.INDEX, level)
+    if exp.Optional {
+      out += "?."
+    }
+    return out + "[" + expression(exp.Index, level) + "]"
+
+  case *ast.ArrayLiteral:
+    elements := []string{}
+    for _, el := range exp.Elements {
+      elements = append(elements, expression(el, level))
+    }
+    return "[" + strings.Join(elements, ", ") + "]"
+
+  case *ast.HashLiteral:
+    pairs := []string{}
+    for i, key := range exp.Keys {
+      pairs = append(pairs, expression(key, level)+": "+expression(exp.Values[i], level))
+    }
+    return "{" + strings.Join(pairs, ", ") + "}"
+
+  case *ast.MatchExpression:
+    arms := []string{}
+    for i, pattern := range exp.Patterns {
+      arms = append(arms, expression(pattern, level)+" => "+expression(exp.Results[i], level))
+    }
+    return "match " + expression(exp.Subject, level) + " { " + strings.Join(arms, ", ") + " }"
+
+  default:
+    // identifiers, integers (keeping 0xFF, 1_000 as written) and booleans
+    return exp.String()
+  }
+}
+
+// exp as the operand of an operator binding with precedence,
+// wrapped in parentheses if it binds looser
+func operand(exp ast.Expression, precedence int, level int) string {
+  if precedenceOf(exp) < precedence {
+    return "(" + expression(exp, level) + ")"
+  }
+  return expression(exp, level)
+}
+
+func precedenceOf(exp ast.Expression) int {
+  switch exp := exp.(type) {
+  case *ast.AssignExpression:
+    return parser.ASSIGN
+  case *ast.PipeExpression:
+    return parser.PIPE
+  case *ast.InfixExpression:
+    return precedences[exp.Operator]
+  case *ast.PrefixExpression:
+    return parser.PREFIX
+  case *ast.IfExpression, *ast.WhileExpression, *ast.DoWhileExpression, *ast.ForExpression,
+    *ast.ForInExpression, *ast.MatchExpression, *ast.TryExpression, *ast.FunctionLiteral, *ast.MacroLiteral:
+    // eg: (fn(x) { x })(1)
+    return parser.LOWEST
+  default:
+    return parser.INDEX
+  }
+}
@@ -0,0 +1,98 @@
+package format
+
+import (
+  "JFFMonkeyLang/src/lexer"
+  "JFFMonkeyLang/src/parser"
+  "flag"
+  "os"
+  "path/filepath"
+  "strings"
+  "testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the .golden files")
+
+// every testdata/*.monkey is formatted and compared with its .golden file
+func TestSourceGolden(t *testing.T) {
+  inputs, err := filepath.Glob(filepath.Join("testdata", "*.monkey"))
+  if err != nil || len(inputs) == 0 {
+    t.Fatalf("no golden inputs found: %v", err)
+  }
+
+  for _, input := range inputs {
+    source, err := os.ReadFile(input)
+    if err != nil {
+      t.Fatalf("could not read %s: %s", input, err)
+    }
+
+    actual := testFormat(t, string(source))
+
+    golden := strings.TrimSuffix(input, ".monkey") + ".golden"
+    if *update {
+      if err := os.WriteFile(golden, []byte(actual), 0644); err != nil {
+        t.Fatalf("could not write %s: %s", golden, err)
+      }
+    }
+
+    expected, err := os.ReadFile(golden)
+    if err != nil {
+      t.Fatalf("could not read %s: %s", golden, err)
+    }
+    if actual != string(expected) {
+      t.Errorf("%s wrong.\nexpected=\n%s\ngot=\n%s", input, expected, actual)
+    }
+
+    // formatting is stable
+    if again := testFormat(t, actual); again != actual {
+      t.Errorf("%s is not stable.\nfirst=\n%s\nsecond=\n%s", input, actual, again)
+    }
+  }
+}
+
+func TestSourceParentheses(t *testing.T) {
+  tests := []struct {
+    input    string
+    expected string
+  }{
+    {"(1 + 2) + 3", "1 + 2 + 3;\n"},
+    {"1 + (2 + 3)", "1 + (2 + 3);\n"},
+    {"(1 * 2) + 3", "1 * 2 + 3;\n"},
+    {"1 * (2 + 3)", "1 * (2 + 3);\n"},
+    {"-(a)", "-a;\n"},
+    {"(-a)[0]", "(-a)[0];\n"},
+    {"a = b = 1", "a = b = 1;\n"},
+    {"(a < b) == (c > d)", "a < b == c > d;\n"},
+    {"fn(x) { x }(1)", "(fn(x) {\n  x;\n})(1);\n"},
+    {"2 ** (3 ** 2)", "2 ** 3 ** 2;\n"},
+    {"(2 ** 3) ** 2", "(2 ** 3) ** 2;\n"},
+    {"(-2) ** 2", "-2 ** 2;\n"},
+    {"-(2 ** 2)", "-(2 ** 2);\n"},
+    {"(a || b) && c", "(a || b) && c;\n"},
+    {"(a ?? b) || c", "(a ?? b) || c;\n"},
+    {"a ?? (b || c)", "a ?? b || c;\n"},
+    {"(x |> f) |> g", "x |> f |> g;\n"},
+    {"(-a).len() + [1].push(2).first()", "(-a).len() + [1].push(2).first();\n"},
+    {"a?.[0]?.key", `a?.[0]?.["key"];` + "\n"},
+    {"x |> (f |> g)", "x |> (f |> g);\n"},
+    {"let y = (x + 1) |> (f || g)", "let y = x + 1 |> f || g;\n"},
+    {"a || (b && c)", "a || b && c;\n"},
+    {"(a == 1) && !(b || c)", "a == 1 && !(b || c);\n"},
+  }
+
+  for _, tt := range tests {
+    if actual := testFormat(t, tt.input); actual != tt.expected {
+      t.Errorf("%s wrong. want=%q, got=%q", tt.input, tt.expected, actual)
+    }
+  }
+}
+
+func testFormat(t *testing.T, source string) string {
+  l := lexer.New(source)
+  p := parser.New(l)
+  program := p.ParseProgram()
+  if len(p.Errors()) != 0 {
+    t.Fatalf("parser errors for %q: %v", source, p.Errors())
+  }
+
+  return Source(program)
+}
@@ -0,0 +1,78 @@
+package interpreter
+
+import (
+  "JFFMonkeyLang/src/evaluator"
+  "JFFMonkeyLang/src/lexer"
+  "JFFMonkeyLang/src/object"
+  "JFFMonkeyLang/src/parser"
+)
+
+// Option adjusts how Run evaluates, eg: Run(src, WithSandbox())
+type Option func(*settings)
+
+type settings struct {
+  sandbox      bool
+  maxCallDepth int
+  budget       int // operations, 0 means unlimited
+}
+
+// WithSandbox runs untrusted src: builtins touching files are disabled,
+// calls nest at most 200 deep and at most a million operations run,
+// building an array or string costs an operation per element or byte
+func WithSandbox() Option {
+  return func(s *settings) {
+    s.sandbox = true
+    s.maxCallDepth = 200
+    s.budget = 1000000
+  }
+}
+
+// lex, parse and eval src against a fresh environment, nothing is printed
+// 1.parser errors: returns nil and every parser error
+// 2.runtime error: returns the *object.Error and its message,
+//   a failing macro expansion counts as one too
+// 3.otherwise:     returns the result and no errors
+func Run(src string, opts ...Option) (object.Object, []string) {
+  s := settings{}
+  for _, opt := range opts {
+    opt(&s)
+  }
+
+  l := lexer.New(src)
+  p := parser.New(l)
+  program := p.ParseProgram()
+
+  if len(p.Errors()) != 0 {
+    return nil, p.Errors().Strings()
+  }
+
+  // the settings belong to these environments, so Runs alongside keep their own
+  macroEnv := s.environment()
+  env := s.environment()
+
+  evaluator.DefineMacros(program, macroEnv)
+  expanded, err := evaluator.ExpandMacros(program, macroEnv)
+  if err != nil {
+    return err, []string{err.Message}
+  }
+
+  evaluated := evaluator.Eval(expanded, env)
+  if errObj, ok := evaluated.(*object.Error); ok {
+    return errObj, []string{errObj.Message}
+  }
+
+  return evaluated, nil
+}
+
+// a fresh environment evaluating with s, anything s leaves out keeps its default
+func (s settings) environment() *object.Environment {
+  env := object.NewEnvironment()
+  env.Settings().Sandbox = s.sandbox
+  if s.maxCallDepth > 0 {
+    env.Settings().MaxCallDepth = s.maxCallDepth
+  }
+  if s.budget > 0 {
+    env.Settings().Budget = &object.Budget{Max: s.budget}
+  }
+  return env
+}
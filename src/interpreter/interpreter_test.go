@@ -0,0 +1,140 @@
+package interpreter
+
+import (
+  "JFFMonkeyLang/src/object"
+  "testing"
+)
+
+func TestRun(t *testing.T) {
+  result, errs := Run("let add = fn(a, b) { a + b }; add(1, 2);")
+
+  if len(errs) != 0 {
+    t.Fatalf("unexpected errors: %v", errs)
+  }
+
+  integer, ok := result.(*object.Integer)
+  if !ok {
+    t.Fatalf("result is not Integer. got=%T (%+v)", result, result)
+  }
+  if integer.Value != 3 {
+    t.Errorf("result has wrong value. got=%d, want=3", integer.Value)
+  }
+}
+
+func TestRunParserErrors(t *testing.T) {
+  result, errs := Run("let = 5; let x 1;")
+
+  if result != nil {
+    t.Errorf("result is not nil. got=%T (%+v)", result, result)
+  }
+
+  expected := []string{
+    "1:5: expected next token to be IDENT, got ASSIGN instead",
+    "1:16: expected next token to be ASSIGN, got INT instead",
+  }
+  if len(errs) != len(expected) {
+    t.Fatalf("wrong number of errors. want=%d, got=%d (%v)", len(expected), len(errs), errs)
+  }
+  for i, msg := range expected {
+    if errs[i] != msg {
+      t.Errorf("errs[%d] wrong. want=%q, got=%q", i, msg, errs[i])
+    }
+  }
+}
+
+func TestRunRuntimeError(t *testing.T) {
+  result, errs := Run("let x = 5; x + true;")
+
+  if _, ok := result.(*object.Error); !ok {
+    t.Errorf("result is not Error. got=%T (%+v)", result, result)
+  }
+
+  if len(errs) != 1 {
+    t.Fatalf("wrong number of errors. want=1, got=%d (%v)", len(errs), errs)
+  }
+  if errs[0] != "type mismatch: INTEGER + BOOLEAN" {
+    t.Errorf("wrong error message. got=%q", errs[0])
+  }
+}
+
+func TestRunMacros(t *testing.T) {
+  result, errs := Run(`
+let unless = macro(condition, consequence, alternative) {
+  quote(if (!(unquote(condition))) { unquote(consequence) } else { unquote(alternative) })
+};
+unless(10 > 5, "not greater", "greater");
+`)
+
+  if len(errs) != 0 {
+    t.Fatalf("unexpected errors: %v", errs)
+  }
+  if result.Inspect() != "greater" {
+    t.Errorf("wrong result. want=%q, got=%q", "greater", result.Inspect())
+  }
+
+  _, errs = Run("let m = macro() { 1 }; m();")
+  if len(errs) != 1 || errs[0] != "macro must return a QUOTE, got INTEGER" {
+    t.Errorf("wrong errors. got=%v", errs)
+  }
+}
+
+func TestRunSandbox(t *testing.T) {
+  tests := []struct {
+    input    string
+    expected string
+  }{
+    {`read_file("/etc/passwd")`, "builtin disabled in sandbox: read_file"},
+    {`write_file("out.txt", "x")`, "builtin disabled in sandbox: write_file"},
+    {"let f = fn(n) { f(n + 1) }; f(0)", "maximum call depth exceeded (200)"},
+    {"while (true) { }", "operation budget exceeded (1000000)"},
+    {"range(1000000000)", "operation budget exceeded (1000000)"},
+    {`let s = "abc"; while (true) { s = s + s }`, "operation budget exceeded (1000000)"},
+  }
+
+  for _, tt := range tests {
+    _, errs := Run(tt.input, WithSandbox())
+    if len(errs) != 1 || errs[0] != tt.expected {
+      t.Errorf("%s: wrong errors. want=%q, got=%v", tt.input, tt.expected, errs)
+    }
+  }
+
+  // pure computation still works
+  result, errs := Run(`let fib = fn(n) { if (n < 2) { n } else { fib(n - 1) + fib(n - 2) } }; fib(15)`, WithSandbox())
+  if len(errs) != 0 {
+    t.Fatalf("unexpected errors: %v", errs)
+  }
+  if result.Inspect() != "610" {
+    t.Errorf("wrong result. want=610, got=%s", result.Inspect())
+  }
+
+  // the sandbox ends with the Run
+  _, errs = Run("let f = fn(n) { if (n == 0) { 0 } else { f(n - 1) } }; f(500)")
+  if len(errs) != 0 {
+    t.Errorf("unexpected errors outside the sandbox: %v", errs)
+  }
+}
+
+// a trusted Run finishing doesn't lift the sandbox of one still going
+func TestRunSandboxConcurrent(t *testing.T) {
+  trusted := make(chan []string)
+  go func() {
+    failed := []string{}
+    for i := 0; i < 50; i++ {
+      _, errs := Run("let f = fn(n) { if (n == 0) { 0 } else { f(n - 1) } }; f(500)")
+      failed = append(failed, errs...)
+    }
+    trusted <- failed
+  }()
+
+  for i := 0; i < 50; i++ {
+    _, errs := Run(`read_file("/etc/passwd")`, WithSandbox())
+    if len(errs) != 1 || errs[0] != "builtin disabled in sandbox: read_file" {
+      t.Fatalf("sandboxed read_file wasn't refused. got=%v", errs)
+    }
+  }
+
+  // nor does the depth limit of the sandbox reach the trusted Runs
+  if failed := <-trusted; len(failed) != 0 {
+    t.Errorf("unexpected errors outside the sandbox: %v", failed)
+  }
+}
@@ -2,30 +2,89 @@ package lexer
 
 import (
   "JFFMonkeyLang/src/token"
+  "bufio"
+  "io"
+  "strings"
+  "unicode"
+  "unicode/utf8"
 )
 
+// how much NewReader pulls from its reader at a time
+const readChunk = 4096
+
 type Lexer struct {
   input string
+  // streaming mode, input only holds the unread source from the current token on
+  reader *bufio.Reader
+  err    error
+  // where the current token starts in input
+  start int
   // current position in input (points to current char)
   position int
   // current reading position in input (after current char)
-  // readPosition = position + 1
+  // readPosition = position + width of ch in bytes
   readPosition int
   // current char under examination
-  ch byte
+  ch rune
+  // position of ch in source, for error messages
+  line   int
+  column int
 }
 
 func New(input string) *Lexer {
-  l := &Lexer{input: input}
+  l := &Lexer{input: input, line: 1}
+  l.readChar()
+  return l
+}
+
+// NewReader lexes r piece by piece instead of loading it all up front,
+// the tokens are the same New gives for the whole source
+func NewReader(r io.Reader) *Lexer {
+  l := &Lexer{reader: bufio.NewReaderSize(r, readChunk), line: 1}
   l.readChar()
   return l
 }
 
+// Err reports a failed read of NewReader's reader, the source
+// just ends there; io.EOF is not an error
+func (l *Lexer) Err() error {
+  return l.err
+}
+
+// make sure input reaches pos if the source is long enough,
+// eg: peekChar needs readPosition
+func (l *Lexer) fill(pos int) bool {
+  for pos >= len(l.input) && l.reader != nil && l.err == nil {
+    buf := make([]byte, readChunk)
+    n, err := l.reader.Read(buf)
+
+    // 1.the finished tokens are not needed anymore
+    l.input = l.input[l.start:] + string(buf[:n])
+    l.position -= l.start
+    l.readPosition -= l.start
+    pos -= l.start
+    l.start = 0
+
+    // 2.stop at the end of the source
+    if err == io.EOF {
+      l.reader = nil
+    } else if err != nil {
+      l.err = err
+    }
+  }
+
+  return pos < len(l.input)
+}
+
 func (l *Lexer) NextToken() token.Token {
   var tok token.Token
 
   l.skipWhitespace()
 
+  // every token starts at the current char
+  l.start = l.position
+  line, column := l.line, l.column
+
   switch l.ch {
   case '=':
     // '==' token
@@ -34,6 +93,12 @@ func (l *Lexer) NextToken() token.Token {
 
       tok.Literal = "=="
       tok.Type = token.EQ
+    } else if l.peekChar() == '>' {
+      // '=>' token
+      l.readChar()
+
+      tok.Literal = "=>"
+      tok.Type = token.ARROW
     } else {
       // '=' token
       tok = newToken(token.ASSIGN, l.ch)
@@ -54,9 +119,46 @@ func (l *Lexer) NextToken() token.Token {
   case '-':
     tok = newToken(token.MINUS, l.ch)
   case '*':
-    tok = newToken(token.ASTERISK, l.ch)
+    // '**' token
+    if l.peekChar() == '*' {
+      l.readChar()
+
+      tok.Literal = "**"
+      tok.Type = token.POWER
+    } else {
+      // '*' token
+      tok = newToken(token.ASTERISK, l.ch)
+    }
   case '/':
     tok = newToken(token.SLASH, l.ch)
+  case '%':
+    tok = newToken(token.PERCENT, l.ch)
+  case '&':
+    // '&&' token, a lone '&' is illegal
+    if l.peekChar() == '&' {
+      l.readChar()
+
+      tok.Literal = "&&"
+      tok.Type = token.AND
+    } else {
+      tok = newToken(token.ILLEGAL, l.ch)
+    }
+  case '|':
+    // '||' token, a lone '|' is illegal
+    if l.peekChar() == '|' {
+      l.readChar()
+
+      tok.Literal = "||"
+      tok.Type = token.OR
+    } else if l.peekChar() == '>' {
+      // '|>' token
+      l.readChar()
+
+      tok.Literal = "|>"
+      tok.Type = token.PIPE
+    } else {
+      tok = newToken(token.ILLEGAL, l.ch)
+    }
   case '<':
     tok = newToken(token.LT, l.ch)
   case '>':
@@ -69,23 +171,78 @@ func (l *Lexer) NextToken() token.Token {
     tok = newToken(token.LBRACE, l.ch)
   case '}':
     tok = newToken(token.RBRACE, l.ch)
+  case '[':
+    tok = newToken(token.LBRACKET, l.ch)
+  case ']':
+    tok = newToken(token.RBRACKET, l.ch)
+  case '"':
+    // a string with ${...} in it is a template
+    tok.Type = token.STRING
+    literal, template := l.readString()
+    if template {
+      tok.Type = token.TEMPLATE
+    }
+    tok.Literal = literal
   case ',':
     tok = newToken(token.COMMA, l.ch)
   case ';':
     tok = newToken(token.SEMICOLON, l.ch)
+  case ':':
+    tok = newToken(token.COLON, l.ch)
+  case '.':
+    // '...' token
+    if l.fill(l.position+2) && strings.HasPrefix(l.input[l.position:], "...") {
+      l.readChar()
+      l.readChar()
+
+      tok.Literal = "..."
+      tok.Type = token.ELLIPSIS
+    } else {
+      // '.' token
+      tok = newToken(token.DOT, l.ch)
+    }
+  case '?':
+    // '?.' token, a lone '?' is illegal
+    if l.peekChar() == '.' {
+      l.readChar()
+
+      tok.Literal = "?."
+      tok.Type = token.QDOT
+    } else if l.peekChar() == '?' {
+      // '??' token
+      l.readChar()
+
+      tok.Literal = "??"
+      tok.Type = token.NULLISH
+    } else {
+      tok = newToken(token.ILLEGAL, l.ch)
+    }
   case 0:
     tok.Literal = ""
     tok.Type = token.EOF
   default:
+    // '_5' is a misplaced digit separator, not an identifier
+    if l.ch == '_' && isDigit(l.peekChar()) {
+      tok.Literal = l.readNumber()
+      tok.Type = token.ILLEGAL
+      tok.Line, tok.Column = line, column
+      return tok
+    }
+
     if isLetter(l.ch) {
       tok.Literal = l.readIdentifier()
       tok.Type = token.LookupIdent(tok.Literal)
+      tok.Line, tok.Column = line, column
       return tok
     }
 
     if isDigit(l.ch) {
       tok.Literal = l.readNumber()
       tok.Type = token.INT
+      if !validNumber(tok.Literal) {
+        tok.Type = token.ILLEGAL
+      }
+      tok.Line, tok.Column = line, column
       return tok
     }
 
@@ -93,45 +250,201 @@ func (l *Lexer) NextToken() token.Token {
     tok = newToken(token.ILLEGAL, l.ch)
   }
 
+  tok.Line, tok.Column = line, column
   l.readChar()
   return tok
 }
 
+// every remaining token, the EOF token included; the lexer is used up after
+func (l *Lexer) Tokens() []token.Token {
+  tokens := []token.Token{}
+
+  for {
+    tok := l.NextToken()
+    tokens = append(tokens, tok)
+    if tok.Type == token.EOF {
+      return tokens
+    }
+  }
+}
+
 func (l *Lexer) readChar() {
-  if (l.readPosition) >= len(l.input) {
+  // moving past '\n' starts a new line
+  if l.ch == '\n' {
+    l.line += 1
+    l.column = 1
+  } else {
+    l.column += 1
+  }
+
+  // a multi-byte char may straddle the end of what NewReader has read so far
+  l.fill(l.readPosition + utf8.UTFMax - 1)
+
+  width := 1
+  if l.readPosition >= len(l.input) {
     l.ch = 0 // 0 is NULL ASCII code
   } else {
-    l.ch = l.input[l.readPosition]
+    l.ch, width = utf8.DecodeRuneInString(l.input[l.readPosition:])
   }
   l.position = l.readPosition
-  l.readPosition += 1
+  l.readPosition += width
 }
 
 func (l *Lexer) readIdentifier() string {
-  position := l.position
   for isLetter(l.ch) {
     l.readChar()
   }
 
-  return l.input[position:l.position]
+  return l.input[l.start:l.position]
 }
 
-func (l *Lexer) peekChar() byte {
+func (l *Lexer) peekChar() rune {
   // check edge cases
+  l.fill(l.readPosition + utf8.UTFMax - 1)
   if l.readPosition >= len(l.input) {
     return 0
   }
 
-  return l.input[l.readPosition]
+  ch, _ := utf8.DecodeRuneInString(l.input[l.readPosition:])
+  return ch
 }
 
+// eg: 5, 1_000_000, 0xFF, 0o17, 0b1010
 func (l *Lexer) readNumber() string {
-  position := l.position
-  for isDigit(l.ch) {
+  if l.ch == '0' && isBasePrefix(l.peekChar()) {
+    // 1.jump '0' and the base letter
+    l.readChar()
+    l.readChar()
+
+    // 2.read all digit-like chars, so `0b12` stays a single token
+    // and validNumber can reject it
+    for isDigit(l.ch) || isLetter(l.ch) {
+      l.readChar()
+    }
+
+    return l.input[l.start:l.position]
+  }
+
+  for isDigit(l.ch) || l.ch == '_' {
     l.readChar()
   }
 
-  return l.input[position:l.position]
+  return l.input[l.start:l.position]
+}
+
+// every digit must belong to the literal's base,
+// eg: 0xFF is valid, 0b12 0x are not
+func validNumber(literal string) bool {
+  digits := literal
+  isBaseDigit := isDigit
+
+  if len(literal) > 1 && literal[0] == '0' && isBasePrefix(rune(literal[1])) {
+    digits = literal[2:]
+
+    switch literal[1] {
+    case 'x', 'X':
+      isBaseDigit = isHexDigit
+    case 'o', 'O':
+      isBaseDigit = isOctalDigit
+    case 'b', 'B':
+      isBaseDigit = isBinaryDigit
+    }
+  }
+
+  if digits == "" || !validDigitSeparators(digits) {
+    return false
+  }
+
+  for i := 0; i < len(digits); i++ {
+    if digits[i] != '_' && !isBaseDigit(rune(digits[i])) {
+      return false
+    }
+  }
+
+  return true
+}
+
+// '_' may only sit between two digits,
+// eg: 1_000 is valid, _5 5_ 5__0 are not
+func validDigitSeparators(literal string) bool {
+  if strings.HasPrefix(literal, "_") || strings.HasSuffix(literal, "_") {
+    return false
+  }
+
+  return !strings.Contains(literal, "__")
+}
+
+// "foo bar"
+// ^.......^
+// curChar is the opening '"', stop at the closing '"' (or the end of input),
+// true if there is a ${...} in between
+func (l *Lexer) readString() (string, bool) {
+  template := l.skipString()
+
+  return l.input[l.start+1 : l.position], template
+}
+
+func (l *Lexer) skipString() bool {
+  template := false
+
+  for {
+    l.readChar()
+    if l.ch == '$' && l.peekChar() == '{' {
+      template = true
+      l.readChar()
+      l.skipInterpolation()
+    }
+    if l.ch == '"' || l.ch == 0 {
+      return template
+    }
+  }
+}
+
+// "a ${f("}")} b"
+// ...^.......^...
+// curChar is the '{' of `${`, stop at its matching '}' (or the end of input),
+// strings in between may hold braces of their own
+func (l *Lexer) skipInterpolation() {
+  for depth := 1; depth > 0; {
+    l.readChar()
+    switch l.ch {
+    case 0:
+      return
+    case '{':
+      depth++
+    case '}':
+      depth--
+    case '"':
+      if l.skipString(); l.ch == 0 {
+        return
+      }
+    }
+  }
+}
+
+// SplitTemplate cuts the literal of a TEMPLATE token into its text and the
+// source of its ${...} parts, eg: `a ${b} c` gives ["a ", " c"] and ["b"],
+// there is always one more text than expressions; false for an unclosed ${
+func SplitTemplate(literal string) (texts, exprs []string, ok bool) {
+  l := New(literal)
+  textStart := 0
+
+  for l.ch != 0 {
+    if l.ch == '$' && l.peekChar() == '{' {
+      texts = append(texts, literal[textStart:l.position])
+      l.readChar()
+      exprStart := l.readPosition
+      l.skipInterpolation()
+      if l.ch == 0 {
+        return nil, nil, false
+      }
+      exprs = append(exprs, literal[exprStart:l.position])
+      textStart = l.readPosition
+    }
+    l.readChar()
+  }
+
+  return append(texts, literal[textStart:]), exprs, true
 }
 
 func (l *Lexer) skipWhitespace() {
@@ -140,16 +453,40 @@ func (l *Lexer) skipWhitespace() {
   }
 }
 
-func newToken(tokenType token.TokenType, ch byte) token.Token {
+func newToken(tokenType token.TokenType, ch rune) token.Token {
   return token.Token{Type: tokenType, Literal: string(ch)}
 }
 
-func isLetter(ch byte) bool {
-  // a-zA-Z_
-  return ('a' <= ch && ch <= 'z') || ('A' <= ch && ch <= 'Z') || ch == '_'
+func isLetter(ch rune) bool {
+  // a-zA-Z_ and any other unicode letter, eg: café 变量
+  return unicode.IsLetter(ch) || ch == '_'
 }
 
-func isDigit(ch byte) bool {
+func isDigit(ch rune) bool {
   // 0-9
   return '0' <= ch && ch <= '9'
 }
+
+// 0x, 0o, 0b
+func isBasePrefix(ch rune) bool {
+  switch ch {
+  case 'x', 'X', 'o', 'O', 'b', 'B':
+    return true
+  }
+  return false
+}
+
+func isHexDigit(ch rune) bool {
+  // 0-9a-fA-F
+  return isDigit(ch) || ('a' <= ch && ch <= 'f') || ('A' <= ch && ch <= 'F')
+}
+
+func isOctalDigit(ch rune) bool {
+  // 0-7
+  return '0' <= ch && ch <= '7'
+}
+
+func isBinaryDigit(ch rune) bool {
+  // 0-1
+  return ch == '0' || ch == '1'
+}
@@ -2,7 +2,10 @@ package lexer
 
 import (
   "JFFMonkeyLang/src/token"
+  "reflect"
+  "strings"
   "testing"
+  "testing/iotest"
 )
 
 func TestNextToken(t *testing.T) {
@@ -15,7 +18,7 @@ let add = fn(x, y) {
 };
 
 let result = add(five, ten);
-!-/*5;
+!-/*%5;
 5 < 10 > 5;
 
 if (5 < 10) {
@@ -26,6 +29,12 @@ if (5 < 10) {
 
 10 == 10;
 10 != 9;
+2 ** 10 * 3;
+match x { 1 => 2 }
+"foobar"
+"foo bar"
+"foo"[1];
+{"foo": "bar"}
 `
 
   tests := []struct {
@@ -72,6 +81,7 @@ if (5 < 10) {
     {token.MINUS, "-"},
     {token.SLASH, "/"},
     {token.ASTERISK, "*"},
+    {token.PERCENT, "%"},
     {token.INT, "5"},
     {token.SEMICOLON, ";"},
     {token.INT, "5"},
@@ -105,6 +115,31 @@ if (5 < 10) {
     {token.NOT_EQ, "!="},
     {token.INT, "9"},
     {token.SEMICOLON, ";"},
+    {token.INT, "2"},
+    {token.POWER, "**"},
+    {token.INT, "10"},
+    {token.ASTERISK, "*"},
+    {token.INT, "3"},
+    {token.SEMICOLON, ";"},
+    {token.MATCH, "match"},
+    {token.IDENT, "x"},
+    {token.LBRACE, "{"},
+    {token.INT, "1"},
+    {token.ARROW, "=>"},
+    {token.INT, "2"},
+    {token.RBRACE, "}"},
+    {token.STRING, "foobar"},
+    {token.STRING, "foo bar"},
+    {token.STRING, "foo"},
+    {token.LBRACKET, "["},
+    {token.INT, "1"},
+    {token.RBRACKET, "]"},
+    {token.SEMICOLON, ";"},
+    {token.LBRACE, "{"},
+    {token.STRING, "foo"},
+    {token.COLON, ":"},
+    {token.STRING, "bar"},
+    {token.RBRACE, "}"},
     {token.EOF, ""},
   }
 
@@ -124,3 +159,385 @@ if (5 < 10) {
     }
   }
 }
+
+func TestTokenPositions(t *testing.T) {
+  input := `let x = 5;
+if (x) {
+  x == 10
+}`
+
+  tests := []struct {
+    expectedLiteral string
+    expectedLine    int
+    expectedColumn  int
+  }{
+    {"let", 1, 1},
+    {"x", 1, 5},
+    {"=", 1, 7},
+    {"5", 1, 9},
+    {";", 1, 10},
+    {"if", 2, 1},
+    {"(", 2, 4},
+    {"x", 2, 5},
+    {")", 2, 6},
+    {"{", 2, 8},
+    {"x", 3, 3},
+    {"==", 3, 5},
+    {"10", 3, 8},
+    {"}", 4, 1},
+    {"", 4, 2},
+  }
+
+  l := New(input)
+
+  for i, tt := range tests {
+    tok := l.NextToken()
+
+    if tok.Literal != tt.expectedLiteral {
+      t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+        i, tt.expectedLiteral, tok.Literal)
+    }
+
+    if tok.Line != tt.expectedLine || tok.Column != tt.expectedColumn {
+      t.Fatalf("tests[%d] - position of %q wrong. expected=%d:%d, got=%d:%d",
+        i, tok.Literal, tt.expectedLine, tt.expectedColumn, tok.Line, tok.Column)
+    }
+  }
+}
+
+func TestEllipsis(t *testing.T) {
+  input := `fn(a, ...rest) . ..`
+
+  tests := []struct {
+    expectedType    token.TokenType
+    expectedLiteral string
+  }{
+    {token.FUNCTION, "fn"},
+    {token.LPAREN, "("},
+    {token.IDENT, "a"},
+    {token.COMMA, ","},
+    {token.ELLIPSIS, "..."},
+    {token.IDENT, "rest"},
+    {token.RPAREN, ")"},
+    {token.DOT, "."},
+    {token.DOT, "."},
+    {token.DOT, "."},
+    {token.EOF, ""},
+  }
+
+  l := New(input)
+
+  for i, tt := range tests {
+    tok := l.NextToken()
+
+    if tok.Type != tt.expectedType || tok.Literal != tt.expectedLiteral {
+      t.Fatalf("tests[%d] - token wrong. expected=%q %q, got=%q %q",
+        i, tt.expectedType, tt.expectedLiteral, tok.Type, tok.Literal)
+    }
+  }
+}
+
+func TestOptionalChaining(t *testing.T) {
+  input := `a?.[0] h?.key ? . b ?? c`
+
+  tests := []struct {
+    expectedType    token.TokenType
+    expectedLiteral string
+  }{
+    {token.IDENT, "a"},
+    {token.QDOT, "?."},
+    {token.LBRACKET, "["},
+    {token.INT, "0"},
+    {token.RBRACKET, "]"},
+    {token.IDENT, "h"},
+    {token.QDOT, "?."},
+    {token.IDENT, "key"},
+    {token.ILLEGAL, "?"},
+    {token.DOT, "."},
+    {token.IDENT, "b"},
+    {token.NULLISH, "??"},
+    {token.IDENT, "c"},
+    {token.EOF, ""},
+  }
+
+  l := New(input)
+
+  for i, tt := range tests {
+    tok := l.NextToken()
+
+    if tok.Type != tt.expectedType || tok.Literal != tt.expectedLiteral {
+      t.Fatalf("tests[%d] - token wrong. expected=%q %q, got=%q %q",
+        i, tt.expectedType, tt.expectedLiteral, tok.Type, tok.Literal)
+    }
+  }
+}
+
+func TestLogicalOperators(t *testing.T) {
+  input := `a && b || !c & | x |> f`
+
+  tests := []struct {
+    expectedType    token.TokenType
+    expectedLiteral string
+  }{
+    {token.IDENT, "a"},
+    {token.AND, "&&"},
+    {token.IDENT, "b"},
+    {token.OR, "||"},
+    {token.BANG, "!"},
+    {token.IDENT, "c"},
+    {token.ILLEGAL, "&"},
+    {token.ILLEGAL, "|"},
+    {token.IDENT, "x"},
+    {token.PIPE, "|>"},
+    {token.IDENT, "f"},
+    {token.EOF, ""},
+  }
+
+  l := New(input)
+
+  for i, tt := range tests {
+    tok := l.NextToken()
+
+    if tok.Type != tt.expectedType || tok.Literal != tt.expectedLiteral {
+      t.Fatalf("tests[%d] - token wrong. expected=%q %q, got=%q %q",
+        i, tt.expectedType, tt.expectedLiteral, tok.Type, tok.Literal)
+    }
+  }
+}
+
+func TestNumberLiterals(t *testing.T) {
+  tests := []struct {
+    input           string
+    expectedType    token.TokenType
+    expectedLiteral string
+  }{
+    {"1_000_000", token.INT, "1_000_000"},
+    {"1_0", token.INT, "1_0"},
+    {"_5", token.ILLEGAL, "_5"},
+    {"5_", token.ILLEGAL, "5_"},
+    {"5__0", token.ILLEGAL, "5__0"},
+    {"_foo", token.IDENT, "_foo"},
+    {"0xFF", token.INT, "0xFF"},
+    {"0Xff", token.INT, "0Xff"},
+    {"0o17", token.INT, "0o17"},
+    {"0b1010", token.INT, "0b1010"},
+    {"0b1010_1010", token.INT, "0b1010_1010"},
+    {"0b12", token.ILLEGAL, "0b12"},
+    {"0o8", token.ILLEGAL, "0o8"},
+    {"0xFG", token.ILLEGAL, "0xFG"},
+    {"0x", token.ILLEGAL, "0x"},
+  }
+
+  for i, tt := range tests {
+    tok := New(tt.input).NextToken()
+
+    if tok.Type != tt.expectedType {
+      t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+        i, tt.expectedType, tok.Type)
+    }
+
+    if tok.Literal != tt.expectedLiteral {
+      t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+        i, tt.expectedLiteral, tok.Literal)
+    }
+  }
+}
+
+func TestNewReader(t *testing.T) {
+  program := `let add = fn(x, ...rest) { x + rest[0] };
+if (add(0x1F, 1_000) != 10) { "a long string, with spaces" } else { !true }
+let h = {"key": [1, 2, 3]}; 5 <= 10 == 9 . 0b12 "unterminated`
+
+  tests := []string{
+    "",
+    program,
+    // spans many reads, so finished tokens get dropped on the way
+    strings.Repeat(program+"\n", 200),
+  }
+
+  for _, input := range tests {
+    streams := map[string]*Lexer{
+      "whole":   NewReader(strings.NewReader(input)),
+      "onebyte": NewReader(iotest.OneByteReader(strings.NewReader(input))),
+    }
+
+    for name, streamed := range streams {
+      whole := New(input)
+      for i := 0; ; i++ {
+        expected := whole.NextToken()
+        actual := streamed.NextToken()
+        if actual != expected {
+          t.Fatalf("%s: token %d differs. expected=%+v, got=%+v", name, i, expected, actual)
+        }
+        if expected.Type == token.EOF {
+          break
+        }
+      }
+
+      // EOF keeps coming back
+      if tok := streamed.NextToken(); tok.Type != token.EOF {
+        t.Errorf("%s: expected EOF after the end, got=%+v", name, tok)
+      }
+      if streamed.Err() != nil {
+        t.Errorf("%s: unexpected read error: %s", name, streamed.Err())
+      }
+    }
+  }
+}
+
+func TestNewReaderError(t *testing.T) {
+  l := NewReader(iotest.TimeoutReader(iotest.OneByteReader(strings.NewReader("let x"))))
+
+  // the second read fails, so the source ends after "l"
+  tok := l.NextToken()
+  if tok.Type != token.IDENT || tok.Literal != "l" {
+    t.Fatalf("wrong token before the error. got=%+v", tok)
+  }
+  if tok := l.NextToken(); tok.Type != token.EOF {
+    t.Fatalf("expected EOF after the error, got=%+v", tok)
+  }
+  if l.Err() != iotest.ErrTimeout {
+    t.Errorf("wrong error. expected=%v, got=%v", iotest.ErrTimeout, l.Err())
+  }
+}
+
+func TestUnicodeIdentifiers(t *testing.T) {
+  input := `let café = "crème";
+变量 != café+1;
+_ö`
+
+  tests := []struct {
+    expectedType    token.TokenType
+    expectedLiteral string
+    expectedLine    int
+    expectedColumn  int
+  }{
+    {token.LET, "let", 1, 1},
+    {token.IDENT, "café", 1, 5},
+    {token.ASSIGN, "=", 1, 10},
+    {token.STRING, "crème", 1, 12},
+    {token.SEMICOLON, ";", 1, 19},
+    {token.IDENT, "变量", 2, 1},
+    {token.NOT_EQ, "!=", 2, 4},
+    {token.IDENT, "café", 2, 7},
+    {token.PLUS, "+", 2, 11},
+    {token.INT, "1", 2, 12},
+    {token.SEMICOLON, ";", 2, 13},
+    {token.IDENT, "_ö", 3, 1},
+    {token.EOF, "", 3, 3},
+  }
+
+  // the streaming lexer must not split a char between two reads
+  lexers := map[string]*Lexer{
+    "New":       New(input),
+    "NewReader": NewReader(iotest.OneByteReader(strings.NewReader(input))),
+  }
+
+  for name, l := range lexers {
+    for i, tt := range tests {
+      tok := l.NextToken()
+
+      if tok.Type != tt.expectedType || tok.Literal != tt.expectedLiteral {
+        t.Fatalf("%s: tests[%d] - token wrong. expected=%q %q, got=%q %q",
+          name, i, tt.expectedType, tt.expectedLiteral, tok.Type, tok.Literal)
+      }
+
+      if tok.Line != tt.expectedLine || tok.Column != tt.expectedColumn {
+        t.Fatalf("%s: tests[%d] - position of %q wrong. expected=%d:%d, got=%d:%d",
+          name, i, tok.Literal, tt.expectedLine, tt.expectedColumn, tok.Line, tok.Column)
+      }
+    }
+  }
+}
+
+// non-letter symbols are still illegal, whole runes at a time
+func TestUnicodeIllegal(t *testing.T) {
+  l := New("a → b")
+
+  expected := []token.Token{
+    {Type: token.IDENT, Literal: "a", Line: 1, Column: 1},
+    {Type: token.ILLEGAL, Literal: "→", Line: 1, Column: 3},
+    {Type: token.IDENT, Literal: "b", Line: 1, Column: 5},
+  }
+
+  for i, tt := range expected {
+    if tok := l.NextToken(); tok != tt {
+      t.Errorf("tests[%d] - wrong token. expected=%+v, got=%+v", i, tt, tok)
+    }
+  }
+}
+
+func TestTemplateStrings(t *testing.T) {
+  input := `"Hello ${name}, you are ${age + 1} years old" "plain $ {x}" "${f("}")}!" "a ${b`
+
+  expected := []token.Token{
+    {Type: token.TEMPLATE, Literal: "Hello ${name}, you are ${age + 1} years old", Line: 1, Column: 1},
+    {Type: token.STRING, Literal: "plain $ {x}", Line: 1, Column: 47},
+    {Type: token.TEMPLATE, Literal: `${f("}")}!`, Line: 1, Column: 61},
+    // an unclosed ${ runs to the end of the input
+    {Type: token.TEMPLATE, Literal: "a ${b", Line: 1, Column: 74},
+    {Type: token.EOF, Literal: "", Line: 1, Column: 81},
+  }
+
+  l := New(input)
+  for i, tt := range expected {
+    if tok := l.NextToken(); tok != tt {
+      t.Errorf("tests[%d] - wrong token. expected=%+v, got=%+v", i, tt, tok)
+    }
+  }
+}
+
+func TestSplitTemplate(t *testing.T) {
+  tests := []struct {
+    literal       string
+    expectedTexts []string
+    expectedExprs []string
+    expectedOk    bool
+  }{
+    {"Hello ${name}, you are ${age} years old", []string{"Hello ", ", you are ", " years old"}, []string{"name", "age"}, true},
+    {"${a}${b}", []string{"", "", ""}, []string{"a", "b"}, true},
+    {`${ {"a": 1}["a"] } and ${f("}")}`, []string{"", " and ", ""}, []string{` {"a": 1}["a"] `, `f("}")`}, true},
+    {"no braces $x {y}", []string{"no braces $x {y}"}, nil, true},
+    {"a ${b", nil, nil, false},
+    {"a ${ {b }", nil, nil, false},
+  }
+
+  for _, tt := range tests {
+    texts, exprs, ok := SplitTemplate(tt.literal)
+    if ok != tt.expectedOk || !reflect.DeepEqual(texts, tt.expectedTexts) || !reflect.DeepEqual(exprs, tt.expectedExprs) {
+      t.Errorf("SplitTemplate(%q) wrong. expected=%q %q %t, got=%q %q %t",
+        tt.literal, tt.expectedTexts, tt.expectedExprs, tt.expectedOk, texts, exprs, ok)
+    }
+  }
+}
+
+func TestTokens(t *testing.T) {
+  input := `let add = fn(x, ...rest) { x + rest[0] };
+add(1, 2) != {"a": [true]}`
+
+  expected := []token.Token{}
+  l := New(input)
+  for {
+    tok := l.NextToken()
+    expected = append(expected, tok)
+    if tok.Type == token.EOF {
+      break
+    }
+  }
+
+  l = New(input)
+  tokens := l.Tokens()
+  if len(tokens) != len(expected) {
+    t.Fatalf("wrong number of tokens. expected=%d, got=%d", len(expected), len(tokens))
+  }
+  for i := range expected {
+    if tokens[i] != expected[i] {
+      t.Errorf("tokens[%d] wrong. expected=%+v, got=%+v", i, expected[i], tokens[i])
+    }
+  }
+
+  // exhausted, only EOF is left
+  if rest := l.Tokens(); len(rest) != 1 || rest[0].Type != token.EOF {
+    t.Errorf("expected only EOF after Tokens, got=%+v", rest)
+  }
+}
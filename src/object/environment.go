@@ -0,0 +1,93 @@
+package object
+
+import (
+  "fmt"
+  "sort"
+)
+
+// Environment binds identifiers to values
+//
+//   outer env: { a: 1 }
+//       ^
+//   inner env: { b: 2 }  <- lookup `a` falls through to outer
+type Environment struct {
+  store    map[string]Object
+  consts   map[string]bool // names in store bound by const
+  outer    *Environment
+  settings *Settings
+}
+
+func NewEnvironment() *Environment {
+  return newScope(nil, NewSettings())
+}
+
+// used by function calls and if/while blocks, each body gets its own scope
+func NewEnclosedEnvironment(outer *Environment) *Environment {
+  return newScope(outer, outer.settings)
+}
+
+func newScope(outer *Environment, settings *Settings) *Environment {
+  s := make(map[string]Object)
+  c := make(map[string]bool)
+  return &Environment{store: s, consts: c, outer: outer, settings: settings}
+}
+
+// the settings of the evaluation in e, changing them changes them
+// for every scope enclosed by the same outermost environment
+func (e *Environment) Settings() *Settings {
+  return e.settings
+}
+
+func (e *Environment) Get(name string) (Object, bool) {
+  obj, ok := e.store[name]
+  if !ok && e.outer != nil {
+    obj, ok = e.outer.Get(name)
+  }
+  return obj, ok
+}
+
+// names bound in this scope (not the outer ones), sorted
+func (e *Environment) Names() []string {
+  names := make([]string, 0, len(e.store))
+  for name := range e.store {
+    names = append(names, name)
+  }
+  sort.Strings(names)
+  return names
+}
+
+func (e *Environment) Set(name string, val Object) Object {
+  e.store[name] = val
+  return val
+}
+
+// bind name in this scope for let (constant false) or const (constant true),
+// a constant of this scope can't be declared again,
+// shadowing it in an enclosed scope is fine
+func (e *Environment) Declare(name string, val Object, constant bool) error {
+  if e.consts[name] {
+    return fmt.Errorf("cannot assign to constant: %s", name)
+  }
+
+  e.store[name] = val
+  if constant {
+    e.consts[name] = true
+  }
+  return nil
+}
+
+// rebind an existing name in the scope that defines it,
+// an error if the name is not bound anywhere or is a constant
+func (e *Environment) Assign(name string, val Object) error {
+  if _, ok := e.store[name]; ok {
+    if e.consts[name] {
+      return fmt.Errorf("cannot assign to constant: %s", name)
+    }
+    e.store[name] = val
+    return nil
+  }
+  if e.outer != nil {
+    return e.outer.Assign(name, val)
+  }
+  return fmt.Errorf("identifier not found: %s", name)
+}
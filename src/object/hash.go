@@ -0,0 +1,92 @@
+package object
+
+import (
+  "hash/fnv"
+)
+
+// the map key of a Hash, equal values give equal keys,
+// eg: two different "name" String objects
+type HashKey struct {
+  Type  ObjectType
+  Value uint64
+}
+
+// objects that can be used as hash keys implement this
+type Hashable interface {
+  HashKey() HashKey
+}
+
+func (i *Integer) HashKey() HashKey {
+  return HashKey{Type: i.Type(), Value: uint64(i.Value)}
+}
+
+func (bi *BigInteger) HashKey() HashKey {
+  h := fnv.New64a()
+  h.Write(bi.Value.Bytes())
+  if bi.Value.Sign() < 0 {
+    h.Write([]byte{'-'})
+  }
+
+  return HashKey{Type: bi.Type(), Value: h.Sum64()}
+}
+
+func (b *Boolean) HashKey() HashKey {
+  var value uint64
+
+  if b.Value {
+    value = 1
+  } else {
+    value = 0
+  }
+
+  return HashKey{Type: b.Type(), Value: value}
+}
+
+func (s *String) HashKey() HashKey {
+  h := fnv.New64a()
+  h.Write([]byte(s.Value))
+
+  return HashKey{Type: s.Type(), Value: h.Sum64()}
+}
+
+// keep the original key object, so it can be printed
+type HashPair struct {
+  Key   Object
+  Value Object
+}
+
+// eg: {"one": 1, true: 2}
+type Hash struct {
+  Pairs map[HashKey]HashPair
+  // keys in insertion order, so iteration and Inspect() are stable
+  Order []HashKey
+}
+
+func NewHash() *Hash {
+  return &Hash{Pairs: make(map[HashKey]HashPair)}
+}
+
+func (h *Hash) Get(key HashKey) (HashPair, bool) {
+  pair, ok := h.Pairs[key]
+  return pair, ok
+}
+
+// a new key goes to the end, an existing key keeps its place
+func (h *Hash) Set(key HashKey, pair HashPair) {
+  if _, ok := h.Pairs[key]; !ok {
+    h.Order = append(h.Order, key)
+  }
+  h.Pairs[key] = pair
+}
+
+// pairs in insertion order
+func (h *Hash) OrderedPairs() []HashPair {
+  pairs := make([]HashPair, 0, len(h.Order))
+  for _, key := range h.Order {
+    pairs = append(pairs, h.Pairs[key])
+  }
+  return pairs
+}
+
+func (h *Hash) Type() ObjectType { return HASH_OBJ }
+func (h *Hash) Inspect() string { return inspect(h, map[Object]bool{}) }
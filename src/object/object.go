@@ -0,0 +1,247 @@
+package object
+
+import (
+  "JFFMonkeyLang/src/ast"
+  "bytes"
+  "fmt"
+  "math/big"
+  "strings"
+)
+
+type ObjectType string
+
+const (
+  INTEGER_OBJ      = "INTEGER"
+  BIG_INTEGER_OBJ  = "BIG_INTEGER"
+  BOOLEAN_OBJ      = "BOOLEAN"
+  NULL_OBJ         = "NULL"
+  RETURN_VALUE_OBJ = "RETURN_VALUE"
+  ERROR_OBJ        = "ERROR"
+  FUNCTION_OBJ     = "FUNCTION"
+  STRING_OBJ       = "STRING"
+  BUILTIN_OBJ      = "BUILTIN"
+  ARRAY_OBJ        = "ARRAY"
+  HASH_OBJ         = "HASH"
+  QUOTE_OBJ        = "QUOTE"
+  MACRO_OBJ        = "MACRO"
+  LAZY_OBJ         = "LAZY"
+)
+
+// Every value in monkeyLang implements this
+type Object interface {
+  Type() ObjectType
+  Inspect() string // print value for repl
+}
+
+// eg: 5
+type Integer struct {
+  Value int64
+}
+
+func (i *Integer) Type() ObjectType { return INTEGER_OBJ }
+func (i *Integer) Inspect() string  { return fmt.Sprintf("%d", i.Value) }
+
+// eg: 100 ** 100, only for values that don't fit an Integer
+type BigInteger struct {
+  Value *big.Int
+}
+
+func (bi *BigInteger) Type() ObjectType { return BIG_INTEGER_OBJ }
+func (bi *BigInteger) Inspect() string  { return bi.Value.String() }
+
+// eg: true, false
+type Boolean struct {
+  Value bool
+}
+
+func (b *Boolean) Type() ObjectType { return BOOLEAN_OBJ }
+func (b *Boolean) Inspect() string  { return fmt.Sprintf("%t", b.Value) }
+
+// the absence of a value
+type Null struct{}
+
+func (n *Null) Type() ObjectType { return NULL_OBJ }
+func (n *Null) Inspect() string  { return "null" }
+
+// wraps the value of a `return` statement,
+// so that evaluation can stop at the outermost block
+type ReturnValue struct {
+  Value Object
+}
+
+func (rv *ReturnValue) Type() ObjectType { return RETURN_VALUE_OBJ }
+func (rv *ReturnValue) Inspect() string  { return rv.Value.Inspect() }
+
+// runtime error, eg: type mismatch: INTEGER + BOOLEAN
+type Error struct {
+  Message string
+  // names of the functions the error came up through, innermost first
+  Trace []string
+}
+
+func (e *Error) Type() ObjectType { return ERROR_OBJ }
+
+// eg:
+//   ERROR: type mismatch: INTEGER + BOOLEAN
+//     in function add
+//     in function loop (3 times)
+func (e *Error) Inspect() string {
+  var out bytes.Buffer
+
+  out.WriteString("ERROR: " + e.Message)
+  for i := 0; i < len(e.Trace); {
+    // a recursion shows up once
+    j := i + 1
+    for j < len(e.Trace) && e.Trace[j] == e.Trace[i] {
+      j++
+    }
+
+    out.WriteString("\n  in function " + e.Trace[i])
+    if j-i > 1 {
+      out.WriteString(fmt.Sprintf(" (%d times)", j-i))
+    }
+    i = j
+  }
+
+  return out.String()
+}
+
+// eg: fn(x, y) { x + y; }
+// Env is the environment the function was defined in (closure)
+type Function struct {
+  Name       string // of the let or const that bound it, "" if there was none
+  Parameters []*ast.Identifier
+  Defaults   []ast.Expression // evaluated at call time, nil if a parameter has none
+  Rest       bool             // the last parameter collects the remaining arguments
+  Body       *ast.BlockStatement
+  Env        *Environment
+}
+
+func (f *Function) Type() ObjectType { return FUNCTION_OBJ }
+
+// Name, or <anonymous> for a function that was never bound
+func (f *Function) DisplayName() string {
+  if f.Name == "" {
+    return "<anonymous>"
+  }
+  return f.Name
+}
+
+func (f *Function) Inspect() string {
+  var out bytes.Buffer
+
+  out.WriteString("fn")
+  if f.Name != "" {
+    out.WriteString(" " + f.Name)
+  }
+  out.WriteString("(")
+  out.WriteString(ast.ParametersString(f.Parameters, f.Defaults, f.Rest))
+  out.WriteString(") {\n")
+  out.WriteString(f.Body.String())
+  out.WriteString("\n}")
+
+  return out.String()
+}
+
+// eg: "hello"
+type String struct {
+  Value string
+}
+
+func (s *String) Type() ObjectType { return STRING_OBJ }
+func (s *String) Inspect() string  { return s.Value }
+
+// functions implemented in go, eg: type(5)
+type BuiltinFunction func(args ...Object) Object
+
+type Builtin struct {
+  Fn BuiltinFunction
+}
+
+func (b *Builtin) Type() ObjectType { return BUILTIN_OBJ }
+func (b *Builtin) Inspect() string  { return "builtin function" }
+
+// a sequence whose elements are only computed when asked for,
+// so it can be infinite, eg: lazy_range(0, 1)
+type Lazy struct {
+  // starts a new pass over the sequence, every call of next gives the
+  // following element, ok is false once the sequence has ended
+  Iterate func() (next func() (element Object, ok bool))
+}
+
+func (l *Lazy) Type() ObjectType { return LAZY_OBJ }
+func (l *Lazy) Inspect() string  { return "lazy sequence" }
+
+// eg: [1, "two", fn(x) { x }]
+type Array struct {
+  Elements []Object
+}
+
+func (ao *Array) Type() ObjectType { return ARRAY_OBJ }
+func (ao *Array) Inspect() string { return inspect(ao, map[Object]bool{}) }
+
+// arrays and hashes are mutable, so they can contain themselves,
+// eg: let a = [1]; a[0] = a; prints [[...]]
+// inside holds the arrays and hashes being printed around obj
+func inspect(obj Object, inside map[Object]bool) string {
+  switch obj := obj.(type) {
+  case *Array:
+    if inside[obj] {
+      return "[...]"
+    }
+    inside[obj] = true
+    defer delete(inside, obj)
+
+    elements := []string{}
+    for _, e := range obj.Elements {
+      elements = append(elements, inspect(e, inside))
+    }
+    return "[" + strings.Join(elements, ", ") + "]"
+
+  case *Hash:
+    if inside[obj] {
+      return "{...}"
+    }
+    inside[obj] = true
+    defer delete(inside, obj)
+
+    pairs := []string{}
+    for _, pair := range obj.OrderedPairs() {
+      pairs = append(pairs, inspect(pair.Key, inside)+": "+inspect(pair.Value, inside))
+    }
+    return "{" + strings.Join(pairs, ", ") + "}"
+
+  default:
+    return obj.Inspect()
+  }
+}
+
+// the unevaluated ast of quote(...), eg: quote(1 + 2) => QUOTE((1 + 2))
+type Quote struct {
+  Node ast.Node
+}
+
+func (q *Quote) Type() ObjectType { return QUOTE_OBJ }
+func (q *Quote) Inspect() string  { return "QUOTE(" + q.Node.String() + ")" }
+
+// eg: macro(x) { quote(unquote(x) + 1) }
+// only lives in the macro env, calls to it are expanded before evaluation
+type Macro struct {
+  Parameters []*ast.Identifier
+  Body       *ast.BlockStatement
+  Env        *Environment
+}
+
+func (m *Macro) Type() ObjectType { return MACRO_OBJ }
+func (m *Macro) Inspect() string {
+  var out bytes.Buffer
+
+  out.WriteString("macro")
+  out.WriteString("(")
+  out.WriteString(ast.ParametersString(m.Parameters, nil, false))
+  out.WriteString(") {\n")
+  out.WriteString(m.Body.String())
+  out.WriteString("\n}")
+
+  return out.String()
+}
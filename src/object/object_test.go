@@ -0,0 +1,80 @@
+package object
+
+import "testing"
+
+func TestStringHashKey(t *testing.T) {
+  hello1 := &String{Value: "Hello World"}
+  hello2 := &String{Value: "Hello World"}
+  diff1 := &String{Value: "My name is johnny"}
+  diff2 := &String{Value: "My name is johnny"}
+
+  if hello1.HashKey() != hello2.HashKey() {
+    t.Errorf("strings with same content have different hash keys")
+  }
+
+  if diff1.HashKey() != diff2.HashKey() {
+    t.Errorf("strings with same content have different hash keys")
+  }
+
+  if hello1.HashKey() == diff1.HashKey() {
+    t.Errorf("strings with different content have same hash keys")
+  }
+}
+
+func TestHashKeyTypes(t *testing.T) {
+  one := &Integer{Value: 1}
+  yes := &Boolean{Value: true}
+
+  if one.HashKey() == yes.HashKey() {
+    t.Errorf("1 and true have the same hash key")
+  }
+}
+
+func TestHashOrder(t *testing.T) {
+  hash := NewHash()
+
+  b := &String{Value: "b"}
+  a := &String{Value: "a"}
+  hash.Set(b.HashKey(), HashPair{Key: b, Value: &Integer{Value: 1}})
+  hash.Set(a.HashKey(), HashPair{Key: a, Value: &Integer{Value: 2}})
+  // overwriting keeps the original position
+  hash.Set(b.HashKey(), HashPair{Key: b, Value: &Integer{Value: 3}})
+
+  expected := "{b: 3, a: 2}"
+  if hash.Inspect() != expected {
+    t.Errorf("hash.Inspect() wrong. want=%q, got=%q", expected, hash.Inspect())
+  }
+}
+
+func TestInspectCycles(t *testing.T) {
+  // let a = [1]; a[0] = a
+  array := &Array{Elements: []Object{&Integer{Value: 1}}}
+  array.Elements[0] = array
+
+  // let h = {}; h["self"] = h; h["list"] = [h]
+  hash := NewHash()
+  self := &String{Value: "self"}
+  list := &String{Value: "list"}
+  hash.Set(self.HashKey(), HashPair{Key: self, Value: hash})
+  hash.Set(list.HashKey(), HashPair{Key: list, Value: &Array{Elements: []Object{hash}}})
+
+  // the same array twice is not a cycle
+  shared := &Array{Elements: []Object{&Integer{Value: 1}}}
+  twice := &Array{Elements: []Object{shared, shared}}
+
+  tests := []struct {
+    obj      Object
+    expected string
+  }{
+    {array, "[[...]]"},
+    {hash, "{self: {...}, list: [{...}]}"},
+    {&Array{Elements: []Object{array}}, "[[[...]]]"},
+    {twice, "[[1], [1]]"},
+  }
+
+  for _, tt := range tests {
+    if actual := tt.obj.Inspect(); actual != tt.expected {
+      t.Errorf("Inspect() wrong. want=%q, got=%q", tt.expected, actual)
+    }
+  }
+}
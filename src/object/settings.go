@@ -0,0 +1,73 @@
+package object
+
+import (
+  "context"
+  "math/rand"
+  "time"
+)
+
+// Settings belong to one evaluation, an environment shares them with every
+// scope enclosed by it, so evaluations in other environments, eg: on the
+// other goroutines of a server, each run with their own
+type Settings struct {
+  // checked at every loop iteration and function call,
+  // a cancelled evaluation stops promptly
+  Context context.Context
+  // builtins reaching outside the program are disabled, for untrusted code,
+  // eg: read_file gives "builtin disabled in sandbox: read_file"
+  Sandbox bool
+  // how deeply monkey functions may call each other, a runaway
+  // recursion returns an error instead of crashing the go stack
+  MaxCallDepth int
+  // monkey function calls in progress, kept by the evaluator
+  CallDepth int
+  // caps the operations of the evaluation, nil for no limit
+  Budget *Budget
+  // a function body that runs off its end is an error,
+  // eg: fn(x) { if (x) { return 1 } } gives NULL for a false x otherwise
+  Strict bool
+  // what the time and sleep builtins go by, tests swap in a fake one
+  Clock Clock
+  // where rand takes its numbers from, eg: rand.New(rand.NewSource(42))
+  // repeats the same ones every run, nil for a source seeded from the time
+  Rand *rand.Rand
+}
+
+// settings of an evaluation nothing was configured for
+func NewSettings() *Settings {
+  return &Settings{Context: context.Background(), MaxCallDepth: 1000, Clock: SystemClock}
+}
+
+// Budget caps how many operations one evaluation may run: every visited
+// ast node is one, and so is every element or byte a program builds,
+// eg: range(1000) costs 1000, so a budget bounds memory as well as time
+type Budget struct {
+  Max  int // 0 means unlimited
+  Used int
+}
+
+// Clock tells the time of an evaluation
+type Clock interface {
+  Now() time.Time
+  // returns ctx's error if ctx is done before d has passed
+  Sleep(ctx context.Context, d time.Duration) error
+}
+
+// the real time
+var SystemClock Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+func (systemClock) Sleep(ctx context.Context, d time.Duration) error {
+  timer := time.NewTimer(d)
+  defer timer.Stop()
+
+  select {
+  case <-timer.C:
+    return nil
+  case <-ctx.Done():
+    return ctx.Err()
+  }
+}
@@ -0,0 +1,114 @@
+package optimize
+
+import "JFFMonkeyLang/src/ast"
+
+// DeadCode drops code that can never run, eg:
+//
+//   if (true) { a } else { b }   =>  a
+//   if (false) { a }             =>  (nothing)
+//   return x; y;                 =>  return x;
+//
+// it only fires for conditions that are boolean literals, anything
+// computed at runtime is left alone
+func DeadCode(node ast.Node) ast.Node {
+  return ast.Modify(node, func(node ast.Node) ast.Node {
+    switch node := node.(type) {
+    case *ast.Program:
+      node.Statements = eliminate(node.Statements)
+    case *ast.BlockStatement:
+      node.Statements = eliminate(node.Statements)
+    case *ast.IfExpression:
+      // eg: let x = if (true) { 1 } else { 2 };
+      if taken, ok := takenBranch(node); ok {
+        if expression, ok := singleExpression(taken); ok {
+          return expression
+        }
+      }
+    }
+    return node
+  })
+}
+
+// statements with constant ifs spliced in and nothing after a return or throw
+func eliminate(statements []ast.Statement) []ast.Statement {
+  out := []ast.Statement{}
+
+  for i, statement := range statements {
+    if replacement, ok := spliceIf(statement, i == len(statements)-1); ok {
+      out = append(out, replacement...)
+    } else {
+      out = append(out, statement)
+    }
+
+    if len(out) > 0 {
+      switch out[len(out)-1].(type) {
+      case *ast.ReturnStatement, *ast.ThrowStatement:
+        return out
+      }
+    }
+  }
+
+  return out
+}
+
+// the statements of the branch a constant if statement always takes
+func spliceIf(statement ast.Statement, last bool) ([]ast.Statement, bool) {
+  es, ok := statement.(*ast.ExpressionStatement)
+  if !ok {
+    return nil, false
+  }
+  ie, ok := es.Expression.(*ast.IfExpression)
+  if !ok {
+    return nil, false
+  }
+  taken, ok := takenBranch(ie)
+  if !ok {
+    return nil, false
+  }
+
+  // 1.nothing runs, but as the last statement the if still gives null
+  if taken == nil {
+    return nil, !last
+  }
+
+  // 2.the branch has its own scope, splicing a let would leak it
+  for _, s := range taken.Statements {
+    switch s.(type) {
+    case *ast.LetStatement, *ast.ConstStatement, *ast.DestructureStatement:
+      return nil, false
+    }
+  }
+
+  // 3.an empty branch is null too
+  if len(taken.Statements) == 0 && last {
+    return nil, false
+  }
+
+  return taken.Statements, true
+}
+
+// nil block means the if has no else and the condition is false
+func takenBranch(ie *ast.IfExpression) (*ast.BlockStatement, bool) {
+  condition, ok := ie.Condition.(*ast.Boolean)
+  if !ok {
+    return nil, false
+  }
+
+  if condition.Value {
+    return ie.Consequence, true
+  }
+  return ie.Alternative, true
+}
+
+// eg: { 1 } gives 1
+func singleExpression(block *ast.BlockStatement) (ast.Expression, bool) {
+  if block == nil || len(block.Statements) != 1 {
+    return nil, false
+  }
+
+  es, ok := block.Statements[0].(*ast.ExpressionStatement)
+  if !ok {
+    return nil, false
+  }
+  return es.Expression, true
+}
@@ -0,0 +1,55 @@
+package optimize
+
+import (
+  "JFFMonkeyLang/src/ast"
+  "JFFMonkeyLang/src/format"
+  "JFFMonkeyLang/src/lexer"
+  "JFFMonkeyLang/src/parser"
+  "testing"
+)
+
+func TestDeadCode(t *testing.T) {
+  tests := []struct {
+    input    string
+    expected string
+  }{
+    // constant conditions pick a branch
+    {"if (true) { a; b } else { c; d }; e", "a; b; e"},
+    {"if (false) { a; b } else { c; d }; e", "c; d; e"},
+    {"if (false) { a }; e", "e"},
+    {"let x = if (true) { 1 } else { 2 };", "let x = 1;"},
+    {"let x = if (false) { 1 } else { 2 };", "let x = 2;"},
+    {"fn() { if (true) { if (false) { a } else { b; c } } }", "fn() { b; c }"},
+    // statements after a return never run
+    {"fn() { a; return b; c; d }", "fn() { a; return b; }"},
+    {"return a; b", "return a;"},
+    {"fn() { if (true) { return a; b } c }", "fn() { return a; }"},
+    {"fn() { throw a; b }", "fn() { throw a; }"},
+    // non-constant conditions stay
+    {"if (x) { a } else { b }", "if (x) { a } else { b }"},
+    {"if (1 < 2) { a } else { b }", "if (1 < 2) { a } else { b }"},
+    {"if (!true) { a }; e", "if (!true) { a }; e"},
+    // the if still gives null as the last statement
+    {"e; if (false) { a }", "e; if (false) { a }"},
+    {"e; if (true) { }", "e; if (true) { }"},
+    // the branch scope keeps its bindings
+    {"if (true) { let a = 1; a; b }", "if (true) { let a = 1; a; b }"},
+  }
+
+  for _, tt := range tests {
+    actual := format.Source(DeadCode(testParse(t, tt.input)).(*ast.Program))
+    expected := format.Source(testParse(t, tt.expected))
+    if actual != expected {
+      t.Errorf("wrong result for %q. expected=\n%s\ngot=\n%s", tt.input, expected, actual)
+    }
+  }
+}
+
+func testParse(t *testing.T, input string) *ast.Program {
+  p := parser.New(lexer.New(input))
+  program := p.ParseProgram()
+  if len(p.Errors()) != 0 {
+    t.Fatalf("parser errors for %q: %v", input, p.Errors())
+  }
+  return program
+}
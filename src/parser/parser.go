@@ -4,22 +4,38 @@ import (
   "JFFMonkeyLang/src/ast"
   "JFFMonkeyLang/src/lexer"
   "JFFMonkeyLang/src/token"
+  "errors"
   "fmt"
+  "math/big"
   "strconv"
+  "strings"
 )
 
 const (
   _ int = iota
   LOWEST
+  ASSIGN      // =
+  PIPE        // |>
+  NULLISH     // ??
+  LOGICAL_OR  // ||
+  LOGICAL_AND // &&
   EQUALS      // ==
   LESSGREATER // > or <
   SUM         // +
   PRODUCT     // *
+  POWER       // **
   PREFIX      // -X or !X
   CALL        // myFunction(X)
+  INDEX       // array[index]
 )
 
+// the default table, every Parser starts from a copy of it
 var precedences = map[token.TokenType]int{
+  token.ASSIGN:   ASSIGN,
+  token.PIPE:     PIPE,
+  token.NULLISH:  NULLISH,
+  token.OR:       LOGICAL_OR,
+  token.AND:      LOGICAL_AND,
   token.EQ:       EQUALS,
   token.NOT_EQ:   EQUALS,
   token.LT:       LESSGREATER,
@@ -27,8 +43,13 @@ var precedences = map[token.TokenType]int{
   token.PLUS:     SUM,
   token.MINUS:    SUM,
   token.SLASH:    PRODUCT,
+  token.PERCENT:  PRODUCT,
   token.ASTERISK: PRODUCT,
+  token.POWER:    POWER,
   token.LPAREN:   CALL,
+  token.LBRACKET: INDEX,
+  token.QDOT:     INDEX,
+  token.DOT:      INDEX,
 }
 
 type (
@@ -36,9 +57,43 @@ type (
   infixParseFn  func(ast.Expression) ast.Expression
 )
 
+// parse functions supplied from outside the package, eg: by WithInfix
+type (
+  PrefixParseFn func(p *Parser) ast.Expression
+  InfixParseFn  func(p *Parser, left ast.Expression) ast.Expression
+)
+
+// Option customizes a Parser built by New
+type Option func(*Parser)
+
+// parse tokens of type t in prefix position with fn,
+// replacing the default if there is one
+func WithPrefix(t token.TokenType, fn PrefixParseFn) Option {
+  return func(p *Parser) {
+    p.registerPrefix(t, func() ast.Expression { return fn(p) })
+  }
+}
+
+// parse tokens of type t in infix position with fn, binding with precedence,
+// eg: treat ':' as an operator between SUM and PRODUCT
+//
+//   parser.New(l, parser.WithInfix(token.COLON, parser.SUM, parser.ParseBinary))
+func WithInfix(t token.TokenType, precedence int, fn InfixParseFn) Option {
+  return func(p *Parser) {
+    p.precedences[t] = precedence
+    p.registerInfix(t, func(left ast.Expression) ast.Expression { return fn(p, left) })
+  }
+}
+
+// ParseBinary is the InfixParseFn of the built-in operators, eg: 1 + 2,
+// curToken is the operator and the right side binds by its precedence
+func ParseBinary(p *Parser, left ast.Expression) ast.Expression {
+  return p.parseInfixExpression(left)
+}
+
 type Parser struct {
   l      *lexer.Lexer
-  errors []string
+  errors ParseErrors
 
   curToken  token.Token
   peekToken token.Token
@@ -48,33 +103,65 @@ type Parser struct {
   //           └-> infixParseFn
   prefixParseFns map[token.TokenType]prefixParseFn
   infixParseFns  map[token.TokenType]infixParseFn
+  // binding power of infix tokens, the defaults plus WithInfix ones
+  precedences map[token.TokenType]int
 }
 
-func New(l *lexer.Lexer) *Parser {
+func New(l *lexer.Lexer, opts ...Option) *Parser {
   p := &Parser{l: l}
 
+  p.precedences = make(map[token.TokenType]int, len(precedences))
+  for t, precedence := range precedences {
+    p.precedences[t] = precedence
+  }
+
   p.prefixParseFns = make(map[token.TokenType]prefixParseFn)
   p.registerPrefix(token.IDENT, p.parseIdentifier)         // eg: foo
   p.registerPrefix(token.INT, p.parseIntegerLiteral)       // eg: 5
+  p.registerPrefix(token.STRING, p.parseStringLiteral)     // eg: "foo"
+  p.registerPrefix(token.TEMPLATE, p.parseTemplateLiteral) // eg: "foo ${bar}"
   p.registerPrefix(token.BANG, p.parsePrefixExpression)    // eg: "!5"
   p.registerPrefix(token.MINUS, p.parsePrefixExpression)   // eg: "-5"
   p.registerPrefix(token.TRUE, p.parseBoolean)             // eg: true
   p.registerPrefix(token.FALSE, p.parseBoolean)            // eg: false
   p.registerPrefix(token.LPAREN, p.parseGroupedExpression) // eg: (
   p.registerPrefix(token.IF, p.parseIfExpression)          // eg: if
+  p.registerPrefix(token.WHILE, p.parseWhileExpression)    // eg: while
+  p.registerPrefix(token.DO, p.parseDoWhileExpression)     // eg: do { } while
+  p.registerPrefix(token.FOR, p.parseForExpression)        // eg: for (;;) { }
+  p.registerPrefix(token.MATCH, p.parseMatchExpression)    // eg: match x { }
+  p.registerPrefix(token.TRY, p.parseTryExpression)        // eg: try { } catch (e) { }
   p.registerPrefix(token.FUNCTION, p.parseFunctionLiteral) // eg: fn() { return foo; }
+  p.registerPrefix(token.MACRO, p.parseMacroLiteral)       // eg: macro(x) { quote(x) }
+  p.registerPrefix(token.LBRACKET, p.parseArrayLiteral)    // eg: [1, 2]
+  p.registerPrefix(token.LBRACE, p.parseHashLiteral)       // eg: {"a": 1}
+  p.registerPrefix(token.ILLEGAL, p.parseIllegal)          // eg: 5_
 
   p.infixParseFns = make(map[token.TokenType]infixParseFn)
   p.registerInfix(token.PLUS, p.parseInfixExpression)     // 1 + 1
   p.registerInfix(token.MINUS, p.parseInfixExpression)    // 1 - 1
   p.registerInfix(token.SLASH, p.parseInfixExpression)    // 1 / 1
   p.registerInfix(token.ASTERISK, p.parseInfixExpression) // 1 + 1
+  p.registerInfix(token.PERCENT, p.parseInfixExpression)  // 1 % 1
+  p.registerInfix(token.POWER, p.parseInfixExpression)    // 2 ** 10
   p.registerInfix(token.EQ, p.parseInfixExpression)       // 1 == 1
   p.registerInfix(token.NOT_EQ, p.parseInfixExpression)   // 1 != 1
   p.registerInfix(token.LT, p.parseInfixExpression)       // 1 < 1
   p.registerInfix(token.GT, p.parseInfixExpression)       // 1 > 1
-
-  p.registerInfix(token.LPAREN, p.parseCallExpression) // add(1, 2)
+  p.registerInfix(token.AND, p.parseInfixExpression)      // a && b
+  p.registerInfix(token.OR, p.parseInfixExpression)       // a || b
+  p.registerInfix(token.NULLISH, p.parseInfixExpression)  // a ?? b
+
+  p.registerInfix(token.ASSIGN, p.parseAssignExpression)      // x = 1, h["a"] = 1
+  p.registerInfix(token.PIPE, p.parsePipeExpression)          // x |> f
+  p.registerInfix(token.LPAREN, p.parseCallExpression)        // add(1, 2)
+  p.registerInfix(token.LBRACKET, p.parseIndexExpression)     // "foo"[1]
+  p.registerInfix(token.QDOT, p.parseOptionalIndexExpression) // a?.[0], h?.key
+  p.registerInfix(token.DOT, p.parseMethodCallExpression)     // [1, 2].len()
+
+  for _, opt := range opts {
+    opt(p)
+  }
 
   // Read two tokens, so curToken and peekToken are both set
   p.nextToken()
@@ -91,7 +178,7 @@ func (p *Parser) ParseProgram() *ast.Program {
   // 2. Parse the token continuously and identify the statement
   // until the end of the file
   for !p.curTokenIs(token.EOF) {
-    stmt := p.parseStatement()
+    stmt := p.parseStatementWithRecovery()
     if stmt != nil {
       program.Statements = append(program.Statements, stmt)
     }
@@ -101,18 +188,142 @@ func (p *Parser) ParseProgram() *ast.Program {
   return program
 }
 
+// parse one statement, if it has errors, drop it
+// and skip to the end of it, so the next statement
+// gets its own independent error message
+func (p *Parser) parseStatementWithRecovery() ast.Statement {
+  errCount := len(p.errors)
+
+  stmt := p.parseStatement()
+  if len(p.errors) > errCount {
+    p.synchronize()
+    return nil
+  }
+
+  return stmt
+}
+
+// let = 5; let y = 6;
+// ....^^^^............
+// skip until curToken is ';', or peekToken is '}',
+// so the caller's nextToken lands on the next statement (or the '}')
+func (p *Parser) synchronize() {
+  for !p.curTokenIs(token.SEMICOLON) &&
+    !p.peekTokenIs(token.RBRACE) &&
+    !p.curTokenIs(token.EOF) {
+    p.nextToken()
+  }
+}
+
+// parse exactly one expression, eg: `1 + 2 * 3`,
+// for callers that don't need full programs (a calculator...)
+func (p *Parser) ParseExpression() (ast.Expression, error) {
+  expression := p.parseExpression(LOWEST)
+
+  // 1.peekToken may be ';', jump to it
+  if p.peekTokenIs(token.SEMICOLON) {
+    p.nextToken()
+  }
+
+  // 2.nothing else may follow
+  // 1 + 2 extra
+  // ......^^^^^
+  if !p.peekTokenIs(token.EOF) {
+    p.peekError(token.EOF)
+  }
+
+  if len(p.errors) != 0 {
+    return nil, errors.New(strings.Join(p.errors.Strings(), "\n"))
+  }
+
+  return expression, nil
+}
+
 /* parse Statements */
 func (p *Parser) parseStatement() ast.Statement {
   switch p.curToken.Type {
   case token.LET:
+    // eg: let [a, b] = pair;
+    if p.peekTokenIs(token.LBRACKET) || p.peekTokenIs(token.LBRACE) {
+      return p.parseDestructureStatement()
+    }
     return p.parseLetStatement()
+  case token.CONST:
+    return p.parseConstStatement()
   case token.RETURN:
     return p.parseReturnStatement()
+  case token.THROW:
+    return p.parseThrowStatement()
+  case token.SEMICOLON:
+    // empty statement, eg: let x = 5;; or a leading ;
+    return nil
   default:
     return p.parseExpressionStatement()
   }
 }
 
+// eg: let [a, ...rest] = list; let {name, age} = person;
+// only an array pattern can have a '...' name, and it must be the last one
+func (p *Parser) parseDestructureStatement() *ast.DestructureStatement {
+  stmt := &ast.DestructureStatement{Token: p.curToken} // token.LET
+
+  // 1.curToken is 'let', peekToken is '[' or '{', jump to it
+  p.nextToken()
+  stmt.Hash = p.curTokenIs(token.LBRACE)
+  end := token.TokenType(token.RBRACKET)
+  if stmt.Hash {
+    end = token.RBRACE
+  }
+
+  // 2.the names, separated by ','
+  // let [a, ...rest] = list;
+  // .....^^^^^^^^^..........
+  for {
+    p.nextToken()
+    if p.curTokenIs(token.ELLIPSIS) && !stmt.Hash {
+      stmt.Rest = true
+      p.nextToken()
+    }
+    if !p.curTokenIs(token.IDENT) {
+      msg := fmt.Sprintf("expected name to destructure into, got %s instead", p.curToken.Type.Name())
+      p.addError(p.curToken, UnexpectedToken, msg)
+      return nil
+    }
+    stmt.Names = append(stmt.Names, &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal})
+
+    if stmt.Rest || !p.peekTokenIs(token.COMMA) {
+      break
+    }
+    p.nextToken()
+  }
+
+  // 3.peekToken must close the pattern, then '='
+  if !p.expectPeek(end) || !p.expectPeek(token.ASSIGN) {
+    return nil
+  }
+
+  // 4.curToken is '=', jump it and parse the value
+  p.nextToken()
+  stmt.Value = p.parseExpression(LOWEST)
+
+  if p.peekTokenIs(token.SEMICOLON) {
+    p.nextToken()
+  }
+
+  return stmt
+}
+
+// eg: const PI = 3;
+// parsed exactly like a let statement
+func (p *Parser) parseConstStatement() *ast.ConstStatement {
+  let := p.parseLetStatement()
+  if let == nil {
+    return nil
+  }
+
+  return &ast.ConstStatement{Token: let.Token, Name: let.Name, Value: let.Value}
+}
+
 func (p *Parser) parseLetStatement() *ast.LetStatement {
   stmt := &ast.LetStatement{Token: p.curToken} // token.LET
 
@@ -120,10 +331,8 @@ func (p *Parser) parseLetStatement() *ast.LetStatement {
   // let a = 1;
   // ....^.....
   if !p.expectPeek(token.IDENT) {
-    // When `nil` is returned here,
-    // ParseProgram will filter and skip the parsing of the statement,
-    // which is equivalent to eating the Error,
-    // a more robust way is to throw an error and terminate the parsing
+    // The error is recorded, parseStatementWithRecovery
+    // drops this statement and skips to the next one
     return nil
   }
 
@@ -178,9 +387,25 @@ func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
   return stmt
 }
 
+// eg: throw "not found";
+func (p *Parser) parseThrowStatement() *ast.ThrowStatement {
+  stmt := &ast.ThrowStatement{Token: p.curToken}
+
+  // 1.curToken is 'throw', jump it
+  p.nextToken()
+  stmt.Value = p.parseExpression(LOWEST)
+
+  // 2.jump the ';'s after it
+  for p.peekTokenIs(token.SEMICOLON) {
+    p.nextToken()
+  }
+
+  return stmt
+}
+
 func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
   // debug print
-  defer untrace(trace("parseExpressionStatement"))
+  defer untrace(trace("parseExpressionStatement", ""))
 
   // 1.build AST node
   stmt := &ast.ExpressionStatement{Token: p.curToken}
@@ -207,7 +432,7 @@ func (p *Parser) parseBlockStatement() *ast.BlockStatement {
   p.nextToken()
 
   for !p.curTokenIs(token.RBRACE) && !p.curTokenIs(token.EOF) {
-    statement := p.parseStatement()
+    statement := p.parseStatementWithRecovery()
     if statement != nil {
       block.Statements = append(block.Statements, statement)
     }
@@ -221,7 +446,7 @@ func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 // !!! Pratt Parsing Core Logic !!!
 func (p *Parser) parseExpression(precedence int) ast.Expression {
   // debug print
-  defer untrace(trace("parseExpression"))
+  defer untrace(trace("parseExpression", ""))
 
   prefixFn := p.prefixParseFns[p.curToken.Type]
 
@@ -258,26 +483,79 @@ func (p *Parser) parseBoolean() ast.Expression {
 // eg: 5;
 func (p *Parser) parseIntegerLiteral() ast.Expression {
   // debug print
-  defer untrace(trace("parseIntegerLiteral " + p.curToken.Literal))
+  defer untrace(trace("parseIntegerLiteral", p.curToken.Literal))
 
   literal := &ast.IntegerLiteral{Token: p.curToken}
 
-  // string to int
-  value, err := strconv.ParseInt(p.curToken.Literal, 0, 64)
-  if err != nil {
+  // string to int, the lexer has already checked the '_' separators
+  // 1_000_000
+  // .^...^...
+  digits := strings.ReplaceAll(p.curToken.Literal, "_", "")
+  value, err := strconv.ParseInt(digits, 0, 64)
+  if err == nil {
+    literal.Value = value
+    return literal
+  }
+
+  // too big for an int64
+  huge, ok := new(big.Int).SetString(digits, 0)
+  if !ok {
     msg := fmt.Sprintf("could not parse %q as integer", p.curToken.Literal)
-    p.errors = append(p.errors, msg)
+    p.addError(p.curToken, BadInteger, msg)
     return nil
   }
-  literal.Value = value
 
-  return literal
+  return &ast.BigIntegerLiteral{Token: p.curToken, Value: huge}
+}
+
+// eg: "foo"
+func (p *Parser) parseStringLiteral() ast.Expression {
+  return &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
+}
+
+// eg: "Hello ${name}!", every ${...} is parsed on its own
+func (p *Parser) parseTemplateLiteral() ast.Expression {
+  template := &ast.TemplateLiteral{Token: p.curToken}
+
+  // 1.cut the texts from the expression sources
+  texts, sources, ok := lexer.SplitTemplate(p.curToken.Literal)
+  if !ok {
+    msg := fmt.Sprintf("unterminated ${ in string %q", p.curToken.Literal)
+    p.addError(p.curToken, BadTemplate, msg)
+    return nil
+  }
+  template.Strings = texts
+  template.Expressions = []ast.Expression{}
+
+  // 2.each source must be exactly one expression,
+  // its errors are reported at the string
+  for _, source := range sources {
+    sub := New(lexer.New(source))
+    exp := sub.parseExpression(LOWEST)
+    if len(sub.errors) == 0 && !sub.peekTokenIs(token.EOF) {
+      msg := fmt.Sprintf("expected } after %s, got %s instead", exp, sub.peekToken.Type.Name())
+      sub.addError(sub.peekToken, UnexpectedToken, msg)
+    }
+    for _, err := range sub.errors {
+      p.addError(p.curToken, err.Kind, fmt.Sprintf("in ${%s}: %s", source, err.Message))
+    }
+    template.Expressions = append(template.Expressions, exp)
+  }
+
+  return template
+}
+
+// eg: 5__0, the lexer could not make sense of it
+func (p *Parser) parseIllegal() ast.Expression {
+  msg := fmt.Sprintf("illegal token %q", p.curToken.Literal)
+  p.addError(p.curToken, IllegalToken, msg)
+  return nil
 }
 
 // eg: !5, -5
 func (p *Parser) parsePrefixExpression() ast.Expression {
   // debug print
-  defer untrace(trace("parsePrefixExpression " + p.curToken.Literal))
+  defer untrace(trace("parsePrefixExpression", p.curToken.Literal))
 
   expression := &ast.PrefixExpression{
     Token:    p.curToken,
@@ -299,7 +577,7 @@ func (p *Parser) parsePrefixExpression() ast.Expression {
 // eg: 1 + 2
 func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
   // debug print
-  defer untrace(trace("parseInfixExpression " + p.curToken.Literal))
+  defer untrace(trace("parseInfixExpression", p.curToken.Literal))
 
   expression := &ast.InfixExpression{
     Token:    p.curToken,         // eg: +
@@ -312,9 +590,72 @@ func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
   // 1.curToken is Infix Token, jump it
   p.nextToken()
 
-  // 2.recursive parsing
+  // 2.recursive parsing, '**' is right associative: 2 ** 3 ** 2 is 2 ** (3 ** 2)
+  if expression.Operator == token.POWER {
+    precedence--
+  }
   expression.Right = p.parseExpression(precedence)
 
+  // 3.`1 < x < 10` would compare a boolean with 10, only the first link of
+  // a longer chain is reported
+  if inner, ok := left.(*ast.InfixExpression); ok && !inner.Grouped &&
+    isComparison(expression.Operator) && isComparison(inner.Operator) && !isComparisonExpression(inner.Left) {
+    msg := fmt.Sprintf("chained comparison %s %s %s %s %s is not supported, compare each pair on its own: %s %s %s and %s %s %s",
+      inner.Left, inner.Operator, inner.Right, expression.Operator, expression.Right,
+      inner.Left, inner.Operator, inner.Right, inner.Right, expression.Operator, expression.Right)
+    p.addError(expression.Token, ChainedComparison, msg)
+  }
+
+  return expression
+}
+
+// relational operators, they give booleans that can't be compared again
+func isComparison(operator string) bool {
+  return operator == "<" || operator == ">"
+}
+
+func isComparisonExpression(exp ast.Expression) bool {
+  infix, ok := exp.(*ast.InfixExpression)
+  return ok && isComparison(infix.Operator)
+}
+
+// eg: h["a"] = 1
+func (p *Parser) parseAssignExpression(target ast.Expression) ast.Expression {
+  // 1.only identifiers and index expressions can be assigned to
+  switch target := target.(type) {
+  case *ast.Identifier:
+  case *ast.IndexExpression:
+    if target.Optional {
+      p.addError(p.curToken, InvalidAssignment, fmt.Sprintf("invalid assignment target: %s", target))
+      return nil
+    }
+  default:
+    p.addError(p.curToken, InvalidAssignment, fmt.Sprintf("invalid assignment target: %s", target))
+    return nil
+  }
+
+  expression := &ast.AssignExpression{Token: p.curToken, Target: target}
+
+  // 2.curToken is '=', jump it
+  // h["a"] = 1
+  // .......^..
+  p.nextToken()
+
+  // 3.right associative: a[0] = b[0] = 1 is a[0] = (b[0] = 1)
+  expression.Value = p.parseExpression(LOWEST)
+
+  return expression
+}
+
+func (p *Parser) parsePipeExpression(left ast.Expression) ast.Expression {
+  expression := &ast.PipeExpression{Token: p.curToken, Left: left}
+
+  // 1.curToken is '|>', jump it
+  p.nextToken()
+
+  // 2.left associative: x |> f |> g is (x |> f) |> g
+  expression.Function = p.parseExpression(PIPE)
+
   return expression
 }
 
@@ -334,8 +675,10 @@ func (p *Parser) parseGroupedExpression() ast.Expression {
   if !p.expectPeek(token.RPAREN) {
     return nil
   }
-  // 4.curToken is ')'
-
+  // 4.curToken is ')', remember the parens, so `(a < b) < c` is not taken for a chain
+  if infix, ok := expression.(*ast.InfixExpression); ok {
+    infix.Grouped = true
+  }
   return expression
 }
 
@@ -394,6 +737,204 @@ func (p *Parser) parseIfExpression() ast.Expression {
   return expression
 }
 
+// eg: while (x < 10) { x = x + 1 }
+func (p *Parser) parseWhileExpression() ast.Expression {
+  expression := &ast.WhileExpression{Token: p.curToken}
+
+  // 1.curToken is 'while', peekToken may be '('
+  // while (x < 10) { x = x + 1 }
+  // ......^.....................
+  if !p.expectPeek(token.LPAREN) {
+    return nil
+  }
+
+  // 2.curToken is '(', jump it
+  p.nextToken()
+
+  // 3.parseExpression
+  expression.Condition = p.parseExpression(LOWEST)
+
+  // 4.peekToken may be ')'
+  // while (x < 10) { x = x + 1 }
+  // .............^..............
+  if !p.expectPeek(token.RPAREN) {
+    return nil
+  }
+
+  // 5.curToken is ')', peekToken may be '{'
+  // while (x < 10) { x = x + 1 }
+  // ...............^............
+  if !p.expectPeek(token.LBRACE) {
+    return nil
+  }
+
+  // 6.curToken is '{'
+  expression.Body = p.parseBlockStatement()
+
+  return expression
+}
+
+// eg: for (let i = 0; i < 10; i = i + 1) { puts(i) }
+func (p *Parser) parseForExpression() ast.Expression {
+  expression := &ast.ForExpression{Token: p.curToken}
+
+  // 1.curToken is 'for', peekToken may be '(', jump to the init clause
+  // for (let i = 0; i < 10; i = i + 1) { puts(i) }
+  // ....^.........................................
+  if !p.expectPeek(token.LPAREN) {
+    return nil
+  }
+  p.nextToken()
+
+  // eg: for (x in arr), for (k, v in hash)
+  if p.curTokenIs(token.IDENT) && (p.peekTokenIs(token.IN) || p.peekTokenIs(token.COMMA)) {
+    return p.parseForInExpression(expression.Token)
+  }
+
+  // 2.init clause, a let statement eats its ';' itself
+  // for (let i = 0; i < 10; i = i + 1) { puts(i) }
+  // .....^^^^^^^^^^................................
+  if !p.curTokenIs(token.SEMICOLON) {
+    errCount := len(p.errors)
+    expression.Init = p.parseStatement()
+    if len(p.errors) > errCount {
+      return nil
+    }
+    if !p.curTokenIs(token.SEMICOLON) && !p.expectPeek(token.SEMICOLON) {
+      return nil
+    }
+  }
+  p.nextToken()
+
+  // 3.condition clause
+  // for (let i = 0; i < 10; i = i + 1) { puts(i) }
+  // ................^^^^^^^........................
+  if !p.curTokenIs(token.SEMICOLON) {
+    expression.Condition = p.parseExpression(LOWEST)
+    if !p.expectPeek(token.SEMICOLON) {
+      return nil
+    }
+  }
+  p.nextToken()
+
+  // 4.post clause
+  // for (let i = 0; i < 10; i = i + 1) { puts(i) }
+  // ........................^^^^^^^^^^.............
+  if !p.curTokenIs(token.RPAREN) {
+    expression.Post = p.parseExpression(LOWEST)
+    if !p.expectPeek(token.RPAREN) {
+      return nil
+    }
+  }
+
+  // 5.curToken is ')', peekToken may be '{'
+  if !p.expectPeek(token.LBRACE) {
+    return nil
+  }
+  expression.Body = p.parseBlockStatement()
+
+  return expression
+}
+
+// eg: for (k, v in {"a": 1}) { puts(k, v) }
+// curToken is the first loop variable
+func (p *Parser) parseForInExpression(tok token.Token) ast.Expression {
+  expression := &ast.ForInExpression{Token: tok}
+  expression.Key = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+  // 1.an optional second variable
+  // for (k, v in h) { }
+  // ......^^^..........
+  if p.peekTokenIs(token.COMMA) {
+    p.nextToken()
+    if !p.expectPeek(token.IDENT) {
+      return nil
+    }
+    expression.Value = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+  }
+
+  // 2.peekToken may be 'in', jump over it to the iterable
+  // for (k, v in h) { }
+  // ..........^^^^.....
+  if !p.expectPeek(token.IN) {
+    return nil
+  }
+  p.nextToken()
+  expression.Iterable = p.parseExpression(LOWEST)
+
+  // 3.peekToken may be ')' and then '{'
+  if !p.expectPeek(token.RPAREN) || !p.expectPeek(token.LBRACE) {
+    return nil
+  }
+  expression.Body = p.parseBlockStatement()
+
+  return expression
+}
+
+// eg: try { 1 / 0 } catch (e) { 0 }
+func (p *Parser) parseTryExpression() ast.Expression {
+  expression := &ast.TryExpression{Token: p.curToken}
+
+  // 1.curToken is 'try', peekToken may be '{'
+  if !p.expectPeek(token.LBRACE) {
+    return nil
+  }
+  expression.Block = p.parseBlockStatement()
+
+  // 2.curToken is '}', then 'catch' '(' and the name
+  // try { 1 / 0 } catch (e) { 0 }
+  // ..............^^^^^^^^^.......
+  if !p.expectPeek(token.CATCH) || !p.expectPeek(token.LPAREN) || !p.expectPeek(token.IDENT) {
+    return nil
+  }
+  expression.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+  // 3.peekToken may be ')' and then '{'
+  if !p.expectPeek(token.RPAREN) || !p.expectPeek(token.LBRACE) {
+    return nil
+  }
+  expression.Catch = p.parseBlockStatement()
+
+  return expression
+}
+
+// eg: do { x = x + 1 } while (x < 10)
+func (p *Parser) parseDoWhileExpression() ast.Expression {
+  expression := &ast.DoWhileExpression{Token: p.curToken}
+
+  // 1.curToken is 'do', peekToken may be '{'
+  // do { x = x + 1 } while (x < 10)
+  // ...^...........................
+  if !p.expectPeek(token.LBRACE) {
+    return nil
+  }
+
+  // 2.curToken is '{'
+  expression.Body = p.parseBlockStatement()
+
+  // 3.curToken is '}', peekToken may be 'while' and then '('
+  // do { x = x + 1 } while (x < 10)
+  // .................^^^^^^^.......
+  if !p.expectPeek(token.WHILE) || !p.expectPeek(token.LPAREN) {
+    return nil
+  }
+
+  // 4.curToken is '(', jump it
+  p.nextToken()
+
+  // 5.parseExpression
+  expression.Condition = p.parseExpression(LOWEST)
+
+  // 6.peekToken may be ')'
+  // do { x = x + 1 } while (x < 10)
+  // ..............................^
+  if !p.expectPeek(token.RPAREN) {
+    return nil
+  }
+
+  return expression
+}
+
 // eg: fn(a, b) { return a + b; }
 func (p *Parser) parseFunctionLiteral() ast.Expression {
   literal := &ast.FunctionLiteral{Token: p.curToken}
@@ -406,7 +947,7 @@ func (p *Parser) parseFunctionLiteral() ast.Expression {
   }
 
   // 2.curToken is '(', parse Function Parameters
-  literal.Parameters = p.parseFunctionParameters()
+  literal.Parameters, literal.Defaults, literal.Rest = p.parseFunctionParameters()
 
   // 3.curToken is ')', peekToken may be '{'
   // fn(a, b) { return a + b; }
@@ -421,8 +962,47 @@ func (p *Parser) parseFunctionLiteral() ast.Expression {
   return literal
 }
 
-func (p *Parser) parseFunctionParameters() []*ast.Identifier {
-  identifiers := []*ast.Identifier{}
+// eg: macro(a, b) { quote(unquote(a) + unquote(b)) }
+func (p *Parser) parseMacroLiteral() ast.Expression {
+  literal := &ast.MacroLiteral{Token: p.curToken}
+
+  // 1.curToken is 'macro', peekToken may be '('
+  if !p.expectPeek(token.LPAREN) {
+    return nil
+  }
+
+  // 2.curToken is '(', parse the parameters like a function's,
+  // but a macro only takes plain names
+  params, defaults, rest := p.parseFunctionParameters()
+  plain := !rest
+  for _, value := range defaults {
+    plain = plain && value == nil
+  }
+  literal.Parameters = params
+
+  // 3.curToken is ')', peekToken may be '{'
+  if !p.expectPeek(token.LBRACE) {
+    return nil
+  }
+
+  // 4.curToken is '{', parse BlockStatement
+  literal.Body = p.parseBlockStatement()
+
+  // the body is still parsed, so the error does not cascade
+  if !plain {
+    p.addError(literal.Token, BadParameter, "macro parameters cannot have defaults or be rest parameters")
+    return nil
+  }
+
+  return literal
+}
+
+// eg: (a, b, c), (x, y = 10), (first, ...rest)
+// defaults has one entry per parameter, nil if it has no default,
+// rest is true when the last parameter is a rest parameter
+func (p *Parser) parseFunctionParameters() (identifiers []*ast.Identifier, defaults []ast.Expression, rest bool) {
+  identifiers = []*ast.Identifier{}
+  defaults = []ast.Expression{}
 
   // CASE 1: No Parameters, eg: fn()
   // 1.1 curToken is '(', peekToken may be ')'
@@ -431,7 +1011,7 @@ func (p *Parser) parseFunctionParameters() []*ast.Identifier {
     p.nextToken()
     // 1.3 curToken is ')'
 
-    return identifiers
+    return identifiers, defaults, false
   }
 
   // CASE 2: Has Parameters, eg: fn(a, b, c)
@@ -441,81 +1021,353 @@ func (p *Parser) parseFunctionParameters() []*ast.Identifier {
   // 2.2 first parameter
   // fn(a, b, c) {}
   // ...^..........
-  identifier := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+  identifier, value, rest := p.parseFunctionParameter()
+  if identifier == nil {
+    return nil, nil, false
+  }
   identifiers = append(identifiers, identifier)
+  defaults = append(defaults, value)
 
-  // 2.3 rest parameters
+  // 2.3 rest parameters, nothing may follow a '...' parameter
   // fn(a, b, c) {}
   // ......^^^^....
-  for p.peekTokenIs(token.COMMA) {
+  for !rest && p.peekTokenIs(token.COMMA) {
     // peekToken is ',', jump to it
     p.nextToken()
+    // a trailing ',' before ')' is fine, eg: fn(a, b,)
+    if p.peekTokenIs(token.RPAREN) {
+      break
+    }
     // curToken is ',', jump it
     p.nextToken()
-    identifier := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+    identifier, value, rest = p.parseFunctionParameter()
+    if identifier == nil {
+      return nil, nil, false
+    }
+
+    // once a parameter has a default, every following one needs one too
+    if value == nil && !rest && defaults[len(defaults)-1] != nil {
+      p.addError(identifier.Token, BadParameter, fmt.Sprintf("parameter %s needs a default value", identifier.Value))
+      return nil, nil, false
+    }
+
     identifiers = append(identifiers, identifier)
+    defaults = append(defaults, value)
   }
 
   // 2.4 peekToken may be ')'
   // fn(a, b, c) {}
   // ..........^....
   if !p.expectPeek(token.RPAREN) {
-    return nil
+    return nil, nil, false
   }
   // 2.5 curToken is ')'
 
-  return identifiers
+  return identifiers, defaults, rest
+}
+
+// eg: a, y = 10, ...rest
+// gives back the name, its default (or nil) and whether it is a rest parameter
+func (p *Parser) parseFunctionParameter() (*ast.Identifier, ast.Expression, bool) {
+  if p.curTokenIs(token.ELLIPSIS) {
+    // curToken is '...', peekToken must be the name
+    if !p.expectPeek(token.IDENT) {
+      return nil, nil, true
+    }
+
+    return &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}, nil, true
+  }
+
+  // eg: fn(,) or fn(1)
+  if !p.curTokenIs(token.IDENT) {
+    msg := fmt.Sprintf("expected parameter name, got %s instead", p.curToken.Type.Name())
+    p.addError(p.curToken, UnexpectedToken, msg)
+    return nil, nil, false
+  }
+
+  identifier := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+  if !p.peekTokenIs(token.ASSIGN) {
+    return identifier, nil, false
+  }
+
+  // fn(x, y = 10) {}
+  // ........^.......
+  // peekToken is '=', jump to the default value
+  p.nextToken()
+  p.nextToken()
+
+  value := p.parseExpression(LOWEST)
+  if value == nil {
+    return nil, nil, false
+  }
+
+  return identifier, value, false
 }
 
 // eg: add(1, 2 * 3, 4 + 5);
 func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
   expression := &ast.CallExpression{Token: p.curToken, Function: function}
-  expression.Arguments = p.parseCallArguments()
+  if !p.parseCallArguments(expression) {
+    return nil
+  }
   return expression
 }
 
-func (p *Parser) parseCallArguments() []ast.Expression {
-  args := []ast.Expression{}
+// eg: "abc".upper(), [1].push(2).len()
+func (p *Parser) parseMethodCallExpression(receiver ast.Expression) ast.Expression {
+  expression := &ast.MethodCallExpression{Token: p.curToken, Receiver: receiver}
 
-  // CASE 1: No Parameters, eg: add()
-  // 1.1 curToken is '(', peekToken may be ')'
-  if p.peekTokenIs(token.RPAREN) {
-    // 1.2 peekToken is ')', jump to it
+  // 1.curToken is '.', the method name follows
+  if !p.expectPeek(token.IDENT) {
+    return nil
+  }
+  expression.Method = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+  // 2.then the arguments, without keywords
+  if !p.expectPeek(token.LPAREN) {
+    return nil
+  }
+  expression.Arguments = p.parseExpressionList(token.RPAREN)
+  if expression.Arguments == nil {
+    return nil
+  }
+
+  return expression
+}
+
+// eg: greet("Hi", name = "Sam")
+// `ident = value` is a keyword argument, it must come after every positional one
+// and may be given only once
+func (p *Parser) parseCallArguments(call *ast.CallExpression) bool {
+  call.Arguments = []ast.Expression{}
+
+  for _, arg := range p.parseExpressionList(token.RPAREN) {
+    // eg: f(h["a"] = 1) stays a positional index assignment
+    assign, ok := arg.(*ast.AssignExpression)
+    var keyword *ast.Identifier
+    if ok {
+      keyword, ok = assign.Target.(*ast.Identifier)
+    }
+
+    if !ok {
+      if len(call.Keywords) > 0 {
+        p.addError(call.Token, BadArgument, fmt.Sprintf("positional argument %s after keyword arguments", arg))
+        return false
+      }
+      call.Arguments = append(call.Arguments, arg)
+      continue
+    }
+
+    for _, seen := range call.Keywords {
+      if seen.Value == keyword.Value {
+        p.addError(assign.Token, BadArgument, fmt.Sprintf("duplicate keyword argument: %s", keyword.Value))
+        return false
+      }
+    }
+
+    call.Keywords = append(call.Keywords, keyword)
+    call.KeywordValues = append(call.KeywordValues, assign.Value)
+  }
+
+  return true
+}
+
+// eg: [1, 2 * 3]
+func (p *Parser) parseArrayLiteral() ast.Expression {
+  array := &ast.ArrayLiteral{Token: p.curToken}
+  array.Elements = p.parseExpressionList(token.RBRACKET)
+  return array
+}
+
+// eg: {"one": 1, "two": 2}
+func (p *Parser) parseHashLiteral() ast.Expression {
+  hash := &ast.HashLiteral{Token: p.curToken}
+  hash.Keys = []ast.Expression{}
+  hash.Values = []ast.Expression{}
+
+  // curToken is '{' or ',', peekToken may be '}'
+  for !p.peekTokenIs(token.RBRACE) {
+    // 1.jump '{' or ',', parse key
+    // {"one": 1, "two": 2}
+    // .^^^^^.....^^^^^....
     p.nextToken()
-    // 1.3 curToken is ')'
+    key := p.parseExpression(LOWEST)
 
-    return args
+    // 2.peekToken may be ':'
+    if !p.expectPeek(token.COLON) {
+      return nil
+    }
+
+    // 3.curToken is ':', jump it, parse value
+    p.nextToken()
+    value := p.parseExpression(LOWEST)
+
+    hash.Keys = append(hash.Keys, key)
+    hash.Values = append(hash.Values, value)
+
+    // 4.peekToken may be ',' or '}', a trailing ',' is fine, eg: {"a": 1,}
+    if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+      return nil
+    }
   }
 
-  // CASE 2: Has Parameters, eg: add(a, b, c)
-  // 2.1 curToken is '(', jump it
+  // 5.peekToken is '}', jump to it
+  if !p.expectPeek(token.RBRACE) {
+    return nil
+  }
+
+  return hash
+}
+
+// eg: match x { 1 => "one", _ => "other" }
+func (p *Parser) parseMatchExpression() ast.Expression {
+  expression := &ast.MatchExpression{Token: p.curToken}
+  expression.Patterns = []ast.Expression{}
+  expression.Results = []ast.Expression{}
+
+  // 1.jump 'match', parse the subject, '{' ends it
+  p.nextToken()
+  expression.Subject = p.parseExpression(LOWEST)
+  if !p.expectPeek(token.LBRACE) {
+    return nil
+  }
+
+  // curToken is '{' or ',', peekToken may be '}'
+  for !p.peekTokenIs(token.RBRACE) {
+    // 2.jump '{' or ',', parse pattern
+    // match x { 1 => "one", _ => "other" }
+    // ..........^...........^.............
+    p.nextToken()
+    pattern := p.parseExpression(LOWEST)
+
+    // 3.peekToken may be '=>', jump over it to the result
+    if !p.expectPeek(token.ARROW) {
+      return nil
+    }
+    p.nextToken()
+    result := p.parseExpression(LOWEST)
+
+    expression.Patterns = append(expression.Patterns, pattern)
+    expression.Results = append(expression.Results, result)
+
+    // 4.peekToken may be ',' or '}', a trailing ',' is fine
+    if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+      return nil
+    }
+  }
+
+  // 5.peekToken is '}', jump to it
+  if !p.expectPeek(token.RBRACE) {
+    return nil
+  }
+
+  return expression
+}
+
+// comma separated expressions up to `end`,
+// shared by call arguments `add(a, b)` and array literals `[a, b]`
+func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
+  list := []ast.Expression{}
+
+  // CASE 1: Empty list, eg: add(), []
+  // 1.1 curToken is the opening token, peekToken may be `end`
+  if p.peekTokenIs(end) {
+    // 1.2 peekToken is `end`, jump to it
+    p.nextToken()
+    // 1.3 curToken is `end`
+
+    return list
+  }
+
+  // CASE 2: Has elements, eg: add(a, b, c)
+  // 2.1 curToken is the opening token, jump it
   p.nextToken()
 
-  // 2.2 first arguments
-  // add(a, b, c) {}
-  // ....^..........
-  args = append(args, p.parseExpression(LOWEST))
+  // 2.2 first element
+  // add(a, b, c)
+  // ....^.......
+  list = append(list, p.parseExpression(LOWEST))
 
-  // 2.3 rest parameters
-  // add(a, b, c) {}
-  // .......^^^^....
+  // 2.3 rest elements
+  // add(a, b, c)
+  // .....^^^^^^.
   for p.peekTokenIs(token.COMMA) {
     // peekToken is ',', jump to it
     p.nextToken()
+    // a trailing ',' before `end` is fine, eg: add(a, b,)
+    if p.peekTokenIs(end) {
+      break
+    }
     // curToken is ',', jump it
     p.nextToken()
-    args = append(args, p.parseExpression(LOWEST))
+    list = append(list, p.parseExpression(LOWEST))
   }
 
-  // 2.4 peekToken may be ')'
-  // add(a, b, c) {}
-  // ...........^....
-  if !p.expectPeek(token.RPAREN) {
+  // 2.4 peekToken may be `end`
+  // add(a, b, c)
+  // ...........^
+  if !p.expectPeek(end) {
     return nil
   }
-  // 2.5 curToken is ')'
+  // 2.5 curToken is `end`
+
+  return list
+}
 
-  return args
+// eg: "foo"[1]
+func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
+  expression := &ast.IndexExpression{Token: p.curToken, Left: left}
+
+  // 1.curToken is '[', jump it
+  // "foo"[1]
+  // .....^..
+  p.nextToken()
+
+  // 2.parseExpression
+  expression.Index = p.parseExpression(LOWEST)
+
+  // 3.peekToken may be ']'
+  // "foo"[1]
+  // .......^
+  if !p.expectPeek(token.RBRACKET) {
+    return nil
+  }
+  // 4.curToken is ']'
+
+  return expression
+}
+
+// eg: a?.[0], h?.key is h?.["key"]
+func (p *Parser) parseOptionalIndexExpression(left ast.Expression) ast.Expression {
+  qdot := p.curToken
+
+  // 1.curToken is '?.', an index or a key follows
+  switch p.peekToken.Type {
+  case token.LBRACKET:
+    p.nextToken()
+    expression, ok := p.parseIndexExpression(left).(*ast.IndexExpression)
+    if !ok {
+      return nil
+    }
+    expression.Token = qdot
+    expression.Optional = true
+    return expression
+
+  case token.IDENT:
+    p.nextToken()
+    key := token.Token{Type: token.STRING, Literal: p.curToken.Literal, Line: p.curToken.Line, Column: p.curToken.Column}
+    return &ast.IndexExpression{
+      Token:    qdot,
+      Left:     left,
+      Index:    &ast.StringLiteral{Token: key, Value: key.Literal},
+      Optional: true,
+    }
+
+  default:
+    msg := fmt.Sprintf("expected next token to be LBRACKET or IDENT, got %s instead", p.peekToken.Type.Name())
+    p.addError(p.peekToken, UnexpectedToken, msg)
+    return nil
+  }
 }
 
 /* parse utils */
@@ -543,7 +1395,7 @@ func (p *Parser) expectPeek(t token.TokenType) bool {
 }
 
 func (p *Parser) peekPrecedence() int {
-  if p, ok := precedences[p.peekToken.Type]; ok {
+  if p, ok := p.precedences[p.peekToken.Type]; ok {
     return p
   }
 
@@ -551,26 +1403,32 @@ func (p *Parser) peekPrecedence() int {
 }
 
 func (p *Parser) curPrecedence() int {
-  if p, ok := precedences[p.curToken.Type]; ok {
+  if p, ok := p.precedences[p.curToken.Type]; ok {
     return p
   }
 
   return LOWEST
 }
 
-func (p *Parser) Errors() []string {
+// eg: p.Errors().Strings() gives the "line:col: msg" form
+func (p *Parser) Errors() ParseErrors {
   return p.errors
 }
 
+// eg: 1:5: expected next token to be IDENT, got ASSIGN instead
+func (p *Parser) addError(tok token.Token, kind ErrorKind, msg string) {
+  p.errors = append(p.errors, ParseError{Kind: kind, Message: msg, Line: tok.Line, Column: tok.Column})
+}
+
 func (p *Parser) peekError(t token.TokenType) {
   msg := fmt.Sprintf("expected next token to be %s, got %s instead",
-    t, p.peekToken.Type)
-  p.errors = append(p.errors, msg)
+    t.Name(), p.peekToken.Type.Name())
+  p.addError(p.peekToken, UnexpectedToken, msg)
 }
 
 func (p *Parser) noPrefixParseFnError(t token.TokenType) {
-  msg := fmt.Sprintf("no prefix parse function for %s found", t)
-  p.errors = append(p.errors, msg)
+  msg := fmt.Sprintf("no prefix parse function for %s found", t.Name())
+  p.addError(p.curToken, NoPrefixFn, msg)
 }
 
 func (p *Parser) registerPrefix(tokenType token.TokenType, fn prefixParseFn) {
@@ -3,7 +3,10 @@ package parser
 import (
   "JFFMonkeyLang/src/ast"
   "JFFMonkeyLang/src/lexer"
+  "JFFMonkeyLang/src/token"
+  "bytes"
   "fmt"
+  "reflect"
   "testing"
 )
 
@@ -42,6 +45,113 @@ func TestLetStatements(t *testing.T) {
   }
 }
 
+func TestConstStatements(t *testing.T) {
+  tests := []struct {
+    input              string
+    expectedIdentifier string
+    expectedValue      interface{}
+  }{
+    {"const x = 5;", "x", 5},
+    {"const PI = y;", "PI", "y"},
+  }
+
+  for _, tt := range tests {
+    l := lexer.New(tt.input)
+    p := New(l)
+    program := p.ParseProgram()
+    checkParserErrors(t, p)
+
+    if len(program.Statements) != 1 {
+      t.Fatalf("program.Statements does not contain 1 statements. got=%d",
+        len(program.Statements))
+    }
+
+    stmt, ok := program.Statements[0].(*ast.ConstStatement)
+    if !ok {
+      t.Fatalf("s not *ast.ConstStatement. got=%T", program.Statements[0])
+    }
+    if stmt.Name.Value != tt.expectedIdentifier {
+      t.Errorf("stmt.Name.Value not '%s'. got=%s", tt.expectedIdentifier, stmt.Name.Value)
+    }
+    testLiteralExpression(t, stmt.Value, tt.expectedValue)
+
+    if stmt.String() != tt.input {
+      t.Errorf("stmt.String() wrong. want=%q, got=%q", tt.input, stmt.String())
+    }
+  }
+}
+
+func TestDestructureStatements(t *testing.T) {
+  tests := []struct {
+    input          string
+    expectedHash   bool
+    expectedNames  []string
+    expectedRest   bool
+    expectedString string
+  }{
+    {"let [a, b] = [1, 2];", false, []string{"a", "b"}, false, "let [a, b] = [1, 2];"},
+    {"let [x, ...xs] = list;", false, []string{"x", "xs"}, true, "let [x, ...xs] = list;"},
+    {"let [...all] = list", false, []string{"all"}, true, "let [...all] = list;"},
+    {"let {name, age} = person;", true, []string{"name", "age"}, false, "let {name, age} = person;"},
+  }
+
+  for _, tt := range tests {
+    l := lexer.New(tt.input)
+    p := New(l)
+    program := p.ParseProgram()
+    checkParserErrors(t, p)
+
+    if len(program.Statements) != 1 {
+      t.Fatalf("program.Statements does not contain 1 statements. got=%d",
+        len(program.Statements))
+    }
+
+    stmt, ok := program.Statements[0].(*ast.DestructureStatement)
+    if !ok {
+      t.Fatalf("stmt not *ast.DestructureStatement. got=%T", program.Statements[0])
+    }
+    if stmt.Hash != tt.expectedHash {
+      t.Errorf("stmt.Hash wrong. want %t, got=%t", tt.expectedHash, stmt.Hash)
+    }
+    if len(stmt.Names) != len(tt.expectedNames) {
+      t.Fatalf("length names wrong. want %d, got=%d", len(tt.expectedNames), len(stmt.Names))
+    }
+    for i, name := range tt.expectedNames {
+      testIdentifier(t, stmt.Names[i], name)
+    }
+    if stmt.Rest != tt.expectedRest {
+      t.Errorf("stmt.Rest wrong. want %t, got=%t", tt.expectedRest, stmt.Rest)
+    }
+    if stmt.String() != tt.expectedString {
+      t.Errorf("stmt.String() wrong. want %q, got=%q", tt.expectedString, stmt.String())
+    }
+  }
+}
+
+func TestDestructureStatementErrors(t *testing.T) {
+  tests := []struct {
+    input         string
+    expectedError string
+  }{
+    {"let [1] = x;", "1:6: expected name to destructure into, got INT instead"},
+    {"let [a, ] = x;", "1:9: expected name to destructure into, got RBRACKET instead"},
+    {"let {...a} = h;", "1:6: expected name to destructure into, got ELLIPSIS instead"},
+    {"let [...a, b] = x;", "1:10: expected next token to be RBRACKET, got COMMA instead"},
+    {"let [a, b] x;", "1:12: expected next token to be ASSIGN, got IDENT instead"},
+  }
+
+  for _, tt := range tests {
+    l := lexer.New(tt.input)
+    p := New(l)
+    p.ParseProgram()
+
+    errors := p.Errors().Strings()
+    if len(errors) == 0 || errors[0] != tt.expectedError {
+      t.Errorf("wrong errors for %q. want first=%q, got=%q", tt.input, tt.expectedError, errors)
+    }
+  }
+}
+
 // return 5;
 func TestReturnStatements(t *testing.T) {
   tests := []struct {
@@ -79,6 +189,38 @@ func TestReturnStatements(t *testing.T) {
   }
 }
 
+func TestThrowStatements(t *testing.T) {
+  tests := []struct {
+    input         string
+    expectedValue interface{}
+  }{
+    {`throw 5;`, 5},
+    {`throw foobar`, "foobar"},
+    {`throw false;;`, false},
+  }
+
+  for _, tt := range tests {
+    l := lexer.New(tt.input)
+    p := New(l)
+    program := p.ParseProgram()
+    checkParserErrors(t, p)
+
+    if len(program.Statements) != 1 {
+      t.Fatalf("program.Statements does not contain 1 statements. got=%d",
+        len(program.Statements))
+    }
+
+    throwStmt, ok := program.Statements[0].(*ast.ThrowStatement)
+    if !ok {
+      t.Fatalf("stmt not *ast.ThrowStatement. got=%T", program.Statements[0])
+    }
+    if throwStmt.TokenLiteral() != "throw" {
+      t.Fatalf("throwStmt.TokenLiteral not 'throw', got %q", throwStmt.TokenLiteral())
+    }
+    testLiteralExpression(t, throwStmt.Value, tt.expectedValue)
+  }
+}
+
 // foo;
 func TestIdentifierExpression(t *testing.T) {
   input := "foobar;"
@@ -112,6 +254,42 @@ func TestIdentifierExpression(t *testing.T) {
 }
 
 // 5;
+func TestBigIntegerLiteral(t *testing.T) {
+  tests := []struct {
+    input    string
+    expected string
+  }{
+    {"9223372036854775808", "9223372036854775808"},
+    {"100_000_000_000_000_000_000", "100000000000000000000"},
+    {"0xFFFF_FFFF_FFFF_FFFF", "18446744073709551615"},
+  }
+
+  for _, tt := range tests {
+    p := New(lexer.New(tt.input))
+    program := p.ParseProgram()
+    checkParserErrors(t, p)
+
+    literal, ok := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.BigIntegerLiteral)
+    if !ok {
+      t.Fatalf("%q: exp not *ast.BigIntegerLiteral. got=%T", tt.input, program.Statements[0])
+    }
+    if literal.Value.String() != tt.expected {
+      t.Errorf("%q: wrong value. expected=%s, got=%s", tt.input, tt.expected, literal.Value)
+    }
+    if literal.String() != tt.input {
+      t.Errorf("%q: wrong String(). got=%s", tt.input, literal.String())
+    }
+  }
+
+  // the largest int64 is still a plain IntegerLiteral
+  p := New(lexer.New("9223372036854775807"))
+  program := p.ParseProgram()
+  checkParserErrors(t, p)
+  if _, ok := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.IntegerLiteral); !ok {
+    t.Errorf("exp not *ast.IntegerLiteral. got=%T", program.Statements[0].(*ast.ExpressionStatement).Expression)
+  }
+}
+
 func TestIntegerLiteralExpression(t *testing.T) {
   input := "5;"
 
@@ -143,6 +321,53 @@ func TestIntegerLiteralExpression(t *testing.T) {
   }
 }
 
+// 1_000_000;
+func TestIntegerLiteralDigitSeparators(t *testing.T) {
+  tests := []struct {
+    input    string
+    expected int64
+  }{
+    {"1_000_000;", 1000000},
+    {"1_2_3;", 123},
+  }
+
+  for _, tt := range tests {
+    l := lexer.New(tt.input)
+    p := New(l)
+    program := p.ParseProgram()
+    checkParserErrors(t, p)
+
+    stmt := program.Statements[0].(*ast.ExpressionStatement)
+    literal, ok := stmt.Expression.(*ast.IntegerLiteral)
+    if !ok {
+      t.Fatalf("exp not *ast.IntegerLiteral. got=%T", stmt.Expression)
+    }
+    if literal.Value != tt.expected {
+      t.Errorf("literal.Value not %d. got=%d", tt.expected, literal.Value)
+    }
+  }
+
+  invalid := []struct {
+    input    string
+    expected string
+  }{
+    {"_5;", `1:1: illegal token "_5"`},
+    {"5_;", `1:1: illegal token "5_"`},
+    {"1 + 5__0;", `1:5: illegal token "5__0"`},
+  }
+
+  for _, tt := range invalid {
+    l := lexer.New(tt.input)
+    p := New(l)
+    p.ParseProgram()
+
+    errors := p.Errors().Strings()
+    if len(errors) != 1 || errors[0] != tt.expected {
+      t.Errorf("wrong errors for %q. want=%q, got=%q", tt.input, tt.expected, errors)
+    }
+  }
+}
+
 // !5;
 // -15;
 func TestParsingPrefixExpressions(t *testing.T) {
@@ -202,6 +427,7 @@ func TestParsingInfixExpressions(t *testing.T) {
     {"5 - 5;", 5, "-", 5},
     {"5 * 5;", 5, "*", 5},
     {"5 / 5;", 5, "/", 5},
+    {"5 % 5;", 5, "%", 5},
     {"5 > 5;", 5, ">", 5},
     {"5 < 5;", 5, "<", 5},
     {"5 == 5;", 5, "==", 5},
@@ -277,6 +503,10 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
       "a + b / c",
       "(a + (b / c))",
     },
+    {
+      "a + b % c * d",
+      "(a + ((b % c) * d))",
+    },
     {
       "a + b * c + d / e - f",
       "(((a + (b * c)) + (d / e)) - f)",
@@ -337,6 +567,18 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
       "!(true == true)",
       "(!(true == true))",
     },
+    {
+      "a * b[1]",
+      "(a * (b[1]))",
+    },
+    {
+      "a * [1, 2, 3, 4][b * c] * d",
+      "((a * ([1, 2, 3, 4][(b * c)])) * d)",
+    },
+    {
+      "-\"abc\"[0 + 1]",
+      "(-(abc[(0 + 1)]))",
+    },
     // {
     //   "a + add(b * c) + d",
     //   "((a + add((b * c))) + d)",
@@ -382,6 +624,58 @@ func TestOperatorPrecedenceParsing2(t *testing.T) {
       "1 * -2 + 3",
       "((1 * (-2)) + 3)",
     },
+    {
+      "2 * 3 ** 2",
+      "(2 * (3 ** 2))",
+    },
+    {
+      "2 ** 3 ** 2",
+      "(2 ** (3 ** 2))",
+    },
+    {
+      "-2 ** 2",
+      "((-2) ** 2)",
+    },
+    {
+      "a || b && c",
+      "(a || (b && c))",
+    },
+    {
+      "a && b || c && d",
+      "((a && b) || (c && d))",
+    },
+    {
+      "a == 1 && !b || c < 2",
+      "(((a == 1) && (!b)) || (c < 2))",
+    },
+    {
+      "x = a || b",
+      "x = (a || b)",
+    },
+    {
+      "a ?? b ?? c",
+      "((a ?? b) ?? c)",
+    },
+    {
+      "a ?? b || c",
+      "(a ?? (b || c))",
+    },
+    {
+      "a |> f ?? g",
+      "(a |> (f ?? g))",
+    },
+    {
+      "a |> b |> c",
+      "((a |> b) |> c)",
+    },
+    {
+      "a + 1 |> f || g",
+      "((a + 1) |> (f || g))",
+    },
+    {
+      "x = a |> f(1)",
+      "x = (a |> f(1))",
+    },
   }
 
   for _, tt := range tests {
@@ -485,8 +779,8 @@ func TestIfExpression(t *testing.T) {
   }
 }
 
-func TestIfElseExpression(t *testing.T) {
-  input := `if (x < y) { x } else { y }`
+func TestWhileExpression(t *testing.T) {
+  input := `while (x < y) { x }`
 
   l := lexer.New(input)
   p := New(l)
@@ -504,101 +798,1056 @@ func TestIfElseExpression(t *testing.T) {
       program.Statements[0])
   }
 
-  exp, ok := stmt.Expression.(*ast.IfExpression)
+  exp, ok := stmt.Expression.(*ast.WhileExpression)
   if !ok {
-    t.Fatalf("stmt.Expression is not ast.IfExpression. got=%T", stmt.Expression)
+    t.Fatalf("stmt.Expression is not ast.WhileExpression. got=%T",
+      stmt.Expression)
   }
 
   if !testInfixExpression(t, exp.Condition, "x", "<", "y") {
     return
   }
 
-  if len(exp.Consequence.Statements) != 1 {
-    t.Errorf("consequence is not 1 statements. got=%d\n",
-      len(exp.Consequence.Statements))
+  if len(exp.Body.Statements) != 1 {
+    t.Errorf("body is not 1 statements. got=%d\n",
+      len(exp.Body.Statements))
   }
 
-  consequence, ok := exp.Consequence.Statements[0].(*ast.ExpressionStatement)
+  body, ok := exp.Body.Statements[0].(*ast.ExpressionStatement)
   if !ok {
     t.Fatalf("Statements[0] is not ast.ExpressionStatement. got=%T",
-      exp.Consequence.Statements[0])
-  }
-
-  if !testIdentifier(t, consequence.Expression, "x") {
-    return
+      exp.Body.Statements[0])
   }
 
-  if len(exp.Alternative.Statements) != 1 {
-    t.Errorf("exp.Alternative.Statements does not contain 1 statements. got=%d\n",
-      len(exp.Alternative.Statements))
-  }
+  testIdentifier(t, body.Expression, "x")
+}
 
-  alternative, ok := exp.Alternative.Statements[0].(*ast.ExpressionStatement)
-  if !ok {
-    t.Fatalf("Statements[0] is not ast.ExpressionStatement. got=%T",
-      exp.Alternative.Statements[0])
+func TestForExpression(t *testing.T) {
+  tests := []struct {
+    input             string
+    expectedInit      string
+    expectedCondition string
+    expectedPost      string
+  }{
+    {"for (let i = 0; i < 10; i = i + 1) { puts(i) }", "let i = 0;", "(i < 10)", "i = (i + 1)"},
+    {"for (i = 0; i < 10; i = i + 1) { i }", "i = 0", "(i < 10)", "i = (i + 1)"},
+    {"for (; i < 10; i = i + 1) { i }", "", "(i < 10)", "i = (i + 1)"},
+    {"for (let i = 0; ; i = i + 1) { i }", "let i = 0;", "", "i = (i + 1)"},
+    {"for (let i = 0; i < 10;) { i }", "let i = 0;", "(i < 10)", ""},
+    {"for (;;) { i }", "", "", ""},
   }
 
-  if !testIdentifier(t, alternative.Expression, "y") {
-    return
-  }
-}
+  for _, tt := range tests {
+    p := New(lexer.New(tt.input))
+    program := p.ParseProgram()
+    checkParserErrors(t, p)
 
-func TestFunctionLiteralParsing(t *testing.T) {
-  input := `fn(x, y) { x + y; }`
+    if len(program.Statements) != 1 {
+      t.Fatalf("program.Statements does not contain 1 statement. got=%d", len(program.Statements))
+    }
+    exp, ok := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.ForExpression)
+    if !ok {
+      t.Fatalf("expression is not ast.ForExpression. got=%T", program.Statements[0])
+    }
 
-  l := lexer.New(input)
-  p := New(l)
-  program := p.ParseProgram()
-  checkParserErrors(t, p)
+    clauses := []struct {
+      name     string
+      node     ast.Node
+      expected string
+    }{
+      {"Init", exp.Init, tt.expectedInit},
+      {"Condition", exp.Condition, tt.expectedCondition},
+      {"Post", exp.Post, tt.expectedPost},
+    }
+    for _, clause := range clauses {
+      actual := ""
+      if v := reflect.ValueOf(clause.node); v.IsValid() && !v.IsNil() {
+        actual = clause.node.String()
+      }
+      if actual != clause.expected {
+        t.Errorf("%q: wrong %s. expected=%q, got=%q", tt.input, clause.name, clause.expected, actual)
+      }
+    }
 
-  if len(program.Statements) != 1 {
-    t.Fatalf("program.Statements does not contain %d statements. got=%d\n",
-      1, len(program.Statements))
+    if len(exp.Body.Statements) != 1 {
+      t.Errorf("%q: body is not 1 statement. got=%d", tt.input, len(exp.Body.Statements))
+    }
   }
+}
 
-  stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
-  if !ok {
-    t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T",
-      program.Statements[0])
+func TestForExpressionErrors(t *testing.T) {
+  tests := []struct {
+    input    string
+    expected string
+  }{
+    {"for i { }", "1:5: expected next token to be LPAREN, got IDENT instead"},
+    {"for (let i = 0 i < 1; ) { }", "1:16: expected next token to be SEMICOLON, got IDENT instead"},
+    {"for (; i < 1 ) { }", "1:14: expected next token to be SEMICOLON, got RPAREN instead"},
+    {"for (; ; i = i + 1 { }", "1:20: expected next token to be RPAREN, got LBRACE instead"},
+    {"for (;;) i", "1:10: expected next token to be LBRACE, got IDENT instead"},
   }
 
-  function, ok := stmt.Expression.(*ast.FunctionLiteral)
-  if !ok {
-    t.Fatalf("stmt.Expression is not ast.FunctionLiteral. got=%T",
-      stmt.Expression)
-  }
+  for _, tt := range tests {
+    p := New(lexer.New(tt.input))
+    p.ParseProgram()
 
-  if len(function.Parameters) != 2 {
-    t.Fatalf("function literal parameters wrong. want 2, got=%d\n",
-      len(function.Parameters))
+    errors := p.Errors().Strings()
+    if len(errors) == 0 || errors[0] != tt.expected {
+      t.Errorf("wrong errors for %q. want first=%q, got=%q", tt.input, tt.expected, errors)
+    }
   }
+}
 
-  testLiteralExpression(t, function.Parameters[0], "x")
-  testLiteralExpression(t, function.Parameters[1], "y")
-
-  if len(function.Body.Statements) != 1 {
-    t.Fatalf("function.Body.Statements has not 1 statements. got=%d\n",
-      len(function.Body.Statements))
+func TestForInExpression(t *testing.T) {
+  tests := []struct {
+    input            string
+    expectedKey      string
+    expectedValue    string
+    expectedIterable string
+  }{
+    {"for (x in arr) { x }", "x", "", "arr"},
+    {"for (k, v in hash) { k }", "k", "v", "hash"},
+    {"for (x in [1, 2]) { x }", "x", "", "[1, 2]"},
+    {"for (x in f(y)) { x }", "x", "", "f(y)"},
   }
 
-  bodyStmt, ok := function.Body.Statements[0].(*ast.ExpressionStatement)
-  if !ok {
-    t.Fatalf("function body stmt is not ast.ExpressionStatement. got=%T",
-      function.Body.Statements[0])
-  }
+  for _, tt := range tests {
+    p := New(lexer.New(tt.input))
+    program := p.ParseProgram()
+    checkParserErrors(t, p)
+
+    if len(program.Statements) != 1 {
+      t.Fatalf("program.Statements does not contain 1 statement. got=%d", len(program.Statements))
+    }
+    exp, ok := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.ForInExpression)
+    if !ok {
+      t.Fatalf("expression is not ast.ForInExpression. got=%T", program.Statements[0])
+    }
+
+    testIdentifier(t, exp.Key, tt.expectedKey)
+    if tt.expectedValue == "" {
+      if exp.Value != nil {
+        t.Errorf("%q: Value is not nil. got=%s", tt.input, exp.Value)
+      }
+    } else {
+      testIdentifier(t, exp.Value, tt.expectedValue)
+    }
+    if exp.Iterable.String() != tt.expectedIterable {
+      t.Errorf("%q: wrong Iterable. expected=%q, got=%q", tt.input, tt.expectedIterable, exp.Iterable.String())
+    }
+    if len(exp.Body.Statements) != 1 {
+      t.Errorf("%q: body is not 1 statement. got=%d", tt.input, len(exp.Body.Statements))
+    }
+  }
+}
+
+func TestForInExpressionErrors(t *testing.T) {
+  tests := []struct {
+    input    string
+    expected string
+  }{
+    {"for (k, in h) { }", "1:9: expected next token to be IDENT, got IN instead"},
+    {"for (k, v h) { }", "1:11: expected next token to be IN, got IDENT instead"},
+    {"for (x in h { }", "1:13: expected next token to be RPAREN, got LBRACE instead"},
+    {"for (x in h) x", "1:14: expected next token to be LBRACE, got IDENT instead"},
+  }
+
+  for _, tt := range tests {
+    p := New(lexer.New(tt.input))
+    p.ParseProgram()
+
+    errors := p.Errors().Strings()
+    if len(errors) == 0 || errors[0] != tt.expected {
+      t.Errorf("wrong errors for %q. want first=%q, got=%q", tt.input, tt.expected, errors)
+    }
+  }
+}
+
+func TestTemplateLiteral(t *testing.T) {
+  input := `"Hello ${name}, you are ${age + 1} years old"`
+
+  p := New(lexer.New(input))
+  program := p.ParseProgram()
+  checkParserErrors(t, p)
+
+  template, ok := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.TemplateLiteral)
+  if !ok {
+    t.Fatalf("exp not *ast.TemplateLiteral. got=%T", program.Statements[0].(*ast.ExpressionStatement).Expression)
+  }
+
+  expectedStrings := []string{"Hello ", ", you are ", " years old"}
+  if !reflect.DeepEqual(template.Strings, expectedStrings) {
+    t.Errorf("template.Strings wrong. expected=%q, got=%q", expectedStrings, template.Strings)
+  }
+  if len(template.Expressions) != 2 {
+    t.Fatalf("template.Expressions does not contain 2 expressions. got=%d", len(template.Expressions))
+  }
+  testIdentifier(t, template.Expressions[0], "name")
+  testInfixExpression(t, template.Expressions[1], "age", "+", 1)
+
+  if expected := "Hello ${name}, you are ${(age + 1)} years old"; template.String() != expected {
+    t.Errorf("template.String() wrong. expected=%q, got=%q", expected, template.String())
+  }
+}
+
+func TestTemplateLiteralErrors(t *testing.T) {
+  tests := []struct {
+    input        string
+    expected     string
+    expectedKind ErrorKind
+  }{
+    {`let s = "a ${b";`, `1:9: unterminated ${ in string "a ${b\";"`, BadTemplate},
+    {`"a ${}"`, "1:1: in ${}: no prefix parse function for EOF found", NoPrefixFn},
+    {`"a ${b c}"`, "1:1: in ${b c}: expected } after b, got IDENT instead", UnexpectedToken},
+    {`x + "${1 +}"`, "1:5: in ${1 +}: no prefix parse function for EOF found", NoPrefixFn},
+  }
+
+  for _, tt := range tests {
+    p := New(lexer.New(tt.input))
+    p.ParseProgram()
+
+    errors := p.Errors()
+    if len(errors) == 0 || errors[0].Error() != tt.expected || errors[0].Kind != tt.expectedKind {
+      t.Errorf("wrong errors for %q. want first=%q (%s), got=%q", tt.input, tt.expected, tt.expectedKind, errors)
+    }
+  }
+}
+
+func TestMatchExpression(t *testing.T) {
+  input := `match x + 1 { 1 => "one", y => y * 2, _ => "other", }`
+
+  p := New(lexer.New(input))
+  program := p.ParseProgram()
+  checkParserErrors(t, p)
+
+  if len(program.Statements) != 1 {
+    t.Fatalf("program.Statements does not contain 1 statement. got=%d", len(program.Statements))
+  }
+  exp, ok := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.MatchExpression)
+  if !ok {
+    t.Fatalf("expression is not ast.MatchExpression. got=%T", program.Statements[0])
+  }
+
+  testInfixExpression(t, exp.Subject, "x", "+", 1)
+
+  if len(exp.Patterns) != 3 || len(exp.Results) != 3 {
+    t.Fatalf("match has wrong number of arms. patterns=%d, results=%d", len(exp.Patterns), len(exp.Results))
+  }
+  testIntegerLiteral(t, exp.Patterns[0], 1)
+  testIdentifier(t, exp.Patterns[1], "y")
+  testIdentifier(t, exp.Patterns[2], "_")
+  testInfixExpression(t, exp.Results[1], "y", "*", 2)
+
+  expected := `match (x + 1) { 1 => one, y => (y * 2), _ => other }`
+  if exp.String() != expected {
+    t.Errorf("wrong String(). expected=%q, got=%q", expected, exp.String())
+  }
+
+  // no arms at all
+  p = New(lexer.New("match x { }"))
+  program = p.ParseProgram()
+  checkParserErrors(t, p)
+  exp = program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.MatchExpression)
+  if len(exp.Patterns) != 0 {
+    t.Errorf("match should have no arms. got=%d", len(exp.Patterns))
+  }
+}
+
+func TestMatchExpressionErrors(t *testing.T) {
+  tests := []struct {
+    input    string
+    expected string
+  }{
+    {"match x 1 => 2", "1:9: expected next token to be LBRACE, got INT instead"},
+    {"match x { 1 2 }", "1:13: expected next token to be ARROW, got INT instead"},
+    {"match x { 1 => 2 _ => 3 }", "1:18: expected next token to be COMMA, got IDENT instead"},
+    {"match x { 1 => 2,", "1:18: no prefix parse function for EOF found"},
+  }
+
+  for _, tt := range tests {
+    p := New(lexer.New(tt.input))
+    p.ParseProgram()
+
+    errors := p.Errors().Strings()
+    if len(errors) == 0 || errors[0] != tt.expected {
+      t.Errorf("wrong errors for %q. want first=%q, got=%q", tt.input, tt.expected, errors)
+    }
+  }
+}
+
+func TestTryExpression(t *testing.T) {
+  input := `try { x / y } catch (e) { e }`
+
+  p := New(lexer.New(input))
+  program := p.ParseProgram()
+  checkParserErrors(t, p)
+
+  if len(program.Statements) != 1 {
+    t.Fatalf("program.Statements does not contain 1 statement. got=%d", len(program.Statements))
+  }
+  exp, ok := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.TryExpression)
+  if !ok {
+    t.Fatalf("expression is not ast.TryExpression. got=%T", program.Statements[0])
+  }
+
+  if len(exp.Block.Statements) != 1 {
+    t.Fatalf("try block is not 1 statement. got=%d", len(exp.Block.Statements))
+  }
+  testInfixExpression(t, exp.Block.Statements[0].(*ast.ExpressionStatement).Expression, "x", "/", "y")
+  testIdentifier(t, exp.Name, "e")
+  if len(exp.Catch.Statements) != 1 {
+    t.Fatalf("catch block is not 1 statement. got=%d", len(exp.Catch.Statements))
+  }
+  testIdentifier(t, exp.Catch.Statements[0].(*ast.ExpressionStatement).Expression, "e")
+}
+
+func TestTryExpressionErrors(t *testing.T) {
+  tests := []struct {
+    input    string
+    expected string
+  }{
+    {"try x catch (e) { }", "1:5: expected next token to be LBRACE, got IDENT instead"},
+    {"try { x } (e) { }", "1:11: expected next token to be CATCH, got LPAREN instead"},
+    {"try { x } catch e { }", "1:17: expected next token to be LPAREN, got IDENT instead"},
+    {"try { x } catch (1) { }", "1:18: expected next token to be IDENT, got INT instead"},
+    {"try { x } catch (e) e", "1:21: expected next token to be LBRACE, got IDENT instead"},
+  }
+
+  for _, tt := range tests {
+    p := New(lexer.New(tt.input))
+    p.ParseProgram()
+
+    errors := p.Errors().Strings()
+    if len(errors) == 0 || errors[0] != tt.expected {
+      t.Errorf("wrong errors for %q. want first=%q, got=%q", tt.input, tt.expected, errors)
+    }
+  }
+}
+
+func TestDoWhileExpression(t *testing.T) {
+  input := `do { x } while (x < y);`
+
+  l := lexer.New(input)
+  p := New(l)
+  program := p.ParseProgram()
+  checkParserErrors(t, p)
+
+  if len(program.Statements) != 1 {
+    t.Fatalf("program.Statements does not contain %d statements. got=%d\n",
+      1, len(program.Statements))
+  }
+
+  stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+  if !ok {
+    t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T",
+      program.Statements[0])
+  }
+
+  exp, ok := stmt.Expression.(*ast.DoWhileExpression)
+  if !ok {
+    t.Fatalf("stmt.Expression is not ast.DoWhileExpression. got=%T",
+      stmt.Expression)
+  }
+
+  if len(exp.Body.Statements) != 1 {
+    t.Fatalf("body is not 1 statements. got=%d\n",
+      len(exp.Body.Statements))
+  }
+
+  body, ok := exp.Body.Statements[0].(*ast.ExpressionStatement)
+  if !ok {
+    t.Fatalf("Statements[0] is not ast.ExpressionStatement. got=%T",
+      exp.Body.Statements[0])
+  }
+  testIdentifier(t, body.Expression, "x")
+
+  testInfixExpression(t, exp.Condition, "x", "<", "y")
+}
+
+func TestDoWhileExpressionErrors(t *testing.T) {
+  tests := []struct {
+    input    string
+    expected string
+  }{
+    {"do x while (y)", "1:4: expected next token to be LBRACE, got IDENT instead"},
+    {"do { x } (y)", "1:10: expected next token to be WHILE, got LPAREN instead"},
+    {"do { x } while y", "1:16: expected next token to be LPAREN, got IDENT instead"},
+    {"do { x } while (y", "1:18: expected next token to be RPAREN, got EOF instead"},
+  }
+
+  for _, tt := range tests {
+    p := New(lexer.New(tt.input))
+    p.ParseProgram()
+
+    errors := p.Errors().Strings()
+    if len(errors) == 0 || errors[0] != tt.expected {
+      t.Errorf("wrong errors for %q. want first=%q, got=%q", tt.input, tt.expected, errors)
+    }
+  }
+}
+
+func TestIfElseExpression(t *testing.T) {
+  input := `if (x < y) { x } else { y }`
+
+  l := lexer.New(input)
+  p := New(l)
+  program := p.ParseProgram()
+  checkParserErrors(t, p)
+
+  if len(program.Statements) != 1 {
+    t.Fatalf("program.Statements does not contain %d statements. got=%d\n",
+      1, len(program.Statements))
+  }
+
+  stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+  if !ok {
+    t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T",
+      program.Statements[0])
+  }
+
+  exp, ok := stmt.Expression.(*ast.IfExpression)
+  if !ok {
+    t.Fatalf("stmt.Expression is not ast.IfExpression. got=%T", stmt.Expression)
+  }
+
+  if !testInfixExpression(t, exp.Condition, "x", "<", "y") {
+    return
+  }
+
+  if len(exp.Consequence.Statements) != 1 {
+    t.Errorf("consequence is not 1 statements. got=%d\n",
+      len(exp.Consequence.Statements))
+  }
+
+  consequence, ok := exp.Consequence.Statements[0].(*ast.ExpressionStatement)
+  if !ok {
+    t.Fatalf("Statements[0] is not ast.ExpressionStatement. got=%T",
+      exp.Consequence.Statements[0])
+  }
+
+  if !testIdentifier(t, consequence.Expression, "x") {
+    return
+  }
+
+  if len(exp.Alternative.Statements) != 1 {
+    t.Errorf("exp.Alternative.Statements does not contain 1 statements. got=%d\n",
+      len(exp.Alternative.Statements))
+  }
+
+  alternative, ok := exp.Alternative.Statements[0].(*ast.ExpressionStatement)
+  if !ok {
+    t.Fatalf("Statements[0] is not ast.ExpressionStatement. got=%T",
+      exp.Alternative.Statements[0])
+  }
+
+  if !testIdentifier(t, alternative.Expression, "y") {
+    return
+  }
+}
+
+func TestFunctionLiteralParsing(t *testing.T) {
+  input := `fn(x, y) { x + y; }`
+
+  l := lexer.New(input)
+  p := New(l)
+  program := p.ParseProgram()
+  checkParserErrors(t, p)
+
+  if len(program.Statements) != 1 {
+    t.Fatalf("program.Statements does not contain %d statements. got=%d\n",
+      1, len(program.Statements))
+  }
+
+  stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+  if !ok {
+    t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T",
+      program.Statements[0])
+  }
+
+  function, ok := stmt.Expression.(*ast.FunctionLiteral)
+  if !ok {
+    t.Fatalf("stmt.Expression is not ast.FunctionLiteral. got=%T",
+      stmt.Expression)
+  }
+
+  if len(function.Parameters) != 2 {
+    t.Fatalf("function literal parameters wrong. want 2, got=%d\n",
+      len(function.Parameters))
+  }
+
+  testLiteralExpression(t, function.Parameters[0], "x")
+  testLiteralExpression(t, function.Parameters[1], "y")
+
+  if len(function.Body.Statements) != 1 {
+    t.Fatalf("function.Body.Statements has not 1 statements. got=%d\n",
+      len(function.Body.Statements))
+  }
+
+  bodyStmt, ok := function.Body.Statements[0].(*ast.ExpressionStatement)
+  if !ok {
+    t.Fatalf("function body stmt is not ast.ExpressionStatement. got=%T",
+      function.Body.Statements[0])
+  }
+
+  testInfixExpression(t, bodyStmt.Expression, "x", "+", "y")
+}
+
+func TestFunctionParameterParsing(t *testing.T) {
+  tests := []struct {
+    input          string
+    expectedParams []string
+  }{
+    {input: "fn() {};", expectedParams: []string{}},
+    {input: "fn(x) {};", expectedParams: []string{"x"}},
+    {input: "fn(x, y, z) {};", expectedParams: []string{"x", "y", "z"}},
+  }
+
+  for _, tt := range tests {
+    l := lexer.New(tt.input)
+    p := New(l)
+    program := p.ParseProgram()
+    checkParserErrors(t, p)
+
+    stmt := program.Statements[0].(*ast.ExpressionStatement)
+    function := stmt.Expression.(*ast.FunctionLiteral)
+
+    if len(function.Parameters) != len(tt.expectedParams) {
+      t.Errorf("length parameters wrong. want %d, got=%d\n",
+        len(tt.expectedParams), len(function.Parameters))
+    }
+
+    for i, ident := range tt.expectedParams {
+      testLiteralExpression(t, function.Parameters[i], ident)
+    }
+  }
+}
+
+func TestRestParameterParsing(t *testing.T) {
+  tests := []struct {
+    input          string
+    expectedParams []string
+    expectedRest   bool
+    expectedString string
+  }{
+    {"fn(...nums) {};", []string{"nums"}, true, "fn(...nums) "},
+    {"fn(first, ...rest) {};", []string{"first", "rest"}, true, "fn(first, ...rest) "},
+    {"fn(a, b) {};", []string{"a", "b"}, false, "fn(a, b) "},
+  }
+
+  for _, tt := range tests {
+    l := lexer.New(tt.input)
+    p := New(l)
+    program := p.ParseProgram()
+    checkParserErrors(t, p)
+
+    stmt := program.Statements[0].(*ast.ExpressionStatement)
+    function := stmt.Expression.(*ast.FunctionLiteral)
+
+    if len(function.Parameters) != len(tt.expectedParams) {
+      t.Fatalf("length parameters wrong. want %d, got=%d\n",
+        len(tt.expectedParams), len(function.Parameters))
+    }
+    for i, ident := range tt.expectedParams {
+      testLiteralExpression(t, function.Parameters[i], ident)
+    }
+
+    if function.Rest != tt.expectedRest {
+      t.Errorf("function.Rest wrong. want %t, got=%t", tt.expectedRest, function.Rest)
+    }
+    if function.String() != tt.expectedString {
+      t.Errorf("function.String() wrong. want %q, got=%q", tt.expectedString, function.String())
+    }
+  }
+}
+
+func TestDefaultParameterParsing(t *testing.T) {
+  tests := []struct {
+    input            string
+    expectedDefaults []bool
+    expectedString   string
+  }{
+    {"fn(x, y = 10) {};", []bool{false, true}, "fn(x, y = 10) "},
+    {"fn(x = 1 + 2, y = x * 2) {};", []bool{true, true}, "fn(x = (1 + 2), y = (x * 2)) "},
+    {"fn(x, y = [1], ...rest) {};", []bool{false, true, false}, "fn(x, y = [1], ...rest) "},
+  }
+
+  for _, tt := range tests {
+    l := lexer.New(tt.input)
+    p := New(l)
+    program := p.ParseProgram()
+    checkParserErrors(t, p)
+
+    stmt := program.Statements[0].(*ast.ExpressionStatement)
+    function := stmt.Expression.(*ast.FunctionLiteral)
+
+    if len(function.Defaults) != len(tt.expectedDefaults) {
+      t.Fatalf("length defaults wrong. want %d, got=%d",
+        len(tt.expectedDefaults), len(function.Defaults))
+    }
+    for i, hasDefault := range tt.expectedDefaults {
+      if (function.Defaults[i] != nil) != hasDefault {
+        t.Errorf("default of %s wrong. want %t, got=%v",
+          function.Parameters[i], hasDefault, function.Defaults[i])
+      }
+    }
+
+    if function.String() != tt.expectedString {
+      t.Errorf("function.String() wrong. want %q, got=%q", tt.expectedString, function.String())
+    }
+  }
+}
+
+func TestDefaultParameterErrors(t *testing.T) {
+  tests := []struct {
+    input         string
+    expectedError string
+  }{
+    {"fn(x = 1, y) {}", "1:11: parameter y needs a default value"},
+    {"fn(...rest = 1) {}", "1:12: expected next token to be RPAREN, got ASSIGN instead"},
+  }
+
+  for _, tt := range tests {
+    l := lexer.New(tt.input)
+    p := New(l)
+    p.ParseProgram()
+
+    errors := p.Errors().Strings()
+    if len(errors) == 0 || errors[0] != tt.expectedError {
+      t.Errorf("wrong errors for %q. want first=%q, got=%q", tt.input, tt.expectedError, errors)
+    }
+  }
+}
+
+func TestRestParameterErrors(t *testing.T) {
+  tests := []struct {
+    input         string
+    expectedError string
+  }{
+    {"fn(...rest, a) {}", "1:11: expected next token to be RPAREN, got COMMA instead"},
+    {"fn(a, ...) {}", "1:10: expected next token to be IDENT, got RPAREN instead"},
+  }
+
+  for _, tt := range tests {
+    l := lexer.New(tt.input)
+    p := New(l)
+    p.ParseProgram()
+
+    errors := p.Errors().Strings()
+    if len(errors) == 0 || errors[0] != tt.expectedError {
+      t.Errorf("wrong errors for %q. want first=%q, got=%q", tt.input, tt.expectedError, errors)
+    }
+  }
+}
+
+func TestMacroLiteralParsing(t *testing.T) {
+  input := `macro(x, y) { x + y; }`
+
+  l := lexer.New(input)
+  p := New(l)
+  program := p.ParseProgram()
+  checkParserErrors(t, p)
+
+  if len(program.Statements) != 1 {
+    t.Fatalf("program.Statements does not contain %d statements. got=%d\n",
+      1, len(program.Statements))
+  }
+
+  stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+  if !ok {
+    t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T",
+      program.Statements[0])
+  }
+
+  macro, ok := stmt.Expression.(*ast.MacroLiteral)
+  if !ok {
+    t.Fatalf("stmt.Expression is not ast.MacroLiteral. got=%T",
+      stmt.Expression)
+  }
+
+  if len(macro.Parameters) != 2 {
+    t.Fatalf("macro literal parameters wrong. want 2, got=%d\n",
+      len(macro.Parameters))
+  }
+
+  testLiteralExpression(t, macro.Parameters[0], "x")
+  testLiteralExpression(t, macro.Parameters[1], "y")
+
+  if len(macro.Body.Statements) != 1 {
+    t.Fatalf("macro.Body.Statements has not 1 statements. got=%d\n",
+      len(macro.Body.Statements))
+  }
+
+  bodyStmt, ok := macro.Body.Statements[0].(*ast.ExpressionStatement)
+  if !ok {
+    t.Fatalf("macro body stmt is not ast.ExpressionStatement. got=%T",
+      macro.Body.Statements[0])
+  }
 
   testInfixExpression(t, bodyStmt.Expression, "x", "+", "y")
 }
 
-func TestFunctionParameterParsing(t *testing.T) {
+func TestMacroLiteralErrors(t *testing.T) {
+  for _, input := range []string{"macro(x = 1) { x }", "macro(...x) { x }"} {
+    l := lexer.New(input)
+    p := New(l)
+    p.ParseProgram()
+
+    expected := "1:1: macro parameters cannot have defaults or be rest parameters"
+    errors := p.Errors().Strings()
+    if len(errors) != 1 || errors[0] != expected {
+      t.Errorf("wrong errors for %q. want=%q, got=%q", input, expected, errors)
+    }
+  }
+}
+
+func TestCallExpressionParsing(t *testing.T) {
+  input := "add(1, 2 * 3, 4 + 5);"
+
+  l := lexer.New(input)
+  p := New(l)
+  program := p.ParseProgram()
+  checkParserErrors(t, p)
+
+  if len(program.Statements) != 1 {
+    t.Fatalf("program.Statements does not contain %d statements. got=%d\n",
+      1, len(program.Statements))
+  }
+
+  stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+  if !ok {
+    t.Fatalf("stmt is not ast.ExpressionStatement. got=%T",
+      program.Statements[0])
+  }
+
+  exp, ok := stmt.Expression.(*ast.CallExpression)
+  if !ok {
+    t.Fatalf("stmt.Expression is not ast.CallExpression. got=%T",
+      stmt.Expression)
+  }
+
+  if !testIdentifier(t, exp.Function, "add") {
+    return
+  }
+
+  if len(exp.Arguments) != 3 {
+    t.Fatalf("wrong length of arguments. got=%d", len(exp.Arguments))
+  }
+
+  testLiteralExpression(t, exp.Arguments[0], 1)
+  testInfixExpression(t, exp.Arguments[1], 2, "*", 3)
+  testInfixExpression(t, exp.Arguments[2], 4, "+", 5)
+}
+
+func TestKeywordArgumentParsing(t *testing.T) {
+  tests := []struct {
+    input            string
+    expectedArgs     int
+    expectedKeywords []string
+    expectedString   string
+  }{
+    {`greet(name = "Sam", greeting = "Hi")`, 0, []string{"name", "greeting"}, "greet(name = Sam, greeting = Hi)"},
+    {`greet("Hi", name = 1 + 2)`, 1, []string{"name"}, "greet(Hi, name = (1 + 2))"},
+    {`f(h["a"] = 1)`, 1, nil, "f((h[a]) = 1)"},
+  }
+
+  for _, tt := range tests {
+    l := lexer.New(tt.input)
+    p := New(l)
+    program := p.ParseProgram()
+    checkParserErrors(t, p)
+
+    stmt := program.Statements[0].(*ast.ExpressionStatement)
+    call, ok := stmt.Expression.(*ast.CallExpression)
+    if !ok {
+      t.Fatalf("stmt.Expression is not ast.CallExpression. got=%T", stmt.Expression)
+    }
+
+    if len(call.Arguments) != tt.expectedArgs {
+      t.Errorf("wrong number of arguments. want=%d, got=%d", tt.expectedArgs, len(call.Arguments))
+    }
+    if len(call.Keywords) != len(tt.expectedKeywords) || len(call.KeywordValues) != len(call.Keywords) {
+      t.Fatalf("wrong keywords. want=%v, got=%v", tt.expectedKeywords, call.Keywords)
+    }
+    for i, keyword := range tt.expectedKeywords {
+      testIdentifier(t, call.Keywords[i], keyword)
+    }
+
+    if call.String() != tt.expectedString {
+      t.Errorf("call.String() wrong. want=%q, got=%q", tt.expectedString, call.String())
+    }
+  }
+}
+
+func TestKeywordArgumentErrors(t *testing.T) {
+  tests := []struct {
+    input         string
+    expectedError string
+  }{
+    {"f(a = 1, 2)", "1:2: positional argument 2 after keyword arguments"},
+    {"f(a = 1, b = 2, a = 3)", "1:19: duplicate keyword argument: a"},
+  }
+
+  for _, tt := range tests {
+    l := lexer.New(tt.input)
+    p := New(l)
+    p.ParseProgram()
+
+    errors := p.Errors().Strings()
+    if len(errors) != 1 || errors[0] != tt.expectedError {
+      t.Errorf("wrong errors for %q. want=%q, got=%q", tt.input, tt.expectedError, errors)
+    }
+  }
+}
+
+func TestStringLiteralExpression(t *testing.T) {
+  input := `"hello world";`
+
+  l := lexer.New(input)
+  p := New(l)
+  program := p.ParseProgram()
+  checkParserErrors(t, p)
+
+  stmt := program.Statements[0].(*ast.ExpressionStatement)
+  literal, ok := stmt.Expression.(*ast.StringLiteral)
+  if !ok {
+    t.Fatalf("exp not *ast.StringLiteral. got=%T", stmt.Expression)
+  }
+
+  if literal.Value != "hello world" {
+    t.Errorf("literal.Value not %q. got=%q", "hello world", literal.Value)
+  }
+}
+
+func TestParsingArrayLiterals(t *testing.T) {
+  input := "[1, 2 * 2, 3 + 3]"
+
+  l := lexer.New(input)
+  p := New(l)
+  program := p.ParseProgram()
+  checkParserErrors(t, p)
+
+  stmt := program.Statements[0].(*ast.ExpressionStatement)
+  array, ok := stmt.Expression.(*ast.ArrayLiteral)
+  if !ok {
+    t.Fatalf("exp not ast.ArrayLiteral. got=%T", stmt.Expression)
+  }
+
+  if len(array.Elements) != 3 {
+    t.Fatalf("len(array.Elements) not 3. got=%d", len(array.Elements))
+  }
+
+  testIntegerLiteral(t, array.Elements[0], 1)
+  testInfixExpression(t, array.Elements[1], 2, "*", 2)
+  testInfixExpression(t, array.Elements[2], 3, "+", 3)
+}
+
+func TestParsingEmptyArrayLiterals(t *testing.T) {
+  l := lexer.New("[]")
+  p := New(l)
+  program := p.ParseProgram()
+  checkParserErrors(t, p)
+
+  stmt := program.Statements[0].(*ast.ExpressionStatement)
+  array, ok := stmt.Expression.(*ast.ArrayLiteral)
+  if !ok {
+    t.Fatalf("exp not ast.ArrayLiteral. got=%T", stmt.Expression)
+  }
+
+  if len(array.Elements) != 0 {
+    t.Fatalf("len(array.Elements) not 0. got=%d", len(array.Elements))
+  }
+}
+
+func TestParsingHashLiterals(t *testing.T) {
+  input := `{"one": 1, "two": 2, "three": 3}`
+
+  l := lexer.New(input)
+  p := New(l)
+  program := p.ParseProgram()
+  checkParserErrors(t, p)
+
+  stmt := program.Statements[0].(*ast.ExpressionStatement)
+  hash, ok := stmt.Expression.(*ast.HashLiteral)
+  if !ok {
+    t.Fatalf("exp is not ast.HashLiteral. got=%T", stmt.Expression)
+  }
+
+  expectedKeys := []string{"one", "two", "three"}
+  expectedValues := []int64{1, 2, 3}
+
+  if len(hash.Keys) != 3 || len(hash.Values) != 3 {
+    t.Fatalf("hash has wrong number of pairs. got=%d", len(hash.Keys))
+  }
+
+  // pairs stay in source order
+  for i, key := range hash.Keys {
+    literal, ok := key.(*ast.StringLiteral)
+    if !ok {
+      t.Errorf("key is not ast.StringLiteral. got=%T", key)
+      continue
+    }
+    if literal.Value != expectedKeys[i] {
+      t.Errorf("key[%d] wrong. want=%q, got=%q", i, expectedKeys[i], literal.Value)
+    }
+    testIntegerLiteral(t, hash.Values[i], expectedValues[i])
+  }
+}
+
+func TestParsingEmptyHashLiteral(t *testing.T) {
+  l := lexer.New("{}")
+  p := New(l)
+  program := p.ParseProgram()
+  checkParserErrors(t, p)
+
+  stmt := program.Statements[0].(*ast.ExpressionStatement)
+  hash, ok := stmt.Expression.(*ast.HashLiteral)
+  if !ok {
+    t.Fatalf("exp is not ast.HashLiteral. got=%T", stmt.Expression)
+  }
+
+  if len(hash.Keys) != 0 {
+    t.Errorf("hash.Keys has wrong length. got=%d", len(hash.Keys))
+  }
+}
+
+func TestParsingHashLiteralsWithExpressions(t *testing.T) {
+  input := `{"one": 0 + 1, "two": 10 - 8, "three": 15 / 5}`
+
+  l := lexer.New(input)
+  p := New(l)
+  program := p.ParseProgram()
+  checkParserErrors(t, p)
+
+  stmt := program.Statements[0].(*ast.ExpressionStatement)
+  hash, ok := stmt.Expression.(*ast.HashLiteral)
+  if !ok {
+    t.Fatalf("exp is not ast.HashLiteral. got=%T", stmt.Expression)
+  }
+
+  tests := []func(ast.Expression){
+    func(e ast.Expression) { testInfixExpression(t, e, 0, "+", 1) },
+    func(e ast.Expression) { testInfixExpression(t, e, 10, "-", 8) },
+    func(e ast.Expression) { testInfixExpression(t, e, 15, "/", 5) },
+  }
+
+  if len(hash.Values) != len(tests) {
+    t.Fatalf("hash has wrong number of pairs. got=%d", len(hash.Values))
+  }
+
+  for i, testFunc := range tests {
+    testFunc(hash.Values[i])
+  }
+}
+
+func TestParsingIndexExpressions(t *testing.T) {
+  input := `"hello"[1 + 1]`
+
+  l := lexer.New(input)
+  p := New(l)
+  program := p.ParseProgram()
+  checkParserErrors(t, p)
+
+  stmt := program.Statements[0].(*ast.ExpressionStatement)
+  indexExp, ok := stmt.Expression.(*ast.IndexExpression)
+  if !ok {
+    t.Fatalf("exp not *ast.IndexExpression. got=%T", stmt.Expression)
+  }
+
+  if _, ok := indexExp.Left.(*ast.StringLiteral); !ok {
+    t.Fatalf("indexExp.Left not *ast.StringLiteral. got=%T", indexExp.Left)
+  }
+
+  if !testInfixExpression(t, indexExp.Index, 1, "+", 1) {
+    return
+  }
+}
+
+func TestParsingOptionalIndexExpressions(t *testing.T) {
   tests := []struct {
-    input          string
-    expectedParams []string
+    input    string
+    expected string
   }{
-    {input: "fn() {};", expectedParams: []string{}},
-    {input: "fn(x) {};", expectedParams: []string{"x"}},
-    {input: "fn(x, y, z) {};", expectedParams: []string{"x", "y", "z"}},
+    {"a?.[0]", "(a?.[0])"},
+    {"h?.key", "(h?.[key])"},
+    {"a?.[0]?.[1]", "((a?.[0])?.[1])"},
+    {"a?.[0][1] + 1", "(((a?.[0])[1]) + 1)"},
+    {"f(x)?.key", "(f(x)?.[key])"},
+  }
+
+  for _, tt := range tests {
+    p := New(lexer.New(tt.input))
+    program := p.ParseProgram()
+    checkParserErrors(t, p)
+
+    if actual := program.String(); actual != tt.expected {
+      t.Errorf("expected=%q, got=%q", tt.expected, actual)
+    }
+  }
+
+  // h?.key indexes by the string "key"
+  p := New(lexer.New("h?.key"))
+  program := p.ParseProgram()
+  checkParserErrors(t, p)
+  indexExp := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.IndexExpression)
+  if !indexExp.Optional {
+    t.Errorf("indexExp.Optional is false")
+  }
+  if key, ok := indexExp.Index.(*ast.StringLiteral); !ok || key.Value != "key" {
+    t.Errorf("indexExp.Index is not the string key. got=%s", indexExp.Index)
+  }
+}
+
+func TestParsingMethodCallExpressions(t *testing.T) {
+  tests := []struct {
+    input    string
+    expected string
+  }{
+    {"a.len()", "a.len()"},
+    {"[1].push(2, 3)", "[1].push(2, 3)"},
+    {"a.push(1).push(2).len()", "a.push(1).push(2).len()"},
+    {"-a.len() + 1", "((-a.len()) + 1)"},
+    {"a[0].first()[1]", "((a[0]).first()[1])"},
+    {"f(x).upper()", "f(x).upper()"},
+  }
+
+  for _, tt := range tests {
+    p := New(lexer.New(tt.input))
+    program := p.ParseProgram()
+    checkParserErrors(t, p)
+
+    if actual := program.String(); actual != tt.expected {
+      t.Errorf("expected=%q, got=%q", tt.expected, actual)
+    }
+  }
+
+  p := New(lexer.New(`"abc".upper(1)`))
+  program := p.ParseProgram()
+  checkParserErrors(t, p)
+  call, ok := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.MethodCallExpression)
+  if !ok {
+    t.Fatalf("exp not *ast.MethodCallExpression. got=%T", program.Statements[0])
+  }
+  if _, ok := call.Receiver.(*ast.StringLiteral); !ok {
+    t.Errorf("call.Receiver not *ast.StringLiteral. got=%T", call.Receiver)
+  }
+  testIdentifier(t, call.Method, "upper")
+  if len(call.Arguments) != 1 {
+    t.Fatalf("wrong length of arguments. got=%d", len(call.Arguments))
+  }
+  testIntegerLiteral(t, call.Arguments[0], 1)
+}
+
+func TestParsingAssignExpressions(t *testing.T) {
+  tests := []struct {
+    input    string
+    expected string
+  }{
+    {`h["a"] = 1`, "(h[a]) = 1"},
+    {"arr[0] = 1 + 2 * 3", "(arr[0]) = (1 + (2 * 3))"},
+    {"a[0] = b[0] = 1", "(a[0]) = (b[0]) = 1"},
+    {"a[i + 1] = a[i] == 2", "(a[(i + 1)]) = ((a[i]) == 2)"},
+    {"x = 1", "x = 1"},
+    {"x = y = x + 1", "x = y = (x + 1)"},
   }
 
   for _, tt := range tests {
@@ -608,55 +1857,133 @@ func TestFunctionParameterParsing(t *testing.T) {
     checkParserErrors(t, p)
 
     stmt := program.Statements[0].(*ast.ExpressionStatement)
-    function := stmt.Expression.(*ast.FunctionLiteral)
-
-    if len(function.Parameters) != len(tt.expectedParams) {
-      t.Errorf("length parameters wrong. want %d, got=%d\n",
-        len(tt.expectedParams), len(function.Parameters))
+    if _, ok := stmt.Expression.(*ast.AssignExpression); !ok {
+      t.Fatalf("exp is not ast.AssignExpression. got=%T", stmt.Expression)
     }
 
-    for i, ident := range tt.expectedParams {
-      testLiteralExpression(t, function.Parameters[i], ident)
+    if program.String() != tt.expected {
+      t.Errorf("expected=%q, got=%q", tt.expected, program.String())
     }
   }
 }
 
-func TestCallExpressionParsing(t *testing.T) {
-  input := "add(1, 2 * 3, 4 + 5);"
-
-  l := lexer.New(input)
+func TestParsingInvalidAssignTarget(t *testing.T) {
+  l := lexer.New("1 + 2 = 3")
   p := New(l)
-  program := p.ParseProgram()
-  checkParserErrors(t, p)
+  p.ParseProgram()
 
-  if len(program.Statements) != 1 {
-    t.Fatalf("program.Statements does not contain %d statements. got=%d\n",
-      1, len(program.Statements))
+  expected := "1:7: invalid assignment target: (1 + 2)"
+  errors := p.Errors().Strings()
+  if len(errors) != 1 || errors[0] != expected {
+    t.Errorf("wrong errors. want=%q, got=%q", expected, errors)
   }
+}
 
-  stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
-  if !ok {
-    t.Fatalf("stmt is not ast.ExpressionStatement. got=%T",
-      program.Statements[0])
+func TestParserErrorRecovery(t *testing.T) {
+  tests := []struct {
+    input          string
+    expectedErrors []string
+    expectedStmts  []string
+  }{
+    {
+      "let = 5; let y 6; let z = 1;",
+      []string{
+        "1:5: expected next token to be IDENT, got ASSIGN instead",
+        "1:16: expected next token to be ASSIGN, got INT instead",
+      },
+      []string{"let z = 1;"},
+    },
+    {
+      "let x = 1;\nlet = 2;\nx + ;\nx",
+      []string{
+        "2:5: expected next token to be IDENT, got ASSIGN instead",
+        "3:5: no prefix parse function for SEMICOLON found",
+      },
+      []string{"let x = 1;", "x"},
+    },
+    {
+      "fn() { let }; let a = 1;",
+      []string{
+        "1:12: expected next token to be IDENT, got RBRACE instead",
+      },
+      []string{"let a = 1;"},
+    },
   }
 
-  exp, ok := stmt.Expression.(*ast.CallExpression)
-  if !ok {
-    t.Fatalf("stmt.Expression is not ast.CallExpression. got=%T",
-      stmt.Expression)
+  for _, tt := range tests {
+    l := lexer.New(tt.input)
+    p := New(l)
+    program := p.ParseProgram()
+
+    errors := p.Errors().Strings()
+    if len(errors) != len(tt.expectedErrors) {
+      t.Fatalf("wrong number of errors for %q. want=%d, got=%d: %q",
+        tt.input, len(tt.expectedErrors), len(errors), errors)
+    }
+    for i, msg := range tt.expectedErrors {
+      if errors[i] != msg {
+        t.Errorf("errors[%d] wrong. want=%q, got=%q", i, msg, errors[i])
+      }
+    }
+
+    if len(program.Statements) != len(tt.expectedStmts) {
+      t.Fatalf("wrong number of statements for %q. want=%d, got=%d",
+        tt.input, len(tt.expectedStmts), len(program.Statements))
+    }
+    for i, stmt := range tt.expectedStmts {
+      if program.Statements[i].String() != stmt {
+        t.Errorf("statements[%d] wrong. want=%q, got=%q",
+          i, stmt, program.Statements[i].String())
+      }
+    }
   }
+}
 
-  if !testIdentifier(t, exp.Function, "add") {
-    return
+func TestParseExpression(t *testing.T) {
+  tests := []struct {
+    input    string
+    expected string
+  }{
+    {"5", "5"},
+    {"1 + 2 * 3", "(1 + (2 * 3))"},
+    {"-(1 + 2);", "(-(1 + 2))"},
+    {"add(1, 2)", "add(1, 2)"},
   }
 
-  if len(exp.Arguments) != 3 {
-    t.Fatalf("wrong length of arguments. got=%d", len(exp.Arguments))
+  for _, tt := range tests {
+    p := New(lexer.New(tt.input))
+    expression, err := p.ParseExpression()
+    if err != nil {
+      t.Fatalf("ParseExpression(%q) returned error: %s", tt.input, err)
+    }
+
+    if expression.String() != tt.expected {
+      t.Errorf("expected=%q, got=%q", tt.expected, expression.String())
+    }
   }
+}
 
-  testLiteralExpression(t, exp.Arguments[0], 1)
-  testInfixExpression(t, exp.Arguments[1], 2, "*", 3)
-  testInfixExpression(t, exp.Arguments[2], 4, "+", 5)
+func TestParseExpressionErrors(t *testing.T) {
+  tests := []struct {
+    input    string
+    expected string
+  }{
+    {"1 + 2 extra", "1:7: expected next token to be EOF, got IDENT instead"},
+    {"1; 2", "1:4: expected next token to be EOF, got INT instead"},
+    {"", "1:1: no prefix parse function for EOF found"},
+  }
+
+  for _, tt := range tests {
+    p := New(lexer.New(tt.input))
+    expression, err := p.ParseExpression()
+    if err == nil {
+      t.Fatalf("ParseExpression(%q) returned no error. got=%s", tt.input, expression)
+    }
+
+    if err.Error() != tt.expected {
+      t.Errorf("wrong error. expected=%q, got=%q", tt.expected, err.Error())
+    }
+  }
 }
 
 func testInfixExpression(t *testing.T, exp ast.Expression, left interface{},
@@ -796,7 +2123,7 @@ func testLetStatement(t *testing.T, s ast.Statement, name string) bool {
 }
 
 func checkParserErrors(t *testing.T, p *Parser) {
-  errors := p.Errors()
+  errors := p.Errors().Strings()
   if len(errors) == 0 {
     return
   }
@@ -807,3 +2134,329 @@ func checkParserErrors(t *testing.T, p *Parser) {
   }
   t.FailNow()
 }
+
+func TestTrace(t *testing.T) {
+  var out bytes.Buffer
+  EnableTrace(&out)
+  defer EnableTrace(nil)
+
+  New(lexer.New("-1 * 2")).ParseProgram()
+
+  expected := `BEGIN parseExpressionStatement
+	BEGIN parseExpression
+		BEGIN parsePrefixExpression -
+			BEGIN parseExpression
+				BEGIN parseIntegerLiteral 1
+				END parseIntegerLiteral 1
+			END parseExpression
+		END parsePrefixExpression -
+		BEGIN parseInfixExpression *
+			BEGIN parseExpression
+				BEGIN parseIntegerLiteral 2
+				END parseIntegerLiteral 2
+			END parseExpression
+		END parseInfixExpression *
+	END parseExpression
+END parseExpressionStatement
+`
+  if out.String() != expected {
+    t.Errorf("wrong trace output. expected=\n%s\ngot=\n%s", expected, out.String())
+  }
+
+  // turned off, nothing more is written
+  EnableTrace(nil)
+  out.Reset()
+  New(lexer.New("-1 * 2")).ParseProgram()
+  if out.Len() != 0 {
+    t.Errorf("trace disabled but got output %q", out.String())
+  }
+}
+
+func TestNodePositions(t *testing.T) {
+  input := `let add = fn(a, b) {
+  a + b;
+};
+add(1,
+    "two");`
+
+  program := New(lexer.New(input)).ParseProgram()
+
+  let := program.Statements[0].(*ast.LetStatement)
+  fn := let.Value.(*ast.FunctionLiteral)
+  infix := fn.Body.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.InfixExpression)
+  call := program.Statements[1].(*ast.ExpressionStatement).Expression.(*ast.CallExpression)
+
+  tests := []struct {
+    node         ast.Node
+    expectedLine int
+    expectedCol  int
+  }{
+    {program, 1, 1},
+    {let, 1, 1},
+    {let.Name, 1, 5},
+    {fn, 1, 11},
+    {fn.Parameters[1], 1, 17},
+    {fn.Body, 1, 20},
+    {infix, 2, 5},
+    {infix.Left, 2, 3},
+    {call, 4, 4},
+    {call.Arguments[1], 5, 5},
+  }
+
+  for _, tt := range tests {
+    line, col := tt.node.Pos()
+    if line != tt.expectedLine || col != tt.expectedCol {
+      t.Errorf("wrong position for %q. expected=%d:%d, got=%d:%d",
+        tt.node.String(), tt.expectedLine, tt.expectedCol, line, col)
+    }
+  }
+}
+
+func TestCustomOperators(t *testing.T) {
+  tests := []struct {
+    input    string
+    opts     []Option
+    expected string
+  }{
+    // ':' between EQUALS and SUM
+    {"1 + 2 : 3 * 4", []Option{WithInfix(token.COLON, LESSGREATER, ParseBinary)}, "((1 + 2) : (3 * 4))"},
+    {"a == b : c", []Option{WithInfix(token.COLON, LESSGREATER, ParseBinary)}, "(a == (b : c))"},
+    // ':' binding tighter than '*'
+    {"1 * 2 : 3", []Option{WithInfix(token.COLON, PREFIX, ParseBinary)}, "(1 * (2 : 3))"},
+    // a built-in operator moved below '+'
+    {"1 + 2 * 3", []Option{WithInfix(token.ASTERISK, EQUALS, ParseBinary)}, "((1 + 2) * 3)"},
+    // '!' as a postfix operator, eg: 5!
+    {
+      "3 + 5!",
+      []Option{WithInfix(token.BANG, CALL, func(p *Parser, left ast.Expression) ast.Expression {
+        return &ast.CallExpression{
+          Function:  &ast.Identifier{Value: "factorial"},
+          Arguments: []ast.Expression{left},
+        }
+      })},
+      "(3 + factorial(5))",
+    },
+    // a prefix '*', eg: *x
+    {
+      "*x + 1",
+      []Option{WithPrefix(token.ASTERISK, func(p *Parser) ast.Expression {
+        p.nextToken()
+        return &ast.CallExpression{
+          Function:  &ast.Identifier{Value: "deref"},
+          Arguments: []ast.Expression{p.parseExpression(PREFIX)},
+        }
+      })},
+      "(deref(x) + 1)",
+    },
+  }
+
+  for _, tt := range tests {
+    p := New(lexer.New(tt.input), tt.opts...)
+    program := p.ParseProgram()
+    checkParserErrors(t, p)
+
+    if actual := program.String(); actual != tt.expected {
+      t.Errorf("wrong tree for %q. expected=%q, got=%q", tt.input, tt.expected, actual)
+    }
+  }
+
+  // the defaults are untouched by other parsers' options
+  p := New(lexer.New("1 + 2 : 3"))
+  p.ParseProgram()
+  if len(p.Errors()) == 0 {
+    t.Errorf("default parser accepted ':' as an operator")
+  }
+}
+
+func TestParse(t *testing.T) {
+  res := Parse("let x = 5; x * 2")
+  if !res.Ok() {
+    t.Fatalf("Parse reported errors: %v", res.Errors)
+  }
+  if actual := res.Program.String(); actual != "let x = 5;(x * 2)" {
+    t.Errorf("wrong program. got=%q", actual)
+  }
+
+  res = Parse("let = 5;\nlet y 6;\nlet z = 1;")
+  if res.Ok() {
+    t.Fatalf("Parse of a broken source reported Ok")
+  }
+
+  expected := ParseErrors{
+    {Kind: UnexpectedToken, Message: "expected next token to be IDENT, got ASSIGN instead", Line: 1, Column: 5},
+    {Kind: UnexpectedToken, Message: "expected next token to be ASSIGN, got INT instead", Line: 2, Column: 7},
+  }
+  if !reflect.DeepEqual(res.Errors, expected) {
+    t.Fatalf("wrong errors. expected=%+v, got=%+v", expected, res.Errors)
+  }
+  if msg := res.Errors[1].Error(); msg != "2:7: expected next token to be ASSIGN, got INT instead" {
+    t.Errorf("wrong error text. got=%q", msg)
+  }
+
+  // the statements that did parse are kept
+  if actual := res.Program.String(); actual != "let z = 1;" {
+    t.Errorf("wrong program after errors. got=%q", actual)
+  }
+}
+
+func TestErrorKinds(t *testing.T) {
+  tests := []struct {
+    input          string
+    expectedKind   ErrorKind
+    expectedLine   int
+    expectedColumn int
+  }{
+    {"let = 5;", UnexpectedToken, 1, 5},
+    {"\n  )", NoPrefixFn, 2, 3},
+    {"x + 5__0", IllegalToken, 1, 5},
+    {"1 = 2", InvalidAssignment, 1, 3},
+    {"fn(a = 1, b) {}", BadParameter, 1, 11},
+    {"macro(a = 1) { a }", BadParameter, 1, 1},
+    {"f(a = 1, 2)", BadArgument, 1, 2},
+    {"f(a = 1, a = 2)", BadArgument, 1, 12},
+    {"a?.[0] = 1", InvalidAssignment, 1, 8},
+    {"a?.1", UnexpectedToken, 1, 4},
+    {"a.1", UnexpectedToken, 1, 3},
+    {"a.len", UnexpectedToken, 1, 6},
+  }
+
+  for _, tt := range tests {
+    p := New(lexer.New(tt.input))
+    p.ParseProgram()
+
+    errors := p.Errors()
+    if len(errors) == 0 {
+      t.Fatalf("no errors for %q", tt.input)
+    }
+
+    err := errors[0]
+    if err.Kind != tt.expectedKind {
+      t.Errorf("wrong kind for %q. expected=%s, got=%s (%s)", tt.input, tt.expectedKind, err.Kind, err)
+    }
+    if err.Line != tt.expectedLine || err.Column != tt.expectedColumn {
+      t.Errorf("wrong position for %q. expected=%d:%d, got=%d:%d",
+        tt.input, tt.expectedLine, tt.expectedColumn, err.Line, err.Column)
+    }
+  }
+}
+
+func TestEmptyStatements(t *testing.T) {
+  tests := []struct {
+    input    string
+    expected []string
+  }{
+    {";", []string{}},
+    {";;;", []string{}},
+    {"let x = 5;;;", []string{"let x = 5;"}},
+    {"; let x = 5; ; x;", []string{"let x = 5;", "x"}},
+    {"return 1;;", []string{"return 1;"}},
+    {"fn() { ;; x;; ; }", []string{"fn() x"}},
+    {"if (x) { ; } else { ;y; }", []string{"ifx  else y"}},
+  }
+
+  for _, tt := range tests {
+    p := New(lexer.New(tt.input))
+    program := p.ParseProgram()
+    checkParserErrors(t, p)
+
+    actual := []string{}
+    for _, stmt := range program.Statements {
+      actual = append(actual, stmt.String())
+    }
+    if !reflect.DeepEqual(actual, tt.expected) {
+      t.Errorf("wrong statements for %q. expected=%q, got=%q", tt.input, tt.expected, actual)
+    }
+  }
+}
+
+func TestTrailingCommas(t *testing.T) {
+  tests := []struct {
+    input    string
+    expected string
+  }{
+    {"add(1, 2,)", "add(1, 2)"},
+    {"add(\n  1,\n  2,\n)", "add(1, 2)"},
+    {"add(1,)", "add(1)"},
+    {"f(a, b = 2,)", "f(a, b = 2)"},
+    {"[1, 2,]", "[1, 2]"},
+    {"[1,]", "[1]"},
+    {`{"a": 1, "b": 2,}`, `{a:1, b:2}`},
+    {`{"a": 1,}`, `{a:1}`},
+    {"fn(a, b,) { a }", "fn(a, b) a"},
+    {"fn(a, b = 1,) { a }", "fn(a, b = 1) a"},
+    {"fn(a,) { a }", "fn(a) a"},
+  }
+
+  for _, tt := range tests {
+    p := New(lexer.New(tt.input))
+    program := p.ParseProgram()
+    checkParserErrors(t, p)
+
+    if actual := program.String(); actual != tt.expected {
+      t.Errorf("wrong program for %q. expected=%q, got=%q", tt.input, tt.expected, actual)
+    }
+  }
+}
+
+func TestTrailingCommaErrors(t *testing.T) {
+  tests := []struct {
+    input    string
+    expected string
+  }{
+    {"add(,)", "1:5: no prefix parse function for COMMA found"},
+    {"[,]", "1:2: no prefix parse function for COMMA found"},
+    {"{,}", "1:2: no prefix parse function for COMMA found"},
+    {"fn(,) {}", "1:4: expected parameter name, got COMMA instead"},
+    {"fn(a,,) {}", "1:6: expected parameter name, got COMMA instead"},
+    {"add(1,,)", "1:7: no prefix parse function for COMMA found"},
+  }
+
+  for _, tt := range tests {
+    p := New(lexer.New(tt.input))
+    p.ParseProgram()
+
+    errors := p.Errors().Strings()
+    if len(errors) == 0 || errors[0] != tt.expected {
+      t.Errorf("wrong errors for %q. want first=%q, got=%q", tt.input, tt.expected, errors)
+    }
+  }
+}
+
+func TestChainedComparison(t *testing.T) {
+  tests := []struct {
+    input    string
+    expected []string
+  }{
+    {"1 < x < 10", []string{"1:7: chained comparison 1 < x < 10 is not supported, compare each pair on its own: 1 < x and x < 10"}},
+    {"a > b > c", []string{"1:7: chained comparison a > b > c is not supported, compare each pair on its own: a > b and b > c"}},
+    {"0 < x + 1 < n * 2", []string{"1:11: chained comparison 0 < (x + 1) < (n * 2) is not supported, compare each pair on its own: 0 < (x + 1) and (x + 1) < (n * 2)"}},
+    {"a < b < c < d", []string{"1:7: chained comparison a < b < c is not supported, compare each pair on its own: a < b and b < c"}},
+    // not chains
+    {"1 < x + 10", nil},
+    {"1 + x < 10 - y", nil},
+    {"(1 < x) < 10", nil},
+    {"(a < b) < (c)", nil},
+    {"(a < b) > (c + 1)", nil},
+    {"((a < b)) < c", nil},
+    // parens around the right operand don't hide a chain
+    {"a < (b) < c", []string{"1:9: chained comparison a < b < c is not supported, compare each pair on its own: a < b and b < c"}},
+    {"1 < x == true", nil},
+    {"a < b == c > d", nil},
+  }
+
+  for _, tt := range tests {
+    p := New(lexer.New(tt.input))
+    p.ParseProgram()
+
+    errors := p.Errors()
+    if !reflect.DeepEqual(errors.Strings(), append([]string{}, tt.expected...)) {
+      t.Errorf("wrong errors for %q. expected=%q, got=%q", tt.input, tt.expected, errors.Strings())
+      continue
+    }
+    for _, err := range errors {
+      if err.Kind != ChainedComparison {
+        t.Errorf("wrong kind for %q. expected=ChainedComparison, got=%s", tt.input, err.Kind)
+      }
+    }
+  }
+}
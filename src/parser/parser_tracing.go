@@ -2,31 +2,56 @@ package parser
 
 import (
   "fmt"
+  "io"
   "strings"
 )
 
+// where trace output goes, nil means tracing is off
+var traceOut io.Writer
+
 var traceLevel int = 0
 
 const traceIdentPlaceholder string = "\t"
 
+// route parse function enter/exit lines to w, nil turns tracing off again
+// eg: parser.EnableTrace(os.Stderr)
+func EnableTrace(w io.Writer) {
+  traceOut = w
+  traceLevel = 0
+}
+
 func identLevel() string {
   return strings.Repeat(traceIdentPlaceholder, traceLevel-1)
 }
 
 func tracePrint(fs string) {
-  fmt.Printf("%s%s\n", identLevel(), fs)
+  fmt.Fprintf(traceOut, "%s%s\n", identLevel(), fs)
 }
 
 func incIdent() { traceLevel = traceLevel + 1 }
 func decIdent() { traceLevel = traceLevel - 1 }
 
-func trace(msg string) string {
+// name is the parse function, arg the token it starts at (may be empty);
+// they are only joined when tracing is on
+func trace(name, arg string) string {
+  if traceOut == nil {
+    return ""
+  }
+
+  msg := name
+  if arg != "" {
+    msg += " " + arg
+  }
   incIdent()
-  // tracePrint("⬇️  " + msg)
+  tracePrint("BEGIN " + msg)
   return msg
 }
 
 func untrace(msg string) {
-  // tracePrint("⬆️  " + msg)
+  if traceOut == nil || msg == "" {
+    return
+  }
+
+  tracePrint("END " + msg)
   decIdent()
 }
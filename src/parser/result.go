@@ -0,0 +1,86 @@
+package parser
+
+import (
+  "JFFMonkeyLang/src/ast"
+  "JFFMonkeyLang/src/lexer"
+  "fmt"
+)
+
+// what went wrong, so callers can tell errors apart without matching messages
+type ErrorKind int
+
+const (
+  UnexpectedToken   ErrorKind = iota // eg: let = 5
+  NoPrefixFn                         // eg: ; at the start of an expression
+  BadInteger                         // a literal the lexer let through but can't be read
+  IllegalToken                       // eg: 5__0, @
+  InvalidAssignment                  // eg: 1 = 2
+  BadParameter                       // eg: fn(a = 1, b) {}
+  BadArgument                        // eg: f(a = 1, 2)
+  ChainedComparison                  // eg: 1 < x < 10
+  BadTemplate                        // eg: "a ${b"
+)
+
+var errorKindNames = map[ErrorKind]string{
+  UnexpectedToken:   "UnexpectedToken",
+  NoPrefixFn:        "NoPrefixFn",
+  BadInteger:        "BadInteger",
+  IllegalToken:      "IllegalToken",
+  InvalidAssignment: "InvalidAssignment",
+  BadParameter:      "BadParameter",
+  BadArgument:       "BadArgument",
+  ChainedComparison: "ChainedComparison",
+  BadTemplate:       "BadTemplate",
+}
+
+func (k ErrorKind) String() string {
+  if name, ok := errorKindNames[k]; ok {
+    return name
+  }
+  return fmt.Sprintf("ErrorKind(%d)", int(k))
+}
+
+// one parser error and where it happened
+type ParseError struct {
+  Kind    ErrorKind
+  Message string
+  Line    int // 1-based
+  Column  int // 1-based
+}
+
+// eg: 1:5: expected next token to be IDENT, got ASSIGN instead
+func (e ParseError) Error() string {
+  return fmt.Sprintf("%d:%d: %s", e.Line, e.Column, e.Message)
+}
+
+// all errors of one parse, in source order
+type ParseErrors []ParseError
+
+// the plain "line:col: msg" messages
+func (errs ParseErrors) Strings() []string {
+  msgs := make([]string, len(errs))
+  for i, err := range errs {
+    msgs[i] = err.Error()
+  }
+  return msgs
+}
+
+// everything Parse found out about a source
+type ParseResult struct {
+  Program *ast.Program
+  Errors  ParseErrors
+}
+
+// Ok means there were no errors, Program is complete
+func (r *ParseResult) Ok() bool {
+  return len(r.Errors) == 0
+}
+
+// Parse lexes and parses src in one go,
+// eg: if res := parser.Parse(src); !res.Ok() { ... }
+func Parse(src string) *ParseResult {
+  p := New(lexer.New(src))
+  program := p.ParseProgram()
+
+  return &ParseResult{Program: program, Errors: p.errors}
+}
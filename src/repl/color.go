@@ -0,0 +1,33 @@
+package repl
+
+import (
+  "JFFMonkeyLang/src/object"
+  "io"
+  "os"
+)
+
+const colorReset = "\x1b[0m"
+
+// ANSI color of each kind of result, everything else is printed plain
+var colors = map[object.ObjectType]string{
+  object.INTEGER_OBJ:     "\x1b[33m", // yellow
+  object.BIG_INTEGER_OBJ: "\x1b[33m",
+  object.STRING_OBJ:      "\x1b[32m", // green
+  object.BOOLEAN_OBJ:     "\x1b[35m", // magenta
+  object.ERROR_OBJ:       "\x1b[31m", // red
+}
+
+// eg: 5 => "\x1b[33m5\x1b[0m", text is printed as is when color is off
+func colorize(text string, t object.ObjectType, color bool) string {
+  code, ok := colors[t]
+  if !color || !ok {
+    return text
+  }
+  return code + text + colorReset
+}
+
+// only a terminal gets colors, pipes and files get plain text
+func isColorTerminal(out io.Writer) bool {
+  f, ok := out.(*os.File)
+  return ok && isTerminal(f)
+}
@@ -0,0 +1,147 @@
+package repl
+
+import (
+  "JFFMonkeyLang/src/ast"
+  "JFFMonkeyLang/src/compiler"
+  "JFFMonkeyLang/src/evaluator"
+  "JFFMonkeyLang/src/lexer"
+  "JFFMonkeyLang/src/object"
+  "JFFMonkeyLang/src/parser"
+  "errors"
+  "fmt"
+  "io"
+  "io/fs"
+  "os"
+  "sort"
+  "strings"
+)
+
+// eg: :load foo.monkey, :tokens on, :ast on, :reset, :env --all, :disasm, :color off
+//     ^^^^^ ^^^^^^^^^^
+//     name  args
+func runCommand(out io.Writer, line string, s *session) {
+  fields := strings.Fields(line)
+  name := fields[0]
+  args := fields[1:]
+
+  switch name {
+  case ":load":
+    if len(args) != 1 {
+      io.WriteString(out, "usage: :load <path>\n")
+      return
+    }
+    loadFile(out, args[0], s)
+  case ":tokens":
+    setToggle(out, name, args, &s.tokens)
+  case ":ast":
+    setToggle(out, name, args, &s.ast)
+  case ":reset":
+    // forget every binding and macro, switch the toggles off,
+    // color stays as it is, the output is still the same
+    color := s.color
+    *s = *newSession()
+    s.color = color
+  case ":color":
+    setToggle(out, name, args, &s.color)
+  case ":env":
+    if len(args) > 1 || (len(args) == 1 && args[0] != "--all") {
+      io.WriteString(out, "usage: :env [--all]\n")
+      return
+    }
+    printEnv(out, s.env, len(args) == 1)
+  case ":disasm":
+    if len(args) != 0 {
+      io.WriteString(out, "usage: :disasm\n")
+      return
+    }
+    disassemble(out, s.last)
+  default:
+    fmt.Fprintf(out, "unknown command: %s\n", name)
+  }
+}
+
+// eg: :tokens on, :ast off
+func setToggle(out io.Writer, name string, args []string, toggle *bool) {
+  if len(args) != 1 || (args[0] != "on" && args[0] != "off") {
+    fmt.Fprintf(out, "usage: %s on|off\n", name)
+    return
+  }
+  *toggle = args[0] == "on"
+}
+
+// eg: x = 5
+// one line per session binding, sorted by name,
+// with all, the builtins are listed too (a binding shadows a builtin)
+func printEnv(out io.Writer, env *object.Environment, all bool) {
+  values := map[string]object.Object{}
+  for _, name := range env.Names() {
+    values[name], _ = env.Get(name)
+  }
+  if all {
+    for _, name := range evaluator.BuiltinNames() {
+      if _, ok := values[name]; !ok {
+        values[name] = evaluator.LookupBuiltin(name)
+      }
+    }
+  }
+
+  names := make([]string, 0, len(values))
+  for name := range values {
+    names = append(names, name)
+  }
+  sort.Strings(names)
+
+  for _, name := range names {
+    fmt.Fprintf(out, "%s = %s\n", name, values[name].Inspect())
+  }
+}
+
+// the bytecode the last line compiles to, whichever engine ran it
+func disassemble(out io.Writer, program ast.Node) {
+  if program == nil {
+    io.WriteString(out, "nothing to disassemble yet\n")
+    return
+  }
+
+  comp := compiler.New()
+  if err := comp.Compile(program); err != nil {
+    fmt.Fprintf(out, "compilation failed: %s\n", err)
+    return
+  }
+  io.WriteString(out, comp.Bytecode().String())
+}
+
+// parse and eval a whole file against the session env,
+// so everything it defines (macros too) is available at the prompt
+func loadFile(out io.Writer, path string, s *session) {
+  content, err := os.ReadFile(path)
+  if err != nil {
+    if errors.Is(err, fs.ErrNotExist) {
+      fmt.Fprintf(out, "could not load %s: file not found\n", path)
+    } else {
+      fmt.Fprintf(out, "could not load %s: %s\n", path, err)
+    }
+    return
+  }
+
+  l := lexer.New(string(content))
+  p := parser.New(l)
+  program := p.ParseProgram()
+
+  if len(p.Errors()) != 0 {
+    printParserErrors(out, path+":", string(content), p.Errors())
+    return
+  }
+
+  evaluator.DefineMacros(program, s.macroEnv)
+  expanded, expandErr := evaluator.ExpandMacros(program, s.macroEnv)
+  if expandErr != nil {
+    fmt.Fprintf(out, "%s: %s\n", path, expandErr.Inspect())
+    return
+  }
+
+  evaluated := evaluator.Eval(expanded, s.env)
+  if evaluated != nil && evaluated.Type() == object.ERROR_OBJ {
+    fmt.Fprintf(out, "%s: %s\n", path, evaluated.Inspect())
+  }
+}
@@ -0,0 +1,130 @@
+package repl
+
+import (
+  "bufio"
+  "io"
+  "os"
+  "path/filepath"
+  "strings"
+)
+
+// how many lines the history keeps, oldest are dropped first
+var HistorySize = 1000
+
+// file under the home dir the interactive repl persists history to
+const HISTORY_FILE = ".monkey_history"
+
+// previous inputs, oldest first
+type History struct {
+  lines []string
+  max   int
+  // navigation cursor, len(lines) means the fresh line being typed
+  pos int
+}
+
+func NewHistory(max int) *History {
+  return &History{max: max}
+}
+
+// eg: blank lines and repeats of the last line are not recorded
+func (h *History) Add(line string) {
+  h.pos = len(h.lines)
+  if strings.TrimSpace(line) == "" {
+    return
+  }
+  if len(h.lines) > 0 && h.lines[len(h.lines)-1] == line {
+    return
+  }
+
+  h.lines = append(h.lines, line)
+  if h.max > 0 && len(h.lines) > h.max {
+    h.lines = h.lines[len(h.lines)-h.max:]
+  }
+  h.pos = len(h.lines)
+}
+
+func (h *History) Lines() []string {
+  return h.lines
+}
+
+// up arrow, false when already at the oldest line
+func (h *History) Prev() (string, bool) {
+  if h.pos == 0 {
+    return "", false
+  }
+  h.pos--
+  return h.lines[h.pos], true
+}
+
+// down arrow, past the newest line it yields an empty input
+func (h *History) Next() (string, bool) {
+  if h.pos >= len(h.lines) {
+    return "", false
+  }
+  h.pos++
+  if h.pos == len(h.lines) {
+    return "", true
+  }
+  return h.lines[h.pos], true
+}
+
+// one line per entry, the cap still applies
+func (h *History) Load(r io.Reader) error {
+  scanner := bufio.NewScanner(r)
+  for scanner.Scan() {
+    h.Add(scanner.Text())
+  }
+  return scanner.Err()
+}
+
+func (h *History) Save(w io.Writer) error {
+  for _, line := range h.lines {
+    if _, err := io.WriteString(w, line+"\n"); err != nil {
+      return err
+    }
+  }
+  return nil
+}
+
+// eg: ~/.monkey_history, empty when there is no home dir
+func historyPath() string {
+  home, err := os.UserHomeDir()
+  if err != nil {
+    return ""
+  }
+  return filepath.Join(home, HISTORY_FILE)
+}
+
+// a missing file is just an empty history
+func loadHistoryFile(path string, max int) *History {
+  h := NewHistory(max)
+  if path == "" {
+    return h
+  }
+
+  f, err := os.Open(path)
+  if err != nil {
+    return h
+  }
+  defer f.Close()
+
+  h.Load(f)
+  return h
+}
+
+func saveHistoryFile(path string, h *History) error {
+  if path == "" {
+    return nil
+  }
+
+  f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+  if err != nil {
+    return err
+  }
+
+  if err := h.Save(f); err != nil {
+    f.Close()
+    return err
+  }
+  return f.Close()
+}
@@ -0,0 +1,108 @@
+package repl
+
+import (
+  "bytes"
+  "path/filepath"
+  "reflect"
+  "strings"
+  "testing"
+)
+
+func TestHistoryAdd(t *testing.T) {
+  tests := []struct {
+    input    []string
+    max      int
+    expected []string
+  }{
+    {[]string{"a", "b", "c"}, 10, []string{"a", "b", "c"}},
+    {[]string{"a", "", "  ", "b"}, 10, []string{"a", "b"}},
+    {[]string{"a", "a", "b", "a"}, 10, []string{"a", "b", "a"}},
+    {[]string{"a", "b", "c", "d"}, 2, []string{"c", "d"}},
+  }
+
+  for _, tt := range tests {
+    h := NewHistory(tt.max)
+    for _, line := range tt.input {
+      h.Add(line)
+    }
+    if !reflect.DeepEqual(h.Lines(), tt.expected) {
+      t.Errorf("wrong history for %q. expected=%q, got=%q", tt.input, tt.expected, h.Lines())
+    }
+  }
+}
+
+func TestHistoryNavigation(t *testing.T) {
+  h := NewHistory(10)
+  h.Add("let a = 1;")
+  h.Add("a + 1")
+
+  steps := []struct {
+    up       bool
+    expected string
+    ok       bool
+  }{
+    {true, "a + 1", true},
+    {true, "let a = 1;", true},
+    {true, "", false},
+    {false, "a + 1", true},
+    {false, "", true},
+    {false, "", false},
+  }
+
+  for i, step := range steps {
+    var line string
+    var ok bool
+    if step.up {
+      line, ok = h.Prev()
+    } else {
+      line, ok = h.Next()
+    }
+    if line != step.expected || ok != step.ok {
+      t.Errorf("step %d: expected=(%q, %t), got=(%q, %t)", i, step.expected, step.ok, line, ok)
+    }
+  }
+
+  // a new line resets the cursor to the newest entry
+  h.Add("a * 2")
+  if line, _ := h.Prev(); line != "a * 2" {
+    t.Errorf("expected newest line after Add, got=%q", line)
+  }
+}
+
+func TestHistoryLoadSave(t *testing.T) {
+  h := NewHistory(2)
+  if err := h.Load(strings.NewReader("one\n\ntwo\nthree\n")); err != nil {
+    t.Fatalf("Load failed: %s", err)
+  }
+  if expected := []string{"two", "three"}; !reflect.DeepEqual(h.Lines(), expected) {
+    t.Fatalf("wrong loaded history. expected=%q, got=%q", expected, h.Lines())
+  }
+
+  var buf bytes.Buffer
+  if err := h.Save(&buf); err != nil {
+    t.Fatalf("Save failed: %s", err)
+  }
+  if buf.String() != "two\nthree\n" {
+    t.Errorf("wrong saved history. got=%q", buf.String())
+  }
+}
+
+func TestHistoryFile(t *testing.T) {
+  path := filepath.Join(t.TempDir(), HISTORY_FILE)
+
+  if h := loadHistoryFile(path, 10); len(h.Lines()) != 0 {
+    t.Fatalf("missing file should give empty history, got=%q", h.Lines())
+  }
+
+  h := NewHistory(10)
+  h.Add("let x = 5;")
+  h.Add("x * 2")
+  if err := saveHistoryFile(path, h); err != nil {
+    t.Fatalf("saveHistoryFile failed: %s", err)
+  }
+
+  loaded := loadHistoryFile(path, 10)
+  if !reflect.DeepEqual(loaded.Lines(), h.Lines()) {
+    t.Errorf("wrong history after reload. expected=%q, got=%q", h.Lines(), loaded.Lines())
+  }
+}
@@ -0,0 +1,171 @@
+package repl
+
+import (
+  "bufio"
+  "fmt"
+  "io"
+  "os"
+)
+
+// where Start gets its lines from
+type lineReader interface {
+  // prints the prompt, false once the input is exhausted
+  readLine(prompt string) (string, bool)
+  close()
+}
+
+// a terminal gets the line editor, pipes and files fall back to plain scanning
+func newLineReader(in io.Reader, out io.Writer) lineReader {
+  if f, ok := in.(*os.File); ok {
+    restore, err := makeRaw(f)
+    if err == nil {
+      restore()
+      path := historyPath()
+      return &editor{
+        in:      bufio.NewReader(f),
+        file:    f,
+        out:     out,
+        history: loadHistoryFile(path, HistorySize),
+        path:    path,
+      }
+    }
+  }
+
+  return &scanReader{scanner: bufio.NewScanner(in), out: out}
+}
+
+// non-interactive input, eg: `echo 'puts(1)' | monkey`
+type scanReader struct {
+  scanner *bufio.Scanner
+  out     io.Writer
+}
+
+func (r *scanReader) readLine(prompt string) (string, bool) {
+  fmt.Fprint(r.out, prompt)
+  if !r.scanner.Scan() {
+    return "", false
+  }
+  return r.scanner.Text(), true
+}
+
+func (r *scanReader) close() {}
+
+const (
+  keyCtrlC     = 3
+  keyCtrlD     = 4
+  keyBackspace = 8
+  keyEscape    = 27
+  keyDelete    = 127
+)
+
+// minimal raw mode line editor: left/right move, up/down walk the history
+type editor struct {
+  in      *bufio.Reader
+  file    *os.File // the terminal behind in
+  out     io.Writer
+  history *History
+  // history file, rewritten after every line
+  path string
+}
+
+func (e *editor) readLine(prompt string) (string, bool) {
+  // raw mode only while the line is typed, the line runs in the terminal's
+  // own mode, so Ctrl-C still stops a runaway `while (true) {}`
+  if restore, err := makeRaw(e.file); err == nil {
+    defer restore()
+  }
+
+  var line []rune
+  cursor := 0
+  e.redraw(prompt, line, cursor)
+
+  for {
+    r, _, err := e.in.ReadRune()
+    if err != nil {
+      fmt.Fprint(e.out, "\n")
+      return "", false
+    }
+
+    switch r {
+    case '\r', '\n':
+      fmt.Fprint(e.out, "\n")
+      text := string(line)
+      e.history.Add(text)
+      saveHistoryFile(e.path, e.history)
+      return text, true
+
+    case keyCtrlC:
+      // drop the line being typed, like a shell
+      fmt.Fprint(e.out, "^C\n")
+      line, cursor = nil, 0
+
+    case keyCtrlD:
+      if len(line) == 0 {
+        fmt.Fprint(e.out, "\n")
+        return "", false
+      }
+
+    case keyBackspace, keyDelete:
+      if cursor > 0 {
+        line = append(line[:cursor-1], line[cursor:]...)
+        cursor--
+      }
+
+    case keyEscape:
+      line, cursor = e.escape(line, cursor)
+
+    default:
+      if r < ' ' {
+        continue
+      }
+      line = append(line[:cursor], append([]rune{r}, line[cursor:]...)...)
+      cursor++
+    }
+
+    e.redraw(prompt, line, cursor)
+  }
+}
+
+// eg: ESC [ A is the up arrow
+func (e *editor) escape(line []rune, cursor int) ([]rune, int) {
+  if b, err := e.in.ReadByte(); err != nil || b != '[' {
+    return line, cursor
+  }
+  b, err := e.in.ReadByte()
+  if err != nil {
+    return line, cursor
+  }
+
+  switch b {
+  case 'A':
+    if prev, ok := e.history.Prev(); ok {
+      line = []rune(prev)
+      cursor = len(line)
+    }
+  case 'B':
+    if next, ok := e.history.Next(); ok {
+      line = []rune(next)
+      cursor = len(line)
+    }
+  case 'C':
+    if cursor < len(line) {
+      cursor++
+    }
+  case 'D':
+    if cursor > 0 {
+      cursor--
+    }
+  }
+  return line, cursor
+}
+
+// rewrite the whole line, then move the cursor back into place
+func (e *editor) redraw(prompt string, line []rune, cursor int) {
+  fmt.Fprintf(e.out, "\r%s%s\x1b[K", prompt, string(line))
+  if back := len(line) - cursor; back > 0 {
+    fmt.Fprintf(e.out, "\x1b[%dD", back)
+  }
+}
+
+// the terminal is only raw inside readLine, nothing is left to undo
+func (e *editor) close() {}
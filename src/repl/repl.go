@@ -1,30 +1,85 @@
 package repl
 
 import (
+  "JFFMonkeyLang/src/ast"
+  "JFFMonkeyLang/src/compiler"
+  "JFFMonkeyLang/src/evaluator"
   "JFFMonkeyLang/src/lexer"
+  "JFFMonkeyLang/src/object"
   "JFFMonkeyLang/src/parser"
-  "bufio"
+  "JFFMonkeyLang/src/vm"
   "fmt"
   "io"
+  "strings"
 )
 
 const PROMPT = ">> "
 
+// prompt for the lines of a :paste block
+const PASTE_PROMPT = ".. "
+
+// Engine picks the backend lines run on: "eval" walks the tree,
+// "vm" compiles each line to bytecode first (integers, booleans, ifs and lets so far)
+var Engine = "eval"
+
+// Strict makes a function ending without a return an error on every line
+var Strict = false
+
+// Sandbox disables the builtins touching files on every line
+var Sandbox = false
+
+// state shared by every line of one repl run
+type session struct {
+  // the environment lives across lines, so bindings stay available
+  env *object.Environment
+  // macros defined so far, expanded in every later line
+  macroEnv *object.Environment
+  // :tokens on, print the lexer stream before parsing
+  tokens bool
+  // :ast on, print the parsed tree instead of evaluating
+  ast bool
+  // the last line run, macros expanded, for :disasm
+  last ast.Node
+  // results in ANSI colors, on for a terminal, :color off switches it off
+  color bool
+}
+
+// fresh bindings, every toggle off
+func newSession() *session {
+  env := object.NewEnvironment()
+  env.Settings().Strict = Strict
+  env.Settings().Sandbox = Sandbox
+  return &session{env: env, macroEnv: object.NewEnvironment()}
+}
+
 func Start(in io.Reader, out io.Writer) {
-  scanner := bufio.NewScanner(in)
+  reader := newLineReader(in, out)
+  defer reader.close()
+  s := newSession()
+  s.color = isColorTerminal(out)
 
   for {
-    fmt.Fprint(out, PROMPT)
     // 1.read from command line input
-    scanned := scanner.Scan()
+    line, ok := reader.readLine(PROMPT)
 
     // 2.check input
-    if !scanned {
+    if !ok {
       return
     }
 
-    // 3.input data format to string
-    line := scanner.Text()
+    // 3.a :paste block runs as one program, eg: a function over several lines
+    if strings.TrimSpace(line) == ":paste" {
+      line = readPaste(reader)
+    } else if strings.HasPrefix(line, ":") {
+      // repl commands, eg: :load foo.monkey
+      runCommand(out, line, s)
+      continue
+    }
+
+    if s.tokens {
+      printTokens(out, line)
+    }
+
     l := lexer.New(line)
     p := parser.New(l)
 
@@ -32,13 +87,79 @@ func Start(in io.Reader, out io.Writer) {
 
     // 4.check error
     if len(p.Errors()) != 0 {
-      printParserErrors(out, p.Errors())
+      printParserErrors(out, "", line, p.Errors())
       continue
     }
 
-    // 5.print result
-    io.WriteString(out, program.String())
-    io.WriteString(out, "\n")
+    if s.ast {
+      io.WriteString(out, ast.Dump(program))
+      continue
+    }
+
+    // 5.define and expand macros
+    evaluator.DefineMacros(program, s.macroEnv)
+    expanded, err := evaluator.ExpandMacros(program, s.macroEnv)
+    if err != nil {
+      printEvalError(out, err, s.color)
+      continue
+    }
+
+    s.last = expanded
+
+    if Engine == "vm" {
+      runVM(out, expanded, s.color)
+      continue
+    }
+
+    // 6.eval and print result
+    evaluated := evaluator.Eval(expanded, s.env)
+    if evaluated != nil {
+      printEvalError(out, evaluated, s.color)
+    }
+  }
+}
+
+// every line up to one with just :end, or the end of the input,
+// joined back together
+func readPaste(reader lineReader) string {
+  lines := []string{}
+  for {
+    line, ok := reader.readLine(PASTE_PROMPT)
+    if !ok || strings.TrimSpace(line) == ":end" {
+      return strings.Join(lines, "\n")
+    }
+    lines = append(lines, line)
+  }
+}
+
+// compile program and run it on the vm,
+// bindings don't carry over to the next line yet
+func runVM(out io.Writer, program ast.Node, color bool) {
+  comp := compiler.New()
+  if err := comp.Compile(program); err != nil {
+    fmt.Fprintf(out, "compilation failed: %s\n", err)
+    return
+  }
+
+  machine := vm.New(comp.Bytecode())
+  if err := machine.Run(); err != nil {
+    fmt.Fprintf(out, "executing bytecode failed: %s\n", err)
+    return
+  }
+
+  if result := machine.Result(); result != nil {
+    printEvalError(out, result, color)
+  }
+}
+
+func printTokens(out io.Writer, line string) {
+  l := lexer.New(line)
+
+  tokens := l.Tokens()
+
+  // the trailing EOF is left out
+  for _, tok := range tokens[:len(tokens)-1] {
+    fmt.Fprintf(out, "%+v\n", tok)
   }
 }
 
@@ -56,11 +177,59 @@ const MONKEY_FACE = `
            '-----'
 `
 
-func printParserErrors(out io.Writer, errors []string) {
+// every error is followed by its source line and a caret under the column,
+// name prefixes the messages, eg: foo.monkey:
+//
+//   1:5: expected next token to be IDENT, got ASSIGN instead
+//   let = 5;
+//       ^
+func printParserErrors(out io.Writer, name, source string, errors parser.ParseErrors) {
+  lines := strings.Split(source, "\n")
+
   io.WriteString(out, MONKEY_FACE)
   io.WriteString(out, "Woops! We ran into some monkey business here!\n")
   io.WriteString(out, " parser errors:\n")
-  for _, msg := range errors {
-    io.WriteString(out, "\t"+msg+"\n")
+  for _, err := range errors {
+    io.WriteString(out, "\t"+name+err.Error()+"\n")
+
+    if err.Line < 1 || err.Line > len(lines) {
+      continue
+    }
+    line := strings.TrimRight(lines[err.Line-1], "\r")
+    io.WriteString(out, "\t"+line+"\n")
+    io.WriteString(out, "\t"+caretPadding(line, err.Column)+"^\n")
+  }
+}
+
+// blanks up to column, tabs are kept so the caret lines up with the source
+func caretPadding(line string, column int) string {
+  var pad strings.Builder
+
+  for i, ch := range []rune(line) {
+    if i >= column-1 {
+      break
+    }
+    if ch == '\t' {
+      pad.WriteRune('\t')
+    } else {
+      pad.WriteRune(' ')
+    }
   }
+
+  return pad.String()
+}
+
+// runtime errors get the monkey face too, so they stand out
+// from normal results, eg: `5 + true`; with color the value or message is colored by type
+func printEvalError(out io.Writer, evaluated object.Object, color bool) {
+  errObj, ok := evaluated.(*object.Error)
+  if !ok {
+    io.WriteString(out, colorize(evaluated.Inspect(), evaluated.Type(), color)+"\n")
+    return
+  }
+
+  io.WriteString(out, MONKEY_FACE)
+  io.WriteString(out, "Woops! We ran into some monkey business here!\n")
+  io.WriteString(out, " runtime error:\n")
+  io.WriteString(out, "\t"+colorize(errObj.Message, object.ERROR_OBJ, color)+"\n")
 }
@@ -0,0 +1,356 @@
+package repl
+
+import (
+  "bytes"
+  "os"
+  "path/filepath"
+  "strings"
+  "testing"
+)
+
+func TestLoadCommand(t *testing.T) {
+  path := filepath.Join(t.TempDir(), "add.monkey")
+  source := `
+let add = fn(a, b) {
+  a + b;
+};
+`
+  if err := os.WriteFile(path, []byte(source), 0644); err != nil {
+    t.Fatalf("could not write temp file: %s", err)
+  }
+
+  output := testStart(":load " + path + "\nadd(1, 2)\n")
+
+  expected := PROMPT + PROMPT + "3\n" + PROMPT
+  if output != expected {
+    t.Errorf("wrong output. expected=%q, got=%q", expected, output)
+  }
+}
+
+func TestLoadCommandErrors(t *testing.T) {
+  dir := t.TempDir()
+
+  badSyntax := filepath.Join(dir, "syntax.monkey")
+  if err := os.WriteFile(badSyntax, []byte("let = 5;"), 0644); err != nil {
+    t.Fatalf("could not write temp file: %s", err)
+  }
+
+  badRuntime := filepath.Join(dir, "runtime.monkey")
+  if err := os.WriteFile(badRuntime, []byte("5 + true;"), 0644); err != nil {
+    t.Fatalf("could not write temp file: %s", err)
+  }
+
+  tests := []struct {
+    input    string
+    expected string
+  }{
+    {
+      ":load " + filepath.Join(dir, "missing.monkey"),
+      "could not load " + filepath.Join(dir, "missing.monkey") + ": file not found",
+    },
+    {
+      ":load " + badSyntax,
+      badSyntax + ":1:5: expected next token to be IDENT, got ASSIGN instead",
+    },
+    {
+      ":load " + badRuntime,
+      badRuntime + ": ERROR: type mismatch: INTEGER + BOOLEAN",
+    },
+    {":load", "usage: :load <path>"},
+    {":tokens maybe", "usage: :tokens on|off"},
+    {":ast", "usage: :ast on|off"},
+    {":nope", "unknown command: :nope"},
+  }
+
+  for _, tt := range tests {
+    output := testStart(tt.input + "\n")
+
+    if !strings.Contains(output, tt.expected) {
+      t.Errorf("output does not contain %q. got=%q", tt.expected, output)
+    }
+  }
+}
+
+func TestTokensCommand(t *testing.T) {
+  output := testStart(":tokens on\nlet x = 5;\n:tokens off\nlet y = 6;\n")
+
+  expected := PROMPT + PROMPT +
+    "{Type:LET Literal:let Line:1 Column:1}\n" +
+    "{Type:IDENT Literal:x Line:1 Column:5}\n" +
+    "{Type:= Literal:= Line:1 Column:7}\n" +
+    "{Type:INT Literal:5 Line:1 Column:9}\n" +
+    "{Type:; Literal:; Line:1 Column:10}\n" +
+    PROMPT + PROMPT + PROMPT
+
+  if output != expected {
+    t.Errorf("wrong output. expected=%q, got=%q", expected, output)
+  }
+}
+
+func TestAstCommand(t *testing.T) {
+  output := testStart(":ast on\n-5\n:ast off\n-5\n")
+
+  expected := PROMPT + PROMPT +
+    "Program\n" +
+    "  Statements:\n" +
+    "    ExpressionStatement\n" +
+    "      Expression: PrefixExpression\n" +
+    "        Operator: \"-\"\n" +
+    "        Right: IntegerLiteral\n" +
+    "          Value: 5\n" +
+    PROMPT + PROMPT + "-5\n" + PROMPT
+
+  if output != expected {
+    t.Errorf("wrong output. expected=%q, got=%q", expected, output)
+  }
+}
+
+func TestResetCommand(t *testing.T) {
+  output := testStart(":tokens on\nlet x = 5;\n:reset\nx\n")
+
+  expected := PROMPT + PROMPT +
+    "{Type:LET Literal:let Line:1 Column:1}\n" +
+    "{Type:IDENT Literal:x Line:1 Column:5}\n" +
+    "{Type:= Literal:= Line:1 Column:7}\n" +
+    "{Type:INT Literal:5 Line:1 Column:9}\n" +
+    "{Type:; Literal:; Line:1 Column:10}\n" +
+    PROMPT + PROMPT + MONKEY_FACE +
+    "Woops! We ran into some monkey business here!\n" +
+    " runtime error:\n" +
+    "\tidentifier not found: x\n" + PROMPT
+
+  if output != expected {
+    t.Errorf("wrong output. expected=%q, got=%q", expected, output)
+  }
+}
+
+func TestEnvCommand(t *testing.T) {
+  output := testStart("let b = [1, 2];\nlet a = 5;\nlet c = \"x\";\n:env\n")
+
+  expected := PROMPT + PROMPT + PROMPT + PROMPT +
+    "a = 5\n" +
+    "b = [1, 2]\n" +
+    "c = x\n" +
+    PROMPT
+
+  if output != expected {
+    t.Errorf("wrong output. expected=%q, got=%q", expected, output)
+  }
+}
+
+func TestEnvCommandAll(t *testing.T) {
+  output := testStart("let type = 1;\n:env --all\n")
+
+  if !strings.Contains(output, "\ntype = 1\n") {
+    t.Errorf("binding does not shadow the builtin. got=%q", output)
+  }
+  if !strings.Contains(output, "\nsplit = builtin function\n") {
+    t.Errorf("builtins are not listed. got=%q", output)
+  }
+
+  output = testStart(":env\n")
+  if output != PROMPT+PROMPT {
+    t.Errorf("builtins listed without --all. got=%q", output)
+  }
+
+  output = testStart(":env foo\n")
+  if output != PROMPT+"usage: :env [--all]\n"+PROMPT {
+    t.Errorf("wrong usage output. got=%q", output)
+  }
+}
+
+func TestPrintEvalError(t *testing.T) {
+  tests := []struct {
+    input    string
+    expected string
+  }{
+    {
+      "5 + true",
+      PROMPT + MONKEY_FACE +
+        "Woops! We ran into some monkey business here!\n" +
+        " runtime error:\n" +
+        "\ttype mismatch: INTEGER + BOOLEAN\n" + PROMPT,
+    },
+    {"if (false) { 5 }", PROMPT + "null\n" + PROMPT},
+    {"let x = 5;", PROMPT + PROMPT},
+  }
+
+  for _, tt := range tests {
+    output := testStart(tt.input + "\n")
+
+    if output != tt.expected {
+      t.Errorf("wrong output. expected=%q, got=%q", tt.expected, output)
+    }
+  }
+}
+
+func TestColorCommand(t *testing.T) {
+  tests := []struct {
+    input    string
+    expected string
+  }{
+    {":color on\n42", PROMPT + PROMPT + "\x1b[33m42\x1b[0m\n" + PROMPT},
+    {":color on\n\"hi\"", PROMPT + PROMPT + "\x1b[32mhi\x1b[0m\n" + PROMPT},
+    {":color on\n1 < 2", PROMPT + PROMPT + "\x1b[35mtrue\x1b[0m\n" + PROMPT},
+    // types without a color stay plain
+    {":color on\n[1, 2]", PROMPT + PROMPT + "[1, 2]\n" + PROMPT},
+    {":color on\n:color off\n42", PROMPT + PROMPT + PROMPT + "42\n" + PROMPT},
+    {":color on\n:reset\n42", PROMPT + PROMPT + PROMPT + "\x1b[33m42\x1b[0m\n" + PROMPT},
+    {":color blue", PROMPT + "usage: :color on|off\n" + PROMPT},
+    // a buffer is not a terminal
+    {"42", PROMPT + "42\n" + PROMPT},
+  }
+
+  for _, tt := range tests {
+    output := testStart(tt.input + "\n")
+
+    if output != tt.expected {
+      t.Errorf("wrong output. expected=%q, got=%q", tt.expected, output)
+    }
+  }
+
+  output := testStart(":color on\n5 + true\n")
+  if !strings.Contains(output, "\t\x1b[31mtype mismatch: INTEGER + BOOLEAN\x1b[0m\n") {
+    t.Errorf("error is not colored. got=%q", output)
+  }
+}
+
+func TestPasteCommand(t *testing.T) {
+  tests := []struct {
+    input    string
+    expected string
+  }{
+    {
+      ":paste\nlet add = fn(a, b) {\n  a + b\n};\nadd(1, 2)\n:end\nadd(3, 4)",
+      PROMPT + PASTE_PROMPT + PASTE_PROMPT + PASTE_PROMPT + PASTE_PROMPT + PASTE_PROMPT +
+        "3\n" + PROMPT + "7\n" + PROMPT,
+    },
+    // the end of the input ends the block too
+    {":paste\nlet x = 2;\nx * 5", PROMPT + PASTE_PROMPT + PASTE_PROMPT + PASTE_PROMPT + "10\n" + PROMPT},
+    {":paste\n:end", PROMPT + PASTE_PROMPT + PROMPT},
+  }
+
+  for _, tt := range tests {
+    output := testStart(tt.input + "\n")
+
+    if output != tt.expected {
+      t.Errorf("wrong output. expected=%q, got=%q", tt.expected, output)
+    }
+  }
+
+  // errors point into the block
+  output := testStart(":paste\nlet x = 1;\nlet = 5;\n:end\n")
+  if !strings.Contains(output, "\t2:5: expected next token to be IDENT, got ASSIGN instead\n\tlet = 5;\n") {
+    t.Errorf("wrong parser error. got=%q", output)
+  }
+}
+
+func TestEngineVM(t *testing.T) {
+  defer func(prev string) { Engine = prev }(Engine)
+  Engine = "vm"
+
+  tests := []struct {
+    input    string
+    expected string
+  }{
+    {"1 + 2 * 3", PROMPT + "7\n" + PROMPT},
+    {"if (1 > 2) { 10 }", PROMPT + "null\n" + PROMPT},
+    {"2 ** 64", PROMPT + "18446744073709551616\n" + PROMPT},
+    {
+      "1 / 0",
+      PROMPT + MONKEY_FACE +
+        "Woops! We ran into some monkey business here!\n" +
+        " runtime error:\n" +
+        "\tdivision by zero\n" + PROMPT,
+    },
+    {"let x = 5; x * 2", PROMPT + "10\n" + PROMPT},
+    {"x", PROMPT + "compilation failed: identifier not found: x\n" + PROMPT},
+    // macros are expanded before compiling
+    {"let inc = macro(x) { quote(unquote(x) + 1) };\ninc(2)", PROMPT + PROMPT + "3\n" + PROMPT},
+  }
+
+  for _, tt := range tests {
+    output := testStart(tt.input + "\n")
+
+    if output != tt.expected {
+      t.Errorf("wrong output. expected=%q, got=%q", tt.expected, output)
+    }
+  }
+}
+
+func TestDisasmCommand(t *testing.T) {
+  tests := []struct {
+    input    string
+    expected string
+  }{
+    {
+      "1 + 2\n:disasm",
+      PROMPT + "3\n" + PROMPT +
+        "0000 OpConstant 0\n" +
+        "0003 OpConstant 1\n" +
+        "0006 OpAdd\n" +
+        "0007 OpPop\n" +
+        "constants:\n" +
+        "0000 INTEGER 1\n" +
+        "0001 INTEGER 2\n" + PROMPT,
+    },
+    {":disasm", PROMPT + "nothing to disassemble yet\n" + PROMPT},
+    {":disasm 1", PROMPT + "usage: :disasm\n" + PROMPT},
+    {
+      "fn() { 1 };\n:disasm",
+      PROMPT + "fn() {\n1\n}\n" + PROMPT + "compilation failed: compiling *ast.FunctionLiteral is not supported yet\n" + PROMPT,
+    },
+  }
+
+  for _, tt := range tests {
+    output := testStart(tt.input + "\n")
+
+    if output != tt.expected {
+      t.Errorf("wrong output. expected=%q, got=%q", tt.expected, output)
+    }
+  }
+
+  // a line that doesn't parse is not the last one
+  output := testStart("true\nlet = 1;\n:disasm\n")
+  if expected := PROMPT + "0000 OpTrue\n0001 OpPop\n" + PROMPT; !strings.HasSuffix(output, expected) {
+    t.Errorf("wrong output. expected suffix=%q, got=%q", expected, output)
+  }
+}
+
+func TestMacrosAcrossLines(t *testing.T) {
+  input := "let double = macro(x) { quote(unquote(x) * 2) };\ndouble(1 + 2)\n"
+  output := testStart(input)
+
+  expected := PROMPT + PROMPT + "6\n" + PROMPT
+  if output != expected {
+    t.Errorf("wrong output. expected=%q, got=%q", expected, output)
+  }
+}
+
+func TestParserErrorCarets(t *testing.T) {
+  output := testStart("let = 5;\n\tf(a = 1, 2)\n")
+
+  expected := PROMPT + MONKEY_FACE +
+    "Woops! We ran into some monkey business here!\n" +
+    " parser errors:\n" +
+    "\t1:5: expected next token to be IDENT, got ASSIGN instead\n" +
+    "\tlet = 5;\n" +
+    "\t    ^\n" +
+    PROMPT + MONKEY_FACE +
+    "Woops! We ran into some monkey business here!\n" +
+    " parser errors:\n" +
+    "\t1:3: positional argument 2 after keyword arguments\n" +
+    "\t\tf(a = 1, 2)\n" +
+    "\t\t ^\n" +
+    PROMPT
+
+  if output != expected {
+    t.Errorf("wrong output. expected=%q, got=%q", expected, output)
+  }
+}
+
+func testStart(input string) string {
+  var out bytes.Buffer
+  Start(strings.NewReader(input), &out)
+  return out.String()
+}
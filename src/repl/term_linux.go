@@ -0,0 +1,46 @@
+//go:build linux
+// +build linux
+
+package repl
+
+import (
+  "os"
+  "syscall"
+  "unsafe"
+)
+
+// switch a terminal to byte-at-a-time input without echo,
+// fails for anything that is not a terminal, eg: a pipe
+func makeRaw(f *os.File) (func(), error) {
+  fd := f.Fd()
+
+  var old syscall.Termios
+  if err := ioctl(fd, syscall.TCGETS, &old); err != nil {
+    return nil, err
+  }
+
+  raw := old
+  raw.Lflag &^= syscall.ICANON | syscall.ECHO | syscall.ISIG
+  raw.Iflag &^= syscall.ICRNL
+  raw.Cc[syscall.VMIN] = 1
+  raw.Cc[syscall.VTIME] = 0
+  if err := ioctl(fd, syscall.TCSETS, &raw); err != nil {
+    return nil, err
+  }
+
+  return func() { ioctl(fd, syscall.TCSETS, &old) }, nil
+}
+
+// the same TCGETS check makeRaw starts with
+func isTerminal(f *os.File) bool {
+  var termios syscall.Termios
+  return ioctl(f.Fd(), syscall.TCGETS, &termios) == nil
+}
+
+func ioctl(fd uintptr, req uintptr, t *syscall.Termios) error {
+  _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, req, uintptr(unsafe.Pointer(t)))
+  if errno != 0 {
+    return errno
+  }
+  return nil
+}
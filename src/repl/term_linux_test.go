@@ -0,0 +1,93 @@
+//go:build linux
+// +build linux
+
+package repl
+
+import (
+  "bytes"
+  "fmt"
+  "os"
+  "syscall"
+  "testing"
+  "time"
+  "unsafe"
+)
+
+func TestEditorRawOnlyWhileReading(t *testing.T) {
+  t.Setenv("HOME", t.TempDir())
+  master, terminal := openTerminal(t)
+  defer master.Close()
+  defer terminal.Close()
+
+  var out bytes.Buffer
+  reader := newLineReader(terminal, &out)
+  defer reader.close()
+  if _, ok := reader.(*editor); !ok {
+    t.Fatalf("a terminal didn't get the editor. got=%T", reader)
+  }
+
+  // 1.before a line is read, Ctrl-C signals like in any program
+  if !signalsOn(t, terminal) {
+    t.Fatalf("Ctrl-C doesn't signal outside readLine")
+  }
+
+  // 2.while the line is typed, the editor gets Ctrl-C and drops the line so far
+  go func() {
+    for signalsOn(t, terminal) {
+      time.Sleep(time.Millisecond)
+    }
+    master.Write([]byte("abc\x031 + 2\r"))
+  }()
+  line, ok := reader.readLine(PROMPT)
+  if !ok || line != "1 + 2" {
+    t.Errorf("wrong line. want=%q, got=%q (%t)", "1 + 2", line, ok)
+  }
+  if !bytes.Contains(out.Bytes(), []byte("^C\n")) {
+    t.Errorf("Ctrl-C not shown. got=%q", out.String())
+  }
+
+  // 3.the line runs with the terminal back in its own mode,
+  //   so Ctrl-C stops a runaway loop
+  if !signalsOn(t, terminal) {
+    t.Errorf("Ctrl-C doesn't signal after readLine")
+  }
+}
+
+// a pseudo terminal, writing to master is typing on terminal
+func openTerminal(t *testing.T) (master, terminal *os.File) {
+  master, err := os.OpenFile("/dev/ptmx", os.O_RDWR, 0)
+  if err != nil {
+    t.Skipf("no pseudo terminals: %s", err)
+  }
+
+  var unlock int32
+  var number uint32
+  if err := ptyIoctl(master, syscall.TIOCSPTLCK, unsafe.Pointer(&unlock)); err != nil {
+    t.Fatalf("could not unlock the terminal: %s", err)
+  }
+  if err := ptyIoctl(master, syscall.TIOCGPTN, unsafe.Pointer(&number)); err != nil {
+    t.Fatalf("could not name the terminal: %s", err)
+  }
+
+  terminal, err = os.OpenFile(fmt.Sprintf("/dev/pts/%d", number), os.O_RDWR|syscall.O_NOCTTY, 0)
+  if err != nil {
+    t.Fatalf("could not open the terminal: %s", err)
+  }
+  return master, terminal
+}
+
+func signalsOn(t *testing.T, f *os.File) bool {
+  var termios syscall.Termios
+  if err := ioctl(f.Fd(), syscall.TCGETS, &termios); err != nil {
+    t.Fatalf("could not read the terminal mode: %s", err)
+  }
+  return termios.Lflag&syscall.ISIG != 0
+}
+
+func ptyIoctl(f *os.File, req uintptr, arg unsafe.Pointer) error {
+  _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), req, uintptr(arg))
+  if errno != 0 {
+    return errno
+  }
+  return nil
+}
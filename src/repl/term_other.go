@@ -0,0 +1,19 @@
+//go:build !linux
+// +build !linux
+
+package repl
+
+import (
+  "errors"
+  "os"
+)
+
+// no raw mode here, the repl always scans lines
+func makeRaw(f *os.File) (func(), error) {
+  return nil, errors.New("raw mode not supported")
+}
+
+// without the terminal check, output is never colored
+func isTerminal(f *os.File) bool {
+  return false
+}
@@ -1,5 +1,7 @@
 package token
 
+import "sort"
+
 type TokenType string
 
 const (
@@ -7,8 +9,11 @@ const (
   EOF     = "EOF"
 
   // Identifiers + literals
-  IDENT = "IDENT" // add, foobar, x, y, ...
-  INT   = "INT"   // 1343456
+  IDENT  = "IDENT"  // add, foobar, x, y, ...
+  INT    = "INT"    // 1343456
+  STRING = "STRING" // "foo bar"
+
+  TEMPLATE = "TEMPLATE" // "foo ${bar}"
 
   // Operators
   ASSIGN   = "="
@@ -16,7 +21,9 @@ const (
   MINUS    = "-"
   BANG     = "!"
   ASTERISK = "*"
+  POWER    = "**"
   SLASH    = "/"
+  PERCENT  = "%"
 
   LT = "<"
   GT = ">"
@@ -24,38 +31,115 @@ const (
   EQ     = "=="
   NOT_EQ = "!="
 
+  AND     = "&&"
+  OR      = "||"
+  NULLISH = "??"
+  PIPE    = "|>"
+
   // Delimiters
   COMMA     = ","
   SEMICOLON = ";"
+  COLON     = ":"
+  DOT       = "."
+  ELLIPSIS  = "..."
+  QDOT      = "?."
+  ARROW     = "=>"
 
-  LPAREN = "("
-  RPAREN = ")"
-  LBRACE = "{"
-  RBRACE = "}"
+  LPAREN   = "("
+  RPAREN   = ")"
+  LBRACE   = "{"
+  RBRACE   = "}"
+  LBRACKET = "["
+  RBRACKET = "]"
 
   // Keywords
   FUNCTION = "FUNCTION"
   LET      = "LET"
+  CONST    = "CONST"
   TRUE     = "TRUE"
   FALSE    = "FALSE"
   IF       = "IF"
   ELSE     = "ELSE"
   RETURN   = "RETURN"
+  WHILE    = "WHILE"
+  DO       = "DO"
+  FOR      = "FOR"
+  IN       = "IN"
+  MATCH    = "MATCH"
+  TRY      = "TRY"
+  CATCH    = "CATCH"
+  THROW    = "THROW"
+  MACRO    = "MACRO"
 )
 
 type Token struct {
   Type    TokenType
   Literal string
+  Line    int // 1-based line of the first char
+  Column  int // 1-based column of the first char
 }
 
 var keywords = map[string]TokenType{
   "fn":     FUNCTION,
   "let":    LET,
+  "const":  CONST,
   "true":   TRUE,
   "false":  FALSE,
   "if":     IF,
   "else":   ELSE,
   "return": RETURN,
+  "while":  WHILE,
+  "do":     DO,
+  "for":    FOR,
+  "in":     IN,
+  "match":  MATCH,
+  "try":    TRY,
+  "catch":  CATCH,
+  "throw":  THROW,
+  "macro":  MACRO,
+}
+
+// operators and delimiters are typed by their literal,
+// diagnostics spell them out instead, eg: "=" is ASSIGN
+var names = map[TokenType]string{
+  ASSIGN:    "ASSIGN",
+  PLUS:      "PLUS",
+  MINUS:     "MINUS",
+  BANG:      "BANG",
+  ASTERISK:  "ASTERISK",
+  POWER:     "POWER",
+  SLASH:     "SLASH",
+  PERCENT:   "PERCENT",
+  LT:        "LT",
+  GT:        "GT",
+  EQ:        "EQ",
+  NOT_EQ:    "NOT_EQ",
+  AND:       "AND",
+  OR:        "OR",
+  NULLISH:   "NULLISH",
+  PIPE:      "PIPE",
+  COMMA:     "COMMA",
+  SEMICOLON: "SEMICOLON",
+  COLON:     "COLON",
+  DOT:       "DOT",
+  ELLIPSIS:  "ELLIPSIS",
+  QDOT:      "QDOT",
+  ARROW:     "ARROW",
+  LPAREN:    "LPAREN",
+  RPAREN:    "RPAREN",
+  LBRACE:    "LBRACE",
+  RBRACE:    "RBRACE",
+  LBRACKET:  "LBRACKET",
+  RBRACKET:  "RBRACKET",
+}
+
+// symbolic name of the type, for error messages
+// eg: ASSIGN for "=", IDENT stays IDENT
+func (t TokenType) Name() string {
+  if name, ok := names[t]; ok {
+    return name
+  }
+  return string(t)
 }
 
 func LookupIdent(ident string) TokenType {
@@ -67,3 +151,20 @@ func LookupIdent(ident string) TokenType {
   // User Identifiers
   return IDENT
 }
+
+// eg: IsKeyword("while") is true, IsKeyword("print") is false
+func IsKeyword(ident string) bool {
+  _, ok := keywords[ident]
+  return ok
+}
+
+// every reserved word, sorted, eg: for syntax highlighting
+func Keywords() []string {
+  names := make([]string, 0, len(keywords))
+  for name := range keywords {
+    names = append(names, name)
+  }
+  sort.Strings(names)
+
+  return names
+}
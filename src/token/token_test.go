@@ -0,0 +1,86 @@
+package token
+
+import (
+  "reflect"
+  "testing"
+)
+
+func TestKeywords(t *testing.T) {
+  tests := []struct {
+    keyword      string
+    expectedType TokenType
+  }{
+    {"catch", CATCH},
+    {"const", CONST},
+    {"do", DO},
+    {"else", ELSE},
+    {"false", FALSE},
+    {"fn", FUNCTION},
+    {"for", FOR},
+    {"if", IF},
+    {"in", IN},
+    {"let", LET},
+    {"macro", MACRO},
+    {"match", MATCH},
+    {"return", RETURN},
+    {"throw", THROW},
+    {"true", TRUE},
+    {"try", TRY},
+    {"while", WHILE},
+  }
+
+  expected := []string{}
+  for _, tt := range tests {
+    expected = append(expected, tt.keyword)
+
+    if tok := LookupIdent(tt.keyword); tok != tt.expectedType {
+      t.Errorf("LookupIdent(%q) wrong. expected=%q, got=%q", tt.keyword, tt.expectedType, tok)
+    }
+    if !IsKeyword(tt.keyword) {
+      t.Errorf("IsKeyword(%q) should be true", tt.keyword)
+    }
+  }
+
+  if !reflect.DeepEqual(Keywords(), expected) {
+    t.Errorf("Keywords() wrong. expected=%q, got=%q", expected, Keywords())
+  }
+
+  for _, ident := range []string{"x", "Let", "function", "null", "break", ""} {
+    if IsKeyword(ident) {
+      t.Errorf("IsKeyword(%q) should be false", ident)
+    }
+    if tok := LookupIdent(ident); tok != IDENT {
+      t.Errorf("LookupIdent(%q) wrong. expected=IDENT, got=%q", ident, tok)
+    }
+  }
+}
+
+func TestName(t *testing.T) {
+  tests := []struct {
+    tokenType TokenType
+    expected  string
+  }{
+    {ASSIGN, "ASSIGN"},
+    {NOT_EQ, "NOT_EQ"},
+    {SEMICOLON, "SEMICOLON"},
+    {DOT, "DOT"},
+    {ELLIPSIS, "ELLIPSIS"},
+    {QDOT, "QDOT"},
+    {ARROW, "ARROW"},
+    {POWER, "POWER"},
+    {AND, "AND"},
+    {OR, "OR"},
+    {NULLISH, "NULLISH"},
+    {PIPE, "PIPE"},
+    {RBRACE, "RBRACE"},
+    {IDENT, "IDENT"},
+    {FUNCTION, "FUNCTION"},
+    {EOF, "EOF"},
+  }
+
+  for _, tt := range tests {
+    if name := tt.tokenType.Name(); name != tt.expected {
+      t.Errorf("wrong name for %q. expected=%q, got=%q", string(tt.tokenType), tt.expected, name)
+    }
+  }
+}
@@ -0,0 +1,92 @@
+package vm
+
+import (
+  "JFFMonkeyLang/src/ast"
+  "JFFMonkeyLang/src/compiler"
+  "JFFMonkeyLang/src/evaluator"
+  "JFFMonkeyLang/src/lexer"
+  "JFFMonkeyLang/src/object"
+  "JFFMonkeyLang/src/parser"
+  "strings"
+  "testing"
+)
+
+// go test -bench . -benchmem ./src/vm
+// parsing and compiling happen once, only the runs are timed
+
+// the evaluator only, the compiler has no functions yet
+const fibProgram = `
+let fib = fn(n) {
+  if (n < 2) { n } else { fib(n - 1) + fib(n - 2) }
+};
+fib(30);
+`
+
+// programs both engines run, by name
+var benchCorpus = []struct {
+  name  string
+  input string
+}{
+  {"arithmetic", "let a = 12345; let b = 678; (a * b + a / b - a % b) * (b - a) ** 2"},
+  {"bigints", "let x = 2 ** 200; let y = x * x - x; y / x + 1"},
+  {"conditionals", "let x = 7; if (x > 5) { if (x % 2 == 0) { 1 } else { 2 } } else { 3 }"},
+  // a long chain, so the run outweighs the setup
+  {"chain", "let x = 1; " + strings.Repeat("x + 2 * x - 1 + ", 500) + "x"},
+}
+
+func BenchmarkEvalFib(b *testing.B) {
+  benchmarkEval(b, fibProgram)
+}
+
+func BenchmarkEvalCorpus(b *testing.B) {
+  for _, program := range benchCorpus {
+    b.Run(program.name, func(b *testing.B) { benchmarkEval(b, program.input) })
+  }
+}
+
+func BenchmarkVMCorpus(b *testing.B) {
+  for _, program := range benchCorpus {
+    b.Run(program.name, func(b *testing.B) { benchmarkVM(b, program.input) })
+  }
+}
+
+func benchmarkEval(b *testing.B, input string) {
+  program := benchParse(b, input)
+  b.ReportAllocs()
+  b.ResetTimer()
+
+  for i := 0; i < b.N; i++ {
+    if result := evaluator.Eval(program, object.NewEnvironment()); result == nil || result.Type() == object.ERROR_OBJ {
+      b.Fatalf("eval failed: %v", result)
+    }
+  }
+}
+
+func benchmarkVM(b *testing.B, input string) {
+  comp := compiler.New()
+  if err := comp.Compile(benchParse(b, input)); err != nil {
+    b.Fatalf("compiler error: %s", err)
+  }
+  bytecode := comp.Bytecode()
+  b.ReportAllocs()
+  b.ResetTimer()
+
+  for i := 0; i < b.N; i++ {
+    machine := New(bytecode)
+    if err := machine.Run(); err != nil {
+      b.Fatalf("vm error: %s", err)
+    }
+    if result := machine.Result(); result == nil || result.Type() == object.ERROR_OBJ {
+      b.Fatalf("vm failed: %v", result)
+    }
+  }
+}
+
+func benchParse(b *testing.B, input string) *ast.Program {
+  p := parser.New(lexer.New(input))
+  program := p.ParseProgram()
+  if len(p.Errors()) != 0 {
+    b.Fatalf("parser errors: %v", p.Errors())
+  }
+  return program
+}
@@ -0,0 +1,173 @@
+package vm
+
+import (
+  "JFFMonkeyLang/src/code"
+  "JFFMonkeyLang/src/compiler"
+  "JFFMonkeyLang/src/evaluator"
+  "JFFMonkeyLang/src/object"
+  "fmt"
+)
+
+const StackSize = 2048
+
+// as many as an OpSetGlobal operand can address
+const GlobalsSize = 65536
+
+// the operator each arithmetic and comparison opcode stands for
+var operators = map[code.Opcode]string{
+  code.OpAdd:         "+",
+  code.OpSub:         "-",
+  code.OpMul:         "*",
+  code.OpDiv:         "/",
+  code.OpMod:         "%",
+  code.OpPower:       "**",
+  code.OpEqual:       "==",
+  code.OpNotEqual:    "!=",
+  code.OpLessThan:    "<",
+  code.OpGreaterThan: ">",
+  code.OpMinus:       "-",
+  code.OpBang:        "!",
+}
+
+// runs the bytecode of a compiler on a stack
+type VM struct {
+  constants    []object.Object
+  instructions code.Instructions
+
+  stack []object.Object
+  sp    int // the next free slot, the top is stack[sp-1]
+
+  globals []object.Object // by symbol index
+
+  // the value of the last expression statement, or the error that stopped the run
+  result object.Object
+}
+
+func New(bytecode *compiler.Bytecode) *VM {
+  return &VM{
+    constants:    bytecode.Constants,
+    instructions: bytecode.Instructions,
+    stack:        make([]object.Object, StackSize),
+    sp:           0,
+    globals:      make([]object.Object, GlobalsSize),
+  }
+}
+
+// what the evaluator would have returned for the program,
+// nil if it had no expression statements
+func (vm *VM) Result() object.Object {
+  return vm.result
+}
+
+// a monkey runtime error ends the run and becomes the Result,
+// the error returned is for broken bytecode, eg: a stack overflow
+func (vm *VM) Run() error {
+  for ip := 0; ip < len(vm.instructions); ip++ {
+    op := code.Opcode(vm.instructions[ip])
+
+    switch op {
+    case code.OpConstant:
+      constIndex := code.ReadUint16(vm.instructions[ip+1:])
+      ip += 2
+      if err := vm.push(vm.constants[constIndex]); err != nil {
+        return err
+      }
+
+    case code.OpTrue:
+      if err := vm.push(evaluator.TRUE); err != nil {
+        return err
+      }
+
+    case code.OpFalse:
+      if err := vm.push(evaluator.FALSE); err != nil {
+        return err
+      }
+
+    case code.OpNull:
+      if err := vm.push(evaluator.NULL); err != nil {
+        return err
+      }
+
+    case code.OpAdd, code.OpSub, code.OpMul, code.OpDiv, code.OpMod, code.OpPower,
+      code.OpEqual, code.OpNotEqual, code.OpLessThan, code.OpGreaterThan:
+      right := vm.pop()
+      left := vm.pop()
+      result := evaluator.Infix(operators[op], left, right)
+      if errObj, ok := result.(*object.Error); ok {
+        vm.result = errObj
+        return nil
+      }
+      if err := vm.push(result); err != nil {
+        return err
+      }
+
+    case code.OpMinus, code.OpBang:
+      result := evaluator.Prefix(operators[op], vm.pop())
+      if errObj, ok := result.(*object.Error); ok {
+        vm.result = errObj
+        return nil
+      }
+      if err := vm.push(result); err != nil {
+        return err
+      }
+
+    case code.OpJump:
+      // the loop moves ip past the target otherwise
+      ip = int(code.ReadUint16(vm.instructions[ip+1:])) - 1
+
+    case code.OpJumpNotTruthy:
+      pos := int(code.ReadUint16(vm.instructions[ip+1:]))
+      ip += 2
+      if !isTruthy(vm.pop()) {
+        ip = pos - 1
+      }
+
+    case code.OpSetGlobal:
+      globalIndex := code.ReadUint16(vm.instructions[ip+1:])
+      ip += 2
+      vm.globals[globalIndex] = vm.pop()
+
+    case code.OpGetGlobal:
+      globalIndex := code.ReadUint16(vm.instructions[ip+1:])
+      ip += 2
+      if err := vm.push(vm.globals[globalIndex]); err != nil {
+        return err
+      }
+
+    case code.OpPop:
+      vm.result = vm.pop()
+
+    default:
+      return fmt.Errorf("unknown opcode %d at %d", op, ip)
+    }
+  }
+
+  return nil
+}
+
+func (vm *VM) push(o object.Object) error {
+  if vm.sp >= StackSize {
+    return fmt.Errorf("stack overflow")
+  }
+
+  vm.stack[vm.sp] = o
+  vm.sp++
+
+  return nil
+}
+
+func (vm *VM) pop() object.Object {
+  o := vm.stack[vm.sp-1]
+  vm.sp--
+  return o
+}
+
+// same as the evaluator: only null and false are falsy
+func isTruthy(obj object.Object) bool {
+  switch obj {
+  case evaluator.NULL, evaluator.FALSE:
+    return false
+  default:
+    return true
+  }
+}
@@ -0,0 +1,83 @@
+package vm
+
+import (
+  "JFFMonkeyLang/src/compiler"
+  "JFFMonkeyLang/src/evaluator"
+  "JFFMonkeyLang/src/lexer"
+  "JFFMonkeyLang/src/object"
+  "JFFMonkeyLang/src/parser"
+  "testing"
+)
+
+// every expression gives the same under both backends
+var sharedSuite = []string{
+  // integer arithmetic
+  "1", "1; 2", "1 + 2", "1 - 2", "2 * 3 + 4", "50 / 2 * 2 + 10 - 5",
+  "5 * (2 + 10)", "-5", "-10 + 20 - -5", "7 % 3", "-7 % 3", "2 ** 10", "2 ** 3 ** 2",
+  // promoted to big integers and back
+  "9223372036854775807 + 1", "2 ** 100 - 2 ** 100 + 1", "-9223372036854775807 - 1",
+  "99999999999999999999 * 0",
+  // booleans and comparisons
+  "true", "false", "!true", "!!5", "1 < 2", "1 > 2", "1 == 1", "1 != 1",
+  "true == true", "(1 < 2) == true", "(1 > 2) != false", "2 ** 64 > 2 ** 63",
+  // conditionals
+  "if (true) { 10 }", "if (false) { 10 }", "if (1) { 10 } else { 20 }",
+  "if (1 > 2) { 10 } else { 20 }", "if (if (false) { 10 }) { 10 } else { 20 }",
+  "if (true) { 1; 2 } else { 3 }", "if (1 < 2) { if (false) { 1 } else { 2 } } + 1",
+  "!if (false) { 5 }",
+  // global bindings
+  "let one = 1; one", "let one = 1; let two = one + one; one + two",
+  "let x = 5; let x = x * 2; x", "const c = 2 ** 70; c - c + 1",
+  "let x = 3; if (x > 2) { x } else { -x }",
+  // a branch's lets are its own
+  "let x = 1; if (true) { let x = 2; }; x", "let x = 1; if (true) { let x = x + 1; x }",
+  "if (true) { let y = 2 }; y", "if (false) { 1 } else { let z = 3; z } + 1",
+  // errors
+  "1 / 0", "5 % 0", "1 + true", "-true", "true > false", "2 ** -1",
+  "1 / 0 + -true", "if (1 / 0) { 1 }", "if (true) { -true; 1 } else { 2 }", "5; 1 / 0; 6",
+}
+
+func TestSharedSuite(t *testing.T) {
+  for _, input := range sharedSuite {
+    program := parser.New(lexer.New(input)).ParseProgram()
+    expected := evaluator.Eval(program, object.NewEnvironment())
+
+    // names are resolved before running, so the vm's error comes from the compiler
+    comp := compiler.New()
+    if err := comp.Compile(program); err != nil {
+      if errObj, ok := expected.(*object.Error); !ok || errObj.Message != err.Error() {
+        t.Errorf("%s: backends differ. eval=%s, compiler error=%s", input, expected.Inspect(), err)
+      }
+      continue
+    }
+    vm := New(comp.Bytecode())
+    if err := vm.Run(); err != nil {
+      t.Fatalf("%s: vm error: %s", input, err)
+    }
+
+    actual := vm.Result()
+    if actual == nil || actual.Type() != expected.Type() || actual.Inspect() != expected.Inspect() {
+      t.Errorf("%s: backends differ. eval=%s, vm=%v", input, expected.Inspect(), actual)
+    }
+  }
+}
+
+func TestStackOverflow(t *testing.T) {
+  // deeper than the stack, each operand waits for the next
+  input := ""
+  for i := 0; i < StackSize; i++ {
+    input += "1 + ("
+  }
+  input += "1"
+  for i := 0; i < StackSize; i++ {
+    input += ")"
+  }
+
+  comp := compiler.New()
+  if err := comp.Compile(parser.New(lexer.New(input)).ParseProgram()); err != nil {
+    t.Fatalf("compiler error: %s", err)
+  }
+  if err := New(comp.Bytecode()).Run(); err == nil || err.Error() != "stack overflow" {
+    t.Errorf("wrong error. want=%q, got=%v", "stack overflow", err)
+  }
+}